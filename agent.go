@@ -0,0 +1,332 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// agentNamePattern matches names accepted by both providers as participant and
+// tool names.
+var agentNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,48}$`)
+
+// Agent is a named participant of a Team: a model with its own instructions and
+// tools, run through a Runner so it can call tools before answering.
+type Agent struct {
+	name         string
+	description  string
+	instructions string
+	model        Model
+	runnerOpts   []RunnerOption
+	chatOpts     []ChatOption
+}
+
+// AgentOption configures an Agent.
+type AgentOption func(a *Agent)
+
+// WithAgentDescription describes what the agent does; supervisors and other
+// agents read it to decide who should act next.
+func WithAgentDescription(description string) AgentOption {
+	return func(a *Agent) { a.description = description }
+}
+
+// WithAgentInstructions sets the system prompt of the agent.
+func WithAgentInstructions(instructions string) AgentOption {
+	return func(a *Agent) { a.instructions = instructions }
+}
+
+// WithAgentRunner configures the Runner of the agent, e.g. with WithRunnerTools.
+func WithAgentRunner(opts ...RunnerOption) AgentOption {
+	return func(a *Agent) { a.runnerOpts = append(a.runnerOpts, opts...) }
+}
+
+// WithAgentChatOptions sets options applied to every model call of the agent.
+func WithAgentChatOptions(opts ...ChatOption) AgentOption {
+	return func(a *Agent) { a.chatOpts = append(a.chatOpts, opts...) }
+}
+
+// NewAgent creates an agent backed by model. The name identifies the agent in
+// the shared conversation and in handoff tools; it fails unless the name
+// consists of 1 to 48 letters, digits, '_' or '-'.
+func NewAgent(name string, model Model, opts ...AgentOption) (*Agent, error) {
+	if !agentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid agent name %q", name)
+	}
+	a := &Agent{name: name, model: model}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// MustNewAgent is like NewAgent but panics on error.
+func MustNewAgent(name string, model Model, opts ...AgentOption) *Agent {
+	a, err := NewAgent(name, model, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Name returns the agent name.
+func (a *Agent) Name() string {
+	return a.name
+}
+
+// Description returns the agent description.
+func (a *Agent) Description() string {
+	return a.description
+}
+
+// Run runs the agent on messages with its instructions and tools.
+func (a *Agent) Run(ctx context.Context, messages []Message, opts ...ChatOption) (*RunResult, error) {
+	return a.run(ctx, messages, nil, opts)
+}
+
+// run runs the agent with extra tools that end the run when called.
+func (a *Agent) run(ctx context.Context, messages []Message, stopTools []Tool, opts []ChatOption) (*RunResult, error) {
+	runnerOpts := a.runnerOpts
+	if len(stopTools) > 0 {
+		names := make([]string, len(stopTools))
+		for i, tool := range stopTools {
+			names[i] = ToolName(tool)
+		}
+		runnerOpts = append(runnerOpts[:len(runnerOpts):len(runnerOpts)], WithRunnerTools(stopTools...), WithStopTools(names...))
+	}
+	all := append([]ChatOption{WithSystemPrompt(a.instructions)}, a.chatOpts...)
+	return NewRunner(a.model, runnerOpts...).Run(ctx, messages, append(all, opts...)...)
+}
+
+// TeamState is what a Router sees when choosing the next agent.
+type TeamState struct {
+	// Agents are the members of the team.
+	Agents []*Agent
+	// Messages is the shared conversation: the input messages followed by the
+	// answer of each turn, attributed to its agent.
+	Messages []Message
+	// Turns are the turns taken so far, in order.
+	Turns []TeamTurn
+}
+
+// TeamTurn is one agent's turn in a Team run.
+type TeamTurn struct {
+	// Agent is the agent that acted.
+	Agent *Agent
+	// Result is the agent's run; its tool calls and results stay private to the agent.
+	Result *RunResult
+	// Handoff is the agent the turn handed over to with a handoff tool, or nil.
+	Handoff *Agent
+}
+
+// Router chooses the agent that acts next, or returns nil to end the run.
+type Router interface {
+	Next(ctx context.Context, state *TeamState) (*Agent, error)
+}
+
+// RouterFunc adapts a function to the Router interface.
+type RouterFunc func(ctx context.Context, state *TeamState) (*Agent, error)
+
+// Next implements Router.
+func (f RouterFunc) Next(ctx context.Context, state *TeamState) (*Agent, error) {
+	return f(ctx, state)
+}
+
+// RoundRobin returns a Router that lets the agents act in order, such as
+// planner, executor and critic, for the given number of rounds.
+func RoundRobin(rounds int) Router {
+	return RouterFunc(func(ctx context.Context, state *TeamState) (*Agent, error) {
+		n := len(state.Turns)
+		if n >= rounds*len(state.Agents) {
+			return nil, nil
+		}
+		return state.Agents[n%len(state.Agents)], nil
+	})
+}
+
+// teamFinish is the label a supervisor picks to end the run.
+const teamFinish = "FINISH"
+
+// Supervisor returns a Router that asks model, after every turn, which agent
+// should act next or whether the task is finished, based on the agent
+// descriptions and the shared conversation (see Classify).
+func Supervisor(model Model, opts ...ChatOption) Router {
+	return RouterFunc(func(ctx context.Context, state *TeamState) (*Agent, error) {
+		labels := make([]string, 0, len(state.Agents)+1)
+		var sb strings.Builder
+		sb.WriteString("You coordinate a team of agents. Decide who should act next, or " + teamFinish +
+			" when the request has been fully answered.\n\nAgents:\n")
+		for _, agent := range state.Agents {
+			labels = append(labels, agent.name)
+			fmt.Fprintf(&sb, "- %s: %s\n", agent.name, agent.description)
+		}
+		labels = append(labels, teamFinish)
+		sb.WriteString("\nConversation:\n" + renderTranscript(state.Messages))
+
+		class, err := Classify(ctx, model, sb.String(), labels, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return state.agent(class.Label), nil
+	})
+}
+
+// handoffRouter is the Router returned by Handoff.
+type handoffRouter struct {
+	start *Agent
+}
+
+// Handoff returns a Router that starts with start and then follows handoffs:
+// every agent is given a transfer_to_<name> tool per other agent, and the run
+// ends when an agent answers without handing over.
+func Handoff(start *Agent) Router {
+	return &handoffRouter{start: start}
+}
+
+// Next implements Router.
+func (r *handoffRouter) Next(ctx context.Context, state *TeamState) (*Agent, error) {
+	if len(state.Turns) == 0 {
+		return r.start, nil
+	}
+	return state.Turns[len(state.Turns)-1].Handoff, nil
+}
+
+// TeamOption configures a Team.
+type TeamOption func(t *Team)
+
+// WithTeamMaxTurns bounds the number of turns of a run (default 10).
+func WithTeamMaxTurns(n int) TeamOption {
+	return func(t *Team) { t.maxTurns = n }
+}
+
+// defaultTeamMaxTurns bounds the number of turns of a Team run.
+const defaultTeamMaxTurns = 10
+
+// Team runs several agents on a shared conversation, with router choosing who
+// acts at each turn. Each agent sees the answers of the others as user messages
+// attributed to them, and its own earlier answers as assistant messages.
+type Team struct {
+	agents   []*Agent
+	router   Router
+	maxTurns int
+}
+
+// NewTeam creates a team of agents coordinated by router.
+func NewTeam(agents []*Agent, router Router, opts ...TeamOption) *Team {
+	t := &Team{agents: agents, router: router, maxTurns: defaultTeamMaxTurns}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// TeamResult is the outcome of Team.Run.
+type TeamResult struct {
+	// Messages is the shared conversation.
+	Messages []Message
+	// Turns are the turns taken, in order.
+	Turns []TeamTurn
+	// Usage is the token usage of all agents.
+	Usage Usage
+}
+
+// Answer returns the text of the last non-empty answer.
+func (r *TeamResult) Answer() string {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if msg := r.Messages[i]; msg.Role() == constants.RoleAssistant && msg.Content() != "" {
+			return msg.Content()
+		}
+	}
+	return ""
+}
+
+// Run runs the team on messages until the router ends it. If the turn limit is
+// reached first, the result so far is returned with ErrMaxIterations. opts are
+// applied to every model call of every agent.
+func (t *Team) Run(ctx context.Context, messages []Message, opts ...ChatOption) (*TeamResult, error) {
+	state := &TeamState{Agents: t.agents, Messages: append([]Message(nil), messages...)}
+	result := &TeamResult{}
+	_, handoffs := t.router.(*handoffRouter)
+
+	for {
+		agent, err := t.router.Next(ctx, state)
+		if err != nil || agent == nil {
+			result.Messages, result.Turns = state.Messages, state.Turns
+			return result, err
+		}
+		if len(state.Turns) >= t.maxTurns {
+			result.Messages, result.Turns = state.Messages, state.Turns
+			return result, ErrMaxIterations
+		}
+
+		var tools []Tool
+		if handoffs {
+			tools = t.handoffTools(agent)
+		}
+		run, err := agent.run(ctx, agentView(agent, state.Messages), tools, opts)
+		if run != nil {
+			result.Usage = result.Usage.Add(run.Usage)
+		}
+		if err != nil {
+			result.Messages, result.Turns = state.Messages, state.Turns
+			return result, fmt.Errorf("agent %s: %w", agent.name, err)
+		}
+
+		turn := TeamTurn{Agent: agent, Result: run}
+		if run.StopCall != nil {
+			turn.Handoff = state.agent(strings.TrimPrefix(run.StopCall.Function().Name(), handoffToolPrefix))
+		}
+		if answer := run.Response.Answer(); answer != nil && answer.Content() != "" {
+			msg := NewAssistantMessage(answer.Content()).(*llmmsg)
+			msg.name = agent.name
+			state.Messages = append(state.Messages, msg)
+		}
+		state.Turns = append(state.Turns, turn)
+	}
+}
+
+// handoffToolPrefix starts the name of every handoff tool.
+const handoffToolPrefix = "transfer_to_"
+
+// handoffTools returns a tool per other agent that hands the conversation over to it.
+func (t *Team) handoffTools(from *Agent) []Tool {
+	var tools []Tool
+	for _, agent := range t.agents {
+		if agent == from {
+			continue
+		}
+		target := agent.name
+		tools = append(tools, DefineTypedFunction(handoffToolPrefix+target,
+			fmt.Sprintf("Hand the conversation over to %s: %s", target, agent.description),
+			func(ctx context.Context, args struct{}) (string, error) {
+				return "Transferred to " + target + ".", nil
+			}))
+	}
+	return tools
+}
+
+// agent returns the member named name, or nil.
+func (s *TeamState) agent(name string) *Agent {
+	for _, agent := range s.Agents {
+		if agent.name == name {
+			return agent
+		}
+	}
+	return nil
+}
+
+// agentView returns the shared conversation as agent sees it: its own answers
+// as assistant messages and those of other agents as attributed user messages.
+func agentView(agent *Agent, messages []Message) []Message {
+	view := make([]Message, len(messages))
+	for i, msg := range messages {
+		view[i] = msg
+		rich, ok := msg.(*llmmsg)
+		if ok && msg.Role() == constants.RoleAssistant && rich.name != "" && rich.name != agent.name {
+			view[i] = NewUserMessage(msg.Content(), WithParticipantName(rich.name))
+		}
+	}
+	return view
+}
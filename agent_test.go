@@ -0,0 +1,12 @@
+package openllm
+
+import "testing"
+
+func TestNewAgentName(t *testing.T) {
+	if _, err := NewAgent("billing team", NewEchoModel()); err == nil {
+		t.Error("NewAgent accepted a name with a space")
+	}
+	if a, err := NewAgent("billing", NewEchoModel()); err != nil || a.Name() != "billing" {
+		t.Errorf("NewAgent = %v, %v", a, err)
+	}
+}
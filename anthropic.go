@@ -5,7 +5,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
@@ -15,6 +17,9 @@ import (
 	"github.com/thecxx/openllm/constants"
 )
 
+// anthropicBetaFineGrainedToolStreaming is the beta flag that streams tool input JSON without buffering.
+const anthropicBetaFineGrainedToolStreaming = "fine-grained-tool-streaming-2025-05-14"
+
 type anthropicLLM struct {
 	name        string
 	description string
@@ -27,9 +32,23 @@ func NewAnthropicLLM(name, description string, client *anthropic.Client) Model {
 }
 
 // NewAnthropicLLMWithAPIKey creates a new Model implementation with an API key.
-func NewAnthropicLLMWithAPIKey(name, description, apiKey string) Model {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
-	return &anthropicLLM{name: name, description: description, client: &client}
+// Client options can set the HTTP client, proxy and endpoint.
+func NewAnthropicLLMWithAPIKey(name, description, apiKey string, opts ...ClientOption) Model {
+	return &anthropicLLM{name: name, description: description, client: newAnthropicClient(apiKey, opts)}
+}
+
+// newAnthropicClient builds an Anthropic client with the client options applied.
+func newAnthropicClient(apiKey string, opts []ClientOption) *anthropic.Client {
+	options := newClientOptions(opts)
+	reqOpts := []option.RequestOption{option.WithAPIKey(apiKey)}
+	if options.httpClient != nil {
+		reqOpts = append(reqOpts, option.WithHTTPClient(options.httpClient))
+	}
+	if options.baseURL != "" {
+		reqOpts = append(reqOpts, option.WithBaseURL(options.baseURL))
+	}
+	client := anthropic.NewClient(reqOpts...)
+	return &client
 }
 
 // Name returns the model identifier string.
@@ -46,11 +65,19 @@ func (a *anthropicLLM) Description() string {
 // It builds the request from messages and options, executes the call,
 // and returns the final assistant message together with any tool-calls.
 func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, a.name, false, messages)
+	defer func() { end(resp, err) }()
+
 	options := &ChatOptions{}
 	// Set chat options
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.history != nil {
+		if messages, err = options.history.Trim(ctx, a, messages, opts...); err != nil {
+			return nil, err
+		}
+	}
 
 	req, err := a.makeRequest(options, messages)
 	if err != nil {
@@ -58,9 +85,11 @@ func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, o
 	}
 
 	start := time.Now()
-	chatResp, err := a.client.Messages.New(ctx, req)
+	var httpResp *http.Response
+	reqOpts := append(anthropicRequestOptions(options), option.WithResponseInto(&httpResp))
+	chatResp, err := a.client.Messages.New(ctx, req, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, normalizeProviderError(constants.ProviderAnthropic, cancelCause(ctx, err))
 	}
 
 	// Defensive: ensure we have at least one content block
@@ -68,15 +97,70 @@ func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, o
 		return nil, ErrEmptyChoices
 	}
 
-	var content strings.Builder
+	answer, err := convertAnthropicAnswer(chatResp.Content)
+	if err != nil {
+		return nil, err
+	}
+	if name := anthropicSchemaTool(options); name != "" {
+		moveSchemaToolCall(answer, name)
+	}
+	if prefix := anthropicAnswerPrefix(options, req); prefix != "" {
+		// The prefill gets a part of its own, so the generated parts stay intact
+		part := ContentPart{Type: constants.ContentPartTypeText, Text: prefix}
+		if len(answer.content) == 1 && answer.content[0].Type == constants.ContentPartTypeText && answer.content[0].Text == "" {
+			answer.content[0] = part
+		} else {
+			answer.content = append([]ContentPart{part}, answer.content...)
+		}
+	}
+	usage := convertAnthropicUsage(chatResp.Usage)
+	duration := time.Since(start)
+	meta := Meta{
+		Provider:     constants.ProviderAnthropic,
+		Model:        a.name,
+		RequestID:    chatResp.ID,
+		StopReason:   string(chatResp.StopReason),
+		FinishReason: finishReasonOf(string(chatResp.StopReason), false),
+		Latency:      blockingLatency(usage, duration),
+	}
+	if httpResp != nil {
+		meta.RateLimit = parseAnthropicRateLimit(httpResp.Header)
+	}
+	usage = usage.withCost(meta.Model)
+
+	resp = &response{
+		answer:   answer,
+		tcalls:   answer.ToolCalls(),
+		usage:    usage,
+		duration: duration,
+		meta:     meta,
+	}
+	return resp, validateAnswer(options, resp)
+}
+
+// convertAnthropicAnswer converts the content blocks of a response into the unified message.
+func convertAnthropicAnswer(content []anthropic.ContentBlockUnion) (*llmmsg, error) {
+	answer := &llmmsg{role: constants.RoleAssistant}
 	var reasoning strings.Builder
-	var tcalls []ToolCall
-	var toolCallIndex int
 
-	for _, block := range chatResp.Content {
+	for _, block := range content {
 		switch b := block.AsAny().(type) {
 		case anthropic.TextBlock:
-			content.WriteString(b.Text)
+			var citations []Citation
+			for _, c := range b.Citations {
+				citations = append(citations, convertAnthropicCitation(c.RawJSON()))
+			}
+			// Consecutive text blocks without citations are merged into a single part
+			if n := len(answer.content); n > 0 && answer.content[n-1].Type == constants.ContentPartTypeText &&
+				len(answer.content[n-1].Citations) == 0 && len(citations) == 0 {
+				answer.content[n-1].Text += b.Text
+			} else {
+				answer.content = append(answer.content, ContentPart{Type: constants.ContentPartTypeText, Text: b.Text, Citations: citations})
+			}
+		case anthropic.ServerToolUseBlock:
+			answer.content = append(answer.content, convertServerToolUse(b.ID, string(b.Name), b.Input))
+		case anthropic.WebSearchToolResultBlock:
+			answer.content = append(answer.content, convertWebSearchResult(b))
 		case anthropic.ThinkingBlock:
 			reasoning.WriteString(b.Thinking)
 		case anthropic.ToolUseBlock:
@@ -84,8 +168,8 @@ func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, o
 			if err != nil {
 				return nil, err
 			}
-			tcalls = append(tcalls, &toolcall{
-				index: toolCallIndex,
+			answer.toolCalls = append(answer.toolCalls, &toolcall{
+				index: len(answer.toolCalls),
 				id:    b.ID,
 				type_: constants.ToolTypeFunction,
 				fcall: funcall{
@@ -93,51 +177,25 @@ func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, o
 					args: string(argsJSON),
 				},
 			})
-			toolCallIndex++
 		}
 	}
 
-	// Create anthropic message wrapper
-	answer := &llmmsg{
-		role:      constants.RoleAssistant,
-		content:   []ContentPart{{Type: constants.ContentPartTypeText, Text: content.String()}},
-		reasoning: reasoning.String(),
-		toolCalls: func() []*toolcall {
-			if len(tcalls) == 0 {
-				return nil
-			}
-			var gtc []*toolcall
-			for _, tc := range tcalls {
-				if tc, ok := tc.(*toolcall); ok {
-					gtc = append(gtc, tc)
-				}
-			}
-			return gtc
-		}(),
+	if len(answer.content) == 0 {
+		answer.content = []ContentPart{{Type: constants.ContentPartTypeText}}
 	}
+	answer.reasoning = reasoning.String()
+	return answer, nil
+}
 
-	usage := Usage{
-		InputTokens:              int(chatResp.Usage.InputTokens),
-		OutputTokens:             int(chatResp.Usage.OutputTokens),
-		TotalTokens:              int(chatResp.Usage.InputTokens + chatResp.Usage.OutputTokens),
-		CacheCreationInputTokens: int(chatResp.Usage.CacheCreationInputTokens),
-		CacheReadInputTokens:     int(chatResp.Usage.CacheReadInputTokens),
+// convertAnthropicUsage maps Anthropic usage statistics to the unified Usage structure.
+func convertAnthropicUsage(u anthropic.Usage) Usage {
+	return Usage{
+		InputTokens:              int(u.InputTokens),
+		OutputTokens:             int(u.OutputTokens),
+		TotalTokens:              int(u.InputTokens + u.OutputTokens),
+		CacheCreationInputTokens: int(u.CacheCreationInputTokens),
+		CacheReadInputTokens:     int(u.CacheReadInputTokens),
 	}
-	duration := time.Since(start)
-	meta := Meta{
-		Provider:   constants.ProviderAnthropic,
-		Model:      a.name,
-		RequestID:  chatResp.ID,
-		StopReason: string(chatResp.StopReason),
-	}
-
-	return &response{
-		answer:   answer,
-		tcalls:   tcalls,
-		usage:    usage,
-		duration: duration,
-		meta:     meta,
-	}, nil
 }
 
 // ChatCompletionStream performs a streaming chat completion request.
@@ -145,41 +203,110 @@ func (a *anthropicLLM) ChatCompletion(ctx context.Context, messages []Message, o
 // collects streamed tool-call arguments, and returns the assembled answer
 // and ordered tool-calls once the stream finishes.
 func (a *anthropicLLM) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, a.name, true, messages)
+	defer func() { end(resp, err) }()
+
 	options := &ChatOptions{}
 	// Set chat options
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.history != nil {
+		if messages, err = options.history.Trim(ctx, a, messages, opts...); err != nil {
+			return nil, err
+		}
+	}
 
 	req, err := a.makeRequest(options, messages)
 	if err != nil {
 		return nil, err
 	}
 
-	start := time.Now()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	stream := a.client.Messages.NewStreaming(ctx, req)
-
-	var (
-		role      string
-		content   strings.Builder
-		reasoning strings.Builder
-		callm     = make(map[int]*toolcall)
-	)
+	acc := newStreamAccumulator(options, Meta{Provider: constants.ProviderAnthropic, Model: a.name})
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	idle := startIdleTimer(options.streamIdleTimeout, cancel)
+	defer idle.stop()
+
+	// Server tool inputs keyed by content block index
+	serverTools := make(map[int]*serverToolInput)
+	// Citations of text blocks keyed by content block index, with the block's start offset
+	citations := make(map[int][]Citation)
+	textStart := make(map[int]int)
+	// Content block carrying the structured answer, if any
+	schemaTool, schemaBlock := anthropicSchemaTool(options), -1
+
+	var httpResp *http.Response
+	reqOpts := append(anthropicRequestOptions(options), option.WithResponseInto(&httpResp))
+	stream := a.client.Messages.NewStreaming(ctx, req, reqOpts...)
+	defer stream.Close()
+	if httpResp != nil {
+		acc.meta.RateLimit = parseAnthropicRateLimit(httpResp.Header)
+	}
 
 	for stream.Next() {
+		select {
+		case <-ctx.Done():
+			return acc.fail(context.Cause(ctx))
+		default:
+		}
+		idle.reset()
 		event := stream.Current()
 
+		if err := acc.onRawEvent(event); err != nil {
+			return acc.fail(err)
+		}
+
 		switch ev := event.AsAny().(type) {
 		case anthropic.MessageStartEvent:
 			if ev.Message.Role != "" {
-				role = constants.RoleAssistant
+				acc.setRole(constants.RoleAssistant)
+			}
+			acc.meta.RequestID = ev.Message.ID
+			if err := acc.onMeta(); err != nil {
+				return acc.fail(err)
+			}
+			if err := acc.onUsage(convertAnthropicUsage(ev.Message.Usage)); err != nil {
+				return acc.fail(err)
+			}
+			if prefix := anthropicAnswerPrefix(options, req); prefix != "" {
+				if err := acc.onPrefill(prefix); err != nil {
+					return acc.fail(err)
+				}
+			}
+		case anthropic.MessageDeltaEvent:
+			// message_delta carries cumulative usage; input counts may be omitted (zero)
+			usage := acc.usage
+			if ev.Usage.InputTokens > 0 {
+				usage.InputTokens = int(ev.Usage.InputTokens)
+			}
+			if ev.Usage.CacheCreationInputTokens > 0 {
+				usage.CacheCreationInputTokens = int(ev.Usage.CacheCreationInputTokens)
+			}
+			if ev.Usage.CacheReadInputTokens > 0 {
+				usage.CacheReadInputTokens = int(ev.Usage.CacheReadInputTokens)
+			}
+			usage.OutputTokens = int(ev.Usage.OutputTokens)
+			usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+			if err := acc.onUsage(usage); err != nil {
+				return acc.fail(err)
+			}
+			if ev.Delta.StopReason != "" {
+				acc.meta.StopReason = string(ev.Delta.StopReason)
+				acc.meta.FinishReason = finishReasonOf(acc.meta.StopReason, false)
+				if err := acc.onMeta(); err != nil {
+					return acc.fail(err)
+				}
 			}
 		case anthropic.ContentBlockStartEvent:
 			switch cb := ev.ContentBlock.AsAny().(type) {
 			case anthropic.ToolUseBlock:
+				if cb.Name == schemaTool && schemaTool != "" {
+					// The structured answer streams as content
+					schemaBlock = int(ev.Index)
+					continue
+				}
 				tcall := &toolcall{
 					index: int(ev.Index),
 					id:    cb.ID,
@@ -188,98 +315,90 @@ func (a *anthropicLLM) ChatCompletionStream(ctx context.Context, messages []Mess
 						name: cb.Name,
 					},
 				}
-				if options.watcher != nil {
-					if err := options.watcher.OnToolCall(ctx, tcall, ""); err != nil {
-						return nil, err
-					}
+				if err := acc.onToolCallStart(ctx, tcall); err != nil {
+					return acc.fail(err)
 				}
-				callm[int(ev.Index)] = tcall
+			case anthropic.TextBlock:
+				textStart[int(ev.Index)] = acc.content.Len()
+			case anthropic.ServerToolUseBlock:
+				serverTools[int(ev.Index)] = &serverToolInput{id: cb.ID, name: string(cb.Name)}
+			case anthropic.WebSearchToolResultBlock:
+				acc.addPart(convertWebSearchResult(cb))
 			}
 		case anthropic.ContentBlockDeltaEvent:
 			switch d := ev.Delta.AsAny().(type) {
 			case anthropic.TextDelta:
-				if options.watcher != nil {
-					if err := options.watcher.OnContent(d.Text); err != nil {
-						return nil, err
-					}
+				if err := acc.onContent(d.Text); err != nil {
+					return acc.fail(err)
 				}
-				content.WriteString(d.Text)
+			case anthropic.CitationsDelta:
+				citations[int(ev.Index)] = append(citations[int(ev.Index)], convertAnthropicCitation(d.Citation.RawJSON()))
 			case anthropic.ThinkingDelta:
-				if options.watcher != nil {
-					if err := options.watcher.OnReasoning(d.Thinking); err != nil {
-						return nil, err
-					}
+				if err := acc.onReasoning(d.Thinking); err != nil {
+					return acc.fail(err)
 				}
-				reasoning.WriteString(d.Thinking)
 			case anthropic.InputJSONDelta:
-				if tcall, found := callm[int(ev.Index)]; found {
-					if options.watcher != nil {
-						if err := options.watcher.OnToolCall(ctx, tcall, d.PartialJSON); err != nil {
-							return nil, err
-						}
+				if int(ev.Index) == schemaBlock {
+					if err := acc.onContent(d.PartialJSON); err != nil {
+						return acc.fail(err)
 					}
-					tcall.fcall.writeArgs(d.PartialJSON)
+					continue
+				}
+				if st, ok := serverTools[int(ev.Index)]; ok {
+					st.input.WriteString(d.PartialJSON)
+					continue
+				}
+				if err := acc.onToolCallArgs(ctx, int(ev.Index), d.PartialJSON); err != nil {
+					return acc.fail(err)
 				}
 			}
+		case anthropic.ContentBlockStopEvent:
+			if start, ok := textStart[int(ev.Index)]; ok {
+				acc.addCitations(start, citations[int(ev.Index)])
+				delete(textStart, int(ev.Index))
+			}
+			if int(ev.Index) == schemaBlock {
+				continue
+			}
+			if st, ok := serverTools[int(ev.Index)]; ok {
+				acc.addPart(st.part())
+				delete(serverTools, int(ev.Index))
+				continue
+			}
+			if err := acc.onToolCallDone(ctx, int(ev.Index)); err != nil {
+				return acc.fail(err)
+			}
 		}
 	}
 
 	if err := stream.Err(); err != nil {
 		if !errors.Is(err, io.EOF) {
-			return nil, err
-		}
-	}
-
-	if options.watcher != nil {
-		if err := options.watcher.OnStop(); err != nil {
-			return nil, err
+			return acc.fail(normalizeProviderError(constants.ProviderAnthropic, cancelCause(ctx, err)))
 		}
 	}
 
-	var tcalls = make([]ToolCall, 0)
-	if len(callm) > 0 {
-		for _, tcall := range callm {
-			tcalls = append(tcalls, tcall)
-		}
-		sort.Slice(tcalls, func(i, j int) bool {
-			return tcalls[i].Index() < tcalls[j].Index()
-		})
+	if err := acc.finishToolCalls(ctx); err != nil {
+		return acc.fail(err)
 	}
 
-	answer := &llmmsg{
-		role:      role,
-		content:   []ContentPart{{Type: constants.ContentPartTypeText, Text: content.String()}},
-		reasoning: reasoning.String(),
-		toolCalls: func() []*toolcall {
-			if len(tcalls) == 0 {
-				return nil
-			}
-			var gtc []*toolcall
-			for _, tc := range tcalls {
-				if tc, ok := tc.(*toolcall); ok {
-					gtc = append(gtc, tc)
-				}
-			}
-			return gtc
-		}(),
+	if err := acc.onStop(); err != nil {
+		return acc.fail(err)
 	}
 
-	return &response{
-		answer:   answer,
-		tcalls:   tcalls,
-		usage:    Usage{},
-		duration: time.Since(start),
-		meta: Meta{
-			Provider: constants.ProviderAnthropic,
-			Model:    a.name,
-		},
-	}, nil
+	return acc.result()
 }
 
 // makeRequest builds an Anthropic MessageNewParams from ChatOptions and Message list.
 // It converts messages to the Anthropic format, applies system prompt and temperature,
 // and attaches tool definitions when provided.
 func (a *anthropicLLM) makeRequest(opts *ChatOptions, messages []Message) (req anthropic.MessageNewParams, err error) {
+	// Option: ValidateMessages
+	if opts.validateMessages {
+		if err := ValidateMessages(constants.ProviderAnthropic, messages); err != nil {
+			return req, err
+		}
+	}
+
 	req.Model = anthropic.Model(a.name)
 	req.MaxTokens = int64(4096) // Default max tokens
 
@@ -343,15 +462,21 @@ func (a *anthropicLLM) makeRequest(opts *ChatOptions, messages []Message) (req a
 	}
 
 	// Set system prompt
-	if opts.prompt != "" {
-		req.System = []anthropic.TextBlockParam{
-			{Text: opts.prompt},
-		}
+	prompts, messages := opts.redact(messages)
+	for _, prompt := range prompts {
+		req.System = append(req.System, anthropic.TextBlockParam{Text: prompt})
 	}
 
 	// Convert messages
 	var anthropicMessages []anthropic.MessageParam
 	for _, message := range messages {
+		// Anthropic takes system prompts separately; hoist them after the option prompts
+		if message.Role() == constants.RoleSystem {
+			if content := message.Content(); content != "" {
+				req.System = append(req.System, anthropic.TextBlockParam{Text: content})
+			}
+			continue
+		}
 		msgParam, err := a.convertMessage(message)
 		if err != nil {
 			return req, err
@@ -359,69 +484,282 @@ func (a *anthropicLLM) makeRequest(opts *ChatOptions, messages []Message) (req a
 		anthropicMessages = append(anthropicMessages, msgParam)
 	}
 
-	req.Messages = anthropicMessages
+	req.Messages = normalizeAnthropicMessages(anthropicMessages)
 
-	for _, tool := range opts.tools {
-		var toolParam anthropic.ToolParam
-		if def, ok := tool.Definition().(anthropic.ToolParam); ok {
-			toolParam = def
-		} else if def, ok := tool.Definition().(*FunctionDefinition); ok {
-			// Convert generic FunctionDefinition to Anthropic ToolParam
-			toolParam = anthropic.ToolParam{
-				Name:        def.Name,
-				Description: anthropic.String(def.Description),
-				Strict:      anthropic.Bool(def.Strict),
-			}
+	// Option: Constraint; only schemas can be emulated
+	if c := opts.constraint; c != nil && c.kind != constants.ConstraintJSONSchema {
+		return req, c.unsupported(constants.ProviderAnthropic)
+	}
 
-			// Handle InputSchema conversion from generic Parameters
-			if schema, ok := def.Parameters.(anthropic.ToolInputSchemaParam); ok {
-				toolParam.InputSchema = schema
-			} else {
-				// Default minimal valid schema
-				toolParam.InputSchema = anthropic.ToolInputSchemaParam{
-					Type:       "object",
-					Properties: map[string]any{},
-				}
+	// Option: ResponseFormat, emulated with an instruction, a prefill and a stop sequence
+	if opts.responseFormat == constants.ResponseFormatJSONObject {
+		req.System = append(req.System, anthropic.TextBlockParam{Text: anthropicJSONInstruction})
+		req.StopSequences = append(req.StopSequences, anthropicJSONStop)
+		if req.Thinking.OfEnabled == nil && anthropicPrefill(req) == "" {
+			req.Messages = append(req.Messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock("{")))
+		}
+	}
 
-				// Try JSON round-trip for complex structures or jsonschema.Definition
-				if def.Parameters != nil {
-					data, err := json.Marshal(def.Parameters)
-					if err == nil {
-						var inputSchema anthropic.ToolInputSchemaParam
-						if err := json.Unmarshal(data, &inputSchema); err == nil && inputSchema.Type != "" {
-							toolParam.InputSchema = inputSchema
-						}
-					}
+	for _, tool := range opts.filterTools(opts.tools) {
+		if t, ok := convertAnthropicTool(tool); ok {
+			req.Tools = append(req.Tools, t)
+		}
+	}
+
+	// ToolSet conversions are cached until the set changes
+	if opts.toolset != nil {
+		convert := func(tools []Tool) any {
+			var out []anthropic.ToolUnionParam
+			for _, tool := range tools {
+				if t, ok := convertAnthropicTool(tool); ok {
+					out = append(out, t)
 				}
 			}
+			return out
+		}
+		var converted any
+		if opts.toolFilter != nil {
+			// Filtered views differ per request and bypass the cache
+			converted = convert(opts.filterTools(opts.toolset.Tools()))
+		} else {
+			converted = opts.toolset.converted(constants.ProviderAnthropic, convert)
+		}
+		req.Tools = append(req.Tools, converted.([]anthropic.ToolUnionParam)...)
+	}
+
+	// Option: ResponseFormat, structured output emulated with a forced tool call
+	if rs := opts.responseSchema; opts.responseFormat == constants.ResponseFormatJSONSchema && rs != nil {
+		tool, err := rs.tool()
+		if err != nil {
+			return req, err
+		}
+		t, _ := convertAnthropicTool(tool)
+		req.Tools = append(req.Tools, t)
+		if req.Thinking.OfEnabled == nil {
+			req.ToolChoice = anthropic.ToolChoiceParamOfTool(rs.name)
+		} else {
+			// Thinking only allows automatic tool choice
+			req.System = append(req.System, anthropic.TextBlockParam{
+				Text: fmt.Sprintf("Call the %s tool exactly once with your final answer.", rs.name),
+			})
+		}
+	}
+
+	return req, nil
+}
+
+// anthropicSchemaTool returns the name of the tool that carries the structured
+// answer, or "" when no response schema is set.
+func anthropicSchemaTool(opts *ChatOptions) string {
+	if opts.responseFormat != constants.ResponseFormatJSONSchema || opts.responseSchema == nil {
+		return ""
+	}
+	return opts.responseSchema.name
+}
+
+// moveSchemaToolCall turns the call of the structured output tool into the answer text.
+func moveSchemaToolCall(answer *llmmsg, name string) {
+	for i, tc := range answer.toolCalls {
+		if tc.fcall.name != name {
+			continue
+		}
+		answer.toolCalls = append(answer.toolCalls[:i], answer.toolCalls[i+1:]...)
+		if len(answer.toolCalls) == 0 {
+			answer.toolCalls = nil
+		}
+		text := ContentPart{Type: constants.ContentPartTypeText, Text: tc.fcall.args}
+		if n := len(answer.content); n == 1 && answer.content[0].Type == constants.ContentPartTypeText && answer.content[0].Text == "" {
+			answer.content[0] = text
 		} else {
-			// Try full JSON round-trip conversion for unknown types
-			data, err := json.Marshal(tool.Definition())
-			if err == nil {
-				var tp anthropic.ToolParam
-				if err := json.Unmarshal(data, &tp); err == nil {
-					toolParam = tp
+			answer.content = append(answer.content, text)
+		}
+		return
+	}
+}
+
+const (
+	// anthropicJSONInstruction asks for bare JSON when emulating JSON mode.
+	anthropicJSONInstruction = "Respond with a single valid JSON object only, without markdown code fences or any other text."
+	// anthropicJSONStop ends generation at a closing code fence. A raw newline cannot
+	// occur inside a JSON string, so it never cuts a valid object short.
+	anthropicJSONStop = "\n```"
+)
+
+// anthropicAnswerPrefix returns the prefill to restore in front of the answer when
+// JSON mode is emulated, so the answer holds the complete object.
+func anthropicAnswerPrefix(opts *ChatOptions, req anthropic.MessageNewParams) string {
+	if opts.responseFormat != constants.ResponseFormatJSONObject {
+		return ""
+	}
+	return anthropicPrefill(req)
+}
+
+// anthropicPrefill returns the text of a trailing assistant message in req, which
+// Anthropic continues rather than repeats.
+func anthropicPrefill(req anthropic.MessageNewParams) string {
+	n := len(req.Messages)
+	if n == 0 || req.Messages[n-1].Role != anthropic.MessageParamRoleAssistant {
+		return ""
+	}
+	var sb strings.Builder
+	for _, block := range req.Messages[n-1].Content {
+		if block.OfText != nil {
+			sb.WriteString(block.OfText.Text)
+		}
+	}
+	return sb.String()
+}
+
+// anthropicPlaceholderText fills a user turn that Anthropic requires but the history lacks.
+const anthropicPlaceholderText = "..."
+
+// normalizeAnthropicMessages makes a history acceptable to Anthropic, which requires
+// strictly alternating roles starting with the user and non-empty text blocks.
+// Empty text blocks are dropped, consecutive messages of the same role are merged
+// (tool results first, as Anthropic expects), and a placeholder user turn is
+// inserted when the history starts with the assistant.
+func normalizeAnthropicMessages(messages []anthropic.MessageParam) []anthropic.MessageParam {
+	var out []anthropic.MessageParam
+	for _, message := range messages {
+		var blocks []anthropic.ContentBlockParamUnion
+		for _, block := range message.Content {
+			if block.OfText != nil && block.OfText.Text == "" {
+				continue
+			}
+			blocks = append(blocks, block)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Role == message.Role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			continue
+		}
+		out = append(out, anthropic.MessageParam{Role: message.Role, Content: blocks})
+	}
+
+	for i := range out {
+		if out[i].Role == anthropic.MessageParamRoleUser {
+			sort.SliceStable(out[i].Content, func(a, b int) bool {
+				return out[i].Content[a].OfToolResult != nil && out[i].Content[b].OfToolResult == nil
+			})
+		}
+	}
+
+	if len(out) > 0 && out[0].Role != anthropic.MessageParamRoleUser {
+		placeholder := anthropic.NewUserMessage(anthropic.NewTextBlock(anthropicPlaceholderText))
+		out = append([]anthropic.MessageParam{placeholder}, out...)
+	}
+	return out
+}
+
+// convertAnthropicTool converts a Tool into Anthropic's tool format.
+// It reports false when the definition cannot be interpreted as a tool.
+func convertAnthropicTool(tool Tool) (anthropic.ToolUnionParam, bool) {
+	if t, ok := convertAnthropicServerTool(tool); ok {
+		return t, true
+	}
+	if tool.Type() == constants.ToolTypeComputer {
+		// Beta-only tool, sent via anthropicRequestOptions
+		return anthropic.ToolUnionParam{}, false
+	}
+
+	var toolParam anthropic.ToolParam
+	if def, ok := tool.Definition().(anthropic.ToolParam); ok {
+		toolParam = def
+	} else if def, ok := tool.Definition().(*FunctionDefinition); ok {
+		// Convert generic FunctionDefinition to Anthropic ToolParam
+		toolParam = anthropic.ToolParam{
+			Name:        def.Name,
+			Description: anthropic.String(def.Description),
+			Strict:      anthropic.Bool(def.Strict),
+		}
+
+		// Handle InputSchema conversion from generic Parameters
+		if schema, ok := def.Parameters.(anthropic.ToolInputSchemaParam); ok {
+			toolParam.InputSchema = schema
+		} else {
+			// Default minimal valid schema
+			toolParam.InputSchema = anthropic.ToolInputSchemaParam{
+				Type:       "object",
+				Properties: map[string]any{},
+			}
+
+			// Try JSON round-trip for raw schemas, jsonschema.Definition or *Schema
+			if def.Parameters != nil {
+				if data, err := json.Marshal(def.Parameters); err == nil {
+					toolParam.InputSchema = convertRawInputSchema(data)
 				}
 			}
 		}
+	} else {
+		// Try full JSON round-trip conversion for unknown types
+		data, err := json.Marshal(tool.Definition())
+		if err == nil {
+			var tp anthropic.ToolParam
+			if err := json.Unmarshal(data, &tp); err == nil {
+				toolParam = tp
+			}
+		}
+	}
+
+	if toolParam.Name == "" {
+		return anthropic.ToolUnionParam{}, false
+	}
+	return anthropic.ToolUnionParam{OfTool: &toolParam}, true
+}
 
-		if toolParam.Name != "" {
-			req.Tools = append(req.Tools, anthropic.ToolUnionParam{OfTool: &toolParam})
+// convertRawInputSchema converts a raw JSON Schema into Anthropic's input schema,
+// keeping keywords other than properties and required (e.g. $defs) as extra fields.
+func convertRawInputSchema(raw json.RawMessage) anthropic.ToolInputSchemaParam {
+	schema := anthropic.ToolInputSchemaParam{Properties: map[string]any{}}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return schema
+	}
+	for key, value := range root {
+		switch key {
+		case "type":
+		case "properties":
+			schema.Properties = value
+		case "required":
+			if list, ok := value.([]any); ok {
+				for _, item := range list {
+					if name, ok := item.(string); ok {
+						schema.Required = append(schema.Required, name)
+					}
+				}
+			}
+		default:
+			if schema.ExtraFields == nil {
+				schema.ExtraFields = make(map[string]any)
+			}
+			schema.ExtraFields[key] = value
 		}
 	}
+	return schema
+}
 
-	return req, nil
+// convertAnthropicDocument maps a Document to a document block.
+// Anthropic accepts PDFs (inline or by URL) and plain text.
+func convertAnthropicDocument(doc *Document) (anthropic.ContentBlockParamUnion, error) {
+	switch {
+	case doc.URL != "":
+		return anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: doc.URL}), nil
+	case doc.MIMEType == "application/pdf":
+		return anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{
+			Data: base64.StdEncoding.EncodeToString(doc.Data),
+		}), nil
+	case strings.HasPrefix(doc.MIMEType, "text/"):
+		return anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(doc.Data)}), nil
+	}
+	return anthropic.ContentBlockParamUnion{}, fmt.Errorf("%w: document of type %q for Anthropic", ErrUnsupportedContent, doc.MIMEType)
 }
 
 // convertMessage transforms the unified Message (llmmsg) into Anthropic's MessageParam.
 // It handles role mapping, content blocks, image conversion, and tool calls.
 func (a *anthropicLLM) convertMessage(message Message) (anthropic.MessageParam, error) {
-	// Cast to llmmsg to access internal structure
-	msg, ok := message.(*llmmsg)
-	if !ok {
-		// Fallback for custom implementations (should ideally not happen with global factories)
-		return anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content())), nil
-	}
+	msg := asLLMMessage(message)
 
 	role := msg.role
 
@@ -430,59 +768,53 @@ func (a *anthropicLLM) convertMessage(message Message) (anthropic.MessageParam,
 		return anthropic.NewUserMessage(anthropic.NewToolResultBlock(
 			msg.toolCallID,
 			message.Content(),
-			false, // isError
+			msg.isError,
 		)), nil
 	}
 
 	// Handle standard roles (user, assistant)
 	var blocks []anthropic.ContentBlockParamUnion
 
+	// Anthropic has no participant name; attribute the first text part instead
+	speaker := ""
+	if msg.name != "" {
+		speaker = msg.name + ": "
+	}
+
 	// 1. Process MultiContent (Images + Text) or standard Content
 	if len(msg.content) > 0 {
 		for _, part := range msg.content {
 			switch part.Type {
 			case constants.ContentPartTypeText:
-				blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+				text := anthropic.TextBlockParam{Text: speaker + part.Text}
+				for _, c := range part.Citations {
+					if citation, ok := convertAnthropicCitationParam(c); ok {
+						text.Citations = append(text.Citations, citation)
+					}
+				}
+				blocks = append(blocks, anthropic.ContentBlockParamUnion{OfText: &text})
+				speaker = ""
+			case constants.ContentPartTypeDocument:
+				if part.Document == nil {
+					continue
+				}
+				block, err := convertAnthropicDocument(part.Document)
+				if err != nil {
+					return anthropic.MessageParam{}, err
+				}
+				blocks = append(blocks, block)
+			case constants.ContentPartTypeVideo:
+				return anthropic.MessageParam{}, fmt.Errorf("%w: video input for Anthropic", ErrUnsupportedContent)
+			case constants.ContentPartTypeServerToolUse, constants.ContentPartTypeWebSearchResult:
+				if block, ok := convertServerToolParts(part); ok {
+					blocks = append(blocks, block)
+				}
 			case constants.ContentPartTypeImageURL:
 				if part.ImageURL == nil {
 					continue
 				}
 				imgURL := part.ImageURL.URL
-
-				// Image conversion logic (URL vs Base64)
-				mediaType := "image/jpeg"
-				data := imgURL
-				isURL := false
-
-				if strings.HasPrefix(imgURL, "http://") || strings.HasPrefix(imgURL, "https://") {
-					isURL = true
-				} else if idx := strings.Index(imgURL, ";base64,"); idx != -1 {
-					prefix := imgURL[:idx]
-					if strings.HasPrefix(prefix, "data:") {
-						mediaType = strings.TrimPrefix(prefix, "data:")
-					}
-					data = imgURL[idx+len(";base64,"):]
-				} else {
-					// Magic number detection for raw base64
-					if len(data) > 15 {
-						prefixData := data
-						if len(prefixData) > 64 {
-							prefixData = prefixData[:64]
-						}
-						decoded, err := base64.StdEncoding.DecodeString(prefixData)
-						if err == nil && len(decoded) > 4 {
-							if len(decoded) >= 8 && string(decoded[0:8]) == "\x89PNG\r\n\x1a\n" {
-								mediaType = "image/png"
-							} else if len(decoded) >= 3 && string(decoded[0:3]) == "\xff\xd8\xff" {
-								mediaType = "image/jpeg"
-							} else if len(decoded) >= 6 && (string(decoded[0:6]) == "GIF87a" || string(decoded[0:6]) == "GIF89a") {
-								mediaType = "image/gif"
-							} else if len(decoded) >= 12 && string(decoded[0:4]) == "RIFF" && string(decoded[8:12]) == "WEBP" {
-								mediaType = "image/webp"
-							}
-						}
-					}
-				}
+				mediaType, data, isURL := parseImageURL(imgURL)
 
 				if isURL {
 					blocks = append(blocks, anthropic.NewImageBlock(
@@ -532,9 +864,8 @@ func (a *anthropicLLM) convertMessage(message Message) (anthropic.MessageParam,
 		}
 		return anthropic.NewAssistantMessage(blocks...), nil
 	case constants.RoleSystem:
-		// System messages should be handled separately (in req.System),
-		// but if one slips here, treat as user or ignore?
-		// Ideally makeRequest filters them out.
+		// System messages are hoisted into req.System by makeRequest;
+		// this only happens when convertMessage is used on its own.
 		return anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content())), nil
 	default:
 		return anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content())), nil
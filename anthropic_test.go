@@ -0,0 +1,34 @@
+package openllm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestAnthropicPrefillPart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",`+
+			`"content":[{"type":"text","text":"\"a\":1}"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":4}}`)
+	}))
+	defer srv.Close()
+
+	model := NewAnthropicLLMWithAPIKey("claude-sonnet-4-5", "", "key", WithBaseURL(srv.URL))
+	resp, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("json please")},
+		WithResponseFormat(constants.ResponseFormatJSONObject))
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if got := resp.Answer().Content(); got != `{"a":1}` {
+		t.Errorf("answer = %s, want {\"a\":1}", got)
+	}
+	parts := resp.Answer().(RichMessage).Parts()
+	if len(parts) != 2 || parts[0].Text != "{" || parts[1].Text != `"a":1}` {
+		t.Errorf("parts = %+v, want the prefill followed by the generated part", parts)
+	}
+}
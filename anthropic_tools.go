@@ -0,0 +1,306 @@
+package openllm
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/thecxx/openllm/constants"
+)
+
+// anthropicBetaComputerUse is the beta flag required by the computer-use tool.
+const anthropicBetaComputerUse = "computer-use-2025-01-24"
+
+// WebSearchOptions configures the Anthropic web search server tool.
+type WebSearchOptions struct {
+	maxUses        int
+	allowedDomains []string
+	blockedDomains []string
+	location       *anthropic.WebSearchTool20250305UserLocationParam
+}
+
+// WebSearchOption applies a configuration to WebSearchOptions.
+type WebSearchOption func(opts *WebSearchOptions)
+
+// WithWebSearchMaxUses limits the number of searches per request.
+func WithWebSearchMaxUses(n int) WebSearchOption {
+	return func(opts *WebSearchOptions) { opts.maxUses = n }
+}
+
+// WithWebSearchAllowedDomains restricts results to the given domains.
+func WithWebSearchAllowedDomains(domains ...string) WebSearchOption {
+	return func(opts *WebSearchOptions) { opts.allowedDomains = append(opts.allowedDomains, domains...) }
+}
+
+// WithWebSearchBlockedDomains excludes results from the given domains.
+func WithWebSearchBlockedDomains(domains ...string) WebSearchOption {
+	return func(opts *WebSearchOptions) { opts.blockedDomains = append(opts.blockedDomains, domains...) }
+}
+
+// WithWebSearchUserLocation localizes results; empty values are omitted.
+func WithWebSearchUserLocation(city, region, country, timezone string) WebSearchOption {
+	return func(opts *WebSearchOptions) {
+		loc := anthropic.WebSearchTool20250305UserLocationParam{}
+		if city != "" {
+			loc.City = anthropic.String(city)
+		}
+		if region != "" {
+			loc.Region = anthropic.String(region)
+		}
+		if country != "" {
+			loc.Country = anthropic.String(country)
+		}
+		if timezone != "" {
+			loc.Timezone = anthropic.String(timezone)
+		}
+		opts.location = &loc
+	}
+}
+
+// NewWebSearchTool creates Anthropic's web_search server tool.
+// Searches run on Anthropic's side: the Response contains ServerToolUse and
+// WebSearchResult content parts instead of tool calls to execute.
+func NewWebSearchTool(opts ...WebSearchOption) Tool {
+	var options WebSearchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	def := anthropic.WebSearchTool20250305Param{
+		AllowedDomains: options.allowedDomains,
+		BlockedDomains: options.blockedDomains,
+	}
+	if options.maxUses > 0 {
+		def.MaxUses = anthropic.Int(int64(options.maxUses))
+	}
+	if options.location != nil {
+		def.UserLocation = *options.location
+	}
+	return &tool{type_: constants.ToolTypeWebSearch, definition: def}
+}
+
+// NewBashTool creates Anthropic's bash tool. The model emits regular tool calls
+// named "bash" that the application executes and answers with tool results.
+func NewBashTool() Tool {
+	return &tool{type_: constants.ToolTypeBash, definition: anthropic.ToolBash20250124Param{}}
+}
+
+// NewTextEditorTool creates Anthropic's text editor tool ("str_replace_based_edit_tool").
+// The model emits regular tool calls that the application executes.
+func NewTextEditorTool() Tool {
+	return &tool{type_: constants.ToolTypeTextEditor, definition: anthropic.ToolTextEditor20250728Param{}}
+}
+
+// NewComputerUseTool creates Anthropic's computer-use tool for a display of the given size.
+// The required beta header is added automatically. The model emits regular tool calls
+// named "computer" that the application executes.
+func NewComputerUseTool(widthPx, heightPx int) Tool {
+	return &tool{type_: constants.ToolTypeComputer, definition: anthropic.BetaToolComputerUse20250124Param{
+		DisplayWidthPx:  int64(widthPx),
+		DisplayHeightPx: int64(heightPx),
+	}}
+}
+
+// convertAnthropicServerTool converts the built-in tool definitions created above.
+func convertAnthropicServerTool(tool Tool) (anthropic.ToolUnionParam, bool) {
+	switch def := tool.Definition().(type) {
+	case anthropic.WebSearchTool20250305Param:
+		return anthropic.ToolUnionParam{OfWebSearchTool20250305: &def}, true
+	case anthropic.ToolBash20250124Param:
+		return anthropic.ToolUnionParam{OfBashTool20250124: &def}, true
+	case anthropic.ToolTextEditor20250728Param:
+		return anthropic.ToolUnionParam{OfTextEditor20250728: &def}, true
+	}
+	return anthropic.ToolUnionParam{}, false
+}
+
+// anthropicRequestOptions returns per-request options (beta headers and tools that
+// the stable Messages API types cannot express) derived from the chat options.
+func anthropicRequestOptions(opts *ChatOptions) []option.RequestOption {
+	var reqOpts []option.RequestOption
+	if opts.fineGrainedToolStreaming {
+		reqOpts = append(reqOpts, option.WithHeaderAdd("anthropic-beta", anthropicBetaFineGrainedToolStreaming))
+	}
+	if opts.idempotencyKey != "" {
+		reqOpts = append(reqOpts, option.WithHeader(idempotencyHeader, opts.idempotencyKey))
+	}
+	if opts.noProviderRetries {
+		reqOpts = append(reqOpts, option.WithMaxRetries(0))
+	}
+
+	tools := opts.tools
+	if opts.toolset != nil {
+		tools = append(append([]Tool(nil), tools...), opts.toolset.Tools()...)
+	}
+	tools = opts.filterTools(tools)
+	beta := false
+	for _, tool := range tools {
+		if def, ok := tool.Definition().(anthropic.BetaToolComputerUse20250124Param); ok {
+			// Append to the serialized tools array
+			reqOpts = append(reqOpts, option.WithJSONSet("tools.-1", def))
+			beta = true
+		}
+	}
+	if beta {
+		reqOpts = append(reqOpts, option.WithHeaderAdd("anthropic-beta", anthropicBetaComputerUse))
+	}
+	return reqOpts
+}
+
+// convertServerToolUse maps a server_tool_use block to a content part.
+func convertServerToolUse(id, name string, input any) ContentPart {
+	raw, _ := json.Marshal(input)
+	return ContentPart{
+		Type:          constants.ContentPartTypeServerToolUse,
+		ServerToolUse: &ServerToolUse{ID: id, Name: name, Input: raw},
+	}
+}
+
+// convertWebSearchResult maps a web_search_tool_result block to a content part.
+func convertWebSearchResult(b anthropic.WebSearchToolResultBlock) ContentPart {
+	result := &WebSearchResult{
+		ToolUseID: b.ToolUseID,
+		ErrorCode: string(b.Content.ErrorCode),
+	}
+	for _, item := range b.Content.OfWebSearchResultBlockArray {
+		result.Results = append(result.Results, WebSearchResultItem{
+			URL:              item.URL,
+			Title:            item.Title,
+			PageAge:          item.PageAge,
+			EncryptedContent: item.EncryptedContent,
+		})
+	}
+	return ContentPart{Type: constants.ContentPartTypeWebSearchResult, WebSearchResult: result}
+}
+
+// convertServerToolParts maps server tool content parts back to Anthropic blocks
+// so that multi-turn conversations keep the search context.
+func convertServerToolParts(part ContentPart) (anthropic.ContentBlockParamUnion, bool) {
+	switch {
+	case part.Type == constants.ContentPartTypeServerToolUse && part.ServerToolUse != nil:
+		var input any = map[string]any{}
+		if len(part.ServerToolUse.Input) > 0 {
+			_ = json.Unmarshal(part.ServerToolUse.Input, &input)
+		}
+		return anthropic.ContentBlockParamUnion{OfServerToolUse: &anthropic.ServerToolUseBlockParam{
+			ID:    part.ServerToolUse.ID,
+			Input: input,
+		}}, true
+	case part.Type == constants.ContentPartTypeWebSearchResult && part.WebSearchResult != nil:
+		res := part.WebSearchResult
+		block := &anthropic.WebSearchToolResultBlockParam{ToolUseID: res.ToolUseID}
+		if res.ErrorCode != "" {
+			block.Content.OfRequestWebSearchToolResultError = &anthropic.WebSearchToolRequestErrorParam{
+				ErrorCode: anthropic.WebSearchToolRequestErrorErrorCode(res.ErrorCode),
+			}
+		} else {
+			items := make([]anthropic.WebSearchResultBlockParam, 0, len(res.Results))
+			for _, item := range res.Results {
+				p := anthropic.WebSearchResultBlockParam{
+					URL:              item.URL,
+					Title:            item.Title,
+					EncryptedContent: item.EncryptedContent,
+				}
+				if item.PageAge != "" {
+					p.PageAge = anthropic.String(item.PageAge)
+				}
+				items = append(items, p)
+			}
+			block.Content.OfWebSearchToolResultBlockItem = items
+		}
+		return anthropic.ContentBlockParamUnion{OfWebSearchToolResult: block}, true
+	}
+	return anthropic.ContentBlockParamUnion{}, false
+}
+
+// serverToolInput accumulates the streamed input of a server_tool_use block.
+type serverToolInput struct {
+	id    string
+	name  string
+	input strings.Builder
+}
+
+// part returns the completed server tool use as a content part.
+func (s *serverToolInput) part() ContentPart {
+	input := json.RawMessage(s.input.String())
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	return ContentPart{
+		Type:          constants.ContentPartTypeServerToolUse,
+		ServerToolUse: &ServerToolUse{ID: s.id, Name: s.name, Input: input},
+	}
+}
+
+// convertAnthropicCitation maps a citation from a text block or citations delta.
+// Both SDK unions share the same JSON shape.
+func convertAnthropicCitation(raw string) Citation {
+	var c struct {
+		Type              string `json:"type"`
+		CitedText         string `json:"cited_text"`
+		DocumentIndex     int    `json:"document_index"`
+		DocumentTitle     string `json:"document_title"`
+		StartCharIndex    int    `json:"start_char_index"`
+		EndCharIndex      int    `json:"end_char_index"`
+		StartPageNumber   int    `json:"start_page_number"`
+		EndPageNumber     int    `json:"end_page_number"`
+		StartBlockIndex   int    `json:"start_block_index"`
+		EndBlockIndex     int    `json:"end_block_index"`
+		EncryptedIndex    string `json:"encrypted_index"`
+		Title             string `json:"title"`
+		URL               string `json:"url"`
+		SearchResultIndex int    `json:"search_result_index"`
+		Source            string `json:"source"`
+	}
+	_ = json.Unmarshal([]byte(raw), &c)
+
+	citation := Citation{Type: c.Type, CitedText: c.CitedText, Title: c.DocumentTitle, DocumentIndex: c.DocumentIndex}
+	switch c.Type {
+	case constants.CitationTypeCharLocation:
+		citation.Start, citation.End = c.StartCharIndex, c.EndCharIndex
+	case constants.CitationTypePageLocation:
+		citation.Start, citation.End = c.StartPageNumber, c.EndPageNumber
+	case constants.CitationTypeContentBlockLocation:
+		citation.Start, citation.End = c.StartBlockIndex, c.EndBlockIndex
+	case constants.CitationTypeWebSearchResultLocation:
+		citation.Title, citation.URL, citation.EncryptedIndex = c.Title, c.URL, c.EncryptedIndex
+	case constants.CitationTypeSearchResultLocation:
+		citation.Title, citation.URL = c.Title, c.Source
+		citation.DocumentIndex = c.SearchResultIndex
+		citation.Start, citation.End = c.StartBlockIndex, c.EndBlockIndex
+	}
+	return citation
+}
+
+// convertAnthropicCitationParam maps a Citation back for multi-turn conversations.
+// It reports false for unknown citation types.
+func convertAnthropicCitationParam(c Citation) (anthropic.TextCitationParamUnion, bool) {
+	title := anthropic.String(c.Title)
+	switch c.Type {
+	case constants.CitationTypeCharLocation:
+		return anthropic.TextCitationParamUnion{OfCharLocation: &anthropic.CitationCharLocationParam{
+			CitedText: c.CitedText, DocumentTitle: title, DocumentIndex: int64(c.DocumentIndex),
+			StartCharIndex: int64(c.Start), EndCharIndex: int64(c.End),
+		}}, true
+	case constants.CitationTypePageLocation:
+		return anthropic.TextCitationParamUnion{OfPageLocation: &anthropic.CitationPageLocationParam{
+			CitedText: c.CitedText, DocumentTitle: title, DocumentIndex: int64(c.DocumentIndex),
+			StartPageNumber: int64(c.Start), EndPageNumber: int64(c.End),
+		}}, true
+	case constants.CitationTypeContentBlockLocation:
+		return anthropic.TextCitationParamUnion{OfContentBlockLocation: &anthropic.CitationContentBlockLocationParam{
+			CitedText: c.CitedText, DocumentTitle: title, DocumentIndex: int64(c.DocumentIndex),
+			StartBlockIndex: int64(c.Start), EndBlockIndex: int64(c.End),
+		}}, true
+	case constants.CitationTypeWebSearchResultLocation:
+		return anthropic.TextCitationParamUnion{OfWebSearchResultLocation: &anthropic.CitationWebSearchResultLocationParam{
+			CitedText: c.CitedText, Title: title, URL: c.URL, EncryptedIndex: c.EncryptedIndex,
+		}}, true
+	case constants.CitationTypeSearchResultLocation:
+		return anthropic.TextCitationParamUnion{OfSearchResultLocation: &anthropic.CitationSearchResultLocationParam{
+			CitedText: c.CitedText, Title: title, Source: c.URL, SearchResultIndex: int64(c.DocumentIndex),
+			StartBlockIndex: int64(c.Start), EndBlockIndex: int64(c.End),
+		}}, true
+	}
+	return anthropic.TextCitationParamUnion{}, false
+}
@@ -0,0 +1,67 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+)
+
+// ApprovalDecision is the outcome of a human review of a tool call.
+type ApprovalDecision struct {
+	// Approved allows the call to run. When false the call is skipped and the
+	// model receives an error tool result explaining the rejection.
+	Approved bool
+	// Arguments, if not empty, replaces the call's JSON arguments (approve with edits).
+	Arguments string
+	// Reason is passed to the model when the call is denied.
+	Reason string
+}
+
+// ApprovalFunc reviews a tool call before it is executed.
+// An error aborts the call and is reported to the model like a tool failure.
+type ApprovalFunc func(ctx context.Context, tcall ToolCall) (ApprovalDecision, error)
+
+// WithToolApproval requires approve to accept calls to the named tools, or to every
+// tool when no names are given, before the Runner executes them.
+func WithToolApproval(approve ApprovalFunc, names ...string) RunnerOption {
+	return WithToolMiddleware(RequireApproval(approve, names...))
+}
+
+// RequireApproval returns a middleware gating the named tools (or all tools) behind approve.
+// Denied calls fail with ErrToolCallDenied.
+func RequireApproval(approve ApprovalFunc, names ...string) ToolMiddleware {
+	only := make(map[string]bool, len(names))
+	for _, name := range names {
+		only[name] = true
+	}
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+			if len(only) > 0 && !only[tcall.Function().Name()] {
+				return next(ctx, tool, tcall)
+			}
+			decision, err := approve(ctx, tcall)
+			if err != nil {
+				return "", fmt.Errorf("approval of %s failed: %w", tcall.Function().Name(), err)
+			}
+			if !decision.Approved {
+				if decision.Reason != "" {
+					return "", fmt.Errorf("%w: %s", ErrToolCallDenied, decision.Reason)
+				}
+				return "", ErrToolCallDenied
+			}
+			if decision.Arguments != "" {
+				tcall = withArguments(tcall, decision.Arguments)
+			}
+			return next(ctx, tool, tcall)
+		}
+	}
+}
+
+// withArguments returns a copy of tcall with its arguments replaced.
+func withArguments(tcall ToolCall, args string) ToolCall {
+	return &toolcall{
+		index: tcall.Index(),
+		id:    tcall.ID(),
+		type_: tcall.Type(),
+		fcall: funcall{name: tcall.Function().Name(), args: args},
+	}
+}
@@ -0,0 +1,213 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through and records their outcome.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails requests immediately with *CircuitOpenError.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe requests through to test recovery.
+	BreakerHalfOpen
+)
+
+// String returns the state name.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig configures WithCircuitBreaker. Zero fields take the defaults noted.
+type BreakerConfig struct {
+	// Window is the number of most recent requests the failure rate is computed over (default 20).
+	Window int
+	// MinRequests is the number of requests in the window before the breaker may open (default 10).
+	MinRequests int
+	// FailureRate opens the breaker when reached, between 0 and 1 (default 0.5).
+	FailureRate float64
+	// OpenTimeout is how long the breaker stays open before probing (default 30s).
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probe requests while half-open (default 1).
+	HalfOpenProbes int
+	// IsFailure reports whether an error counts as a provider failure; nil uses
+	// IsRetryable, so client errors such as invalid requests do not open the breaker.
+	IsFailure func(err error) bool
+	// OnStateChange, if set, is called on every transition. It runs while the
+	// breaker is locked and must not block or call the model.
+	OnStateChange func(model string, from, to BreakerState)
+}
+
+// WithCircuitBreaker returns a Middleware that stops calling a failing model.
+// Each wrapped model gets its own breaker, which opens once the failure rate over the
+// recent window reaches the threshold. While open, requests fail immediately with a
+// *CircuitOpenError; after OpenTimeout a few probe requests are let through and the
+// breaker closes again if they succeed.
+func WithCircuitBreaker(cfg BreakerConfig) Middleware {
+	if cfg.Window <= 0 {
+		cfg.Window = 20
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.MinRequests > cfg.Window {
+		cfg.MinRequests = cfg.Window
+	}
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = IsRetryable
+	}
+	return func(next Model) Model {
+		return &breakerModel{Model: next, cfg: cfg, outcomes: make([]bool, 0, cfg.Window)}
+	}
+}
+
+// breakerModel is the Model returned by WithCircuitBreaker.
+type breakerModel struct {
+	Model
+	cfg BreakerConfig
+
+	mu    sync.Mutex
+	state BreakerState
+	// outcomes is a ring of recent results (true for failure) while closed.
+	outcomes []bool
+	next     int
+	// openedAt is when the breaker last opened.
+	openedAt time.Time
+	// probes is the number of probe requests in flight while half-open.
+	probes int
+}
+
+// Unwrap returns the wrapped model.
+func (m *breakerModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *breakerModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *breakerModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do runs request if the breaker allows it and records the outcome.
+func (m *breakerModel) do(request func() (Response, error)) (Response, error) {
+	probe, err := m.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := request()
+	m.record(probe, err != nil && m.cfg.IsFailure(err))
+	return resp, err
+}
+
+// allow reports whether a request may proceed and whether it is a half-open probe.
+func (m *breakerModel) allow() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == BreakerOpen {
+		if retryAt := m.openedAt.Add(m.cfg.OpenTimeout); time.Now().Before(retryAt) {
+			return false, &CircuitOpenError{Model: m.Name(), RetryAt: retryAt}
+		}
+		m.setState(BreakerHalfOpen)
+	}
+	if m.state == BreakerHalfOpen {
+		if m.probes >= m.cfg.HalfOpenProbes {
+			return false, &CircuitOpenError{Model: m.Name(), RetryAt: time.Now().Add(m.cfg.OpenTimeout)}
+		}
+		m.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the breaker with the outcome of a request.
+func (m *breakerModel) record(probe, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if probe {
+		m.probes--
+		if m.state != BreakerHalfOpen {
+			return
+		}
+		if failed {
+			m.open()
+		} else {
+			m.outcomes, m.next = m.outcomes[:0], 0
+			m.setState(BreakerClosed)
+		}
+		return
+	}
+	if m.state != BreakerClosed {
+		return
+	}
+
+	if len(m.outcomes) < m.cfg.Window {
+		m.outcomes = append(m.outcomes, failed)
+	} else {
+		m.outcomes[m.next] = failed
+		m.next = (m.next + 1) % m.cfg.Window
+	}
+	if len(m.outcomes) < m.cfg.MinRequests {
+		return
+	}
+	failures := 0
+	for _, f := range m.outcomes {
+		if f {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(m.outcomes)) >= m.cfg.FailureRate {
+		m.open()
+	}
+}
+
+// open trips the breaker.
+func (m *breakerModel) open() {
+	m.openedAt = time.Now()
+	m.outcomes, m.next = m.outcomes[:0], 0
+	m.setState(BreakerOpen)
+}
+
+// setState transitions to state and notifies OnStateChange.
+func (m *breakerModel) setState(state BreakerState) {
+	if m.state == state {
+		return
+	}
+	from := m.state
+	m.state = state
+	if m.cfg.OnStateChange != nil {
+		m.cfg.OnStateChange(m.Name(), from, state)
+	}
+}
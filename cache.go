@@ -0,0 +1,271 @@
+package openllm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheStore stores serialized responses for WithCache. Implementations must be
+// safe for concurrent use; a Redis or memcached client can be adapted in a few lines.
+type CacheStore interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key; a zero ttl means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheMode controls how WithCache serves a single request (see WithCacheMode).
+type CacheMode int
+
+const (
+	// CacheDefault serves the request from the cache when possible and stores new responses.
+	CacheDefault CacheMode = iota
+	// CacheBypass neither reads nor writes the cache.
+	CacheBypass
+	// CacheRefresh skips the lookup but stores the new response, replacing a stale entry.
+	CacheRefresh
+)
+
+// WithCacheMode sets how WithCache handles the current request.
+func WithCacheMode(mode CacheMode) ChatOption {
+	return func(opts *ChatOptions) { opts.cacheMode = mode }
+}
+
+// WithCache returns a Middleware that serves repeated requests from store. The key is
+// a hash of the model name, messages and every option that affects the answer, so it
+// is meant for deterministic workloads such as temperature 0 extraction or
+// classification. Responses are kept for ttl (zero keeps them until evicted).
+// Cached streams are replayed to the watcher as a single delta per kind of output.
+// Meta.CacheHit reports responses served from the cache. Store errors are ignored
+// and the request goes to the model.
+func WithCache(store CacheStore, ttl time.Duration) Middleware {
+	return func(next Model) Model {
+		return &cacheModel{Model: next, store: store, ttl: ttl}
+	}
+}
+
+// cacheModel is the Model returned by WithCache.
+type cacheModel struct {
+	Model
+	store CacheStore
+	ttl   time.Duration
+}
+
+// Unwrap returns the wrapped model.
+func (m *cacheModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *cacheModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, false, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *cacheModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, true, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do looks the request up in the store, calling request on a miss.
+func (m *cacheModel) do(ctx context.Context, messages []Message, opts []ChatOption, stream bool, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.cacheMode == CacheBypass {
+		return request()
+	}
+	key, err := cacheKey(m.Name(), messages, options)
+	if err != nil {
+		return request()
+	}
+
+	if options.cacheMode != CacheRefresh {
+		if data, ok, err := m.store.Get(ctx, key); err == nil && ok {
+			if resp, err := DecodeResponse(data); err == nil {
+				resp = withMeta(resp, func(meta *Meta) { meta.CacheHit = true })
+				if stream && options.watcher != nil {
+					return resp, replayResponse(ctx, options.watcher, resp)
+				}
+				return resp, nil
+			}
+		}
+	}
+
+	resp, err := request()
+	if err != nil {
+		return resp, err
+	}
+	if data, err := EncodeResponse(resp); err == nil {
+		_ = m.store.Set(ctx, key, data, m.ttl)
+	}
+	return resp, nil
+}
+
+// cacheKey hashes the model name, messages and the options that affect the answer.
+func cacheKey(model string, messages []Message, opts *ChatOptions) (string, error) {
+	type toolKey struct {
+		Type       string `json:"type"`
+		Definition any    `json:"definition"`
+	}
+	options, err := requestOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	key := struct {
+		Model    string            `json:"model"`
+		Prompts  []string          `json:"prompts,omitempty"`
+		Messages []json.RawMessage `json:"messages"`
+		Tools    []toolKey         `json:"tools,omitempty"`
+		Options  TranscriptOptions `json:"options"`
+	}{
+		Model:   model,
+		Options: options,
+	}
+
+	prompts, messages := opts.redact(messages)
+	key.Prompts = prompts
+	for _, message := range messages {
+		data, err := EncodeMessage(message)
+		if err != nil {
+			return "", err
+		}
+		key.Messages = append(key.Messages, data)
+	}
+	for _, tool := range opts.allTools() {
+		key.Tools = append(key.Tools, toolKey{Type: tool.Type(), Definition: tool.Definition()})
+	}
+
+	data, err := json.Marshal(&key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayResponse delivers a complete response to watcher as a stream would:
+// reasoning, content, refusal, tool calls, usage and metadata, then OnStop.
+func replayResponse(ctx context.Context, watcher StreamWatcher, resp Response) error {
+	answer := resp.Answer()
+	if reasoning := answer.Reasoning(); reasoning != "" {
+		if err := watcher.OnReasoning(reasoning); err != nil {
+			return err
+		}
+	}
+	if content := answer.Content(); content != "" {
+		if err := watcher.OnContent(content); err != nil {
+			return err
+		}
+	}
+	if refusal := asLLMMessage(answer).refusal; refusal != "" {
+		if err := watcher.OnRefusal(refusal); err != nil {
+			return err
+		}
+	}
+	for _, tcall := range resp.ToolCalls() {
+		if err := watcher.OnToolCall(ctx, tcall, tcall.Function().Arguments()); err != nil {
+			return err
+		}
+		if w, ok := watcher.(ToolCallDoneWatcher); ok {
+			if err := w.OnToolCallDone(ctx, tcall); err != nil {
+				return err
+			}
+		}
+	}
+	if w, ok := watcher.(UsageWatcher); ok {
+		if err := w.OnUsage(resp.Usage()); err != nil {
+			return err
+		}
+		if err := w.OnMeta(resp.Meta()); err != nil {
+			return err
+		}
+	}
+	return watcher.OnStop()
+}
+
+// LRUCache is an in-memory CacheStore that evicts the least recently used entry
+// once it holds capacity entries.
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is an element of LRUCache.order.
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache creates an LRUCache holding up to capacity entries; a capacity
+// below 1 is treated as 1.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStore.
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set implements CacheStore.
+func (c *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(elem)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,172 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/thecxx/openllm/constants"
+)
+
+// ModelInfo describes a model available from a provider.
+type ModelInfo struct {
+	// ID is the model name used in requests.
+	ID string `json:"id"`
+	// Provider is the backend serving the model (see constants/provider.go).
+	Provider string `json:"provider"`
+	// DisplayName is a human-readable name, when the provider has one.
+	DisplayName string `json:"display_name,omitempty"`
+	// Created is when the model was released or created, if known.
+	Created time.Time `json:"created"`
+	// ContextWindow is the maximum input tokens, or zero when unknown.
+	ContextWindow int `json:"context_window,omitempty"`
+	// MaxOutputTokens is the maximum tokens per response, or zero when unknown.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+	// InputModalities and OutputModalities list what the model accepts and
+	// produces (see constants.Modality*); empty when unknown.
+	InputModalities  []string `json:"input_modalities,omitempty"`
+	OutputModalities []string `json:"output_modalities,omitempty"`
+}
+
+var (
+	modalitiesText         = []string{constants.ModalityText}
+	modalitiesTextImage    = []string{constants.ModalityText, constants.ModalityImage}
+	modalitiesTextImageDoc = []string{constants.ModalityText, constants.ModalityImage, constants.ModalityDocument}
+	modalitiesEmbedding    = []string{constants.ModalityEmbedding}
+)
+
+// catalog is the built-in table of model metadata, keyed by model ID without a
+// date suffix. Provider list endpoints rarely report limits or modalities, so
+// listed models are completed from it.
+var catalog = map[string]ModelInfo{
+	"gpt-4o":                 {Provider: constants.ProviderOpenAI, ContextWindow: 128000, MaxOutputTokens: 16384, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-4o-mini":            {Provider: constants.ProviderOpenAI, ContextWindow: 128000, MaxOutputTokens: 16384, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-4.1":                {Provider: constants.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-4.1-mini":           {Provider: constants.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-4.1-nano":           {Provider: constants.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-3.5-turbo":          {Provider: constants.ProviderOpenAI, ContextWindow: 16385, MaxOutputTokens: 4096, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	"o1":                     {Provider: constants.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"o1-mini":                {Provider: constants.ProviderOpenAI, ContextWindow: 128000, MaxOutputTokens: 65536, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	"o3":                     {Provider: constants.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"o3-mini":                {Provider: constants.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	"o4-mini":                {Provider: constants.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"gpt-image-1":            {Provider: constants.ProviderOpenAI, InputModalities: modalitiesTextImage, OutputModalities: []string{constants.ModalityImage}},
+	"dall-e-3":               {Provider: constants.ProviderOpenAI, InputModalities: modalitiesText, OutputModalities: []string{constants.ModalityImage}},
+	"dall-e-2":               {Provider: constants.ProviderOpenAI, InputModalities: modalitiesTextImage, OutputModalities: []string{constants.ModalityImage}},
+	"whisper-1":              {Provider: constants.ProviderOpenAI, InputModalities: []string{constants.ModalityAudio}, OutputModalities: modalitiesText},
+	"text-embedding-3-small": {Provider: constants.ProviderOpenAI, ContextWindow: 8191, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"text-embedding-3-large": {Provider: constants.ProviderOpenAI, ContextWindow: 8191, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"text-embedding-ada-002": {Provider: constants.ProviderOpenAI, ContextWindow: 8191, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"omni-moderation":        {Provider: constants.ProviderOpenAI, InputModalities: modalitiesTextImage},
+
+	"claude-3-haiku":    {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 4096, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"claude-3-opus":     {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 4096, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"claude-3-5-haiku":  {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 8192, InputModalities: modalitiesTextImage, OutputModalities: modalitiesText},
+	"claude-3-5-sonnet": {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 8192, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-3-7-sonnet": {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-sonnet-4":   {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-sonnet-4-5": {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-haiku-4-5":  {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-opus-4":     {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 32000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-opus-4-1":   {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 32000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+	"claude-opus-4-5":   {Provider: constants.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, InputModalities: modalitiesTextImageDoc, OutputModalities: modalitiesText},
+
+	"embed-v4.0":              {Provider: constants.ProviderCohere, ContextWindow: 128000, InputModalities: modalitiesTextImage, OutputModalities: modalitiesEmbedding},
+	"embed-english-v3.0":      {Provider: constants.ProviderCohere, ContextWindow: 512, InputModalities: modalitiesTextImage, OutputModalities: modalitiesEmbedding},
+	"embed-multilingual-v3.0": {Provider: constants.ProviderCohere, ContextWindow: 512, InputModalities: modalitiesTextImage, OutputModalities: modalitiesEmbedding},
+	"rerank-v3.5":             {Provider: constants.ProviderCohere, ContextWindow: 4096, InputModalities: modalitiesText},
+
+	"text-embedding-005":              {Provider: constants.ProviderVertex, ContextWindow: 2048, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"text-multilingual-embedding-002": {Provider: constants.ProviderVertex, ContextWindow: 2048, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+
+	"voyage-3":      {Provider: constants.ProviderVoyage, ContextWindow: 32000, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"voyage-3-lite": {Provider: constants.ProviderVoyage, ContextWindow: 32000, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"rerank-2":      {Provider: constants.ProviderVoyage, ContextWindow: 16000, InputModalities: modalitiesText},
+	"rerank-2-lite": {Provider: constants.ProviderVoyage, ContextWindow: 8000, InputModalities: modalitiesText},
+
+	"jina-embeddings-v3":                 {Provider: constants.ProviderJina, ContextWindow: 8192, InputModalities: modalitiesText, OutputModalities: modalitiesEmbedding},
+	"jina-reranker-v2-base-multilingual": {Provider: constants.ProviderJina, ContextWindow: 1024, InputModalities: modalitiesText},
+}
+
+// modelVersionSuffix matches the date or alias suffix of a model snapshot,
+// such as "-2024-08-06", "-20241022" or "-latest".
+var modelVersionSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}|\d{8}|latest)$`)
+
+// LookupModel returns the built-in metadata of a model, matching snapshots
+// such as "claude-3-5-sonnet-20241022" to their base entry.
+func LookupModel(id string) (ModelInfo, bool) {
+	key := id
+	info, ok := catalog[key]
+	if !ok {
+		key = modelVersionSuffix.ReplaceAllString(id, "")
+		info, ok = catalog[key]
+	}
+	if !ok {
+		return ModelInfo{}, false
+	}
+	info.ID = id
+	info.InputModalities = slices.Clone(info.InputModalities)
+	info.OutputModalities = slices.Clone(info.OutputModalities)
+	return info, true
+}
+
+// ListModels returns the models available from provider (see constants/provider.go),
+// sorted by ID. OpenAI and Anthropic are asked with apiKey through their list
+// endpoints, and the results completed with the built-in metadata; other
+// providers are answered from the built-in table alone and ignore apiKey.
+// Client options can set the HTTP client, proxy and endpoint.
+func ListModels(ctx context.Context, provider, apiKey string, opts ...ClientOption) ([]ModelInfo, error) {
+	var models []ModelInfo
+	switch provider {
+	case constants.ProviderOpenAI:
+		list, err := newOpenAIClient(apiKey, opts).ListModels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range list.Models {
+			info := completeModelInfo(ModelInfo{ID: m.ID, Provider: provider})
+			if m.CreatedAt > 0 {
+				info.Created = time.Unix(m.CreatedAt, 0)
+			}
+			models = append(models, info)
+		}
+	case constants.ProviderAnthropic:
+		pager := newAnthropicClient(apiKey, opts).Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+		for pager.Next() {
+			m := pager.Current()
+			models = append(models, completeModelInfo(ModelInfo{ID: m.ID, Provider: provider, DisplayName: m.DisplayName, Created: m.CreatedAt}))
+		}
+		if err := pager.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		for id, info := range catalog {
+			if info.Provider == provider {
+				info, _ = LookupModel(id)
+				models = append(models, info)
+			}
+		}
+		if models == nil {
+			return nil, fmt.Errorf("unknown model provider %q", provider)
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// completeModelInfo fills the limits and modalities of info from the built-in table.
+func completeModelInfo(info ModelInfo) ModelInfo {
+	known, ok := LookupModel(info.ID)
+	if !ok {
+		return info
+	}
+	info.ContextWindow = known.ContextWindow
+	info.MaxOutputTokens = known.MaxOutputTokens
+	info.InputModalities = known.InputModalities
+	info.OutputModalities = known.OutputModalities
+	return info
+}
@@ -0,0 +1,55 @@
+package openllm
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		id       string
+		provider string
+		window   int
+		output   string
+	}{
+		{"gpt-4o-2024-08-06", constants.ProviderOpenAI, 128000, constants.ModalityText},
+		{"claude-3-5-sonnet-20241022", constants.ProviderAnthropic, 200000, constants.ModalityText},
+		{"claude-3-7-sonnet-latest", constants.ProviderAnthropic, 200000, constants.ModalityText},
+		{"text-embedding-3-small", constants.ProviderOpenAI, 8191, constants.ModalityEmbedding},
+		{"text-embedding-3-large", constants.ProviderOpenAI, 8191, constants.ModalityEmbedding},
+		{"text-embedding-ada-002", constants.ProviderOpenAI, 8191, constants.ModalityEmbedding},
+		{"text-embedding-005", constants.ProviderVertex, 2048, constants.ModalityEmbedding},
+		{"text-multilingual-embedding-002", constants.ProviderVertex, 2048, constants.ModalityEmbedding},
+	}
+	for _, tt := range tests {
+		info, ok := LookupModel(tt.id)
+		if !ok {
+			t.Errorf("LookupModel(%q) not found", tt.id)
+			continue
+		}
+		if info.ID != tt.id || info.Provider != tt.provider || info.ContextWindow != tt.window || !slices.Contains(info.OutputModalities, tt.output) {
+			t.Errorf("LookupModel(%q) = %+v", tt.id, info)
+		}
+	}
+
+	if _, ok := LookupModel("text-modalitiesEmbedding-3-small"); ok {
+		t.Error("LookupModel found a corrupted catalog key")
+	}
+}
+
+func TestListModelsCatalog(t *testing.T) {
+	models, err := ListModels(context.Background(), constants.ProviderVertex, "")
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	var ids []string
+	for _, m := range models {
+		ids = append(ids, m.ID)
+	}
+	if !slices.Contains(ids, "text-embedding-005") || !slices.Contains(ids, "text-multilingual-embedding-002") {
+		t.Fatalf("ListModels(vertex) = %v", ids)
+	}
+}
@@ -0,0 +1,170 @@
+package openllm
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// defaultEventBufferSize is the channel capacity used by StreamEvents when
+// WithEventBuffer is not given.
+const defaultEventBufferSize = 64
+
+// OverflowPolicy decides what happens when an EventStream buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the consumer, applying backpressure to the provider stream.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered event to make room for the new one.
+	OverflowDropOldest
+	// OverflowError aborts the stream with ErrStreamOverflow.
+	OverflowError
+)
+
+// EventStream delivers the events of a streaming request over a bounded channel.
+// The channel is closed once the request finishes; Wait returns the final result.
+type EventStream struct {
+	ctx     context.Context
+	events  chan StreamEvent
+	policy  OverflowPolicy
+	dropped atomic.Int64
+	done    chan struct{}
+	resp    Response
+	err     error
+}
+
+// StreamEvents runs ChatCompletionStream in the background and exposes its events as a channel.
+// Buffer size and overflow behavior are set with WithEventBuffer (default 64 events, OverflowBlock).
+// Any watcher set with WithStreamWatcher is replaced by the channel.
+func StreamEvents(ctx context.Context, model Model, messages []Message, opts ...ChatOption) *EventStream {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	size := options.eventBufferSize
+	if size <= 0 {
+		size = defaultEventBufferSize
+	}
+
+	s := &EventStream{
+		ctx:    ctx,
+		events: make(chan StreamEvent, size),
+		policy: options.overflowPolicy,
+		done:   make(chan struct{}),
+	}
+
+	opts = append(opts, WithStreamWatcher(s))
+	go func() {
+		defer close(s.done)
+		defer close(s.events)
+		s.resp, s.err = model.ChatCompletionStream(ctx, messages, opts...)
+		if s.err != nil {
+			s.force(StreamEvent{Type: constants.StreamEventError, Error: s.err.Error()})
+		}
+	}()
+	return s
+}
+
+// Events returns the channel of stream events. It is closed when the request finishes.
+func (s *EventStream) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Wait blocks until the request finishes and returns its result.
+// Callers must keep draining Events (or use a non-blocking overflow policy) to avoid a deadlock.
+func (s *EventStream) Wait() (Response, error) {
+	<-s.done
+	return s.resp, s.err
+}
+
+// Dropped returns the number of events discarded by OverflowDropOldest.
+func (s *EventStream) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// OnRefusal implements StreamWatcher.
+func (s *EventStream) OnRefusal(delta string) error {
+	return s.send(StreamEvent{Type: constants.StreamEventRefusal, Delta: delta})
+}
+
+// OnReasoning implements StreamWatcher.
+func (s *EventStream) OnReasoning(delta string) error {
+	return s.send(StreamEvent{Type: constants.StreamEventReasoning, Delta: delta})
+}
+
+// OnContent implements StreamWatcher.
+func (s *EventStream) OnContent(delta string) error {
+	return s.send(StreamEvent{Type: constants.StreamEventContent, Delta: delta})
+}
+
+// OnToolCall implements StreamWatcher.
+func (s *EventStream) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	return s.send(StreamEvent{Type: constants.StreamEventToolCall, Delta: args, ToolCall: newStreamToolCall(tcall)})
+}
+
+// OnToolCallDone implements ToolCallDoneWatcher.
+func (s *EventStream) OnToolCallDone(ctx context.Context, tcall ToolCall) error {
+	return s.send(StreamEvent{Type: constants.StreamEventToolCallDone, ToolCall: newStreamToolCall(tcall)})
+}
+
+// OnField implements FieldWatcher.
+func (s *EventStream) OnField(path string, value any) error {
+	return s.send(StreamEvent{Type: constants.StreamEventField, Path: path, Value: value})
+}
+
+// OnUsage implements UsageWatcher.
+func (s *EventStream) OnUsage(usage Usage) error {
+	return s.send(StreamEvent{Type: constants.StreamEventUsage, Usage: &usage})
+}
+
+// OnMeta implements UsageWatcher.
+func (s *EventStream) OnMeta(meta Meta) error {
+	return s.send(StreamEvent{Type: constants.StreamEventMeta, Meta: &meta})
+}
+
+// OnStop implements StreamWatcher.
+func (s *EventStream) OnStop() error {
+	return s.send(StreamEvent{Type: constants.StreamEventStop})
+}
+
+// send delivers an event according to the overflow policy.
+func (s *EventStream) send(event StreamEvent) error {
+	select {
+	case s.events <- event:
+		return nil
+	default:
+	}
+
+	switch s.policy {
+	case OverflowDropOldest:
+		s.force(event)
+		return nil
+	case OverflowError:
+		return ErrStreamOverflow
+	default:
+		select {
+		case s.events <- event:
+			return nil
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+}
+
+// force delivers an event without blocking, discarding the oldest buffered events as needed.
+func (s *EventStream) force(event StreamEvent) {
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+		}
+		select {
+		case <-s.events:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
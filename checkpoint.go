@@ -0,0 +1,113 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// checkpointVersion is the format version written by RunCheckpoint.MarshalJSON.
+const checkpointVersion = 1
+
+// RunCheckpoint is the state of a Runner between two steps, from which
+// Runner.Resume continues the run, e.g. in another process.
+type RunCheckpoint struct {
+	// Messages is the conversation so far.
+	Messages []Message
+	// PendingToolCalls are the tool calls of the last response that have not
+	// been executed yet; Resume executes them first.
+	PendingToolCalls []ToolCall
+	// Iterations is the number of model calls made.
+	Iterations int
+	// Usage is the token usage accumulated over all model calls.
+	Usage Usage
+}
+
+// checkpointJSON is the serialized form of a RunCheckpoint.
+type checkpointJSON struct {
+	Version          int         `json:"version"`
+	Messages         []*llmmsg   `json:"messages"`
+	PendingToolCalls []*toolcall `json:"pending_tool_calls,omitempty"`
+	Iterations       int         `json:"iterations"`
+	Usage            Usage       `json:"usage"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (cp *RunCheckpoint) MarshalJSON() ([]byte, error) {
+	data := checkpointJSON{
+		Version:    checkpointVersion,
+		Messages:   make([]*llmmsg, len(cp.Messages)),
+		Iterations: cp.Iterations,
+		Usage:      cp.Usage,
+	}
+	for i, msg := range cp.Messages {
+		data.Messages[i] = asLLMMessage(msg)
+	}
+	for _, tcall := range cp.PendingToolCalls {
+		data.PendingToolCalls = append(data.PendingToolCalls, &toolcall{
+			index: tcall.Index(),
+			id:    tcall.ID(),
+			type_: tcall.Type(),
+			fcall: funcall{name: tcall.Function().Name(), args: tcall.Function().Arguments()},
+		})
+	}
+	return json.Marshal(&data)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (cp *RunCheckpoint) UnmarshalJSON(b []byte) error {
+	var data checkpointJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if data.Version > checkpointVersion {
+		return fmt.Errorf("unsupported checkpoint version %d", data.Version)
+	}
+	*cp = RunCheckpoint{Iterations: data.Iterations, Usage: data.Usage}
+	for i, msg := range data.Messages {
+		if msg == nil {
+			return fmt.Errorf("checkpoint message %d is null", i)
+		}
+		cp.Messages = append(cp.Messages, msg)
+	}
+	for _, tcall := range data.PendingToolCalls {
+		cp.PendingToolCalls = append(cp.PendingToolCalls, tcall)
+	}
+	return nil
+}
+
+// WithCheckpoint makes the Runner call save with a checkpoint after each step:
+// once the model has answered, and again once its tool calls have been executed.
+// A save error stops the run and is returned by Run. The last checkpoint of a
+// finished run has no pending tool calls and ends with the answer. Checkpoints
+// are taken between steps, so a run resumed after a crash repeats at most the
+// step in progress; WithIdempotentTools with WithIdempotencyKey keeps repeated
+// tool calls from running twice.
+func WithCheckpoint(save func(ctx context.Context, cp *RunCheckpoint) error) RunnerOption {
+	return func(r *Runner) { r.onCheckpoint = save }
+}
+
+// checkpoint passes the state of result to the checkpoint callback, if any.
+func (r *Runner) checkpoint(ctx context.Context, result *RunResult, pending []ToolCall) error {
+	if r.onCheckpoint == nil {
+		return nil
+	}
+	return r.onCheckpoint(ctx, &RunCheckpoint{
+		Messages:         append([]Message(nil), result.Messages...),
+		PendingToolCalls: append([]ToolCall(nil), pending...),
+		Iterations:       result.Iterations,
+		Usage:            result.Usage,
+	})
+}
+
+// Resume continues a run from cp: it executes the pending tool calls, then
+// carries on like Run, counting the iterations and usage of cp towards the
+// limits and the result. opts must provide the same tools as the original run.
+func (r *Runner) Resume(ctx context.Context, cp *RunCheckpoint, opts ...ChatOption) (*RunResult, error) {
+	result := &RunResult{
+		Messages:   append([]Message(nil), cp.Messages...),
+		Iterations: cp.Iterations,
+		Usage:      cp.Usage,
+	}
+	return r.run(ctx, result, cp.PendingToolCalls, opts)
+}
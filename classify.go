@@ -0,0 +1,105 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Classification is the outcome of Classify.
+type Classification struct {
+	// Label is the chosen label, always one of the given labels.
+	Label string
+	// Confidence is the model's probability of the label, in (0, 1], derived
+	// from token log probabilities. It is zero when the provider returns none.
+	Confidence float64
+	// Response is the underlying model response.
+	Response Response
+}
+
+// Classify asks model to assign input to exactly one of labels. The answer is
+// constrained with a response schema whose label property is an enum of labels,
+// and log probabilities are requested so Confidence can be reported where the
+// provider supports them (OpenAI). opts are applied after Classify's own options.
+func Classify(ctx context.Context, model Model, input string, labels []string, opts ...ChatOption) (*Classification, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("classify: no labels given")
+	}
+	enum := make([]any, len(labels))
+	for i, label := range labels {
+		enum[i] = label
+	}
+	schema := &Schema{
+		Type: jsonschema.Object,
+		Properties: map[string]*Schema{
+			"label": {Type: jsonschema.String, Enum: enum},
+		},
+		Required:             []string{"label"},
+		AdditionalProperties: false,
+	}
+
+	opts = append([]ChatOption{
+		WithSystemPrompt("Classify the input into exactly one of these labels: " + strings.Join(labels, ", ") + "."),
+		WithResponseSchema("classification", schema, true),
+		WithLogProbs(0),
+	}, opts...)
+
+	resp, err := model.ChatCompletion(ctx, []Message{NewUserMessage(input)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var answer struct {
+		Label string `json:"label"`
+	}
+	if err := DecodeJSON(resp, &answer); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(labels, answer.Label) {
+		return nil, &OutputParseError{
+			Text: resp.Answer().Content(),
+			Err:  fmt.Errorf("label %q is not one of %v", answer.Label, labels),
+		}
+	}
+
+	return &Classification{
+		Label:      answer.Label,
+		Confidence: labelConfidence(resp.Answer().Content(), answer.Label, LogProbs(resp)),
+		Response:   resp,
+	}, nil
+}
+
+// labelConfidence returns the joint probability of the tokens that spell label
+// within text, or zero when logprobs do not cover it.
+func labelConfidence(text, label string, logprobs []TokenLogProb) float64 {
+	quoted, _ := json.Marshal(label)
+	start := strings.LastIndex(text, string(quoted))
+	if start < 0 || len(logprobs) == 0 {
+		return 0
+	}
+	// Skip the opening quote
+	start++
+	end := start + len(quoted) - 2
+
+	var sum float64
+	var covered bool
+	offset := 0
+	for _, lp := range logprobs {
+		tokStart, tokEnd := offset, offset+len(lp.Token)
+		offset = tokEnd
+		if tokEnd <= start || tokStart >= end {
+			continue
+		}
+		sum += lp.LogProb
+		covered = true
+	}
+	if !covered || offset != len(text) {
+		// The tokens do not line up with the answer text
+		return 0
+	}
+	return math.Exp(sum)
+}
@@ -0,0 +1,164 @@
+package openllm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ClientOption configures the provider client built by the *WithAPIKey constructors.
+type ClientOption func(*clientOptions)
+
+// clientOptions holds the settings applied when building a provider client.
+type clientOptions struct {
+	// httpClient sends the requests; nil uses DefaultHTTPClient, then the SDK default.
+	httpClient *http.Client
+	// baseURL overrides the API endpoint, e.g. for gateways and compatible servers.
+	baseURL string
+	// constraintBackend is the OpenAI-compatible server enforcing WithConstraint.
+	constraintBackend string
+}
+
+var (
+	defaultHTTPClientMu sync.RWMutex
+	defaultHTTPClient   *http.Client
+)
+
+// SetDefaultHTTPClient sets the *http.Client used by clients built afterwards by the
+// *WithAPIKey constructors when WithHTTPClient is not given, so a corporate proxy,
+// mTLS or custom dialer can be configured once for every provider. Nil restores the
+// SDK defaults.
+func SetDefaultHTTPClient(client *http.Client) {
+	defaultHTTPClientMu.Lock()
+	defer defaultHTTPClientMu.Unlock()
+	defaultHTTPClient = client
+}
+
+// DefaultHTTPClient returns the client set by SetDefaultHTTPClient, or nil.
+func DefaultHTTPClient() *http.Client {
+	defaultHTTPClientMu.RLock()
+	defer defaultHTTPClientMu.RUnlock()
+	return defaultHTTPClient
+}
+
+// WithHTTPClient sends the provider's requests through client.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(opts *clientOptions) { opts.httpClient = client }
+}
+
+// WithProxy sends the provider's requests through the HTTP(S) proxy at proxyURL,
+// using a copy of http.DefaultTransport. It replaces any client set by WithHTTPClient;
+// an invalid URL is reported by the first request.
+func WithProxy(proxyURL string) ClientOption {
+	return func(opts *clientOptions) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = func(*http.Request) (*url.URL, error) { return url.Parse(proxyURL) }
+		opts.httpClient = &http.Client{Transport: transport}
+	}
+}
+
+// WithBaseURL sends the provider's requests to baseURL instead of the public API,
+// e.g. an Azure-compatible gateway or a local OpenAI-compatible server.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(opts *clientOptions) { opts.baseURL = baseURL }
+}
+
+// WithConstraintBackend declares the OpenAI-compatible server behind the endpoint
+// (constants.ConstraintBackendVLLM or constants.ConstraintBackendLlamaCpp), so
+// that WithConstraint regexes, grammars and choices are sent in the fields it
+// understands: vLLM guided_regex, guided_grammar and guided_choice, or a llama.cpp
+// GBNF grammar. Without it only JSON schema constraints are accepted, as the
+// OpenAI API rejects the extra fields.
+func WithConstraintBackend(backend string) ClientOption {
+	return func(opts *clientOptions) { opts.constraintBackend = backend }
+}
+
+// newClientOptions applies opts over the global defaults.
+func newClientOptions(opts []ClientOption) *clientOptions {
+	options := &clientOptions{httpClient: DefaultHTTPClient()}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// constraintFieldsKey is the context key of the extra request fields injected
+// by constraintTransport.
+type constraintFieldsKey struct{}
+
+// withConstraintFields returns ctx carrying fields for constraintTransport.
+func withConstraintFields(ctx context.Context, fields map[string]any) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, constraintFieldsKey{}, fields)
+}
+
+// responseHeaderKey is the context key of the slot filled by headerTransport.
+type responseHeaderKey struct{}
+
+// withResponseHeader returns ctx carrying a slot that headerTransport fills with
+// the headers of the response to the request made with ctx.
+func withResponseHeader(ctx context.Context) (context.Context, *http.Header) {
+	header := &http.Header{}
+	return context.WithValue(ctx, responseHeaderKey{}, header), header
+}
+
+// headerTransport records the response headers of requests whose context carries
+// a slot (see withResponseHeader), as the errors of the OpenAI client do not.
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if header, ok := req.Context().Value(responseHeaderKey{}).(*http.Header); ok && resp != nil {
+		*header = resp.Header
+	}
+	return resp, err
+}
+
+// constraintTransport adds the constraint fields carried by the request context
+// to JSON request bodies, which the OpenAI client has no fields for.
+type constraintTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *constraintTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields, ok := req.Context().Value(constraintFieldsKey{}).(map[string]any)
+	if !ok || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("constraint fields: %w", err)
+	}
+	for name, value := range fields {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("constraint fields: %w", err)
+		}
+		body[name] = raw
+	}
+	if data, err = json.Marshal(body); err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	req.ContentLength = int64(len(data))
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,108 @@
+package openllm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// cohereBaseURL is the Cohere API endpoint.
+const cohereBaseURL = "https://api.cohere.com"
+
+// cohereEmbedBatchSize is the maximum number of texts per Cohere embed request.
+const cohereEmbedBatchSize = 96
+
+// newCohereAPI creates the HTTP client of the Cohere backends.
+func newCohereAPI(apiKey string, opts []ClientOption) *httpAPI {
+	return newHTTPAPI(constants.ProviderCohere, cohereBaseURL, opts, func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return nil
+	})
+}
+
+type cohereEmbedder struct {
+	model string
+	api   *httpAPI
+}
+
+// NewCohereEmbedder creates an Embedder for a Cohere embedding model, e.g. "embed-v4.0".
+// Client options can set the HTTP client, proxy and endpoint.
+func NewCohereEmbedder(model, apiKey string, opts ...ClientOption) Embedder {
+	return &cohereEmbedder{model: model, api: newCohereAPI(apiKey, opts)}
+}
+
+// Embed implements Embedder with the v2 embed endpoint.
+func (e *cohereEmbedder) Embed(ctx context.Context, inputs []string, opts ...EmbedOption) ([][]float32, Usage, error) {
+	options := &EmbedOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return embedBatches(ctx, inputs, options, cohereEmbedBatchSize, func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+		req := struct {
+			Model           string   `json:"model"`
+			Texts           []string `json:"texts"`
+			InputType       string   `json:"input_type"`
+			EmbeddingTypes  []string `json:"embedding_types"`
+			OutputDimension *int     `json:"output_dimension,omitempty"`
+		}{
+			Model:           e.model,
+			Texts:           batch,
+			InputType:       embedInputType(options.inputType, "search_query", "search_document"),
+			EmbeddingTypes:  []string{"float"},
+			OutputDimension: options.dimensions,
+		}
+		var resp struct {
+			Embeddings struct {
+				Float [][]float32 `json:"float"`
+			} `json:"embeddings"`
+			Meta struct {
+				BilledUnits struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"billed_units"`
+			} `json:"meta"`
+		}
+		if err := e.api.post(ctx, "/v2/embed", &req, &resp); err != nil {
+			return nil, Usage{}, err
+		}
+		tokens := resp.Meta.BilledUnits.InputTokens
+		return resp.Embeddings.Float, Usage{InputTokens: tokens, TotalTokens: tokens}.withCost(e.model), nil
+	})
+}
+
+type cohereReranker struct {
+	model string
+	api   *httpAPI
+}
+
+// NewCohereReranker creates a Reranker for a Cohere rerank model, e.g. "rerank-v3.5".
+// Client options can set the HTTP client, proxy and endpoint.
+func NewCohereReranker(model, apiKey string, opts ...ClientOption) Reranker {
+	return &cohereReranker{model: model, api: newCohereAPI(apiKey, opts)}
+}
+
+// Rerank implements Reranker with the v2 rerank endpoint. Cohere bills rerank by
+// search units rather than tokens, so the usage is zero.
+func (r *cohereReranker) Rerank(ctx context.Context, query string, documents []string, opts ...RerankOption) ([]RerankResult, Usage, error) {
+	options := &RerankOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if len(documents) == 0 {
+		return nil, Usage{}, nil
+	}
+	req := struct {
+		Model     string   `json:"model"`
+		Query     string   `json:"query"`
+		Documents []string `json:"documents"`
+		TopN      int      `json:"top_n,omitempty"`
+	}{Model: r.model, Query: query, Documents: documents, TopN: options.topN}
+	var resp struct {
+		Results []rerankScore `json:"results"`
+	}
+	if err := r.api.post(ctx, "/v2/rerank", &req, &resp); err != nil {
+		return nil, Usage{}, err
+	}
+	results, err := rerankResults(documents, resp.Results)
+	return results, Usage{}, err
+}
@@ -0,0 +1,105 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+const (
+	// defaultSummaryTokens bounds the length of a history summary.
+	defaultSummaryTokens = 1024
+	// summaryPrefix starts every history summary message, so a later compaction
+	// can fold the previous summary into the new one.
+	summaryPrefix = "Summary of the earlier conversation:\n"
+)
+
+// WithHistorySummarizer makes the HistoryManager compact history instead of
+// dropping it: the turns it cuts are summarized by model, typically a cheaper
+// one, and replaced by a single summary message. A previous summary is folded
+// into the next one, so a long-running session keeps one summary of everything
+// before its recent turns. Part of the token budget, up to 1024 tokens, is
+// reserved for the summary. If the summarizer fails, Trim returns its error rather
+// than dropping the turns unsummarized.
+func WithHistorySummarizer(model Model, opts ...ChatOption) HistoryOption {
+	return func(h *HistoryManager) {
+		h.summarizer = model
+		h.summarizerOpts = opts
+	}
+}
+
+// summaryTokens returns the maximum length of a summary.
+func (h *HistoryManager) summaryTokens() int {
+	if h.maxTokens > 0 {
+		return min(defaultSummaryTokens, h.maxTokens/4)
+	}
+	return defaultSummaryTokens
+}
+
+// summarize replaces the cut turns, and any previous summary, with a summary
+// message placed so the tool call and result pairs of kept stay valid: after the
+// system messages when kept starts with a user message, or as a user message
+// before kept otherwise, including when nothing is kept.
+func (h *HistoryManager) summarize(ctx context.Context, system, cut, kept []Message) ([]Message, error) {
+	var sb strings.Builder
+	others := make([]Message, 0, len(system))
+	for _, msg := range system {
+		if previous, ok := strings.CutPrefix(msg.Content(), summaryPrefix); ok {
+			sb.WriteString("Earlier summary:\n" + previous + "\n\n")
+			continue
+		}
+		others = append(others, msg)
+	}
+	sb.WriteString(renderTranscript(cut))
+
+	messages := []Message{
+		NewSystemMessage("Summarize the conversation below for the assistant that continues it. " +
+			"Keep facts, decisions, open tasks, names, identifiers and tool results that later turns may need. " +
+			"Reply with the summary only."),
+		NewUserMessage(sb.String()),
+	}
+	opts := append(h.summarizerOpts[:len(h.summarizerOpts):len(h.summarizerOpts)], WithMaxTokens(h.summaryTokens()))
+	resp, err := h.summarizer.ChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("summarize history: %w", err)
+	}
+	if resp.Answer() == nil || strings.TrimSpace(resp.Answer().Content()) == "" {
+		return nil, errors.New("summarize history: empty summary")
+	}
+
+	summary := summaryPrefix + strings.TrimSpace(resp.Answer().Content())
+	if len(kept) > 0 && kept[0].Role() == constants.RoleUser {
+		return joinMessages(append(others, NewSystemMessage(summary)), kept), nil
+	}
+	return joinMessages(others, append([]Message{NewUserMessage(summary)}, kept...)), nil
+}
+
+// renderTranscript writes messages as plain text for the summarizer. A previous
+// summary sent as a user message is labelled as such.
+func renderTranscript(messages []Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		content := msg.Content()
+		if previous, ok := strings.CutPrefix(content, summaryPrefix); ok {
+			sb.WriteString("Earlier summary:\n" + previous + "\n\n")
+			continue
+		}
+		rich, _ := msg.(RichMessage)
+		if rich != nil && msg.Role() == constants.RoleTool {
+			fmt.Fprintf(&sb, "tool result %s: %s\n", rich.ToolCallID(), content)
+			continue
+		}
+		if content != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", msg.Role(), content)
+		}
+		if rich != nil {
+			for _, tc := range rich.ToolCalls() {
+				fmt.Fprintf(&sb, "%s called %s %s: %s\n", msg.Role(), tc.Function().Name(), tc.ID(), tc.Function().Arguments())
+			}
+		}
+	}
+	return sb.String()
+}
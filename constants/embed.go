@@ -0,0 +1,8 @@
+package constants
+
+// Embedding input types, used to optimize vectors for retrieval where the
+// provider supports it (Cohere input_type, Vertex task_type).
+const (
+	EmbedInputQuery    = "query"
+	EmbedInputDocument = "document"
+)
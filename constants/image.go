@@ -9,3 +9,26 @@ const (
 	ImageURLDetailLow  = string(openai.ImageURLDetailLow)
 	ImageURLDetailAuto = string(openai.ImageURLDetailAuto)
 )
+
+// Image generation sizes. Support varies by model: dall-e-2 takes the square
+// sizes up to 1024x1024, dall-e-3 adds 1792x1024 and 1024x1792, and gpt-image-1
+// takes 1024x1024, 1536x1024, 1024x1536 and "auto".
+const (
+	ImageSize256x256   = openai.CreateImageSize256x256
+	ImageSize512x512   = openai.CreateImageSize512x512
+	ImageSize1024x1024 = openai.CreateImageSize1024x1024
+	ImageSize1792x1024 = openai.CreateImageSize1792x1024
+	ImageSize1024x1792 = openai.CreateImageSize1024x1792
+	ImageSize1536x1024 = openai.CreateImageSize1536x1024
+	ImageSize1024x1536 = openai.CreateImageSize1024x1536
+	ImageSizeAuto      = "auto"
+)
+
+// Image generation qualities: standard and hd for dall-e-3, low to high for gpt-image-1.
+const (
+	ImageQualityStandard = openai.CreateImageQualityStandard
+	ImageQualityHD       = openai.CreateImageQualityHD
+	ImageQualityLow      = openai.CreateImageQualityLow
+	ImageQualityMedium   = openai.CreateImageQualityMedium
+	ImageQualityHigh     = openai.CreateImageQualityHigh
+)
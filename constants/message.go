@@ -4,4 +4,19 @@ package constants
 const (
 	ContentPartTypeText     = "text"
 	ContentPartTypeImageURL = "image_url"
+	ContentPartTypeDocument = "document"
+	ContentPartTypeVideo    = "video"
+
+	// Server-side tool activity (Anthropic web search).
+	ContentPartTypeServerToolUse   = "server_tool_use"
+	ContentPartTypeWebSearchResult = "web_search_tool_result"
+)
+
+// CitationType defines the kind of location a Citation points to.
+const (
+	CitationTypeCharLocation            = "char_location"
+	CitationTypePageLocation            = "page_location"
+	CitationTypeContentBlockLocation    = "content_block_location"
+	CitationTypeWebSearchResultLocation = "web_search_result_location"
+	CitationTypeSearchResultLocation    = "search_result_location"
 )
@@ -0,0 +1,10 @@
+package constants
+
+// Modalities a model accepts or produces (see ModelInfo).
+const (
+	ModalityText      = "text"
+	ModalityImage     = "image"
+	ModalityAudio     = "audio"
+	ModalityDocument  = "document"
+	ModalityEmbedding = "embedding"
+)
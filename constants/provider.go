@@ -3,4 +3,9 @@ package constants
 const (
 	ProviderOpenAI    = "openai"
 	ProviderAnthropic = "anthropic"
+	ProviderCohere    = "cohere"
+	ProviderVertex    = "vertex"
+	ProviderVoyage    = "voyage"
+	ProviderJina      = "jina"
+	ProviderFake      = "fake"
 )
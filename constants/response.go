@@ -0,0 +1,23 @@
+package constants
+
+// ResponseFormat defines the output format requested from the model.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// Constraint kinds accepted by WithConstraint.
+const (
+	ConstraintJSONSchema = "json_schema"
+	ConstraintRegex      = "regex"
+	ConstraintGrammar    = "grammar"
+	ConstraintChoice     = "choice"
+)
+
+// Constraint backends accepted by WithConstraintBackend: OpenAI-compatible servers
+// that enforce regex, grammar and choice constraints through extra request fields.
+const (
+	ConstraintBackendVLLM     = "vllm"
+	ConstraintBackendLlamaCpp = "llama.cpp"
+)
@@ -0,0 +1,15 @@
+package constants
+
+// StreamEventType identifies the kind of a unified stream event.
+const (
+	StreamEventContent      = "content"
+	StreamEventReasoning    = "reasoning"
+	StreamEventRefusal      = "refusal"
+	StreamEventToolCall     = "tool_call"
+	StreamEventToolCallDone = "tool_call_done"
+	StreamEventField        = "field"
+	StreamEventUsage        = "usage"
+	StreamEventMeta         = "meta"
+	StreamEventStop         = "stop"
+	StreamEventError        = "error"
+)
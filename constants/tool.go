@@ -6,4 +6,10 @@ import (
 
 const (
 	ToolTypeFunction = string(openai.ToolTypeFunction)
+
+	// Anthropic built-in tools.
+	ToolTypeWebSearch  = "web_search"
+	ToolTypeBash       = "bash"
+	ToolTypeTextEditor = "text_editor"
+	ToolTypeComputer   = "computer"
 )
@@ -0,0 +1,241 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// defaultContinuePrompt asks the model to resume a truncated answer.
+const defaultContinuePrompt = "Your previous answer was cut off. Continue exactly where it stopped, without repeating anything or adding an introduction."
+
+// AutoContinueConfig configures WithAutoContinue.
+type AutoContinueConfig struct {
+	// MaxContinuations is the maximum number of continuation requests per call (default 2).
+	MaxContinuations int
+	// Prompt is the user message asking the model to resume its answer.
+	Prompt string
+}
+
+// WithAutoContinue returns a Middleware that resumes answers truncated by the
+// output token limit (see IsTruncated). The truncated answer is sent back
+// with a user message asking the model to continue, up to MaxContinuations times,
+// and the parts are stitched into one response whose usage is the sum of the
+// requests. Answers carrying tool calls are not continued. Streams continue on
+// the same watcher, which sees OnStop once, after the last part; usage reported
+// to a UsageWatcher includes the earlier parts. Meta.Continuations reports the
+// number of continuation requests made; a response that is still truncated after
+// the last one is still reported by IsTruncated. Requests for structured output
+// (WithResponseFormat other than text, WithResponseSchema or WithConstraint) are
+// not continued, as a second part would start a new JSON value rather than
+// resume the first; their truncated answer is returned as is.
+func WithAutoContinue(cfg AutoContinueConfig) Middleware {
+	if cfg.MaxContinuations <= 0 {
+		cfg.MaxContinuations = 2
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = defaultContinuePrompt
+	}
+	return func(next Model) Model {
+		return &continueModel{Model: next, cfg: cfg}
+	}
+}
+
+// continueModel is the Model returned by WithAutoContinue.
+type continueModel struct {
+	Model
+	cfg AutoContinueConfig
+}
+
+// Unwrap returns the wrapped model.
+func (m *continueModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *continueModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, m.Model.ChatCompletion)
+}
+
+// ChatCompletionStream implements Model.
+func (m *continueModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, m.Model.ChatCompletionStream)
+}
+
+// do sends the request and its continuations, stitching the parts together.
+func (m *continueModel) do(ctx context.Context, messages []Message, opts []ChatOption, request func(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	var watcher *continueWatcher
+	if options.watcher != nil {
+		watcher = &continueWatcher{watcher: options.watcher}
+		opts = append(opts[:len(opts):len(opts)], WithStreamWatcher(watcher))
+	}
+
+	start := time.Now()
+	var parts []Response
+	for {
+		resp, err := request(ctx, messages, opts...)
+		if resp != nil {
+			parts = append(parts, resp)
+		}
+		if err != nil {
+			if len(parts) < 2 {
+				return resp, err
+			}
+			stitched := stitchResponses(parts, time.Since(start))
+			var partial *PartialResponseError
+			if errors.As(err, &partial) {
+				err = partial.Err
+			}
+			return stitched, &PartialResponseError{Response: stitched, Err: err}
+		}
+
+		answer := resp.Answer()
+		if !IsTruncated(resp) || answer == nil || answer.Content() == "" || len(resp.ToolCalls()) > 0 ||
+			structuredOutput(options) || len(parts) > m.cfg.MaxContinuations || ctx.Err() != nil {
+			break
+		}
+		messages = append(messages[:len(messages):len(messages)],
+			NewAssistantMessage(answer.Content()), NewUserMessage(m.cfg.Prompt))
+		if watcher != nil {
+			watcher.finish(resp.Usage())
+		}
+	}
+
+	resp := parts[0]
+	if len(parts) > 1 {
+		resp = stitchResponses(parts, time.Since(start))
+	}
+	if watcher != nil && watcher.stopped {
+		if err := options.watcher.OnStop(); err != nil && !errors.Is(err, ErrStopStreaming) {
+			return resp, &PartialResponseError{Response: resp, Err: err}
+		}
+	}
+	return resp, nil
+}
+
+// structuredOutput reports whether options constrain the answer to a structured
+// format that a continuation cannot resume.
+func structuredOutput(options *ChatOptions) bool {
+	return options.responseFormat != "" && options.responseFormat != constants.ResponseFormatText ||
+		options.responseSchema != nil || options.constraint != nil
+}
+
+// stitchResponses joins the parts of a continued answer into one response with
+// the metadata and tool calls of the last part and the summed usage.
+func stitchResponses(parts []Response, duration time.Duration) Response {
+	last := parts[len(parts)-1]
+	var content, reasoning strings.Builder
+	var usage Usage
+	var logprobs []TokenLogProb
+	for _, part := range parts {
+		if answer := part.Answer(); answer != nil {
+			content.WriteString(answer.Content())
+			reasoning.WriteString(answer.Reasoning())
+		}
+		usage = usage.Add(part.Usage())
+		logprobs = append(logprobs, LogProbs(part)...)
+	}
+
+	answer := asLLMMessage(NewAssistantMessage(content.String(), last.ToolCalls()...))
+	answer.reasoning = reasoning.String()
+	meta := last.Meta()
+	meta.Continuations = len(parts) - 1
+	return &response{
+		answer:   answer,
+		tcalls:   last.ToolCalls(),
+		usage:    usage,
+		meta:     meta,
+		duration: duration,
+		logprobs: logprobs,
+	}
+}
+
+// continueWatcher forwards the events of every part of a continued stream to the
+// caller's watcher, holding back OnStop until the last part and offsetting usage
+// by the parts already finished.
+type continueWatcher struct {
+	watcher StreamWatcher
+	// base is the usage of the finished parts.
+	base Usage
+	// stopped reports whether the current part ended with OnStop.
+	stopped bool
+}
+
+// finish records the usage of a part that is being continued.
+func (w *continueWatcher) finish(usage Usage) {
+	w.base = w.base.Add(usage)
+	w.stopped = false
+}
+
+// OnRefusal implements StreamWatcher.
+func (w *continueWatcher) OnRefusal(delta string) error {
+	return w.watcher.OnRefusal(delta)
+}
+
+// OnReasoning implements StreamWatcher.
+func (w *continueWatcher) OnReasoning(delta string) error {
+	return w.watcher.OnReasoning(delta)
+}
+
+// OnContent implements StreamWatcher.
+func (w *continueWatcher) OnContent(delta string) error {
+	return w.watcher.OnContent(delta)
+}
+
+// OnToolCall implements StreamWatcher.
+func (w *continueWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	return w.watcher.OnToolCall(ctx, tcall, args)
+}
+
+// OnStop implements StreamWatcher.
+func (w *continueWatcher) OnStop() error {
+	w.stopped = true
+	return nil
+}
+
+// OnUsage implements UsageWatcher.
+func (w *continueWatcher) OnUsage(usage Usage) error {
+	if uw, ok := w.watcher.(UsageWatcher); ok {
+		return uw.OnUsage(w.base.Add(usage))
+	}
+	return nil
+}
+
+// OnMeta implements UsageWatcher.
+func (w *continueWatcher) OnMeta(meta Meta) error {
+	if uw, ok := w.watcher.(UsageWatcher); ok {
+		return uw.OnMeta(meta)
+	}
+	return nil
+}
+
+// OnToolCallDone implements ToolCallDoneWatcher.
+func (w *continueWatcher) OnToolCallDone(ctx context.Context, tcall ToolCall) error {
+	if dw, ok := w.watcher.(ToolCallDoneWatcher); ok {
+		return dw.OnToolCallDone(ctx, tcall)
+	}
+	return nil
+}
+
+// OnField implements FieldWatcher.
+func (w *continueWatcher) OnField(path string, value any) error {
+	if fw, ok := w.watcher.(FieldWatcher); ok {
+		return fw.OnField(path, value)
+	}
+	return nil
+}
+
+// OnRawEvent implements RawEventWatcher.
+func (w *continueWatcher) OnRawEvent(providerEvent any) error {
+	if rw, ok := w.watcher.(RawEventWatcher); ok {
+		return rw.OnRawEvent(providerEvent)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package openllm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestAutoContinueSkipsStructuredOutput(t *testing.T) {
+	base := &truncatingModel{Model: NewEchoModel()}
+	model := Wrap(base, WithAutoContinue(AutoContinueConfig{MaxContinuations: 2}))
+	messages := []Message{NewUserMessage("answer in JSON")}
+
+	resp, err := model.ChatCompletion(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if base.calls != 3 || resp.Meta().Continuations != 2 {
+		t.Errorf("text: %d calls, %d continuations, want 3 and 2", base.calls, resp.Meta().Continuations)
+	}
+
+	for name, opt := range map[string]ChatOption{
+		"json_object": WithResponseFormat(constants.ResponseFormatJSONObject),
+		"json_schema": WithResponseSchema("answer", &Schema{Type: "object"}, true),
+		"constraint":  WithConstraint(constants.ConstraintRegex, `\{.*\}`),
+	} {
+		base.calls = 0
+		resp, err := model.ChatCompletion(context.Background(), messages, opt)
+		if err != nil {
+			t.Fatalf("%s: ChatCompletion: %v", name, err)
+		}
+		if base.calls != 1 {
+			t.Errorf("%s: %d calls, want 1", name, base.calls)
+		}
+		if got := resp.Answer().Content(); got != `{"items":[1,` || !IsTruncated(resp) {
+			t.Errorf("%s: answer = %q (truncated %v), want the first part, truncated", name, got, IsTruncated(resp))
+		}
+	}
+}
+
+// truncatingModel answers every request with a JSON prefix cut off by the token limit.
+type truncatingModel struct {
+	Model
+	calls int
+}
+
+func (m *truncatingModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	m.calls++
+	return &response{
+		answer: asLLMMessage(NewAssistantMessage(`{"items":[1,`)),
+		meta:   Meta{FinishReason: FinishLength},
+	}, nil
+}
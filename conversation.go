@@ -0,0 +1,152 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// Conversation accumulates the messages of a chat session.
+// The system prompt is kept apart from the turns and always comes first in Messages,
+// so it can be changed at any time. A Conversation is safe for concurrent use.
+type Conversation struct {
+	mu sync.RWMutex
+	// system is the system prompt; empty means none.
+	system string
+	// messages holds the turns in order.
+	messages []Message
+}
+
+// NewConversation creates a Conversation starting with the given messages.
+// A leading system message becomes the system prompt.
+func NewConversation(messages ...Message) *Conversation {
+	c := &Conversation{}
+	if len(messages) > 0 && messages[0].Role() == constants.RoleSystem {
+		c.system = messages[0].Content()
+		messages = messages[1:]
+	}
+	c.messages = append(c.messages, messages...)
+	return c
+}
+
+// SetSystem sets or replaces the system prompt; an empty prompt removes it.
+func (c *Conversation) SetSystem(prompt string) *Conversation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.system = prompt
+	return c
+}
+
+// System returns the system prompt.
+func (c *Conversation) System() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.system
+}
+
+// Add appends messages to the conversation.
+func (c *Conversation) Add(messages ...Message) *Conversation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, messages...)
+	return c
+}
+
+// AddUser appends a user message.
+func (c *Conversation) AddUser(text string, opts ...MessageOption) *Conversation {
+	return c.Add(NewUserMessage(text, opts...))
+}
+
+// AddAssistant appends an assistant message, optionally requesting tool calls.
+func (c *Conversation) AddAssistant(text string, toolCalls ...ToolCall) *Conversation {
+	return c.Add(NewAssistantMessage(text, toolCalls...))
+}
+
+// AddToolResult appends the result of a tool call; see NewToolResultMessage.
+func (c *Conversation) AddToolResult(toolCallID string, result any, isError bool) *Conversation {
+	return c.Add(NewToolResultMessage(toolCallID, result, isError))
+}
+
+// AddResponse appends the answer of a model response.
+func (c *Conversation) AddResponse(resp Response) *Conversation {
+	return c.Add(resp.Answer())
+}
+
+// Messages returns the system prompt (as a system message) followed by the turns.
+// The returned slice is a copy.
+func (c *Conversation) Messages() []Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	messages := make([]Message, 0, len(c.messages)+1)
+	if c.system != "" {
+		messages = append(messages, NewSystemMessage(c.system))
+	}
+	return append(messages, c.messages...)
+}
+
+// Len returns the number of turns, excluding the system prompt.
+func (c *Conversation) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.messages)
+}
+
+// Last returns the most recent turn, or nil if there is none.
+func (c *Conversation) Last() Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.messages) == 0 {
+		return nil
+	}
+	return c.messages[len(c.messages)-1]
+}
+
+// Clone returns an independent copy, e.g. to branch a conversation.
+// Messages themselves are shared as they are not modified after creation.
+func (c *Conversation) Clone() *Conversation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Conversation{
+		system:   c.system,
+		messages: append([]Message(nil), c.messages...),
+	}
+}
+
+// MarshalJSON implements json.Marshaler with the format of EncodeConversation;
+// the system prompt is stored as a leading system message.
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	return EncodeConversation(c.Messages())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the system prompt and turns.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	messages, err := DecodeConversation(data)
+	if err != nil {
+		return err
+	}
+	decoded := NewConversation(messages...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.system, c.messages = decoded.system, decoded.messages
+	return nil
+}
+
+// Send calls model with the conversation and appends the answer on success.
+func (c *Conversation) Send(ctx context.Context, model Model, opts ...ChatOption) (Response, error) {
+	resp, err := model.ChatCompletion(ctx, c.Messages(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.AddResponse(resp)
+	return resp, nil
+}
@@ -0,0 +1,118 @@
+package openllm
+
+import (
+	"strings"
+	"sync"
+)
+
+// Price is the price of a model in USD per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+	// CacheRead is the price of input tokens read from the prompt cache; zero charges Input.
+	CacheRead float64 `json:"cache_read,omitempty"`
+	// CacheWrite is the price of input tokens written to the prompt cache; zero charges Input.
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+// Cost is the price of a request in USD, broken out by kind of token.
+type Cost struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+	Total      float64 `json:"total"`
+}
+
+// Add returns the sum of c and other.
+func (c Cost) Add(other Cost) Cost {
+	return Cost{
+		Input:      c.Input + other.Input,
+		Output:     c.Output + other.Output,
+		CacheRead:  c.CacheRead + other.CacheRead,
+		CacheWrite: c.CacheWrite + other.CacheWrite,
+		Total:      c.Total + other.Total,
+	}
+}
+
+var (
+	pricesMu sync.RWMutex
+	// prices holds list prices keyed by model name prefix. They change over time;
+	// use SetPrice to correct them or add negotiated rates.
+	prices = map[string]Price{
+		"gpt-4o":            {Input: 2.50, Output: 10, CacheRead: 1.25},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+		"gpt-4.1":           {Input: 2, Output: 8, CacheRead: 0.50},
+		"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, CacheRead: 0.10},
+		"gpt-4.1-nano":      {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+		"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+		"o1":                {Input: 15, Output: 60, CacheRead: 7.50},
+		"o1-mini":           {Input: 1.10, Output: 4.40, CacheRead: 0.55},
+		"o3":                {Input: 2, Output: 8, CacheRead: 0.50},
+		"o3-mini":           {Input: 1.10, Output: 4.40, CacheRead: 0.55},
+		"o4-mini":           {Input: 1.10, Output: 4.40, CacheRead: 0.275},
+		"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.30},
+		"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+		"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.50, CacheWrite: 6.25},
+		// Text input of gpt-image-1; image input tokens cost more and are not told apart.
+		"gpt-image-1": {Input: 5, Output: 40},
+	}
+)
+
+// SetPrice sets the price of model, or of every model whose name starts with it
+// and has no longer matching entry, e.g. "claude-3-7-sonnet" for dated versions.
+func SetPrice(model string, price Price) {
+	pricesMu.Lock()
+	defer pricesMu.Unlock()
+	prices[model] = price
+}
+
+// LookupPrice returns the price of model from the entry with the longest matching prefix.
+func LookupPrice(model string) (Price, bool) {
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+	best, found := "", false
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	return prices[best], found
+}
+
+// Cost returns the price of u at price. Input tokens read from or written to the
+// prompt cache are charged at the cache prices; OpenAI counts cached tokens within
+// InputTokens, Anthropic separately.
+func (u Usage) Cost(price Price) Cost {
+	const perToken = 1e-6
+	cacheRead, cacheWrite := price.CacheRead, price.CacheWrite
+	if cacheRead == 0 {
+		cacheRead = price.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = price.Input
+	}
+	c := Cost{
+		Input:      float64(u.InputTokens-u.CachedTokens) * price.Input * perToken,
+		Output:     float64(u.OutputTokens) * price.Output * perToken,
+		CacheRead:  float64(u.CachedTokens+u.CacheReadInputTokens) * cacheRead * perToken,
+		CacheWrite: float64(u.CacheCreationInputTokens) * cacheWrite * perToken,
+	}
+	c.Total = c.Input + c.Output + c.CacheRead + c.CacheWrite
+	return c
+}
+
+// withCost returns u with CostUSD set from the price of model, when known.
+func (u Usage) withCost(model string) Usage {
+	if price, ok := LookupPrice(model); ok {
+		cost := u.Cost(price)
+		u.CostUSD = &cost
+	}
+	return u
+}
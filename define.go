@@ -3,6 +3,7 @@ package openllm
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -23,9 +24,12 @@ type FunctionOptions struct {
 type FunctionDefinition struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	Parameters  any    `json:"parameters"`
-	Strict      bool   `json:"strict,omitempty"`
-	InvokeFunc  any    `json:"-"`
+	// Parameters is the parameter schema: a *Schema when generated from the
+	// function (a jsonschema.Definition in earlier versions), a json.RawMessage
+	// from DefineFunctionFromJSONSchema, and otherwise a jsonschema.Definition.
+	Parameters any  `json:"parameters"`
+	Strict     bool `json:"strict,omitempty"`
+	InvokeFunc any  `json:"-"`
 }
 
 // FunctionOption defines a functional option for configuring a function tool.
@@ -33,7 +37,9 @@ type FunctionOption func(opts *FunctionOptions)
 
 // WithFunction sets a callback function for the tool.
 // The parameter struct T should use `openllm` for parameter configuration.
-// Format: `openllm:"name,required,desc=..."`
+// Format: `openllm:"name,required,enum=a|b,default=a,min=1,max=10,format=email,desc=..."`
+// Fields without an `openllm` tag fall back to their `json` tag and embedded
+// structs are flattened, so existing DTOs can be used as-is.
 func WithFunction(fnptr any) FunctionOption {
 	return func(opts *FunctionOptions) { opts.InvokeFunc = fnptr }
 }
@@ -49,6 +55,11 @@ func WithFunctionStrict(strict bool) FunctionOption {
 }
 
 // DefineFunction creates a generic function tool definition.
+//
+// Breaking change: parameters generated from a WithFunction callback are now a
+// *Schema rather than a jsonschema.Definition, so that they keep the format,
+// default and bounds keywords. Code that type-asserts FunctionDefinition.Parameters
+// to jsonschema.Definition must handle *Schema as well.
 func DefineFunction(name, description string, opts ...FunctionOption) Tool {
 	options := &FunctionOptions{
 		Name:        name,
@@ -62,7 +73,7 @@ func DefineFunction(name, description string, opts ...FunctionOption) Tool {
 	if options.Parameters == nil && options.InvokeFunc != nil {
 		parameters := generateParametersFromFunc(options.InvokeFunc)
 		if parameters != nil {
-			options.Parameters = *parameters
+			options.Parameters = parameters
 		}
 	}
 
@@ -73,7 +84,7 @@ func DefineFunction(name, description string, opts ...FunctionOption) Tool {
 			Properties: make(map[string]jsonschema.Definition),
 			Required:   make([]string, 0),
 		}
-	} else {
+	} else if !isSchemaPassthrough(options.Parameters) {
 		// Normalize parameters to jsonschema.Definition if possible
 		if _, ok := options.Parameters.(jsonschema.Definition); !ok {
 			data, err := json.Marshal(options.Parameters)
@@ -104,9 +115,42 @@ func DefineFunction(name, description string, opts ...FunctionOption) Tool {
 	}
 }
 
+// DefineFunctionFromJSONSchema creates a function tool whose parameters are given
+// as a raw JSON Schema document, e.g. loaded from a file shared with other services.
+// The schema is passed to providers verbatim; it must be a JSON object describing
+// an object type. Options such as WithFunction and WithFunctionStrict still apply.
+func DefineFunctionFromJSONSchema(name, description string, schema json.RawMessage, opts ...FunctionOption) (Tool, error) {
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema for %q: %w", name, err)
+	}
+	if typ, ok := root["type"]; ok && typ != string(jsonschema.Object) {
+		return nil, fmt.Errorf("invalid JSON schema for %q: parameters must be of type object, got %v", name, typ)
+	}
+	// Copy so later changes to the caller's buffer don't leak into the tool
+	raw := append(json.RawMessage(nil), schema...)
+	return DefineFunction(name, description, append(opts, WithFunctionParameters(raw))...), nil
+}
+
+// DefineTypedFunction creates a function tool from a typed callback.
+// The schema is generated from TArgs once; at call time the arguments are decoded
+// straight into TArgs and fn is invoked directly, without reflective calls.
+// Options such as WithFunctionStrict or WithFunctionParameters still apply.
+func DefineTypedFunction[TArgs any, TResult any](name, description string, fn func(ctx context.Context, args TArgs) (TResult, error), opts ...FunctionOption) Tool {
+	var params any
+	if parameters := generateParametersFromFunc(fn); parameters != nil {
+		params = parameters
+	}
+	typed := newTypedFunction(fn)
+	return DefineFunction(name, description, append([]FunctionOption{
+		WithFunctionParameters(params),
+		func(opts *FunctionOptions) { opts.InvokeFunc = typed },
+	}, opts...)...)
+}
+
 // generateParametersFromFunc analyzes the signature of the provided function
 // and generates a JSON Schema definition based on the parameter struct's tags.
-func generateParametersFromFunc(fn any) *jsonschema.Definition {
+func generateParametersFromFunc(fn any) *Schema {
 	if fn == nil {
 		return nil
 	}
@@ -147,73 +191,150 @@ func generateParametersFromFunc(fn any) *jsonschema.Definition {
 	return parseStructToDefinition(paramType)
 }
 
-func parseStructToDefinition(t reflect.Type) *jsonschema.Definition {
-	def := &jsonschema.Definition{
-		Type:       jsonschema.Object,
-		Properties: make(map[string]jsonschema.Definition),
-		Required:   []string{},
+// fieldTag is the parsed form of an `openllm` struct tag.
+type fieldTag struct {
+	// name is the JSON property name.
+	name string
+	// required marks the property as required.
+	required bool
+	// enum lists the allowed values ("enum=a|b|c").
+	enum []string
+	// def is the default value ("default="); hasDef reports whether it was set.
+	def    string
+	hasDef bool
+	// min and max bound numbers, string lengths, array items or map entries ("min=", "max=").
+	min, max string
+	// format is the JSON Schema format, e.g. "email" or "uri" ("format=").
+	format string
+	// desc is the property description; it must be the last option as it may contain commas.
+	desc string
+}
+
+// parseFieldTag parses the `openllm` tag of a struct field, falling back to its
+// `json` tag, in which case the field is required unless marked omitempty/omitzero.
+// It returns false for unexported or untagged fields, which are not tool parameters.
+func parseFieldTag(field reflect.StructField) (tag fieldTag, ok bool) {
+	// Skip unexported fields
+	if field.PkgPath != "" {
+		return tag, false
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
+	argTag := field.Tag.Get("openllm")
+	if argTag == "" {
+		return parseJSONFieldTag(field)
+	}
 
-		// Skip unexported fields
-		if field.PkgPath != "" {
-			continue
+	parts := strings.Split(argTag, ",")
+	tag.name = parts[0]
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			tag.required = true
+		case "enum":
+			tag.enum = strings.Split(value, "|")
+		case "default":
+			tag.def, tag.hasDef = value, true
+		case "min":
+			tag.min = value
+		case "max":
+			tag.max = value
+		case "format":
+			tag.format = value
+		case "desc":
+			tag.desc = strings.Join(append([]string{value}, parts[i+1:]...), ",")
+			return tag, true
 		}
+	}
+	return tag, true
+}
 
-		argTag := field.Tag.Get("openllm")
-		if argTag == "" {
-			continue
+// parseJSONFieldTag derives a fieldTag from the `json` tag of a struct field.
+func parseJSONFieldTag(field reflect.StructField) (tag fieldTag, ok bool) {
+	jsonTag, found := field.Tag.Lookup("json")
+	if !found || jsonTag == "-" {
+		return tag, false
+	}
+	name, opts, _ := strings.Cut(jsonTag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	tag.name = name
+	tag.required = true
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			tag.required = false
 		}
+	}
+	return tag, true
+}
 
-		parts := strings.Split(argTag, ",")
-
-		var (
-			name     = parts[0]
-			required bool
-			desc     string
-		)
-		for i := 1; i < len(parts); i++ {
-			part := parts[i]
-			if part == "required" {
-				required = true
-			} else if strings.HasPrefix(part, "desc=") {
-				desc = strings.TrimPrefix(part, "desc=")
-				break
-			}
-		}
+// paramField is a tool parameter field of a struct, possibly promoted from an embedded struct.
+type paramField struct {
+	field reflect.StructField
+	// index is the field path from the outer struct, as for reflect.Value.FieldByIndex.
+	index []int
+	tag   fieldTag
+}
 
-		fieldDef := jsonschema.Definition{
-			Description: desc,
-		}
+// structFields returns the parameter fields of struct type t in declaration order.
+// Untagged embedded structs are flattened like encoding/json does; fields of the
+// outer struct shadow promoted fields with the same name.
+func structFields(t reflect.Type) []paramField {
+	var fields []paramField
+	names := make(map[string]bool)
+	collectFields(t, nil, names, map[reflect.Type]bool{t: true}, &fields)
+	return fields
+}
 
-		// Map Go types to JSON Schema types
-		switch field.Type.Kind() {
-		case reflect.String:
-			fieldDef.Type = jsonschema.String
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			fieldDef.Type = jsonschema.Integer
-		case reflect.Float32, reflect.Float64:
-			fieldDef.Type = jsonschema.Number
-		case reflect.Bool:
-			fieldDef.Type = jsonschema.Boolean
-		case reflect.Struct:
-			subDef := parseStructToDefinition(field.Type)
-			fieldDef = *subDef
-		case reflect.Ptr:
-			if field.Type.Elem().Kind() == reflect.Struct {
-				subDef := parseStructToDefinition(field.Type.Elem())
-				fieldDef = *subDef
-			}
+// collectFields appends the fields of t, then those of its embedded structs, skipping taken names.
+func collectFields(t reflect.Type, index []int, names map[string]bool, seen map[reflect.Type]bool, fields *[]paramField) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && isEmbeddedStruct(field) {
+			embedded = append(embedded, field)
+			continue
 		}
+		tag, ok := parseFieldTag(field)
+		if !ok || names[tag.name] {
+			continue
+		}
+		names[tag.name] = true
+		*fields = append(*fields, paramField{
+			field: field,
+			index: append(append([]int(nil), index...), i),
+			tag:   tag,
+		})
+	}
 
-		def.Properties[name] = fieldDef
-		if required {
-			def.Required = append(def.Required, name)
+	for _, field := range embedded {
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if seen[ft] {
+			continue
 		}
+		seen[ft] = true
+		collectFields(ft, append(append([]int(nil), index...), field.Index...), names, seen, fields)
 	}
+}
 
-	return def
+// isEmbeddedStruct reports whether an anonymous field is a struct to flatten,
+// i.e. it has no tag giving it a name of its own.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if ft.Kind() != reflect.Struct {
+		return false
+	}
+	if field.Tag.Get("openllm") != "" {
+		return false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name == "" && field.Tag.Get("json") != "-"
 }
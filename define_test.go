@@ -0,0 +1,29 @@
+package openllm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+func TestDefineFunctionParametersType(t *testing.T) {
+	type params struct {
+		Query string `openllm:"query,required,format=email"`
+	}
+	generated := DefineFunction("search", "Search", WithFunction(func(ctx context.Context, p *params) (string, error) {
+		return p.Query, nil
+	}))
+	schema, ok := generated.Definition().(*FunctionDefinition).Parameters.(*Schema)
+	if !ok {
+		t.Fatalf("generated parameters are %T, want *Schema", generated.Definition().(*FunctionDefinition).Parameters)
+	}
+	if schema.Properties["query"].Format != "email" {
+		t.Errorf("query format = %q, want email", schema.Properties["query"].Format)
+	}
+
+	explicit := DefineFunction("noop", "No-op", WithFunctionParameters(map[string]any{"type": "object"}))
+	if _, ok := explicit.Definition().(*FunctionDefinition).Parameters.(jsonschema.Definition); !ok {
+		t.Errorf("explicit parameters are %T, want jsonschema.Definition", explicit.Definition().(*FunctionDefinition).Parameters)
+	}
+}
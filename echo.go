@@ -0,0 +1,242 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// defaultEchoTemplate answers with the tool results after a tool call and with
+// the user's text otherwise.
+const defaultEchoTemplate = `{{if .ToolResults}}{{join .ToolResults "\n"}}{{else}}{{.Text}}{{end}}`
+
+// EchoInput is the data passed to the templates of an echo model.
+type EchoInput struct {
+	// Text is the content of the last user message.
+	Text string
+	// Groups holds the submatches of the rule's pattern; Groups[0] is the whole match.
+	Groups []string
+	// ToolResults holds the contents of the tool results ending the conversation,
+	// when the model is answering its own tool calls.
+	ToolResults []string
+	// Messages are the messages of the request.
+	Messages []Message
+}
+
+// EchoOption configures the Model returned by NewEchoModel.
+type EchoOption func(m *echoModel)
+
+// WithEchoName sets the model name (default "echo").
+func WithEchoName(name string) EchoOption {
+	return func(m *echoModel) { m.name = name }
+}
+
+// WithEchoAnswers adds canned answers keyed by question. Questions match the last
+// user message ignoring case and surrounding space, and take precedence over rules.
+func WithEchoAnswers(answers map[string]string) EchoOption {
+	return func(m *echoModel) {
+		for question, answer := range answers {
+			m.answers[normalizeEchoQuestion(question)] = answer
+		}
+	}
+}
+
+// WithEchoRule answers user messages matching pattern with tmpl, a text/template
+// rendered with an EchoInput; join, trim and json are available as in prompt
+// templates. Rules are tried in the order added. It panics if tmpl does not parse.
+func WithEchoRule(pattern *regexp.Regexp, tmpl string) EchoOption {
+	return func(m *echoModel) {
+		m.rules = append(m.rules, echoRule{pattern: pattern, answer: parseEchoTemplate(tmpl)})
+	}
+}
+
+// WithEchoToolRule calls tool for user messages matching pattern, when the request
+// offers the tool, with arguments rendered from argsTmpl as in WithEchoRule. The
+// next request, carrying the tool result, is answered by the fallback template.
+// It panics if argsTmpl does not parse.
+func WithEchoToolRule(pattern *regexp.Regexp, tool, argsTmpl string) EchoOption {
+	return func(m *echoModel) {
+		m.rules = append(m.rules, echoRule{pattern: pattern, tool: tool, answer: parseEchoTemplate(argsTmpl)})
+	}
+}
+
+// WithEchoFallback sets the template for messages no answer or rule matches.
+// The default echoes the user's text, or the tool results after a tool call.
+// It panics if tmpl does not parse.
+func WithEchoFallback(tmpl string) EchoOption {
+	return func(m *echoModel) { m.fallback = parseEchoTemplate(tmpl) }
+}
+
+// WithEchoPacing streams answers in chunks of chunkRunes runes with delay between
+// them, as in FakeStream. Blocking calls take as long as the stream would.
+func WithEchoPacing(chunkRunes int, delay time.Duration) EchoOption {
+	return func(m *echoModel) {
+		m.chunkRunes = chunkRunes
+		m.delay = delay
+	}
+}
+
+// NewEchoModel returns an in-memory Model for offline development, demos and
+// examples. It answers from canned answers and template rules, calls tools for
+// tool rules, and otherwise echoes the last user message. Responses report an
+// estimated usage and the provider constants.ProviderFake.
+func NewEchoModel(opts ...EchoOption) Model {
+	m := &echoModel{
+		name:     "echo",
+		answers:  map[string]string{},
+		fallback: parseEchoTemplate(defaultEchoTemplate),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// echoModel is the Model returned by NewEchoModel.
+type echoModel struct {
+	name       string
+	answers    map[string]string
+	rules      []echoRule
+	fallback   *template.Template
+	chunkRunes int
+	delay      time.Duration
+	// calls numbers the tool calls made, so their IDs are unique across turns.
+	calls atomic.Int64
+}
+
+// echoRule is a rule of an echo model; tool is empty for answer rules.
+type echoRule struct {
+	pattern *regexp.Regexp
+	tool    string
+	answer  *template.Template
+}
+
+// parseEchoTemplate parses an echo template, panicking on errors.
+func parseEchoTemplate(tmpl string) *template.Template {
+	return template.Must(template.New("echo").Funcs(promptFuncs).Parse(tmpl))
+}
+
+// normalizeEchoQuestion folds a question for canned answer lookup.
+func normalizeEchoQuestion(question string) string {
+	return strings.ToLower(strings.TrimSpace(question))
+}
+
+// Name implements Model.
+func (m *echoModel) Name() string {
+	return m.name
+}
+
+// Description implements Model.
+func (m *echoModel) Description() string {
+	return "In-memory echo model for offline development"
+}
+
+// ChatCompletion implements Model.
+func (m *echoModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, m.name, false, messages)
+	defer func() { end(resp, err) }()
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.watcher = nil
+	return m.do(ctx, messages, options)
+}
+
+// ChatCompletionStream implements Model.
+func (m *echoModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, m.name, true, messages)
+	defer func() { end(resp, err) }()
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return m.do(ctx, messages, options)
+}
+
+// do computes the answer and plays it as a FakeStream.
+func (m *echoModel) do(ctx context.Context, messages []Message, options *ChatOptions) (Response, error) {
+	script, err := m.answer(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	script.ChunkRunes = m.chunkRunes
+	script.Delay = m.delay
+	return script.play(ctx, m.name, messages, options)
+}
+
+// answer selects the output for messages.
+func (m *echoModel) answer(messages []Message, options *ChatOptions) (FakeStream, error) {
+	input := EchoInput{Messages: messages}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role() != constants.RoleTool {
+			break
+		}
+		input.ToolResults = append([]string{messages[i].Content()}, input.ToolResults...)
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role() == constants.RoleUser {
+			input.Text = messages[i].Content()
+			break
+		}
+	}
+
+	// Tool results are always answered by the fallback, so tool rules cannot loop
+	if len(input.ToolResults) == 0 {
+		if answer, ok := m.answers[normalizeEchoQuestion(input.Text)]; ok {
+			return FakeStream{Content: answer}, nil
+		}
+		tools := offeredTools(options)
+		for _, rule := range m.rules {
+			if rule.tool != "" && !tools[rule.tool] {
+				continue
+			}
+			groups := rule.pattern.FindStringSubmatch(input.Text)
+			if groups == nil {
+				continue
+			}
+			input.Groups = groups
+			text, err := renderEcho(rule.answer, input)
+			if err != nil {
+				return FakeStream{}, err
+			}
+			if rule.tool == "" {
+				return FakeStream{Content: text}, nil
+			}
+			id := fmt.Sprintf("call_echo_%d", m.calls.Add(1))
+			return FakeStream{ToolCalls: []FakeToolCall{{ID: id, Name: rule.tool, Arguments: text}}}, nil
+		}
+	}
+	text, err := renderEcho(m.fallback, input)
+	return FakeStream{Content: text}, err
+}
+
+// renderEcho executes an echo template.
+func renderEcho(tmpl *template.Template, input EchoInput) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, input); err != nil {
+		return "", fmt.Errorf("echo template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// offeredTools returns the names of the tools offered by a request.
+func offeredTools(options *ChatOptions) map[string]bool {
+	tools := options.filterTools(options.tools)
+	if options.toolset != nil {
+		tools = append(tools, options.filterTools(options.toolset.Tools())...)
+	}
+	names := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		names[ToolName(tool)] = true
+	}
+	return names
+}
@@ -0,0 +1,149 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+// EmbedOption represents a functional option to configure a single embedding request.
+type EmbedOption func(*EmbedOptions)
+
+// EmbedOptions holds per-request configuration for Embedder.Embed.
+// Fields are intentionally unexported; use With* helpers to set them.
+type EmbedOptions struct {
+	// dimensions truncates the vectors to the given size where the model supports it.
+	dimensions *int
+	// batchSize bounds the inputs sent per request; zero uses the provider's limit.
+	batchSize int
+	// inputType is what the inputs are used for (see constants/embed.go).
+	inputType string
+}
+
+// WithDimensions requests vectors of the given size from models that support
+// shortened embeddings.
+func WithDimensions(dimensions int) EmbedOption {
+	return func(opts *EmbedOptions) { opts.dimensions = &dimensions }
+}
+
+// WithEmbedBatchSize bounds the number of inputs sent per request. Larger input
+// lists are split into batches and the vectors returned in input order.
+func WithEmbedBatchSize(size int) EmbedOption {
+	return func(opts *EmbedOptions) { opts.batchSize = size }
+}
+
+// WithInputType tells providers that optimize for retrieval whether the inputs
+// are queries or documents, one of the constants.EmbedInput* values
+// (default document). OpenAI ignores it.
+func WithInputType(inputType string) EmbedOption {
+	return func(opts *EmbedOptions) { opts.inputType = inputType }
+}
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	// Embed returns one vector per input, in order, and the tokens used.
+	Embed(ctx context.Context, inputs []string, opts ...EmbedOption) ([][]float32, Usage, error)
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, between -1
+// and 1, or 0 when the vectors differ in length or either is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// embedBatches embeds inputs in batches of at most size (or defaultSize) with
+// embed, and returns the vectors in order with the summed usage.
+func embedBatches(ctx context.Context, inputs []string, opts *EmbedOptions, defaultSize int,
+	embed func(ctx context.Context, batch []string) ([][]float32, Usage, error)) ([][]float32, Usage, error) {
+	size := opts.batchSize
+	if size <= 0 {
+		size = defaultSize
+	}
+	vectors := make([][]float32, 0, len(inputs))
+	var usage Usage
+	for start := 0; start < len(inputs); start += size {
+		batch := inputs[start:min(start+size, len(inputs))]
+		batchVectors, batchUsage, err := embed(ctx, batch)
+		if err != nil {
+			return nil, usage, err
+		}
+		if len(batchVectors) != len(batch) {
+			return nil, usage, fmt.Errorf("embedding returned %d vectors for %d inputs", len(batchVectors), len(batch))
+		}
+		vectors = append(vectors, batchVectors...)
+		usage = usage.Add(batchUsage)
+	}
+	return vectors, usage, nil
+}
+
+// openAIEmbedBatchSize is the maximum number of inputs per OpenAI embeddings request.
+const openAIEmbedBatchSize = 2048
+
+type openAIEmbedder struct {
+	model  string
+	client *openai.Client
+}
+
+// NewEmbedder creates an Embedder for an OpenAI embedding model, e.g. "text-embedding-3-small".
+func NewEmbedder(model string, client *openai.Client) Embedder {
+	return &openAIEmbedder{model: model, client: client}
+}
+
+// NewEmbedderWithAPIKey creates an OpenAI Embedder with an auth token.
+// Client options can set the HTTP client, proxy and endpoint.
+func NewEmbedderWithAPIKey(model, authToken string, opts ...ClientOption) Embedder {
+	return &openAIEmbedder{model: model, client: newOpenAIClient(authToken, opts)}
+}
+
+// Embed implements Embedder.
+func (e *openAIEmbedder) Embed(ctx context.Context, inputs []string, opts ...EmbedOption) ([][]float32, Usage, error) {
+	options := &EmbedOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return embedBatches(ctx, inputs, options, openAIEmbedBatchSize, func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+		req := openai.EmbeddingRequest{
+			Input:          batch,
+			Model:          openai.EmbeddingModel(e.model),
+			EncodingFormat: openai.EmbeddingEncodingFormatFloat,
+		}
+		if options.dimensions != nil {
+			req.Dimensions = *options.dimensions
+		}
+		resp, err := e.client.CreateEmbeddings(ctx, req)
+		if err != nil {
+			return nil, Usage{}, err
+		}
+		vectors := make([][]float32, len(batch))
+		for _, data := range resp.Data {
+			if data.Index >= 0 && data.Index < len(vectors) {
+				vectors[data.Index] = data.Embedding
+			}
+		}
+		usage := Usage{InputTokens: resp.Usage.PromptTokens, TotalTokens: resp.Usage.TotalTokens}
+		return vectors, usage.withCost(e.model), nil
+	})
+}
+
+// embedInputType maps an input type to the provider value for query and document.
+func embedInputType(inputType, query, document string) string {
+	if inputType == constants.EmbedInputQuery {
+		return query
+	}
+	return document
+}
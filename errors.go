@@ -2,8 +2,326 @@ package openllm
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
 var (
 	ErrEmptyChoices = errors.New("empty choices from completion response")
+
+	// ErrStopStreaming may be returned from any StreamWatcher callback to stop the
+	// stream early. The streaming call then returns the partial Response and a nil error.
+	ErrStopStreaming = errors.New("stop streaming")
+
+	// ErrStreamIdleTimeout is returned when a stream receives no event within
+	// the window configured by WithStreamIdleTimeout.
+	ErrStreamIdleTimeout = errors.New("stream idle timeout")
+
+	// ErrStreamOverflow is returned when an EventStream buffer is full and
+	// its overflow policy is OverflowError.
+	ErrStreamOverflow = errors.New("stream event buffer overflow")
+
+	// ErrRequestShed is returned by WithScheduler when a low-priority request is
+	// dropped because the queue is full or it waited too long.
+	ErrRequestShed = errors.New("request shed by scheduler")
+
+	// ErrBudgetExceeded is returned by UsageTracker when a key has used up its budget.
+	ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+	// ErrIdempotencyKeyReused is returned by WithIdempotency when a key is reused
+	// for a request that differs from the one it was first used for.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+
+	// ErrMaxIterations is returned by Runner.Run when the model still requests tools
+	// after the configured number of iterations.
+	ErrMaxIterations = errors.New("runner reached max iterations")
+
+	// ErrToolNotFound is returned when a tool call names a tool that is not available.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolConflict is returned when a ToolSet already holds a tool with the same name.
+	ErrToolConflict = errors.New("tool name conflict")
+
+	// ErrToolNotExecutable is returned when a tool has no Go function bound to it.
+	ErrToolNotExecutable = errors.New("tool has no bound function")
+
+	// ErrUnsupportedContent is returned when a message contains a content part
+	// the provider cannot accept, e.g. a PDF document sent to OpenAI.
+	ErrUnsupportedContent = errors.New("unsupported content part")
+
+	// ErrUnsupportedConstraint is returned when a backend cannot enforce the
+	// output constraint set with WithConstraint.
+	ErrUnsupportedConstraint = errors.New("unsupported output constraint")
+
+	// ErrUnsupportedImageOperation is returned by an ImageModel that cannot
+	// perform the requested operation, e.g. variations of a gpt-image-1 image.
+	ErrUnsupportedImageOperation = errors.New("unsupported image operation")
+
+	// ErrCircuitOpen is matched by *CircuitOpenError, returned while a circuit breaker
+	// rejects requests (see WithCircuitBreaker).
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrContentBlocked is matched by *ModerationError and *GuardrailError, returned
+	// when moderation or a guardrail blocks a request or response (see WithModeration
+	// and WithGuardrails).
+	ErrContentBlocked = errors.New("content blocked")
+
+	// ErrHistoryTooLong is returned when a HistoryManager cannot trim the
+	// messages to its token budget without dropping the last user turn.
+	ErrHistoryTooLong = errors.New("history does not fit the token budget")
+
+	// ErrToolCallDenied is returned when a tool call is rejected by an approval gate.
+	ErrToolCallDenied = errors.New("tool call denied by user")
+
+	// ErrOutputLimitExceeded is matched by *OutputLimitError, returned when a stream
+	// exceeds its size limits (see WithStreamLimits).
+	ErrOutputLimitExceeded = errors.New("output limit exceeded")
+)
+
+// Provider failures are normalized into these kinds, matched with errors.Is on
+// the *ProviderError returned by the chat models and on *HTTPError, so callers
+// need not inspect provider-specific codes or messages.
+var (
+	// ErrRateLimited reports that a request or token rate limit was exceeded (HTTP 429);
+	// RetryAfter returns the delay the provider asked for, if any.
+	ErrRateLimited = errors.New("rate limited by provider")
+
+	// ErrQuotaExceeded reports that the account ran out of credit or quota, such as
+	// OpenAI's insufficient_quota. Unlike ErrRateLimited it is not retryable.
+	ErrQuotaExceeded = errors.New("provider quota exceeded")
+
+	// ErrContextLengthExceeded reports that the request does not fit the model's context window.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+
+	// ErrAuthentication reports a missing or invalid API key (HTTP 401).
+	ErrAuthentication = errors.New("provider authentication failed")
+
+	// ErrPermissionDenied reports a valid API key that may not make the request,
+	// e.g. a model or region it has no access to (HTTP 403).
+	ErrPermissionDenied = errors.New("provider permission denied")
+
+	// ErrContentFiltered reports that the provider's content filter rejected the request.
+	ErrContentFiltered = errors.New("content filtered by provider")
+
+	// ErrOverloaded reports that the provider is temporarily overloaded (HTTP 503 and 529).
+	ErrOverloaded = errors.New("provider overloaded")
+
+	// ErrInvalidRequest reports other rejections of the request itself, such as
+	// unknown models or malformed parameters (HTTP 400, 404, 413 and 422).
+	ErrInvalidRequest = errors.New("invalid request")
 )
+
+// PartialResponseError reports a streaming failure that occurred once the stream
+// was open, whether or not output had started. Response holds everything
+// assembled before the failure, possibly nothing, so callers can log or resume
+// the generation.
+type PartialResponseError struct {
+	// Response is the partially assembled response.
+	Response Response
+	// Err is the underlying cause (network error, context cancellation, watcher error, ...).
+	Err error
+}
+
+// Error implements error.
+func (e *PartialResponseError) Error() string {
+	return "partial response: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying cause.
+func (e *PartialResponseError) Unwrap() error {
+	return e.Err
+}
+
+// WatcherError reports a StreamWatcher callback failure that did not cancel the
+// stream (see WithCancelOnWatcherError). The watcher received no events after it
+// failed, but Response holds the complete response.
+type WatcherError struct {
+	// Response is the complete response.
+	Response Response
+	// Err is the error returned by the watcher.
+	Err error
+}
+
+// Error implements error.
+func (e *WatcherError) Error() string {
+	return "stream watcher: " + e.Err.Error()
+}
+
+// Unwrap returns the watcher's error.
+func (e *WatcherError) Unwrap() error {
+	return e.Err
+}
+
+// ArgumentsError describes malformed or truncated JSON in tool-call arguments.
+type ArgumentsError struct {
+	// Offset is the byte offset in the arguments at which the problem was detected.
+	Offset int
+	// Truncated reports whether the arguments ended before the JSON value was complete.
+	Truncated bool
+	// Reason describes the syntax problem.
+	Reason string
+}
+
+// Error implements error.
+func (e *ArgumentsError) Error() string {
+	return fmt.Sprintf("invalid tool call arguments at offset %d: %s", e.Offset, e.Reason)
+}
+
+// OutputParseError reports a model answer that could not be decoded as JSON.
+type OutputParseError struct {
+	// Text is the raw answer text.
+	Text string
+	// Err is the underlying decoding error.
+	Err error
+}
+
+// Error implements error.
+func (e *OutputParseError) Error() string {
+	return "invalid JSON output: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *OutputParseError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpenError is returned without calling the model while its circuit breaker
+// is open. It matches ErrCircuitOpen with errors.Is.
+type CircuitOpenError struct {
+	// Model is the name of the model whose breaker is open.
+	Model string
+	// RetryAt is the earliest time the breaker lets a probe request through.
+	RetryAt time.Time
+}
+
+// Error implements error.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s until %s", e.Model, e.RetryAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// OutputLimitError reports a stream aborted for exceeding a limit set with
+// WithStreamLimits. It matches ErrOutputLimitExceeded with errors.Is.
+type OutputLimitError struct {
+	// Limit is the exceeded limit in bytes.
+	Limit int
+	// ToolCall is the call whose arguments exceeded MaxToolArgumentBytes, or nil
+	// when the whole output exceeded MaxOutputBytes.
+	ToolCall ToolCall
+}
+
+// Error implements error.
+func (e *OutputLimitError) Error() string {
+	if e.ToolCall != nil {
+		return fmt.Sprintf("arguments of tool call %s (%s) exceed %d bytes", e.ToolCall.ID(), e.ToolCall.Function().Name(), e.Limit)
+	}
+	return fmt.Sprintf("stream output exceeds %d bytes", e.Limit)
+}
+
+// Is reports whether target is ErrOutputLimitExceeded.
+func (e *OutputLimitError) Is(target error) bool {
+	return target == ErrOutputLimitExceeded
+}
+
+// HTTPError is returned by backends implemented over plain HTTP, such as Cohere
+// and Vertex AI, when the API responds with an error status.
+type HTTPError struct {
+	// Provider is the backend that failed (see constants/provider.go).
+	Provider string
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Body is the response body, usually a JSON error description.
+	Body string
+	// Header holds the response headers, e.g. Retry-After.
+	Header http.Header
+}
+
+// Error implements error.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Is reports whether target is the kind of the failure, such as ErrRateLimited.
+func (e *HTTPError) Is(target error) bool {
+	typ, message := parseErrorBody(e.Body)
+	kind := errorKind(e.StatusCode, typ, message)
+	return kind != nil && target == kind
+}
+
+// ProviderError is a failure reported by a provider API, normalized into one of
+// the error kinds such as ErrRateLimited. It matches its Kind with errors.Is and
+// unwraps to the SDK error, so errors.As still reaches *openai.APIError or
+// *anthropic.Error.
+type ProviderError struct {
+	// Provider is the backend that failed (see constants/provider.go).
+	Provider string
+	// Kind is the sentinel the failure matches, e.g. ErrContextLengthExceeded.
+	Kind error
+	// StatusCode is the HTTP status, or 0 for errors reported mid-stream.
+	StatusCode int
+	// Type is the provider's error type or code, e.g. "overloaded_error".
+	Type string
+	// Message is the provider's error message.
+	Message string
+	// RetryAfter is the delay the provider asked for before retrying, or zero.
+	RetryAfter time.Duration
+	// Err is the SDK error.
+	Err error
+}
+
+// Error implements error and returns the SDK error message.
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the SDK error.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the failure.
+func (e *ProviderError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// ModerationError is returned when WithModeration blocks a request or response.
+// It matches ErrContentBlocked with errors.Is.
+type ModerationError struct {
+	// Stage is "input" when the request was blocked, "output" for the response.
+	Stage string
+	// Categories lists the categories over their thresholds.
+	Categories []string
+}
+
+// Error implements error.
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%s blocked by moderation: %s", e.Stage, strings.Join(e.Categories, ", "))
+}
+
+// Is reports whether target is ErrContentBlocked.
+func (e *ModerationError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
+// GuardrailError is returned when WithGuardrails blocks a request or response.
+// It matches ErrContentBlocked with errors.Is.
+type GuardrailError struct {
+	// Decision is the blocking decision.
+	Decision GuardrailDecision
+}
+
+// Error implements error.
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("%s blocked by guardrail %s: %s", e.Decision.Stage, e.Decision.Guardrail, e.Decision.Reason)
+}
+
+// Is reports whether target is ErrContentBlocked.
+func (e *GuardrailError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
@@ -0,0 +1,182 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/thecxx/openllm"
+)
+
+// CriterionFunc adapts a function to the Criterion interface.
+func CriterionFunc(name string, score func(ctx context.Context, c *Case, resp openllm.Response) (Score, error)) Criterion {
+	return &funcCriterion{name: name, score: score}
+}
+
+// funcCriterion is the Criterion returned by CriterionFunc.
+type funcCriterion struct {
+	name  string
+	score func(ctx context.Context, c *Case, resp openllm.Response) (Score, error)
+}
+
+// Name implements Criterion.
+func (f *funcCriterion) Name() string { return f.name }
+
+// Score implements Criterion.
+func (f *funcCriterion) Score(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+	return f.score(ctx, c, resp)
+}
+
+// answerText returns the text of the answer of resp.
+func answerText(resp openllm.Response) string {
+	if answer := resp.Answer(); answer != nil {
+		return answer.Content()
+	}
+	return ""
+}
+
+// verdict returns a pass/fail Score.
+func verdict(pass bool, reason string) Score {
+	if pass {
+		return Score{Value: 1, Pass: true}
+	}
+	return Score{Reason: reason}
+}
+
+// Exact passes when the answer equals expected, ignoring surrounding whitespace.
+func Exact(expected string) Criterion {
+	return CriterionFunc("exact", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		got := strings.TrimSpace(answerText(resp))
+		return verdict(got == strings.TrimSpace(expected), fmt.Sprintf("got %q, want %q", got, expected)), nil
+	})
+}
+
+// Contains passes when the answer contains every substring, ignoring case.
+// The score is the fraction of substrings found.
+func Contains(substrings ...string) Criterion {
+	return CriterionFunc("contains", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		got := strings.ToLower(answerText(resp))
+		var missing []string
+		for _, s := range substrings {
+			if !strings.Contains(got, strings.ToLower(s)) {
+				missing = append(missing, s)
+			}
+		}
+		score := verdict(len(missing) == 0, fmt.Sprintf("missing %q", missing))
+		if len(substrings) > 0 {
+			score.Value = float64(len(substrings)-len(missing)) / float64(len(substrings))
+		}
+		return score, nil
+	})
+}
+
+// NotContains passes when the answer contains none of the substrings, ignoring case.
+func NotContains(substrings ...string) Criterion {
+	return CriterionFunc("not_contains", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		got := strings.ToLower(answerText(resp))
+		var found []string
+		for _, s := range substrings {
+			if strings.Contains(got, strings.ToLower(s)) {
+				found = append(found, s)
+			}
+		}
+		return verdict(len(found) == 0, fmt.Sprintf("found %q", found)), nil
+	})
+}
+
+// Matches passes when the answer matches the regular expression pattern. It
+// panics if pattern does not compile, like regexp.MustCompile.
+func Matches(pattern string) Criterion {
+	re := regexp.MustCompile(pattern)
+	return CriterionFunc("matches", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		return verdict(re.MatchString(answerText(resp)), fmt.Sprintf("does not match %s", pattern)), nil
+	})
+}
+
+// maxJSONDiffs bounds the differences listed in a JSONEqual reason.
+const maxJSONDiffs = 5
+
+// JSONEqual passes when the JSON in the answer (see openllm.DecodeJSON) equals
+// expected, which may be any value that marshals to JSON. Object key order and
+// number formatting do not matter. The reason lists the differing paths.
+func JSONEqual(expected any) Criterion {
+	return CriterionFunc("json_equal", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		data, err := json.Marshal(expected)
+		if err != nil {
+			return Score{}, fmt.Errorf("marshal expected value: %w", err)
+		}
+		var want any
+		if err := json.Unmarshal(data, &want); err != nil {
+			return Score{}, err
+		}
+		var got any
+		if err := openllm.DecodeJSON(resp, &got); err != nil {
+			return Score{Reason: err.Error()}, nil
+		}
+		diffs := jsonDiff("$", want, got, nil)
+		if len(diffs) > maxJSONDiffs {
+			diffs = append(diffs[:maxJSONDiffs], fmt.Sprintf("and %d more", len(diffs)-maxJSONDiffs))
+		}
+		return verdict(len(diffs) == 0, strings.Join(diffs, "; ")), nil
+	})
+}
+
+// jsonDiff appends the paths where the decoded JSON values want and got differ.
+func jsonDiff(path string, want, got any, diffs []string) []string {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return append(diffs, fmt.Sprintf("%s: want object, got %s", path, compactJSON(got)))
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing", path, k))
+			case !inWant:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected", path, k))
+			default:
+				diffs = jsonDiff(path+"."+k, wv, gv, diffs)
+			}
+		}
+		return diffs
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return append(diffs, fmt.Sprintf("%s: want array, got %s", path, compactJSON(got)))
+		}
+		if len(w) != len(g) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %d elements, got %d", path, len(w), len(g)))
+		}
+		for i := 0; i < min(len(w), len(g)); i++ {
+			diffs = jsonDiff(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], diffs)
+		}
+		return diffs
+	}
+	if !reflect.DeepEqual(want, got) {
+		diffs = append(diffs, fmt.Sprintf("%s: want %s, got %s", path, compactJSON(want), compactJSON(got)))
+	}
+	return diffs
+}
+
+// compactJSON formats a decoded JSON value for a reason.
+func compactJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,253 @@
+// Package eval runs prompt regression tests: cases of input messages are sent to
+// one or more openllm.Model values and every answer is scored by criteria, from
+// exact matchers to rubric-based judge models. The resulting Report is meant for
+// CI, where a failing case should fail the build.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/thecxx/openllm"
+)
+
+// Case is an evaluation case.
+type Case struct {
+	// Name identifies the case in reports.
+	Name string
+	// Messages are sent to each model.
+	Messages []openllm.Message
+	// Options are applied to the request, after the options of Run.
+	Options []openllm.ChatOption
+	// Criteria score the answer; the case passes when all of them pass.
+	Criteria []Criterion
+}
+
+// Score is the verdict of a criterion on one answer.
+type Score struct {
+	// Value is the score between 0 and 1.
+	Value float64
+	// Pass reports whether the answer meets the criterion.
+	Pass bool
+	// Reason explains the verdict, e.g. the first mismatch or the judge's reasoning.
+	Reason string
+	// Usage is the token usage of any model calls made to score.
+	Usage openllm.Usage
+}
+
+// Criterion scores an answer to a case.
+type Criterion interface {
+	// Name identifies the criterion in reports.
+	Name() string
+	// Score scores resp, the answer of a model to c.
+	Score(ctx context.Context, c *Case, resp openllm.Response) (Score, error)
+}
+
+// CriterionResult is the score of one criterion.
+type CriterionResult struct {
+	Criterion string
+	Score     Score
+	// Err is set when the criterion could not score the answer, e.g. a judge call failed.
+	Err error
+}
+
+// Result is the outcome of one case on one model.
+type Result struct {
+	Case  string
+	Model string
+	// Answer is the text of the model's answer.
+	Answer string
+	// Scores are the criterion results, in the order of Case.Criteria.
+	Scores []CriterionResult
+	// Score is the mean score of the criteria, or 0 when the request failed.
+	Score float64
+	// Passed reports whether the request succeeded and every criterion passed.
+	Passed bool
+	// Err is the request error, if any.
+	Err error
+	// Duration is the time the model took to answer.
+	Duration time.Duration
+	// Usage is the token usage of the request and of scoring.
+	Usage openllm.Usage
+}
+
+// ModelSummary aggregates the results of one model.
+type ModelSummary struct {
+	Model  string
+	Cases  int
+	Passed int
+	// MeanScore is the mean of the case scores.
+	MeanScore float64
+	Usage     openllm.Usage
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	// Results holds one result per case and model, cases first, models in order.
+	Results []Result
+	// Models summarizes the results of each model, in order.
+	Models []ModelSummary
+}
+
+// Passed reports whether every case passed on every model.
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns the results that did not pass.
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if !result.Passed {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// WriteText writes the model summaries and the failures as plain text, e.g.
+// for CI logs.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tPASSED\tSCORE\tTOKENS")
+	for _, m := range r.Models {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.2f\t%d\n", m.Model, m.Passed, m.Cases, m.MeanScore, m.Usage.TotalTokens)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, f := range r.Failures() {
+		fmt.Fprintf(w, "\nFAIL %s on %s\n", f.Case, f.Model)
+		if f.Err != nil {
+			fmt.Fprintf(w, "  error: %v\n", f.Err)
+			continue
+		}
+		for _, s := range f.Scores {
+			switch {
+			case s.Err != nil:
+				fmt.Fprintf(w, "  %s: error: %v\n", s.Criterion, s.Err)
+			case !s.Score.Pass:
+				fmt.Fprintf(w, "  %s: %.2f %s\n", s.Criterion, s.Score.Value, s.Score.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+// RunOption configures Run.
+type RunOption func(opts *runOptions)
+
+// runOptions holds the settings of Run.
+type runOptions struct {
+	concurrency int
+	chatOpts    []openllm.ChatOption
+}
+
+// WithConcurrency sets how many cases run at once (default 4).
+func WithConcurrency(n int) RunOption {
+	return func(opts *runOptions) { opts.concurrency = n }
+}
+
+// WithChatOptions sets options applied to every request, before those of the case.
+func WithChatOptions(opts ...openllm.ChatOption) RunOption {
+	return func(o *runOptions) { o.chatOpts = append(o.chatOpts, opts...) }
+}
+
+// Run sends every case to every model and scores the answers. Failed requests
+// and criteria are recorded in the report rather than returned; the error is
+// only set when ctx ends before all cases ran.
+func Run(ctx context.Context, models []openllm.Model, cases []Case, opts ...RunOption) (*Report, error) {
+	options := &runOptions{concurrency: 4}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	report := &Report{Results: make([]Result, len(cases)*len(models))}
+	sem := make(chan struct{}, options.concurrency)
+	var wg sync.WaitGroup
+	for i := range cases {
+		for j, model := range models {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return report, ctx.Err()
+			}
+			wg.Add(1)
+			go func(c *Case, model openllm.Model, result *Result) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				*result = runCase(ctx, model, c, options.chatOpts)
+			}(&cases[i], model, &report.Results[i*len(models)+j])
+		}
+	}
+	wg.Wait()
+
+	for j, model := range models {
+		summary := ModelSummary{Model: model.Name()}
+		for i := range cases {
+			result := report.Results[i*len(models)+j]
+			summary.Cases++
+			if result.Passed {
+				summary.Passed++
+			}
+			summary.MeanScore += result.Score
+			summary.Usage = summary.Usage.Add(result.Usage)
+		}
+		if summary.Cases > 0 {
+			summary.MeanScore /= float64(summary.Cases)
+		}
+		report.Models = append(report.Models, summary)
+	}
+	return report, ctx.Err()
+}
+
+// runCase sends c to model and scores the answer.
+func runCase(ctx context.Context, model openllm.Model, c *Case, chatOpts []openllm.ChatOption) Result {
+	result := Result{Case: c.Name, Model: model.Name()}
+	opts := append(chatOpts[:len(chatOpts):len(chatOpts)], c.Options...)
+	start := time.Now()
+	resp, err := model.ChatCompletion(ctx, c.Messages, opts...)
+	result.Duration = time.Since(start)
+	if resp != nil {
+		result.Usage = resp.Usage()
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if answer := resp.Answer(); answer != nil {
+		result.Answer = answer.Content()
+	}
+
+	result.Passed = true
+	for _, criterion := range c.Criteria {
+		score, err := criterion.Score(ctx, c, resp)
+		result.Scores = append(result.Scores, CriterionResult{Criterion: criterion.Name(), Score: score, Err: err})
+		result.Usage = result.Usage.Add(score.Usage)
+		result.Score += score.Value
+		if err != nil || !score.Pass {
+			result.Passed = false
+		}
+	}
+	if n := len(c.Criteria); n > 0 {
+		result.Score /= float64(n)
+	} else {
+		result.Score = 1
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thecxx/openllm"
+)
+
+// judgement is the structured answer of a judge model.
+type judgement struct {
+	Reasoning string `openllm:"reasoning,required,desc=Short justification of the score"`
+	Score     int    `openllm:"score,required,min=1,max=5,desc=Score from 1 (fails the rubric) to 5 (fully meets it)"`
+}
+
+// Judge returns a Criterion that asks judge to grade the answer against rubric
+// on a scale from 1 to 5, with the conversation as context. The answer passes
+// with at least minScore; the Score value maps 1 to 5 onto 0 to 1. opts are
+// applied to the judge request, e.g. WithTemperature(0).
+func Judge(judge openllm.Model, rubric string, minScore int, opts ...openllm.ChatOption) Criterion {
+	return CriterionFunc("judge", func(ctx context.Context, c *Case, resp openllm.Response) (Score, error) {
+		var sb strings.Builder
+		sb.WriteString("Conversation:\n")
+		for _, msg := range c.Messages {
+			fmt.Fprintf(&sb, "%s: %s\n", msg.Role(), msg.Content())
+		}
+		sb.WriteString("\nAnswer to grade:\n" + answerText(resp) + "\n\nRubric:\n" + rubric)
+
+		all := append([]openllm.ChatOption{openllm.WithSystemPrompt(
+			"You grade an AI assistant's answer against a rubric. Judge only what the rubric asks for, " +
+				"be strict, and do not reward length.")}, opts...)
+		verdict, judged, err := openllm.Complete[judgement](ctx, judge,
+			[]openllm.Message{openllm.NewUserMessage(sb.String())}, all...)
+		var usage openllm.Usage
+		if judged != nil {
+			usage = judged.Usage()
+		}
+		if err != nil {
+			return Score{Usage: usage}, err
+		}
+		score := min(max(verdict.Score, 1), 5)
+		return Score{
+			Value:  float64(score-1) / 4,
+			Pass:   score >= minScore,
+			Reason: fmt.Sprintf("%d/5: %s", score, verdict.Reasoning),
+			Usage:  usage,
+		}, nil
+	})
+}
@@ -0,0 +1,188 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Variant is an arm of an Experiment.
+type Variant struct {
+	// Name identifies the variant in Meta and Stats.
+	Name string
+	// Model serves the variant; nil uses the experiment's model.
+	Model Model
+	// Options are applied after the request's own options, e.g. WithSystemPrompt
+	// with the prompt under test or WithTemperature.
+	Options []ChatOption
+	// Weight is the relative share of requests; values below 1 count as 1.
+	Weight int
+}
+
+// WithExperimentKey assigns the request to an experiment variant by key, such
+// as a user or session ID: the same key always gets the same variant of a given
+// experiment, and keys spread over the variants in proportion to their weights.
+func WithExperimentKey(key string) ChatOption {
+	return func(opts *ChatOptions) { opts.experimentKey = key }
+}
+
+// Experiment is a Model that splits requests between prompt or model variants
+// and records per-variant usage and latency, so variants can be compared in
+// production. Requests with WithExperimentKey are assigned deterministically;
+// others are assigned at random by weight. Responses carry the experiment and
+// variant names in Meta.
+type Experiment struct {
+	name     string
+	model    Model
+	variants []Variant
+	total    int
+
+	mu    sync.Mutex
+	stats []VariantStats
+}
+
+// VariantStats aggregates the requests served by a variant.
+type VariantStats struct {
+	// Variant is the variant name.
+	Variant string
+	// Requests and Errors count the requests served and those that failed.
+	Requests int64
+	Errors   int64
+	// Usage is the summed token usage and cost.
+	Usage Usage
+	// Latency is the summed request duration; divide by Requests for the mean.
+	Latency time.Duration
+}
+
+// MeanLatency returns the mean request duration, or 0 without requests.
+func (s VariantStats) MeanLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Requests)
+}
+
+// NewExperiment creates an experiment named name over variants, which default
+// to model. The name is part of the assignment hash, so different experiments
+// split the same keys independently. It fails if variants is empty.
+func NewExperiment(name string, model Model, variants ...Variant) (*Experiment, error) {
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("experiment %s: no variants", name)
+	}
+	e := &Experiment{name: name, model: model, stats: make([]VariantStats, len(variants))}
+	for i, v := range variants {
+		if v.Weight < 1 {
+			v.Weight = 1
+		}
+		if v.Model == nil {
+			v.Model = model
+		}
+		e.variants = append(e.variants, v)
+		e.total += v.Weight
+		e.stats[i].Variant = v.Name
+	}
+	return e, nil
+}
+
+// MustNewExperiment is like NewExperiment but panics on error.
+func MustNewExperiment(name string, model Model, variants ...Variant) *Experiment {
+	e, err := NewExperiment(name, model, variants...)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Name implements Model and returns the name of the experiment's model.
+func (e *Experiment) Name() string {
+	return e.model.Name()
+}
+
+// Description implements Model and returns the description of the experiment's model.
+func (e *Experiment) Description() string {
+	return e.model.Description()
+}
+
+// ChatCompletion implements Model.
+func (e *Experiment) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return e.do(opts, func(v *Variant, opts []ChatOption) (Response, error) {
+		return v.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (e *Experiment) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return e.do(opts, func(v *Variant, opts []ChatOption) (Response, error) {
+		return v.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// Assign returns the name of the variant that requests with key are assigned to.
+func (e *Experiment) Assign(key string) string {
+	return e.variants[e.assign(key)].Name
+}
+
+// Stats returns the statistics of each variant, in order.
+func (e *Experiment) Stats() []VariantStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]VariantStats(nil), e.stats...)
+}
+
+// do assigns the request to a variant, runs it and records the outcome.
+func (e *Experiment) do(opts []ChatOption, request func(v *Variant, opts []ChatOption) (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	var i int
+	if options.experimentKey != "" {
+		i = e.assign(options.experimentKey)
+	} else {
+		i = e.pick(rand.IntN(e.total))
+	}
+	v := &e.variants[i]
+
+	start := time.Now()
+	resp, err := request(v, append(opts[:len(opts):len(opts)], v.Options...))
+	elapsed := time.Since(start)
+
+	e.mu.Lock()
+	stats := &e.stats[i]
+	stats.Requests++
+	stats.Latency += elapsed
+	if err != nil {
+		stats.Errors++
+	}
+	if resp != nil {
+		stats.Usage = stats.Usage.Add(resp.Usage())
+	}
+	e.mu.Unlock()
+
+	return withMeta(resp, func(meta *Meta) {
+		meta.Experiment = e.name
+		meta.Variant = v.Name
+	}), err
+}
+
+// assign hashes key with the experiment name onto the variant weights.
+func (e *Experiment) assign(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(e.name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return e.pick(int(h.Sum64() % uint64(e.total)))
+}
+
+// pick returns the variant covering n in [0, total) of the cumulative weights.
+func (e *Experiment) pick(n int) int {
+	for i, v := range e.variants {
+		if n -= v.Weight; n < 0 {
+			return i
+		}
+	}
+	return len(e.variants) - 1
+}
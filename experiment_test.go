@@ -0,0 +1,12 @@
+package openllm
+
+import "testing"
+
+func TestNewExperimentVariants(t *testing.T) {
+	if _, err := NewExperiment("empty", NewEchoModel()); err == nil {
+		t.Error("NewExperiment accepted no variants")
+	}
+	if _, err := NewExperiment("prompt", NewEchoModel(), Variant{Name: "control"}); err != nil {
+		t.Errorf("NewExperiment: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package openllm
+
+import (
+	"context"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// defaultFakeChunkRunes is the number of runes per delta of a FakeStream.
+const defaultFakeChunkRunes = 4
+
+// FakeToolCall is a tool call played back by a FakeStream.
+type FakeToolCall struct {
+	// ID identifies the call; empty IDs are numbered "call_0", "call_1", ...
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// FakeStream is scripted output played back as a stream, driving the StreamWatcher
+// callbacks the way a provider stream does, for UI development, demos and load
+// tests that should not spend tokens. The output is deterministic; only the
+// pacing depends on the clock.
+type FakeStream struct {
+	// Reasoning is streamed first, then Content, then the tool calls.
+	Reasoning string
+	Content   string
+	ToolCalls []FakeToolCall
+	// ChunkRunes is the number of runes per delta (default 4).
+	ChunkRunes int
+	// FirstDelay is the wait before the first delta, simulating time to first token.
+	FirstDelay time.Duration
+	// Delay is the wait between deltas.
+	Delay time.Duration
+}
+
+// Play streams the script to the watcher set by opts (see WithStreamWatcher),
+// honoring stream buffering and structured output watchers, and returns the
+// assembled response. The usage is estimated from the text lengths. Cancelling
+// ctx stops the stream as a provider would, with a *PartialResponseError.
+func (s FakeStream) Play(ctx context.Context, opts ...ChatOption) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return s.play(ctx, "fake", nil, options)
+}
+
+// NewFakeStreamModel returns a Model named name that answers every request with
+// the script. Blocking calls wait as long as the stream would take, without a
+// watcher, so latency stays realistic under load.
+func NewFakeStreamModel(name string, script FakeStream) Model {
+	return &fakeStreamModel{name: name, script: script}
+}
+
+// fakeStreamModel is the Model returned by NewFakeStreamModel.
+type fakeStreamModel struct {
+	name   string
+	script FakeStream
+}
+
+// Name implements Model.
+func (m *fakeStreamModel) Name() string {
+	return m.name
+}
+
+// Description implements Model.
+func (m *fakeStreamModel) Description() string {
+	return "Scripted fake stream"
+}
+
+// ChatCompletion implements Model.
+func (m *fakeStreamModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, m.name, false, messages)
+	defer func() { end(resp, err) }()
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.watcher = nil
+	return m.script.play(ctx, m.name, messages, options)
+}
+
+// ChatCompletionStream implements Model.
+func (m *fakeStreamModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, m.name, true, messages)
+	defer func() { end(resp, err) }()
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return m.script.play(ctx, m.name, messages, options)
+}
+
+// play streams the script through a stream accumulator.
+func (s FakeStream) play(ctx context.Context, model string, messages []Message, options *ChatOptions) (Response, error) {
+	acc := newStreamAccumulator(options, Meta{Provider: constants.ProviderFake, Model: model})
+	acc.setRole(constants.RoleAssistant)
+	if err := acc.onMeta(); err != nil {
+		return acc.fail(err)
+	}
+
+	size := s.ChunkRunes
+	if size <= 0 {
+		size = defaultFakeChunkRunes
+	}
+	delay := s.FirstDelay
+	// wait paces the next delta; it fails when ctx ends first.
+	wait := func() error {
+		d := delay
+		delay = s.Delay
+		if d <= 0 {
+			return ctx.Err()
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+
+	for _, chunk := range chunkRunes(s.Reasoning, size) {
+		if err := wait(); err != nil {
+			return acc.fail(err)
+		}
+		if err := acc.onReasoning(chunk); err != nil {
+			return acc.fail(err)
+		}
+	}
+	for _, chunk := range chunkRunes(s.Content, size) {
+		if err := wait(); err != nil {
+			return acc.fail(err)
+		}
+		if err := acc.onContent(chunk); err != nil {
+			return acc.fail(err)
+		}
+	}
+	outputChars := len(s.Reasoning) + len(s.Content)
+	for i, call := range s.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = "call_" + strconv.Itoa(i)
+		}
+		if err := wait(); err != nil {
+			return acc.fail(err)
+		}
+		tcall := &toolcall{index: i, id: id, type_: constants.ToolTypeFunction, fcall: funcall{name: call.Name}}
+		if err := acc.onToolCallStart(ctx, tcall); err != nil {
+			return acc.fail(err)
+		}
+		for _, chunk := range chunkRunes(call.Arguments, size) {
+			if err := wait(); err != nil {
+				return acc.fail(err)
+			}
+			if err := acc.onToolCallArgs(ctx, i, chunk); err != nil {
+				return acc.fail(err)
+			}
+		}
+		if err := acc.onToolCallDone(ctx, i); err != nil {
+			return acc.fail(err)
+		}
+		outputChars += len(call.Name) + len(call.Arguments)
+	}
+
+	usage := Usage{OutputTokens: (outputChars + 3) / 4}
+	if len(messages) > 0 {
+		options.maxTokens = nil
+		usage.InputTokens = EstimateTokens(messages, options)
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	if err := acc.onUsage(usage); err != nil {
+		return acc.fail(err)
+	}
+	acc.meta.FinishReason = FinishStop
+	if len(s.ToolCalls) > 0 {
+		acc.meta.FinishReason = FinishToolUse
+	}
+	acc.meta.StopReason = string(acc.meta.FinishReason)
+	if err := acc.onMeta(); err != nil {
+		return acc.fail(err)
+	}
+	if err := acc.onStop(); err != nil {
+		return acc.fail(err)
+	}
+	return acc.result()
+}
+
+// chunkRunes splits text into chunks of size runes.
+func chunkRunes(text string, size int) []string {
+	var chunks []string
+	for text != "" {
+		end, n := 0, 0
+		for end < len(text) && n < size {
+			_, w := utf8.DecodeRuneInString(text[end:])
+			end += w
+			n++
+		}
+		chunks = append(chunks, text[:end])
+		text = text[end:]
+	}
+	return chunks
+}
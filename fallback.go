@@ -0,0 +1,51 @@
+package openllm
+
+import "context"
+
+// WithFallback returns a Middleware that sends a failed request to each of
+// fallbacks in turn, e.g. another provider, until one succeeds. Only errors
+// reported by IsRetryable move on, and streams only when the failed attempt
+// produced no output. The error of the last model is returned when all fail.
+func WithFallback(fallbacks ...Model) Middleware {
+	return func(next Model) Model {
+		return &fallbackModel{Model: next, models: append([]Model{next}, fallbacks...)}
+	}
+}
+
+// fallbackModel is the Model returned by WithFallback.
+type fallbackModel struct {
+	Model
+	models []Model
+}
+
+// Unwrap returns the wrapped model.
+func (m *fallbackModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *fallbackModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, func(model Model) (Response, error) {
+		return model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *fallbackModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, func(model Model) (Response, error) {
+		return model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do tries the models in order.
+func (m *fallbackModel) do(ctx context.Context, request func(model Model) (Response, error)) (Response, error) {
+	resp, err := request(m.models[0])
+	for i := 1; i < len(m.models); i++ {
+		if err == nil || ctx.Err() != nil || outputStarted(resp) || !IsRetryable(err) {
+			break
+		}
+		emitFallback(ctx, FallbackEvent{From: m.models[i-1].Name(), To: m.models[i].Name(), Err: err})
+		resp, err = request(m.models[i])
+	}
+	return resp, err
+}
@@ -0,0 +1,100 @@
+package openllm
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// fieldEvent is a completed JSON value found while streaming structured output.
+type fieldEvent struct {
+	path  string
+	value any
+}
+
+// fieldStream parses streamed JSON output and records every value as soon as it is
+// complete, with its path such as "$.days[0].city". It stops silently at the first
+// syntax error, e.g. when the model answers with prose instead of JSON.
+type fieldStream struct {
+	scan jsonScanner
+	// text holds all input written so far.
+	text strings.Builder
+	// open holds the values that have begun but not ended, outermost first.
+	open []openValue
+	// lastKey is the most recent object key, used by the next value.
+	lastKey string
+	// pending holds completed values not yet delivered.
+	pending []fieldEvent
+}
+
+// openValue is a JSON value that has begun but not ended.
+type openValue struct {
+	path  string
+	start int
+	// next is the index of the next element when the value is an array.
+	next int
+}
+
+// newFieldStream creates a fieldStream ready for input.
+func newFieldStream() *fieldStream {
+	fs := &fieldStream{}
+	fs.scan.events = fs
+	return fs
+}
+
+// write feeds a chunk of output and returns the values it completed.
+func (fs *fieldStream) write(p string) []fieldEvent {
+	if fs.scan.invalid() != nil {
+		return nil
+	}
+	// Skip leading text before the JSON value, such as a code fence
+	if fs.text.Len() == 0 && len(fs.open) == 0 {
+		i := strings.IndexAny(p, "{[")
+		if i < 0 {
+			return nil
+		}
+		p = p[i:]
+	}
+	fs.text.WriteString(p)
+	fs.scan.write(p)
+	events := fs.pending
+	fs.pending = nil
+	return events
+}
+
+// key implements jsonEvents.
+func (fs *fieldStream) key(start, end int) {
+	var key string
+	if err := json.Unmarshal([]byte(fs.text.String()[start:end]), &key); err == nil {
+		fs.lastKey = key
+	}
+}
+
+// begin implements jsonEvents.
+func (fs *fieldStream) begin(offset int) {
+	path := "$"
+	if n := len(fs.open); n > 0 {
+		parent := &fs.open[n-1]
+		if fs.text.String()[parent.start] == '[' {
+			path = parent.path + "[" + strconv.Itoa(parent.next) + "]"
+			parent.next++
+		} else {
+			path = parent.path + "." + fs.lastKey
+		}
+	}
+	fs.open = append(fs.open, openValue{path: path, start: offset})
+}
+
+// end implements jsonEvents.
+func (fs *fieldStream) end(offset int) {
+	n := len(fs.open)
+	if n == 0 {
+		return
+	}
+	v := fs.open[n-1]
+	fs.open = fs.open[:n-1]
+	var value any
+	if err := json.Unmarshal([]byte(fs.text.String()[v.start:offset]), &value); err == nil {
+		fs.pending = append(fs.pending, fieldEvent{path: v.path, value: value})
+	}
+}
@@ -0,0 +1,401 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// GuardrailMatch is a violation found by a Detector.
+type GuardrailMatch struct {
+	// Start and End are the byte offsets of the violating text; both zero covers
+	// the whole text, e.g. for classifier verdicts.
+	Start, End int
+	// Reason describes the violation, such as the matched keyword or flagged category.
+	Reason string
+}
+
+// Detector finds violations of a rule in a text. Implementations must be safe
+// for concurrent use.
+type Detector interface {
+	Detect(ctx context.Context, text string) ([]GuardrailMatch, error)
+}
+
+// DetectorFunc adapts an ordinary function to the Detector interface.
+type DetectorFunc func(ctx context.Context, text string) ([]GuardrailMatch, error)
+
+// Detect implements Detector.
+func (f DetectorFunc) Detect(ctx context.Context, text string) ([]GuardrailMatch, error) {
+	return f(ctx, text)
+}
+
+// DetectPatterns returns a Detector that reports every match of the patterns.
+func DetectPatterns(patterns ...*regexp.Regexp) Detector {
+	return DetectorFunc(func(_ context.Context, text string) ([]GuardrailMatch, error) {
+		var matches []GuardrailMatch
+		for _, pattern := range patterns {
+			for _, loc := range pattern.FindAllStringIndex(text, -1) {
+				matches = append(matches, GuardrailMatch{Start: loc[0], End: loc[1], Reason: "matches " + pattern.String()})
+			}
+		}
+		return matches, nil
+	})
+}
+
+// DetectKeywords returns a Detector that reports the keywords found in a text,
+// ignoring case. Keywords that start or end with a letter or digit only match
+// whole words, so "ass" does not match "class".
+func DetectKeywords(keywords ...string) Detector {
+	patterns := make([]*regexp.Regexp, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword == "" {
+			continue
+		}
+		expr := regexp.QuoteMeta(keyword)
+		if first, _ := utf8.DecodeRuneInString(keyword); isWordRune(first) {
+			expr = `\b` + expr
+		}
+		if last, _ := utf8.DecodeLastRuneInString(keyword); isWordRune(last) {
+			expr += `\b`
+		}
+		patterns = append(patterns, regexp.MustCompile(`(?i)`+expr))
+	}
+	return DetectorFunc(func(_ context.Context, text string) ([]GuardrailMatch, error) {
+		var matches []GuardrailMatch
+		for i, pattern := range patterns {
+			for _, loc := range pattern.FindAllStringIndex(text, -1) {
+				matches = append(matches, GuardrailMatch{Start: loc[0], End: loc[1], Reason: "keyword " + strings.ToLower(keywords[i])})
+			}
+		}
+		return matches, nil
+	})
+}
+
+// isWordRune reports whether r is a letter, digit or underscore.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// DetectModeration returns a Detector that flags texts whose moderation categories
+// reach their thresholds, as in ModerationConfig; nil thresholds follow the
+// moderator's own verdict.
+func DetectModeration(moderator Moderator, thresholds map[string]float64) Detector {
+	return DetectorFunc(func(ctx context.Context, text string) ([]GuardrailMatch, error) {
+		results, err := moderator.Moderate(ctx, []string{text})
+		if err != nil || len(results) == 0 {
+			return nil, err
+		}
+		flagged := flaggedCategories(results[0], thresholds)
+		categories := make([]string, 0, len(flagged))
+		for category := range flagged {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		matches := make([]GuardrailMatch, len(categories))
+		for i, category := range categories {
+			matches[i] = GuardrailMatch{Reason: fmt.Sprintf("moderation %s (%.2f)", category, flagged[category])}
+		}
+		return matches, nil
+	})
+}
+
+// jailbreakPatterns are phrasings common in prompt injection and jailbreak attempts.
+var jailbreakPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|system)\s+(instructions|prompts?|rules|directions)`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions|initial\s+instructions)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(DAN|in\s+developer\s+mode|unfiltered|jailbroken)\b`),
+	regexp.MustCompile(`(?i)\b(pretend|act\s+as\s+if)\s+(that\s+)?you\s+(are|have)\s+(no|not\s+bound\s+by\s+any)\s+(rules|restrictions|guidelines|filters)`),
+	regexp.MustCompile(`(?i)\b(developer|god|jailbreak)\s+mode\s+(enabled|on|activated)\b`),
+}
+
+// DetectJailbreak returns a Detector that flags common prompt injection and
+// jailbreak phrasings, such as "ignore all previous instructions". It is a cheap
+// heuristic that catches naive attempts; combine it with a classifier for more.
+func DetectJailbreak() Detector {
+	return DetectorFunc(func(_ context.Context, text string) ([]GuardrailMatch, error) {
+		var matches []GuardrailMatch
+		for _, pattern := range jailbreakPatterns {
+			for _, loc := range pattern.FindAllStringIndex(text, -1) {
+				matches = append(matches, GuardrailMatch{Start: loc[0], End: loc[1], Reason: fmt.Sprintf("jailbreak phrase %q", text[loc[0]:loc[1]])})
+			}
+		}
+		return matches, nil
+	})
+}
+
+// GuardrailAction is what a guardrail does with a violation.
+type GuardrailAction int
+
+const (
+	// GuardrailBlock fails the request with a *GuardrailError.
+	GuardrailBlock GuardrailAction = iota
+	// GuardrailRedact replaces the violating text with "[REDACTED]" and lets the request through.
+	GuardrailRedact
+	// GuardrailAnnotate lets the request through unchanged and records the decision.
+	GuardrailAnnotate
+)
+
+// String returns the action name.
+func (a GuardrailAction) String() string {
+	switch a {
+	case GuardrailBlock:
+		return "block"
+	case GuardrailRedact:
+		return "redact"
+	case GuardrailAnnotate:
+		return "annotate"
+	}
+	return fmt.Sprintf("GuardrailAction(%d)", int(a))
+}
+
+// Guardrail applies an action to the violations found by a Detector.
+type Guardrail struct {
+	// Name identifies the guardrail in decisions and errors.
+	Name string
+	// Detector finds the violations.
+	Detector Detector
+	// Action is what to do with them (default GuardrailBlock).
+	Action GuardrailAction
+}
+
+// GuardrailPolicy configures WithGuardrails.
+type GuardrailPolicy struct {
+	// MaxPromptTokens blocks requests whose estimated size (see EstimateTokens),
+	// not counting the maximum output tokens, exceeds it; zero disables the check.
+	MaxPromptTokens int
+	// Input guardrails check the new user messages of each request, i.e. those after
+	// the last assistant message; earlier turns were checked before.
+	Input []Guardrail
+	// Output guardrails check the answer of each response.
+	Output []Guardrail
+}
+
+// GuardrailDecision records a guardrail that fired, for auditing.
+type GuardrailDecision struct {
+	// Stage is "input" or "output".
+	Stage string `json:"stage"`
+	// Guardrail is the name of the guardrail.
+	Guardrail string `json:"guardrail"`
+	// Action is "block", "redact" or "annotate".
+	Action string `json:"action"`
+	// Reason describes the violations.
+	Reason string `json:"reason"`
+}
+
+// guardrailRedaction replaces redacted text.
+const guardrailRedaction = "[REDACTED]"
+
+// WithGuardrails returns a Middleware that enforces policy on requests and responses.
+// Guardrails run in order on each stage: the first blocking violation fails the
+// request with a *GuardrailError, redactions are seen by the guardrails after them,
+// and the decisions to redact or annotate are recorded in Meta.Guardrails. The
+// caller's messages are not modified. Streamed output can only be checked once
+// complete, after the watcher has seen it; blocking or redacting then changes the
+// response returned to the caller but not what the watcher saw.
+func WithGuardrails(policy GuardrailPolicy) Middleware {
+	return func(next Model) Model {
+		return &guardrailModel{Model: next, policy: policy}
+	}
+}
+
+// guardrailModel is the Model returned by WithGuardrails.
+type guardrailModel struct {
+	Model
+	policy GuardrailPolicy
+}
+
+// Unwrap returns the wrapped model.
+func (m *guardrailModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *guardrailModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func(messages []Message) (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *guardrailModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func(messages []Message) (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do checks the input, runs request with the possibly redacted messages and checks the output.
+func (m *guardrailModel) do(ctx context.Context, messages []Message, opts []ChatOption, request func([]Message) (Response, error)) (Response, error) {
+	if m.policy.MaxPromptTokens > 0 {
+		options := &ChatOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		options.maxTokens = nil
+		if n := EstimateTokens(messages, options); n > m.policy.MaxPromptTokens {
+			return nil, &GuardrailError{Decision: GuardrailDecision{
+				Stage:     "input",
+				Guardrail: "max_prompt_tokens",
+				Action:    GuardrailBlock.String(),
+				Reason:    fmt.Sprintf("estimated %d prompt tokens exceed %d", n, m.policy.MaxPromptTokens),
+			}}
+		}
+	}
+
+	messages, decisions, err := m.checkInput(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := request(messages)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if answer := resp.Answer(); answer != nil && answer.Content() != "" && len(m.policy.Output) > 0 {
+		var outputDecisions []GuardrailDecision
+		texts := map[string]string{}
+		for _, part := range asLLMMessage(answer).content {
+			if part.Text == "" {
+				continue
+			}
+			text, partDecisions, err := m.check(ctx, "output", m.policy.Output, part.Text)
+			if err != nil {
+				return nil, err
+			}
+			texts[part.Text] = text
+			outputDecisions = append(outputDecisions, partDecisions...)
+		}
+		if redacts(outputDecisions) {
+			resp = withAnswer(resp, redactTexts(answer, texts))
+		}
+		decisions = append(decisions, outputDecisions...)
+	}
+	if len(decisions) == 0 {
+		return resp, nil
+	}
+	return withMeta(resp, func(meta *Meta) { meta.Guardrails = append(meta.Guardrails, decisions...) }), nil
+}
+
+// checkInput runs the input guardrails on the new user messages and returns the
+// messages to send, with redactions applied to copies.
+func (m *guardrailModel) checkInput(ctx context.Context, messages []Message) ([]Message, []GuardrailDecision, error) {
+	if len(m.policy.Input) == 0 {
+		return messages, nil, nil
+	}
+	var decisions []GuardrailDecision
+	checked, copied := messages, false
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role() == constants.RoleAssistant {
+			break
+		}
+		if messages[i].Role() != constants.RoleUser {
+			continue
+		}
+		var messageDecisions []GuardrailDecision
+		texts := map[string]string{}
+		for _, part := range asLLMMessage(messages[i]).content {
+			if strings.TrimSpace(part.Text) == "" {
+				continue
+			}
+			text, partDecisions, err := m.check(ctx, "input", m.policy.Input, part.Text)
+			if err != nil {
+				return nil, nil, err
+			}
+			texts[part.Text] = text
+			messageDecisions = append(messageDecisions, partDecisions...)
+		}
+		if redacts(messageDecisions) {
+			if !copied {
+				checked, copied = append([]Message(nil), messages...), true
+			}
+			checked[i] = redactTexts(messages[i], texts)
+		}
+		decisions = append(messageDecisions, decisions...)
+	}
+	return checked, decisions, nil
+}
+
+// check runs guardrails on text and returns the text with redactions applied and
+// the decisions made, or a *GuardrailError when a guardrail blocks.
+func (m *guardrailModel) check(ctx context.Context, stage string, guardrails []Guardrail, text string) (string, []GuardrailDecision, error) {
+	var decisions []GuardrailDecision
+	for _, g := range guardrails {
+		matches, err := g.Detector.Detect(ctx, text)
+		if err != nil {
+			return "", nil, fmt.Errorf("guardrail %s: %w", g.Name, err)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		reasons := make([]string, len(matches))
+		for i, match := range matches {
+			reasons[i] = match.Reason
+		}
+		decision := GuardrailDecision{
+			Stage:     stage,
+			Guardrail: g.Name,
+			Action:    g.Action.String(),
+			Reason:    strings.Join(reasons, "; "),
+		}
+		switch g.Action {
+		case GuardrailBlock:
+			return "", nil, &GuardrailError{Decision: decision}
+		case GuardrailRedact:
+			text = redactMatches(text, matches)
+		}
+		decisions = append(decisions, decision)
+	}
+	return text, decisions, nil
+}
+
+// redactMatches replaces the matched spans of text, merging overlapping ones.
+func redactMatches(text string, matches []GuardrailMatch) string {
+	spans := make([][2]int, 0, len(matches))
+	for _, match := range matches {
+		if match.Start == 0 && match.End == 0 {
+			return guardrailRedaction
+		}
+		spans = append(spans, [2]int{max(match.Start, 0), min(match.End, len(text))})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[1] <= last {
+			continue
+		}
+		if span[0] >= last {
+			b.WriteString(text[last:span[0]])
+			b.WriteString(guardrailRedaction)
+		}
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// redacts reports whether any of decisions redacted text.
+func redacts(decisions []GuardrailDecision) bool {
+	for _, d := range decisions {
+		if d.Action == GuardrailRedact.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// redactTexts returns a copy of message with its text parts replaced as in texts.
+func redactTexts(message Message, texts map[string]string) Message {
+	return RedactMessage(RedactorFunc(func(text string) string {
+		if redacted, ok := texts[text]; ok {
+			return redacted
+		}
+		return text
+	}), message)
+}
@@ -0,0 +1,274 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errHedgeLost aborts the stream of a hedged request that lost the race.
+var errHedgeLost = errors.New("hedged request lost")
+
+// WithHedging returns a Middleware that cuts tail latency by sending a backup
+// request when the first one is slow. If no response has arrived after delay, the
+// request is sent again to the next of alternates (or to the wrapped model when
+// none are given), and so on every delay until one request per alternate is in
+// flight. The first successful response wins and the other requests are cancelled.
+// Streams race on their first event instead: the watcher only sees the stream that
+// produced output first. A failed request does not start a hedge early; wrap the
+// hedging model with WithRetry for that. Meta.Hedged reports a backup's response.
+// Hedging multiplies cost for slow requests, so choose delay around the p95 latency.
+func WithHedging(delay time.Duration, alternates ...Model) Middleware {
+	return func(next Model) Model {
+		models := alternates
+		if len(models) == 0 {
+			models = []Model{next}
+		}
+		return &hedgeModel{Model: next, delay: delay, hedges: models}
+	}
+}
+
+// hedgeModel is the Model returned by WithHedging.
+type hedgeModel struct {
+	Model
+	delay  time.Duration
+	hedges []Model
+}
+
+// Unwrap returns the wrapped model.
+func (m *hedgeModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *hedgeModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, opts, func(ctx context.Context, model Model, opts []ChatOption) (Response, error) {
+		return model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *hedgeModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, opts, func(ctx context.Context, model Model, opts []ChatOption) (Response, error) {
+		return model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// hedgeResult is the outcome of one request of a race.
+type hedgeResult struct {
+	index int
+	resp  Response
+	err   error
+}
+
+// do races the first request against the hedges.
+func (m *hedgeModel) do(ctx context.Context, opts []ChatOption, request func(ctx context.Context, model Model, opts []ChatOption) (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	race := &hedgeRace{winner: -1}
+	results := make(chan hedgeResult, 1+len(m.hedges))
+
+	launch := func(index int, model Model) {
+		ctx, cancel := context.WithCancel(ctx)
+		race.cancels = append(race.cancels, cancel)
+		attemptOpts := opts
+		if options.watcher != nil {
+			gate := &hedgeWatcher{watcher: options.watcher, race: race, index: index}
+			attemptOpts = append(opts[:len(opts):len(opts)], WithStreamWatcher(gate))
+		}
+		go func() {
+			resp, err := request(ctx, model, attemptOpts)
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	race.mu.Lock()
+	launch(0, m.Model)
+	race.mu.Unlock()
+	defer race.cancelAll()
+
+	timer := time.NewTimer(m.delay)
+	defer timer.Stop()
+	launched, pending := 1, 1
+	var last hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			race.mu.Lock()
+			if race.winner < 0 && launched <= len(m.hedges) {
+				launch(launched, m.hedges[launched-1])
+				launched++
+				pending++
+				timer.Reset(m.delay)
+			}
+			race.mu.Unlock()
+		case result := <-results:
+			pending--
+			race.mu.Lock()
+			winner := race.winner
+			if winner < 0 && result.err == nil {
+				race.claim(result.index)
+				winner = result.index
+			}
+			race.mu.Unlock()
+			if result.index == winner || winner < 0 && pending == 0 {
+				return withMeta(result.resp, func(meta *Meta) { meta.Hedged = result.index > 0 }), result.err
+			}
+			if winner < 0 {
+				last = result
+			}
+		case <-ctx.Done():
+			if last.err != nil {
+				return last.resp, last.err
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// hedgeRace tracks the requests of one hedged call.
+type hedgeRace struct {
+	mu sync.Mutex
+	// winner is the index of the request whose output is used, or -1.
+	winner  int
+	cancels []context.CancelFunc
+}
+
+// claim makes index the winner and cancels the other requests. The lock must be held.
+func (r *hedgeRace) claim(index int) {
+	r.winner = index
+	for i, cancel := range r.cancels {
+		if i != index {
+			cancel()
+		}
+	}
+}
+
+// cancelAll cancels every request once the call returns.
+func (r *hedgeRace) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+}
+
+// hedgeWatcher forwards the events of one hedged stream to the caller's watcher
+// if that stream is the first to produce any, and aborts it otherwise.
+type hedgeWatcher struct {
+	watcher StreamWatcher
+	race    *hedgeRace
+	index   int
+}
+
+// forward claims the race on the first event and reports whether this stream won.
+func (w *hedgeWatcher) forward() error {
+	w.race.mu.Lock()
+	defer w.race.mu.Unlock()
+	if w.race.winner < 0 {
+		w.race.claim(w.index)
+	}
+	if w.race.winner != w.index {
+		return errHedgeLost
+	}
+	return nil
+}
+
+// OnRefusal implements StreamWatcher.
+func (w *hedgeWatcher) OnRefusal(delta string) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	return w.watcher.OnRefusal(delta)
+}
+
+// OnReasoning implements StreamWatcher.
+func (w *hedgeWatcher) OnReasoning(delta string) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	return w.watcher.OnReasoning(delta)
+}
+
+// OnContent implements StreamWatcher.
+func (w *hedgeWatcher) OnContent(delta string) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	return w.watcher.OnContent(delta)
+}
+
+// OnToolCall implements StreamWatcher.
+func (w *hedgeWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	return w.watcher.OnToolCall(ctx, tcall, args)
+}
+
+// OnStop implements StreamWatcher.
+func (w *hedgeWatcher) OnStop() error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	return w.watcher.OnStop()
+}
+
+// OnUsage implements UsageWatcher.
+func (w *hedgeWatcher) OnUsage(usage Usage) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	if uw, ok := w.watcher.(UsageWatcher); ok {
+		return uw.OnUsage(usage)
+	}
+	return nil
+}
+
+// OnMeta implements UsageWatcher.
+func (w *hedgeWatcher) OnMeta(meta Meta) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	if uw, ok := w.watcher.(UsageWatcher); ok {
+		meta.Hedged = w.index > 0
+		return uw.OnMeta(meta)
+	}
+	return nil
+}
+
+// OnToolCallDone implements ToolCallDoneWatcher.
+func (w *hedgeWatcher) OnToolCallDone(ctx context.Context, tcall ToolCall) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	if dw, ok := w.watcher.(ToolCallDoneWatcher); ok {
+		return dw.OnToolCallDone(ctx, tcall)
+	}
+	return nil
+}
+
+// OnField implements FieldWatcher.
+func (w *hedgeWatcher) OnField(path string, value any) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	if fw, ok := w.watcher.(FieldWatcher); ok {
+		return fw.OnField(path, value)
+	}
+	return nil
+}
+
+// OnRawEvent implements RawEventWatcher.
+func (w *hedgeWatcher) OnRawEvent(providerEvent any) error {
+	if err := w.forward(); err != nil {
+		return err
+	}
+	if rw, ok := w.watcher.(RawEventWatcher); ok {
+		return rw.OnRawEvent(providerEvent)
+	}
+	return nil
+}
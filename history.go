@@ -0,0 +1,225 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// HistoryManager trims conversation history to fit a context budget. Leading
+// system messages are always kept; the remaining turns are cut from the oldest,
+// always starting at a user message, and tool results whose call was cut are dropped.
+// With WithHistorySummarizer the cut turns are replaced by a summary instead.
+type HistoryManager struct {
+	// maxTokens is the input token budget; zero disables the token window.
+	maxTokens int
+	// lastN keeps at most the last N messages after the system messages; zero keeps all.
+	lastN int
+	// summarizer summarizes the cut turns; nil drops them.
+	summarizer     Model
+	summarizerOpts []ChatOption
+
+	// counts caches token counts by request, see countTokens.
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// HistoryOption configures a HistoryManager.
+type HistoryOption func(h *HistoryManager)
+
+// WithHistoryMaxTokens keeps the most recent turns that fit within maxTokens input
+// tokens, including system prompts and tools, as counted by CountTokens.
+func WithHistoryMaxTokens(maxTokens int) HistoryOption {
+	return func(h *HistoryManager) { h.maxTokens = maxTokens }
+}
+
+// WithHistoryLastN keeps at most the last n messages after the system messages.
+func WithHistoryLastN(n int) HistoryOption {
+	return func(h *HistoryManager) { h.lastN = n }
+}
+
+// NewHistoryManager creates a HistoryManager. Without options it only drops
+// orphaned tool results.
+func NewHistoryManager(opts ...HistoryOption) *HistoryManager {
+	h := &HistoryManager{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithHistory trims the messages of the request with manager before it is sent,
+// counting tokens with the model that sends it.
+func WithHistory(manager *HistoryManager) ChatOption {
+	return func(opts *ChatOptions) { opts.history = manager }
+}
+
+// Trim returns messages cut to the manager's limits for a request to model with
+// opts. Tokens are counted like CountTokens, with the system prompts and tools
+// of opts. It fails with ErrHistoryTooLong when even the last user turn does not
+// fit, and with the summarizer's error when the cut turns cannot be summarized.
+// Each turn is counted once and the counts are cached by the manager, so with a
+// counting endpoint such as Anthropic's, retries and later requests of the same
+// conversation only count the turns they add. The input slice is not modified.
+func (h *HistoryManager) Trim(ctx context.Context, model Model, messages []Message, opts ...ChatOption) ([]Message, error) {
+	head := 0
+	for head < len(messages) && messages[head].Role() == constants.RoleSystem {
+		head++
+	}
+	system, turns := messages[:head], messages[head:]
+	start := 0
+	if h.lastN > 0 && len(turns) > h.lastN {
+		start = len(turns) - h.lastN
+		if i := h.nextStart(turns, start); i < len(turns) {
+			start = i
+		}
+	}
+
+	if h.maxTokens > 0 {
+		budget := h.maxTokens
+		if h.summarizer != nil {
+			budget -= h.summaryTokens()
+		}
+		count := func(turns []Message) (int, error) {
+			return h.countTokens(ctx, model, joinMessages(system, DropOrphanedToolResults(turns)), opts)
+		}
+		total, err := count(turns[start:])
+		if err != nil {
+			return nil, err
+		}
+		if total > budget {
+			// Count every turn on its own, once: the counts are cached, so
+			// retries and later requests of the conversation only count new
+			// turns. Each count includes the system prompts and tools, which
+			// the sum of the counts repeats once more per extra turn.
+			starts := []int{start}
+			for i := h.nextStart(turns, start+1); i < len(turns); i = h.nextStart(turns, i+1) {
+				starts = append(starts, i)
+			}
+			if len(starts) == 1 {
+				return nil, ErrHistoryTooLong
+			}
+			counts := make([]int, len(starts))
+			sum := 0
+			for i, from := range starts {
+				to := len(turns)
+				if i+1 < len(starts) {
+					to = starts[i+1]
+				}
+				if counts[i], err = count(turns[from:to]); err != nil {
+					return nil, err
+				}
+				sum += counts[i]
+			}
+			base := max((sum-total)/(len(starts)-1), 0)
+
+			found := 0
+			for i := 1; i < len(starts) && found == 0; i++ {
+				if total -= counts[i-1] - base; total <= budget {
+					found = i
+				}
+			}
+			if found == 0 {
+				return nil, ErrHistoryTooLong
+			}
+			start = starts[found]
+		}
+	}
+
+	kept := DropOrphanedToolResults(turns[start:])
+	if h.summarizer == nil || start == 0 {
+		return joinMessages(system, kept), nil
+	}
+	return h.summarize(ctx, system, turns[:start], kept)
+}
+
+// nextStart returns the index of the first message at or after i that a trimmed
+// history may start with, or len(turns). Without a summarizer that is a user
+// message; with one, the summary message comes first, so an assistant message
+// is also a valid start, which lets long tool loops be compacted.
+func (h *HistoryManager) nextStart(turns []Message, i int) int {
+	for ; i < len(turns); i++ {
+		role := turns[i].Role()
+		if role == constants.RoleUser || h.summarizer != nil && role == constants.RoleAssistant {
+			break
+		}
+	}
+	return i
+}
+
+// maxCachedCounts bounds the token counts a HistoryManager caches.
+const maxCachedCounts = 4096
+
+// countTokens counts a request like countRequestTokens, caching the count by
+// model, messages and options.
+func (h *HistoryManager) countTokens(ctx context.Context, model Model, messages []Message, opts []ChatOption) (int, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	key, err := cacheKey(model.Name(), messages, options)
+	if err != nil {
+		return countRequestTokens(ctx, model, messages, opts)
+	}
+
+	h.mu.Lock()
+	n, ok := h.counts[key]
+	h.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+	n, err = countRequestTokens(ctx, model, messages, opts)
+	if err != nil {
+		return 0, err
+	}
+	h.mu.Lock()
+	if h.counts == nil || len(h.counts) >= maxCachedCounts {
+		h.counts = make(map[string]int)
+	}
+	h.counts[key] = n
+	h.mu.Unlock()
+	return n, nil
+}
+
+// countRequestTokens counts the input tokens of a request with the TokenCounter
+// of the model or of the model it wraps, or estimates them without the output budget.
+func countRequestTokens(ctx context.Context, model Model, messages []Message, opts []ChatOption) (int, error) {
+	if counter, ok := asModel[TokenCounter](model); ok {
+		return counter.CountTokens(ctx, messages, opts...)
+	}
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.maxTokens = nil
+	return EstimateTokens(messages, options), nil
+}
+
+// DropOrphanedToolResults returns messages without the tool results whose tool
+// call is not in a preceding assistant message, which providers reject. The
+// input slice is not modified.
+func DropOrphanedToolResults(messages []Message) []Message {
+	calls := map[string]bool{}
+	kept := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		rich, ok := msg.(RichMessage)
+		if !ok {
+			kept = append(kept, msg)
+			continue
+		}
+		for _, tc := range rich.ToolCalls() {
+			calls[tc.ID()] = true
+		}
+		if msg.Role() == constants.RoleTool && !calls[rich.ToolCallID()] {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
+// joinMessages returns a new slice with a followed by b.
+func joinMessages(a, b []Message) []Message {
+	return append(append(make([]Message, 0, len(a)+len(b)), a...), b...)
+}
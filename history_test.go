@@ -0,0 +1,104 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHistorySummarizeNothingKept(t *testing.T) {
+	call := NewToolCall("call_1", "lookup", "{}")
+	messages := []Message{
+		NewUserMessage("look it up"),
+		NewAssistantMessage("", call),
+		NewToolMessage(call, "found"),
+	}
+	h := NewHistoryManager(WithHistoryLastN(1), WithHistorySummarizer(NewEchoModel(WithEchoFallback("the user asked for a lookup"))))
+
+	got, err := h.Trim(context.Background(), NewEchoModel(), messages)
+	if err != nil {
+		t.Fatalf("Trim: %v", err)
+	}
+	if len(got) != 1 || got[0].Role() != "user" || !strings.HasPrefix(got[0].Content(), summaryPrefix) {
+		t.Fatalf("Trim = %v, want a single user summary message", got)
+	}
+}
+
+func TestHistorySummarizerError(t *testing.T) {
+	messages := []Message{
+		NewUserMessage("first"),
+		NewAssistantMessage("one"),
+		NewUserMessage("second"),
+	}
+	errDown := errors.New("summarizer down")
+	h := NewHistoryManager(WithHistoryLastN(1), WithHistorySummarizer(failingModel{err: errDown}))
+
+	if _, err := h.Trim(context.Background(), NewEchoModel(), messages); !errors.Is(err, errDown) {
+		t.Fatalf("Trim error = %v, want %v", err, errDown)
+	}
+}
+
+func TestHistoryTrimCachesCounts(t *testing.T) {
+	var messages []Message
+	for _, turn := range []string{"one", "two", "three", "four", "five"} {
+		messages = append(messages, NewUserMessage(turn), NewAssistantMessage(turn))
+	}
+	model := &countingModel{Model: NewEchoModel()}
+	h := NewHistoryManager(WithHistoryMaxTokens(55))
+
+	for i, wantCalls := range []int{6, 0} {
+		got, err := h.Trim(context.Background(), model, messages)
+		if err != nil {
+			t.Fatalf("Trim %d: %v", i, err)
+		}
+		if len(got) != 4 || got[0].Content() != "four" {
+			t.Fatalf("Trim %d = %v, want the last two turns", i, got)
+		}
+		if model.calls != wantCalls {
+			t.Fatalf("Trim %d counted %d times, want %d", i, model.calls, wantCalls)
+		}
+		model.calls = 0
+	}
+
+	// a new turn only counts the new request and the new turn
+	messages = append(messages, NewUserMessage("six"))
+	got, err := h.Trim(context.Background(), model, messages)
+	if err != nil {
+		t.Fatalf("Trim: %v", err)
+	}
+	if len(got) != 3 || got[0].Content() != "five" {
+		t.Fatalf("Trim = %v, want the last two turns", got)
+	}
+	if model.calls != 2 {
+		t.Fatalf("Trim counted %d times, want 2", model.calls)
+	}
+}
+
+// countingModel is a TokenCounter charging 10 tokens per request and per
+// message, recording how often it counts.
+type countingModel struct {
+	Model
+	calls int
+}
+
+func (m *countingModel) CountTokens(ctx context.Context, messages []Message, opts ...ChatOption) (int, error) {
+	m.calls++
+	return 10 + 10*len(messages), nil
+}
+
+// failingModel is a Model whose requests fail with err.
+type failingModel struct {
+	err error
+}
+
+func (m failingModel) Name() string        { return "failing" }
+func (m failingModel) Description() string { return "" }
+
+func (m failingModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return nil, m.err
+}
+
+func (m failingModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return nil, m.err
+}
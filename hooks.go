@@ -0,0 +1,141 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Hooks are callbacks for lifecycle events of every model, runner and middleware
+// in the process, so observability and policy systems can subscribe in one place
+// instead of wrapping each model. Nil fields are skipped. Hooks run synchronously
+// on the request's goroutine and should return quickly.
+type Hooks struct {
+	// OnRequestStart is called before a provider request is sent.
+	OnRequestStart func(ctx context.Context, event RequestStartEvent)
+	// OnRequestEnd is called after a provider request completes or fails.
+	OnRequestEnd func(ctx context.Context, event RequestEndEvent)
+	// OnToolExecuted is called after a Runner executes a tool call.
+	OnToolExecuted func(ctx context.Context, event ToolExecutedEvent)
+	// OnRetry is called when WithRetry is about to retry a failed request.
+	OnRetry func(ctx context.Context, event RetryEvent)
+	// OnFallback is called when WithFallback moves on to the next model.
+	OnFallback func(ctx context.Context, event FallbackEvent)
+}
+
+// RequestStartEvent describes a provider request about to be sent.
+type RequestStartEvent struct {
+	Model    string
+	Stream   bool
+	Messages []Message
+}
+
+// RequestEndEvent describes a finished provider request. Response may be a
+// partial response when Err is set.
+type RequestEndEvent struct {
+	Model    string
+	Stream   bool
+	Response Response
+	Err      error
+	Duration time.Duration
+}
+
+// ToolExecutedEvent describes a tool call executed by a Runner.
+type ToolExecutedEvent struct {
+	Call     ToolCall
+	Result   string
+	Err      error
+	Duration time.Duration
+}
+
+// RetryEvent describes a retry scheduled by WithRetry.
+type RetryEvent struct {
+	Model string
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt int
+	Err     error
+	// Delay is the wait before the next attempt.
+	Delay time.Duration
+}
+
+// FallbackEvent describes WithFallback moving from one model to the next.
+type FallbackEvent struct {
+	From string
+	To   string
+	Err  error
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []*Hooks
+)
+
+// RegisterHooks subscribes h to lifecycle events until the returned function is called.
+func RegisterHooks(h Hooks) (unregister func()) {
+	entry := &h
+	hooksMu.Lock()
+	hooks = append(hooks, entry)
+	hooksMu.Unlock()
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		for i, registered := range hooks {
+			if registered == entry {
+				hooks = append(hooks[:i:i], hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// registeredHooks returns the current subscribers.
+func registeredHooks() []*Hooks {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks
+}
+
+// startRequest emits OnRequestStart and returns the function that emits OnRequestEnd.
+func startRequest(ctx context.Context, model string, stream bool, messages []Message) func(resp Response, err error) {
+	for _, h := range registeredHooks() {
+		if h.OnRequestStart != nil {
+			h.OnRequestStart(ctx, RequestStartEvent{Model: model, Stream: stream, Messages: messages})
+		}
+	}
+	start := time.Now()
+	return func(resp Response, err error) {
+		event := RequestEndEvent{Model: model, Stream: stream, Response: resp, Err: err, Duration: time.Since(start)}
+		for _, h := range registeredHooks() {
+			if h.OnRequestEnd != nil {
+				h.OnRequestEnd(ctx, event)
+			}
+		}
+	}
+}
+
+// emitToolExecuted emits OnToolExecuted.
+func emitToolExecuted(ctx context.Context, event ToolExecutedEvent) {
+	for _, h := range registeredHooks() {
+		if h.OnToolExecuted != nil {
+			h.OnToolExecuted(ctx, event)
+		}
+	}
+}
+
+// emitRetry emits OnRetry.
+func emitRetry(ctx context.Context, event RetryEvent) {
+	for _, h := range registeredHooks() {
+		if h.OnRetry != nil {
+			h.OnRetry(ctx, event)
+		}
+	}
+}
+
+// emitFallback emits OnFallback.
+func emitFallback(ctx context.Context, event FallbackEvent) {
+	for _, h := range registeredHooks() {
+		if h.OnFallback != nil {
+			h.OnFallback(ctx, event)
+		}
+	}
+}
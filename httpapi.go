@@ -0,0 +1,63 @@
+package openllm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// httpAPI calls a JSON API over plain HTTP, for providers without an SDK dependency.
+type httpAPI struct {
+	provider string
+	client   *http.Client
+	baseURL  string
+	// auth sets the credentials of a request.
+	auth func(ctx context.Context, req *http.Request) error
+}
+
+// newHTTPAPI creates an httpAPI for provider with the client options applied;
+// defaultURL is used unless WithBaseURL is given.
+func newHTTPAPI(provider, defaultURL string, opts []ClientOption, auth func(ctx context.Context, req *http.Request) error) *httpAPI {
+	options := newClientOptions(opts)
+	api := &httpAPI{provider: provider, client: options.httpClient, baseURL: defaultURL, auth: auth}
+	if api.client == nil {
+		api.client = http.DefaultClient
+	}
+	if options.baseURL != "" {
+		api.baseURL = options.baseURL
+	}
+	return api
+}
+
+// post sends body as JSON to path and decodes the response into out.
+// Error statuses are returned as *HTTPError.
+func (api *httpAPI) post(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if api.auth != nil {
+		if err := api.auth(ctx, req); err != nil {
+			return err
+		}
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return &HTTPError{Provider: api.provider, StatusCode: resp.StatusCode, Body: string(text), Header: resp.Header}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
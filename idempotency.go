@@ -0,0 +1,205 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// idempotencyHeader carries the idempotency key to providers that honor it.
+const idempotencyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey identifies the request so that repeating it has no further
+// effect. The key is sent as the Idempotency-Key header where the provider client
+// allows per-request headers (Anthropic), and is used by WithIdempotency for local
+// deduplication. Runner.Run derives a key per model call and per tool call from it.
+func WithIdempotencyKey(key string) ChatOption {
+	return func(opts *ChatOptions) { opts.idempotencyKey = key }
+}
+
+// WithIdempotency returns a Middleware that deduplicates requests carrying an
+// idempotency key: the first successful response is stored in store for ttl and
+// returned for every later request with the same key, and concurrent duplicates wait
+// for the request in flight instead of calling the model again. Failed requests are
+// not stored, so retries proceed. Requests without a key pass through.
+// Replayed responses report Meta.CacheHit. A key reused for a different request
+// (other messages, tools or options) fails with ErrIdempotencyKeyReused.
+func WithIdempotency(store CacheStore, ttl time.Duration) Middleware {
+	inflight := &inflightGroup{calls: make(map[string]*inflightCall)}
+	return func(next Model) Model {
+		return &idempotentModel{Model: next, store: store, ttl: ttl, inflight: inflight}
+	}
+}
+
+// idempotentModel is the Model returned by WithIdempotency.
+type idempotentModel struct {
+	Model
+	store    CacheStore
+	ttl      time.Duration
+	inflight *inflightGroup
+}
+
+// Unwrap returns the wrapped model.
+func (m *idempotentModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *idempotentModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, false, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *idempotentModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, true, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// idempotentEntry is the stored outcome of a request with an idempotency key.
+type idempotentEntry struct {
+	// Request is the cacheKey of the request, which later uses of the key must match.
+	Request  string          `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// do returns the stored response for the request's key or runs request once.
+func (m *idempotentModel) do(ctx context.Context, messages []Message, opts []ChatOption, stream bool, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.idempotencyKey == "" {
+		return request()
+	}
+	key := "idempotency:" + m.Name() + ":" + options.idempotencyKey
+	hash, err := cacheKey(m.Name(), messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := func(data []byte) (Response, error) {
+		var entry idempotentEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		if entry.Request != hash {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, options.idempotencyKey)
+		}
+		resp, err := DecodeResponse(entry.Response)
+		if err != nil {
+			return nil, err
+		}
+		resp = withMeta(resp, func(meta *Meta) { meta.CacheHit = true })
+		if stream && options.watcher != nil {
+			return resp, replayResponse(ctx, options.watcher, resp)
+		}
+		return resp, nil
+	}
+
+	for {
+		if data, ok, err := m.store.Get(ctx, key); err == nil && ok {
+			return replay(data)
+		}
+		call, leader := m.inflight.join(key)
+		if !leader {
+			// Wait for the request in flight, then look again: it stored its
+			// response on success, or this request takes over on failure
+			select {
+			case <-call.done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		return m.lead(ctx, key, hash, call, request)
+	}
+}
+
+// lead runs request as the call in flight for key and stores a successful response
+// with the request hash. The call is left even if request panics, so that the
+// duplicates waiting for it are released.
+func (m *idempotentModel) lead(ctx context.Context, key, hash string, call *inflightCall, request func() (Response, error)) (Response, error) {
+	defer m.inflight.leave(key, call)
+	resp, err := request()
+	if err == nil {
+		if data, err := EncodeResponse(resp); err == nil {
+			if data, err := json.Marshal(idempotentEntry{Request: hash, Response: data}); err == nil {
+				_ = m.store.Set(ctx, key, data, m.ttl)
+			}
+		}
+	}
+	return resp, err
+}
+
+// inflightGroup tracks the requests in flight per key.
+type inflightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// inflightCall is closed once its request finishes.
+type inflightCall struct {
+	done chan struct{}
+}
+
+// join returns the call in flight for key, or registers a new one and reports
+// that the caller leads it.
+func (g *inflightGroup) join(key string) (*inflightCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// leave unregisters call and wakes its waiters.
+func (g *inflightGroup) leave(key string, call *inflightCall) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// WithIdempotentTools makes Run record the result of every tool call in store for
+// ttl when the run carries an idempotency key (see WithIdempotencyKey). A repeated
+// Run with the same key, e.g. after a crash or a failed later step, then replays the
+// recorded results instead of executing side-effecting tools again. Combined with
+// WithIdempotency on the model, which replays the same tool calls, a retried run
+// repeats no effects. Tool errors are not recorded.
+func WithIdempotentTools(store CacheStore, ttl time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.toolStore = store
+		r.toolStoreTTL = ttl
+	}
+}
+
+// idempotentTool returns a middleware that records and replays tool results under key.
+func idempotentTool(store CacheStore, ttl time.Duration, key string) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+			k := "idempotency:" + key + ":tool:" + tcall.ID()
+			if data, ok, err := store.Get(ctx, k); err == nil && ok {
+				var result string
+				if err := json.Unmarshal(data, &result); err == nil {
+					return result, nil
+				}
+			}
+			result, err := next(ctx, tool, tcall)
+			if err == nil {
+				if data, err := json.Marshal(result); err == nil {
+					_ = store.Set(ctx, k, data, ttl)
+				}
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	model := Wrap(NewEchoModel(), WithIdempotency(NewLRUCache(10), time.Minute))
+	key := WithIdempotencyKey("order-42")
+
+	first, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("hello")}, key)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	again, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("hello")}, key)
+	if err != nil || !again.Meta().CacheHit || again.Answer().Content() != first.Answer().Content() {
+		t.Fatalf("repeated request = %v, %v; want the stored answer", again, err)
+	}
+	if _, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("goodbye")}, key); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("request with other messages: err = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestIdempotencyReleasesKeyAfterPanic(t *testing.T) {
+	base := &panickingModel{Model: NewEchoModel()}
+	model := Wrap(base, WithIdempotency(NewLRUCache(10), time.Minute))
+	messages := []Message{NewUserMessage("hello")}
+	key := WithIdempotencyKey("order-42")
+
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = model.ChatCompletion(context.Background(), messages, key)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := model.ChatCompletion(ctx, messages, key); err != nil {
+		t.Errorf("request after a panicking one: %v, want it to run", err)
+	}
+}
+
+// panickingModel panics on its first request.
+type panickingModel struct {
+	Model
+	calls int
+}
+
+func (m *panickingModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	if m.calls++; m.calls == 1 {
+		panic("boom")
+	}
+	return m.Model.ChatCompletion(ctx, messages, opts...)
+}
@@ -0,0 +1,110 @@
+package openllm
+
+import (
+	"context"
+	"net/http"
+)
+
+// ImageOption represents a functional option to configure a single image request.
+type ImageOption func(*ImageOptions)
+
+// ImageOptions holds per-request configuration for ImageModel.
+// Fields are intentionally unexported; use With* helpers to set them.
+type ImageOptions struct {
+	// n is the number of images to produce; zero lets the provider decide (usually 1).
+	n int
+	// size is the image size, e.g. constants.ImageSize1024x1024.
+	size string
+	// quality is the rendering quality, e.g. constants.ImageQualityHigh.
+	quality string
+	// style is a provider-specific style, such as "vivid" or "natural" for dall-e-3.
+	style string
+	// background is "transparent", "opaque" or "auto" where supported.
+	background string
+	// outputFormat is the encoding of the returned bytes: "png", "jpeg" or "webp".
+	outputFormat string
+	// url requests hosted URLs instead of image bytes.
+	url bool
+	// mask marks the area to edit with transparent pixels.
+	mask []byte
+}
+
+// WithImageCount sets the number of images to produce.
+func WithImageCount(n int) ImageOption {
+	return func(opts *ImageOptions) { opts.n = n }
+}
+
+// WithImageSize sets the image size (see constants.ImageSize*).
+func WithImageSize(size string) ImageOption {
+	return func(opts *ImageOptions) { opts.size = size }
+}
+
+// WithImageQuality sets the rendering quality (see constants.ImageQuality*).
+func WithImageQuality(quality string) ImageOption {
+	return func(opts *ImageOptions) { opts.quality = quality }
+}
+
+// WithImageStyle sets a provider-specific style, such as "vivid" or "natural" for dall-e-3.
+func WithImageStyle(style string) ImageOption {
+	return func(opts *ImageOptions) { opts.style = style }
+}
+
+// WithImageBackground requests a "transparent" or "opaque" background where supported.
+func WithImageBackground(background string) ImageOption {
+	return func(opts *ImageOptions) { opts.background = background }
+}
+
+// WithImageOutputFormat sets the encoding of the returned images: "png", "jpeg" or "webp".
+func WithImageOutputFormat(format string) ImageOption {
+	return func(opts *ImageOptions) { opts.outputFormat = format }
+}
+
+// WithImageURLResponse returns hosted URLs instead of image bytes, for providers that
+// host results (OpenAI DALL·E; the URLs expire after an hour).
+func WithImageURLResponse() ImageOption {
+	return func(opts *ImageOptions) { opts.url = true }
+}
+
+// WithImageMask sets the mask of an edit: a PNG the size of the image whose
+// transparent pixels mark the area to change.
+func WithImageMask(mask []byte) ImageOption {
+	return func(opts *ImageOptions) { opts.mask = mask }
+}
+
+// Image is a generated image, either as bytes or as a hosted URL.
+type Image struct {
+	// Data holds the encoded image unless a URL was requested.
+	Data []byte
+	// URL is the hosted image when requested with WithImageURLResponse.
+	URL string
+	// MIMEType is the type of Data, e.g. "image/png".
+	MIMEType string
+	// RevisedPrompt is the prompt the provider actually used, when it rewrote it.
+	RevisedPrompt string
+}
+
+// ImageResult holds the images of a request and the tokens (or cost) spent.
+type ImageResult struct {
+	Images []Image
+	// Usage reports tokens for token-billed models; per-image prices are
+	// reported in Usage.CostUSD only.
+	Usage Usage
+}
+
+// ImageModel creates images. Backends implement the operations their provider
+// supports and return ErrUnsupportedImageOperation for the others.
+type ImageModel interface {
+	// Name returns the model name.
+	Name() string
+	// Generate creates images from a prompt.
+	Generate(ctx context.Context, prompt string, opts ...ImageOption) (*ImageResult, error)
+	// Edit changes an image as the prompt describes, optionally within a mask (WithImageMask).
+	Edit(ctx context.Context, image []byte, prompt string, opts ...ImageOption) (*ImageResult, error)
+	// Variation creates variations of an image.
+	Variation(ctx context.Context, image []byte, opts ...ImageOption) (*ImageResult, error)
+}
+
+// imageMIMEType returns the MIME type of encoded image data.
+func imageMIMEType(data []byte) string {
+	return http.DetectContentType(data)
+}
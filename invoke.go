@@ -0,0 +1,285 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// funcSignature describes the shape of a callback registered via WithFunction.
+// Supported shapes are func([ctx context.Context,] [params P]) ([R,] [error]).
+type funcSignature struct {
+	fn reflect.Value
+	// withContext reports whether the first argument is a context.Context.
+	withContext bool
+	// param is the type of the parameters argument, or nil if there is none.
+	param reflect.Type
+	// withResult reports whether the function returns a result value.
+	withResult bool
+	// withError reports whether the last return value is an error.
+	withError bool
+}
+
+// inspectFunction validates fn and describes its signature.
+func inspectFunction(fn any) (*funcSignature, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("no function bound to tool")
+	}
+	val := reflect.ValueOf(fn)
+	typ := val.Type()
+	if typ.Kind() != reflect.Func {
+		return nil, fmt.Errorf("tool callback must be a function, got %s", typ)
+	}
+
+	sig := &funcSignature{fn: val}
+
+	in := 0
+	if typ.NumIn() > in && typ.In(in).Implements(contextType) {
+		sig.withContext = true
+		in++
+	}
+	if typ.NumIn() > in {
+		sig.param = typ.In(in)
+		in++
+	}
+	if typ.NumIn() != in || typ.IsVariadic() {
+		return nil, fmt.Errorf("unsupported tool callback signature %s", typ)
+	}
+
+	switch typ.NumOut() {
+	case 0:
+	case 1:
+		if typ.Out(0) == errorType {
+			sig.withError = true
+		} else {
+			sig.withResult = true
+		}
+	case 2:
+		if typ.Out(1) != errorType {
+			return nil, fmt.Errorf("unsupported tool callback signature %s: second result must be error", typ)
+		}
+		sig.withResult = true
+		sig.withError = true
+	default:
+		return nil, fmt.Errorf("unsupported tool callback signature %s", typ)
+	}
+	return sig, nil
+}
+
+// call decodes args into the parameter type, invokes the function,
+// and returns its result value together with the returned error.
+func (sig *funcSignature) call(ctx context.Context, args string) (any, error) {
+	var in []reflect.Value
+	if sig.withContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	if sig.param != nil {
+		param, err := decodeArguments(args, sig.param)
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, param)
+	}
+
+	out := sig.fn.Call(in)
+
+	var (
+		result any
+		err    error
+	)
+	if sig.withResult {
+		result = out[0].Interface()
+	}
+	if sig.withError {
+		if e := out[len(out)-1]; !e.IsNil() {
+			err = e.Interface().(error)
+		}
+	}
+	return result, err
+}
+
+// ExecuteToolCall runs the Go function bound to tool (see WithFunction) for tcall.
+// The JSON arguments are decoded into the function's parameter struct using the same
+// `openllm` tags that produced the schema, and the result is encoded as the text to send
+// back with NewToolMessage: strings and byte slices verbatim, other values as JSON.
+// Errors returned by the function are passed through unchanged.
+func ExecuteToolCall(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+	def, ok := tool.Definition().(*FunctionDefinition)
+	if !ok || def.InvokeFunc == nil {
+		return "", ErrToolNotExecutable
+	}
+	if name := tcall.Function().Name(); name != def.Name {
+		return "", fmt.Errorf("tool call %q does not match tool %q", name, def.Name)
+	}
+	return invokeFunction(ctx, def.InvokeFunc, tcall.Function().Arguments())
+}
+
+// invokeFunction runs a tool callback with the given JSON arguments
+// and returns its result encoded as a string.
+func invokeFunction(ctx context.Context, fn any, args string) (string, error) {
+	if typed, ok := fn.(toolInvoker); ok {
+		return typed.invoke(ctx, args)
+	}
+	sig, err := inspectFunction(fn)
+	if err != nil {
+		return "", err
+	}
+	result, err := sig.call(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	return encodeResult(result)
+}
+
+// toolInvoker is implemented by callbacks that decode and run themselves,
+// such as those created by DefineTypedFunction.
+type toolInvoker interface {
+	invoke(ctx context.Context, args string) (string, error)
+}
+
+// typedFunction is the invoker of a DefineTypedFunction callback.
+type typedFunction[TArgs any, TResult any] struct {
+	fn func(ctx context.Context, args TArgs) (TResult, error)
+	// tagged reports whether TArgs needs `openllm` tag-aware decoding.
+	tagged bool
+}
+
+// newTypedFunction wraps fn, deciding once how its arguments are decoded.
+func newTypedFunction[TArgs any, TResult any](fn func(ctx context.Context, args TArgs) (TResult, error)) *typedFunction[TArgs, TResult] {
+	t := reflect.TypeOf((*TArgs)(nil)).Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &typedFunction[TArgs, TResult]{
+		fn:     fn,
+		tagged: t.Kind() == reflect.Struct && hasFieldTags(t),
+	}
+}
+
+// invoke implements toolInvoker.
+func (f *typedFunction[TArgs, TResult]) invoke(ctx context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+	var params TArgs
+	var err error
+	if f.tagged {
+		err = decodeValue(json.RawMessage(args), reflect.ValueOf(&params).Elem())
+	} else {
+		err = json.Unmarshal([]byte(args), &params)
+	}
+	if err != nil {
+		return "", fmt.Errorf("decode tool arguments: %w", err)
+	}
+	result, err := f.fn(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return encodeResult(result)
+}
+
+// decodeArguments decodes JSON arguments into a new value of type t.
+// Structs whose fields carry `openllm` tags are decoded by tag name,
+// matching the schema produced by DefineFunction; other types use encoding/json.
+func decodeArguments(args string, t reflect.Type) (reflect.Value, error) {
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+	ptr := reflect.New(t)
+	if err := decodeValue(json.RawMessage(args), ptr.Elem()); err != nil {
+		return reflect.Value{}, fmt.Errorf("decode tool arguments: %w", err)
+	}
+	return ptr.Elem(), nil
+}
+
+// decodeValue decodes raw into the addressable value v.
+func decodeValue(raw json.RawMessage, v reflect.Value) error {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && hasFieldTags(t.Elem()) {
+		if string(raw) == "null" {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return decodeValue(raw, v.Elem())
+	}
+	if t.Kind() != reflect.Struct || !hasFieldTags(t) {
+		return json.Unmarshal(raw, v.Addr().Interface())
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	for _, pf := range structFields(t) {
+		fraw, found := fields[pf.tag.name]
+		if !found {
+			continue
+		}
+		fv, ok := fieldByIndexAlloc(v, pf.index)
+		if !ok {
+			continue
+		}
+		if err := decodeValue(fraw, fv); err != nil {
+			return fmt.Errorf("%s: %w", pf.tag.name, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates nil embedded pointers.
+// It reports false when a nil pointer to an unexported struct cannot be allocated.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// hasFieldTags reports whether any parameter field of struct type t carries an `openllm` tag.
+// Structs described by `json` tags alone decode identically with encoding/json.
+func hasFieldTags(t reflect.Type) bool {
+	for _, pf := range structFields(t) {
+		if pf.field.Tag.Get("openllm") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeResult converts a tool callback result to the text sent back to the model.
+// Strings and byte slices are passed through; other values are JSON-encoded.
+func encodeResult(result any) (string, error) {
+	switch r := result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return r, nil
+	case []byte:
+		return string(r), nil
+	case json.RawMessage:
+		return string(r), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
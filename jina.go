@@ -0,0 +1,57 @@
+package openllm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// jinaBaseURL is the Jina AI API endpoint.
+const jinaBaseURL = "https://api.jina.ai"
+
+type jinaReranker struct {
+	model string
+	api   *httpAPI
+}
+
+// NewJinaReranker creates a Reranker for a Jina AI rerank model, e.g.
+// "jina-reranker-v2-base-multilingual".
+// Client options can set the HTTP client, proxy and endpoint.
+func NewJinaReranker(model, apiKey string, opts ...ClientOption) Reranker {
+	api := newHTTPAPI(constants.ProviderJina, jinaBaseURL, opts, func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return nil
+	})
+	return &jinaReranker{model: model, api: api}
+}
+
+// Rerank implements Reranker.
+func (r *jinaReranker) Rerank(ctx context.Context, query string, documents []string, opts ...RerankOption) ([]RerankResult, Usage, error) {
+	options := &RerankOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if len(documents) == 0 {
+		return nil, Usage{}, nil
+	}
+	req := struct {
+		Model           string   `json:"model"`
+		Query           string   `json:"query"`
+		Documents       []string `json:"documents"`
+		TopN            int      `json:"top_n,omitempty"`
+		ReturnDocuments bool     `json:"return_documents"`
+	}{Model: r.model, Query: query, Documents: documents, TopN: options.topN}
+	var resp struct {
+		Results []rerankScore `json:"results"`
+		Usage   struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := r.api.post(ctx, "/v1/rerank", &req, &resp); err != nil {
+		return nil, Usage{}, err
+	}
+	results, err := rerankResults(documents, resp.Results)
+	tokens := resp.Usage.TotalTokens
+	return results, Usage{InputTokens: tokens, TotalTokens: tokens}, err
+}
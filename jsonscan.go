@@ -0,0 +1,341 @@
+package openllm
+
+import (
+	"fmt"
+)
+
+// Scanner states.
+const (
+	scanValue        = iota // expecting any value
+	scanValueOrEnd          // after '[': expecting a value or ']'
+	scanKeyOrEnd            // after '{': expecting a key or '}'
+	scanKey                 // after ',' in an object: expecting a key
+	scanColon               // after a key: expecting ':'
+	scanCommaOrEnd          // after a value inside a container
+	scanString              // inside a string
+	scanEscape              // after '\' inside a string
+	scanUnicode             // inside a \uXXXX escape
+	scanLiteral             // inside true/false/null
+	scanNumMinus            // after '-'
+	scanNumZero             // after a leading '0'
+	scanNumInt              // inside integer digits
+	scanNumDot              // after '.'
+	scanNumFrac             // inside fraction digits
+	scanNumExp              // after 'e' or 'E'
+	scanNumExpSign          // after the exponent sign
+	scanNumExpDigits        // inside exponent digits
+	scanDone                // top-level value complete
+)
+
+// jsonScanner is an incremental JSON syntax validator. Input may be written in
+// arbitrary chunks; the first syntax error is reported as soon as the offending
+// byte is seen, while truncation is only detectable when finish is called.
+type jsonScanner struct {
+	// stack holds the currently open containers ('{' or '[').
+	stack []byte
+	// state is the current scanner state.
+	state int
+	// literal holds the bytes still expected for true/false/null.
+	literal string
+	// hex counts the digits consumed in a \u escape.
+	hex int
+	// key reports whether the current string is an object key.
+	key bool
+	// started reports whether any non-whitespace byte has been seen.
+	started bool
+	// offset is the number of bytes consumed so far.
+	offset int
+	// err is the first error encountered.
+	err *ArgumentsError
+	// keyStart is the offset of the opening quote of the current object key.
+	keyStart int
+	// events, when set, is notified of keys and values as they are scanned.
+	events jsonEvents
+}
+
+// jsonEvents observes the structure of the input seen by a jsonScanner.
+// Offsets refer to the bytes consumed by the scanner.
+type jsonEvents interface {
+	// key reports an object key string spanning [start, end).
+	key(start, end int)
+	// begin reports a value starting at offset.
+	begin(offset int)
+	// end reports that the innermost open value ends before offset.
+	end(offset int)
+}
+
+// write feeds a chunk of input to the scanner.
+func (s *jsonScanner) write(p string) {
+	for i := 0; i < len(p) && s.err == nil; i++ {
+		s.step(p[i])
+		s.offset++
+	}
+}
+
+// invalid reports whether an error has already been detected.
+func (s *jsonScanner) invalid() error {
+	if s.err != nil {
+		return s.err
+	}
+	return nil
+}
+
+// finish reports whether the input seen so far forms exactly one complete JSON value.
+// Empty input is accepted and treated as an empty argument object.
+func (s *jsonScanner) finish() error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.started || s.state == scanDone {
+		return nil
+	}
+	if len(s.stack) == 0 && s.numberComplete() {
+		return nil
+	}
+	return &ArgumentsError{Offset: s.offset, Truncated: true, Reason: "unexpected end of JSON input"}
+}
+
+// fail records a syntax error at the current offset.
+func (s *jsonScanner) fail(format string, args ...any) {
+	s.err = &ArgumentsError{Offset: s.offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// step consumes a single byte.
+func (s *jsonScanner) step(c byte) {
+	if !isSpace(c) {
+		s.started = true
+	}
+
+	switch s.state {
+	case scanValue, scanValueOrEnd:
+		if isSpace(c) {
+			return
+		}
+		if s.state == scanValueOrEnd && c == ']' {
+			s.pop()
+			return
+		}
+		s.beginValue(c)
+	case scanKeyOrEnd, scanKey:
+		if isSpace(c) {
+			return
+		}
+		if s.state == scanKeyOrEnd && c == '}' {
+			s.pop()
+			return
+		}
+		if c != '"' {
+			s.fail("invalid character %q looking for beginning of object key string", c)
+			return
+		}
+		s.key = true
+		s.keyStart = s.offset
+		s.state = scanString
+	case scanColon:
+		if isSpace(c) {
+			return
+		}
+		if c != ':' {
+			s.fail("invalid character %q after object key", c)
+			return
+		}
+		s.state = scanValue
+	case scanCommaOrEnd:
+		if isSpace(c) {
+			return
+		}
+		top := s.stack[len(s.stack)-1]
+		switch {
+		case c == ',' && top == '{':
+			s.state = scanKey
+		case c == ',' && top == '[':
+			s.state = scanValue
+		case c == '}' && top == '{', c == ']' && top == '[':
+			s.pop()
+		default:
+			s.fail("invalid character %q after %s value", c, containerName(top))
+		}
+	case scanString:
+		switch {
+		case c == '"':
+			if s.key {
+				s.key = false
+				s.state = scanColon
+				if s.events != nil {
+					s.events.key(s.keyStart, s.offset+1)
+				}
+			} else {
+				s.endValue(s.offset + 1)
+			}
+		case c == '\\':
+			s.state = scanEscape
+		case c < 0x20:
+			s.fail("invalid control character %q in string literal", c)
+		}
+	case scanEscape:
+		switch c {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			s.state = scanString
+		case 'u':
+			s.hex = 0
+			s.state = scanUnicode
+		default:
+			s.fail("invalid character %q in string escape code", c)
+		}
+	case scanUnicode:
+		if !isHex(c) {
+			s.fail("invalid character %q in \\u hexadecimal character escape", c)
+			return
+		}
+		if s.hex++; s.hex == 4 {
+			s.state = scanString
+		}
+	case scanLiteral:
+		if c != s.literal[0] {
+			s.fail("invalid character %q in literal", c)
+			return
+		}
+		if s.literal = s.literal[1:]; s.literal == "" {
+			s.endValue(s.offset + 1)
+		}
+	case scanNumMinus:
+		switch {
+		case c == '0':
+			s.state = scanNumZero
+		case isDigit(c):
+			s.state = scanNumInt
+		default:
+			s.fail("invalid character %q in numeric literal", c)
+		}
+	case scanNumZero, scanNumInt:
+		switch {
+		case s.state == scanNumInt && isDigit(c):
+		case c == '.':
+			s.state = scanNumDot
+		case c == 'e' || c == 'E':
+			s.state = scanNumExp
+		default:
+			s.endNumber(c)
+		}
+	case scanNumDot:
+		if !isDigit(c) {
+			s.fail("invalid character %q after decimal point in numeric literal", c)
+			return
+		}
+		s.state = scanNumFrac
+	case scanNumFrac:
+		switch {
+		case isDigit(c):
+		case c == 'e' || c == 'E':
+			s.state = scanNumExp
+		default:
+			s.endNumber(c)
+		}
+	case scanNumExp:
+		switch {
+		case c == '+' || c == '-':
+			s.state = scanNumExpSign
+		case isDigit(c):
+			s.state = scanNumExpDigits
+		default:
+			s.fail("invalid character %q in exponent of numeric literal", c)
+		}
+	case scanNumExpSign:
+		if !isDigit(c) {
+			s.fail("invalid character %q in exponent of numeric literal", c)
+			return
+		}
+		s.state = scanNumExpDigits
+	case scanNumExpDigits:
+		if !isDigit(c) {
+			s.endNumber(c)
+		}
+	case scanDone:
+		if !isSpace(c) {
+			s.fail("invalid character %q after top-level value", c)
+		}
+	}
+}
+
+// beginValue dispatches on the first byte of a value.
+func (s *jsonScanner) beginValue(c byte) {
+	if s.events != nil && (c == '{' || c == '[' || c == '"' || c == '-' || isDigit(c) || c == 't' || c == 'f' || c == 'n') {
+		s.events.begin(s.offset)
+	}
+	switch {
+	case c == '{':
+		s.stack = append(s.stack, '{')
+		s.state = scanKeyOrEnd
+	case c == '[':
+		s.stack = append(s.stack, '[')
+		s.state = scanValueOrEnd
+	case c == '"':
+		s.state = scanString
+	case c == '-':
+		s.state = scanNumMinus
+	case c == '0':
+		s.state = scanNumZero
+	case isDigit(c):
+		s.state = scanNumInt
+	case c == 't':
+		s.literal, s.state = "rue", scanLiteral
+	case c == 'f':
+		s.literal, s.state = "alse", scanLiteral
+	case c == 'n':
+		s.literal, s.state = "ull", scanLiteral
+	default:
+		s.fail("invalid character %q looking for beginning of value", c)
+	}
+}
+
+// endNumber terminates a number at byte c, which is then re-scanned.
+func (s *jsonScanner) endNumber(c byte) {
+	s.endValue(s.offset)
+	s.step(c)
+}
+
+// endValue transitions after a complete value that ends before offset end.
+func (s *jsonScanner) endValue(end int) {
+	if s.events != nil {
+		s.events.end(end)
+	}
+	if len(s.stack) == 0 {
+		s.state = scanDone
+	} else {
+		s.state = scanCommaOrEnd
+	}
+}
+
+// pop closes the innermost container.
+func (s *jsonScanner) pop() {
+	s.stack = s.stack[:len(s.stack)-1]
+	s.endValue(s.offset + 1)
+}
+
+// numberComplete reports whether the scanner stopped in a state that ends a valid number.
+func (s *jsonScanner) numberComplete() bool {
+	switch s.state {
+	case scanNumZero, scanNumInt, scanNumFrac, scanNumExpDigits:
+		return true
+	}
+	return false
+}
+
+func containerName(c byte) string {
+	if c == '{' {
+		return "object key:value pair"
+	}
+	return "array element"
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
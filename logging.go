@@ -0,0 +1,208 @@
+package openllm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LogConfig configures WithLogging.
+type LogConfig struct {
+	// Level is the level of successful requests; failed requests are logged at Error.
+	Level slog.Level
+	// Content adds the prompts, messages, answer and tool arguments to the records.
+	// Without it only metadata such as sizes, usage and latency is logged.
+	Content bool
+	// Redactor masks logged content. Redactors set on the request with WithRedactor
+	// are applied as well, so the log never shows more than the provider received.
+	Redactor Redactor
+}
+
+// WithLogging returns a Middleware that writes one structured record per request to
+// logger (slog.Default when nil) with the model, options, tool calls, usage, latency
+// and error, plus the conversation when cfg.Content is set.
+func WithLogging(logger *slog.Logger, cfg LogConfig) Middleware {
+	return func(next Model) Model {
+		return &loggingModel{Model: next, logger: logger, cfg: cfg}
+	}
+}
+
+// loggingModel is the Model returned by WithLogging.
+type loggingModel struct {
+	Model
+	logger *slog.Logger
+	cfg    LogConfig
+}
+
+// Unwrap returns the wrapped model.
+func (m *loggingModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *loggingModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, false, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *loggingModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, true, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do runs request and logs its outcome.
+func (m *loggingModel) do(ctx context.Context, messages []Message, opts []ChatOption, stream bool, request func() (Response, error)) (Response, error) {
+	start := time.Now()
+	resp, err := request()
+	elapsed := time.Since(start)
+
+	logger := m.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := m.cfg.Level
+	if err != nil {
+		level = slog.LevelError
+	}
+	if !logger.Enabled(ctx, level) {
+		return resp, err
+	}
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	attrs := []slog.Attr{
+		slog.String("model", m.Name()),
+		slog.Bool("stream", stream),
+		slog.Int("messages", len(messages)),
+		slog.Duration("latency", elapsed),
+		m.optionsAttr(options),
+	}
+	if m.cfg.Content {
+		prompts, redacted := options.redact(messages)
+		for i, prompt := range prompts {
+			prompts[i] = m.redact(prompt)
+		}
+		if len(prompts) > 0 {
+			attrs = append(attrs, slog.Any("prompts", prompts))
+		}
+		attrs = append(attrs, slog.Any("conversation", m.messagesValue(redacted)))
+	}
+	if resp != nil {
+		attrs = append(attrs, m.responseAttrs(resp, options)...)
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "llm request", attrs...)
+	return resp, err
+}
+
+// optionsAttr summarizes the options that shape the request (see TranscriptOptions).
+// Schemas and constraints are named by kind rather than logged in full.
+func (m *loggingModel) optionsAttr(opts *ChatOptions) slog.Attr {
+	to, _ := requestOptions(opts)
+	var attrs []slog.Attr
+	if to.MaxTokens != nil {
+		attrs = append(attrs, slog.Int("max_tokens", *to.MaxTokens))
+	}
+	if to.Temperature != nil {
+		attrs = append(attrs, slog.Float64("temperature", *to.Temperature))
+	}
+	if to.TopP != nil {
+		attrs = append(attrs, slog.Float64("top_p", *to.TopP))
+	}
+	if to.TopK != nil {
+		attrs = append(attrs, slog.Int("top_k", *to.TopK))
+	}
+	if to.Seed != nil {
+		attrs = append(attrs, slog.Int("seed", *to.Seed))
+	}
+	if to.ReasoningEffort != nil {
+		attrs = append(attrs, slog.String("reasoning_effort", *to.ReasoningEffort))
+	}
+	if to.ResponseFormat != "" {
+		attrs = append(attrs, slog.String("response_format", to.ResponseFormat))
+	}
+	if to.SchemaName != "" {
+		attrs = append(attrs, slog.String("schema", to.SchemaName))
+	}
+	if to.ConstraintKind != "" {
+		attrs = append(attrs, slog.String("constraint", to.ConstraintKind))
+	}
+	if to.LogProbs != nil {
+		attrs = append(attrs, slog.Int("logprobs", *to.LogProbs))
+	}
+	if len(to.Tools) > 0 {
+		attrs = append(attrs, slog.Any("tools", to.Tools))
+	}
+	return slog.Attr{Key: "options", Value: slog.GroupValue(attrs...)}
+}
+
+// responseAttrs describes the answer, tool calls and usage of resp.
+func (m *loggingModel) responseAttrs(resp Response, opts *ChatOptions) []slog.Attr {
+	usage, meta := resp.Usage(), resp.Meta()
+	attrs := []slog.Attr{
+		slog.Group("usage",
+			slog.Int("input_tokens", usage.InputTokens),
+			slog.Int("output_tokens", usage.OutputTokens),
+			slog.Int("total_tokens", usage.TotalTokens),
+		),
+	}
+	if meta.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", meta.RequestID))
+	}
+	if meta.StopReason != "" {
+		attrs = append(attrs, slog.String("stop_reason", meta.StopReason))
+	}
+	if meta.FinishReason != "" {
+		attrs = append(attrs, slog.String("finish_reason", string(meta.FinishReason)))
+	}
+	if tcalls := resp.ToolCalls(); len(tcalls) > 0 {
+		values := make([]any, len(tcalls))
+		for i, tcall := range tcalls {
+			call := map[string]string{"id": tcall.ID(), "name": tcall.Function().Name()}
+			if m.cfg.Content {
+				call["arguments"] = m.redact(m.redactWith(opts, tcall.Function().Arguments()))
+			}
+			values[i] = call
+		}
+		attrs = append(attrs, slog.Any("tool_calls", values))
+	}
+	if answer := resp.Answer(); m.cfg.Content && answer != nil {
+		attrs = append(attrs, slog.String("answer", m.redact(m.redactWith(opts, answer.Content()))))
+		if refusal := asLLMMessage(answer).refusal; refusal != "" {
+			attrs = append(attrs, slog.String("refusal", m.redact(m.redactWith(opts, refusal))))
+		}
+	}
+	return attrs
+}
+
+// messagesValue renders messages as role and content pairs after redaction.
+func (m *loggingModel) messagesValue(messages []Message) []map[string]string {
+	values := make([]map[string]string, len(messages))
+	for i, message := range messages {
+		values[i] = map[string]string{"role": message.Role(), "content": m.redact(message.Content())}
+	}
+	return values
+}
+
+// redactWith applies the request's redactors to text.
+func (m *loggingModel) redactWith(opts *ChatOptions, text string) string {
+	for _, r := range opts.redactors {
+		text = r.Redact(text)
+	}
+	return text
+}
+
+// redact applies the configured log redactor to text.
+func (m *loggingModel) redact(text string) string {
+	if m.cfg.Redactor == nil || text == "" {
+		return text
+	}
+	return m.cfg.Redactor.Redact(text)
+}
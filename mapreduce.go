@@ -0,0 +1,280 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultChunkTokens is the default chunk size of MapReduce.
+	defaultChunkTokens = 4000
+	// defaultMapConcurrency is the default number of concurrent MapReduce calls.
+	defaultMapConcurrency = 4
+	// charsPerToken converts token sizes to bytes, as EstimateTokens does.
+	charsPerToken = 4
+)
+
+// MapReduceOptions holds the settings of MapReduce.
+type MapReduceOptions struct {
+	chunkTokens   int
+	overlapTokens int
+	concurrency   int
+	mapPrompt     string
+	reducePrompt  string
+	chatOpts      []ChatOption
+}
+
+// MapReduceOption configures MapReduce.
+type MapReduceOption func(opts *MapReduceOptions)
+
+// WithChunkTokens sets the size of the chunks, in tokens estimated at four
+// bytes each (default 4000). Partial results are also reduced in groups of
+// this size.
+func WithChunkTokens(tokens int) MapReduceOption {
+	return func(opts *MapReduceOptions) { opts.chunkTokens = tokens }
+}
+
+// WithChunkOverlap repeats the last tokens of each chunk at the start of the
+// next one, so facts spanning a boundary are seen whole.
+func WithChunkOverlap(tokens int) MapReduceOption {
+	return func(opts *MapReduceOptions) { opts.overlapTokens = tokens }
+}
+
+// WithMapConcurrency sets how many model calls run at once (default 4).
+// Wrap the model with WithRateLimit or WithScheduler to stay within provider quotas.
+func WithMapConcurrency(n int) MapReduceOption {
+	return func(opts *MapReduceOptions) { opts.concurrency = n }
+}
+
+// WithMapPrompt replaces the instructions given with each chunk.
+func WithMapPrompt(prompt string) MapReduceOption {
+	return func(opts *MapReduceOptions) { opts.mapPrompt = prompt }
+}
+
+// WithReducePrompt replaces the instructions given with the partial results.
+func WithReducePrompt(prompt string) MapReduceOption {
+	return func(opts *MapReduceOptions) { opts.reducePrompt = prompt }
+}
+
+// WithMapChatOptions sets options applied to every model call, e.g.
+// WithPriority(PriorityBatch) or WithMaxTokens.
+func WithMapChatOptions(opts ...ChatOption) MapReduceOption {
+	return func(o *MapReduceOptions) { o.chatOpts = append(o.chatOpts, opts...) }
+}
+
+// MapReduceResult is the outcome of MapReduce.
+type MapReduceResult struct {
+	// Answer is the text of the final reduce call.
+	Answer string
+	// Partials are the map results, one per chunk in document order.
+	Partials []string
+	// Response is the final reduce response.
+	Response Response
+	// Usage is the token usage of all calls.
+	Usage Usage
+}
+
+// MapReduce performs task over a document too large for one request: it splits
+// document into chunks (see SplitText), runs task on each chunk concurrently,
+// and combines the partial results with a final call. When the partial results
+// are themselves too large, they are combined in groups first. The first failed
+// call cancels the others and its error is returned.
+func MapReduce(ctx context.Context, model Model, task, document string, opts ...MapReduceOption) (*MapReduceResult, error) {
+	options := &MapReduceOptions{
+		chunkTokens: defaultChunkTokens,
+		concurrency: defaultMapConcurrency,
+		mapPrompt: "You are given one part of a longer document. Perform the task on this part only; " +
+			"the results for all parts are combined later. If the part holds nothing relevant, reply with NONE.",
+		reducePrompt: "You are given partial results, each computed on one part of a longer document. " +
+			"Combine them into a single result for the task. Remove duplicates and ignore parts answered with NONE.",
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	chunkBytes := options.chunkTokens * charsPerToken
+	chunks := SplitText(document, chunkBytes, options.overlapTokens*charsPerToken)
+
+	var mu sync.Mutex
+	result := &MapReduceResult{}
+	call := func(ctx context.Context, prompt, input string) (Response, error) {
+		all := append([]ChatOption{WithSystemPrompts(prompt, "Task: "+task)}, options.chatOpts...)
+		resp, err := model.ChatCompletion(ctx, []Message{NewUserMessage(input)}, all...)
+		if resp != nil {
+			mu.Lock()
+			result.Usage = result.Usage.Add(resp.Usage())
+			mu.Unlock()
+		}
+		return resp, err
+	}
+
+	partials, err := fanOut(ctx, options.concurrency, chunks, func(ctx context.Context, chunk string) (string, error) {
+		resp, err := call(ctx, options.mapPrompt, chunk)
+		if err != nil {
+			return "", err
+		}
+		return resp.Answer().Content(), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Partials = partials
+
+	// Combine groups of partial results until they fit in one call
+	for {
+		groups := groupPartials(partials, chunkBytes)
+		if len(groups) == 1 || len(groups) == len(partials) {
+			resp, err := call(ctx, options.reducePrompt, joinPartials(partials))
+			if err != nil {
+				return result, err
+			}
+			result.Response = resp
+			result.Answer = resp.Answer().Content()
+			return result, nil
+		}
+		partials, err = fanOut(ctx, options.concurrency, groups, func(ctx context.Context, group []string) (string, error) {
+			resp, err := call(ctx, options.reducePrompt, joinPartials(group))
+			if err != nil {
+				return "", err
+			}
+			return resp.Answer().Content(), nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+}
+
+// fanOut runs fn over inputs with at most concurrency calls at once and returns
+// the outputs in input order. The first error cancels the remaining calls.
+func fanOut[T any](ctx context.Context, concurrency int, inputs []T, fn func(ctx context.Context, input T) (string, error)) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	outputs := make([]string, len(inputs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, input := range inputs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, input T) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			output, err := fn(ctx, input)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			outputs[i] = output
+		}(i, input)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return outputs, ctx.Err()
+}
+
+// groupPartials groups consecutive partial results into groups of at most
+// maxBytes; a result larger than maxBytes forms a group of its own.
+func groupPartials(partials []string, maxBytes int) [][]string {
+	var groups [][]string
+	size := 0
+	for _, partial := range partials {
+		if n := len(groups); n > 0 && size+len(partial) <= maxBytes {
+			groups[n-1] = append(groups[n-1], partial)
+			size += len(partial)
+			continue
+		}
+		groups = append(groups, []string{partial})
+		size = len(partial)
+	}
+	return groups
+}
+
+// joinPartials numbers partial results for a reduce call.
+func joinPartials(partials []string) string {
+	var sb strings.Builder
+	for i, partial := range partials {
+		fmt.Fprintf(&sb, "Part %d:\n%s\n\n", i+1, strings.TrimSpace(partial))
+	}
+	return sb.String()
+}
+
+// textSeparators are the boundaries SplitText prefers, coarsest first.
+var textSeparators = []string{"\n\n", "\n", ". ", " "}
+
+// SplitText splits text into chunks of at most maxBytes, preferring paragraph,
+// then line, sentence and word boundaries, and never splitting a UTF-8
+// character. With overlapBytes > 0, each chunk after the first starts with up
+// to overlapBytes from the end of the previous one, within the same maxBytes.
+func SplitText(text string, maxBytes, overlapBytes int) []string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []string{text}
+	}
+	overlapBytes = min(max(overlapBytes, 0), maxBytes/2)
+	pieces := splitPieces(text, maxBytes-overlapBytes, textSeparators)
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range pieces {
+		if current.Len() > 0 && current.Len()+len(piece) > maxBytes-overlapBytes {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(piece)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	if overlapBytes > 0 {
+		for i := len(chunks) - 1; i > 0; i-- {
+			chunks[i] = utf8Suffix(chunks[i-1], overlapBytes) + chunks[i]
+		}
+	}
+	return chunks
+}
+
+// splitPieces cuts text into pieces of at most maxBytes at the coarsest
+// separator that works, keeping each separator at the end of its piece.
+func splitPieces(text string, maxBytes int, separators []string) []string {
+	if len(text) <= maxBytes {
+		return []string{text}
+	}
+	if len(separators) == 0 {
+		var pieces []string
+		for len(text) > 0 {
+			piece := utf8Prefix(text, maxBytes)
+			if piece == "" {
+				// maxBytes is smaller than one character
+				piece = text[:1]
+			}
+			pieces = append(pieces, piece)
+			text = text[len(piece):]
+		}
+		return pieces
+	}
+	var pieces []string
+	for _, part := range strings.SplitAfter(text, separators[0]) {
+		pieces = append(pieces, splitPieces(part, maxBytes, separators[1:])...)
+	}
+	return pieces
+}
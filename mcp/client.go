@@ -0,0 +1,226 @@
+// Package mcp connects to Model Context Protocol servers and exposes their
+// tools as openllm.Tool values, so they can be offered to any Model and executed
+// by openllm.Runner like locally defined functions.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP protocol revision announced during initialization.
+const ProtocolVersion = "2024-11-05"
+
+// ErrClosed is returned for requests issued after the client was closed.
+var ErrClosed = errors.New("mcp: client closed")
+
+// Transport carries JSON-RPC messages between the client and an MCP server.
+// Implementations must be safe for one concurrent Send and one concurrent Recv.
+type Transport interface {
+	// Send delivers one JSON-RPC message.
+	Send(ctx context.Context, message []byte) error
+	// Recv blocks until the next JSON-RPC message arrives.
+	Recv() ([]byte, error)
+	// Close releases the underlying connection or process.
+	Close() error
+}
+
+// Implementation identifies an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Client is an MCP client bound to a single server connection.
+type Client struct {
+	transport Transport
+	info      Implementation
+	server    Implementation
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[int64]chan *rpcMessage
+	closed  chan struct{}
+	err     error
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithClientInfo sets the client name and version reported to the server.
+func WithClientInfo(name, version string) Option {
+	return func(c *Client) { c.info = Implementation{Name: name, Version: version} }
+}
+
+// NewClient creates a client over transport and starts reading server messages.
+// Call Initialize before issuing other requests.
+func NewClient(transport Transport, opts ...Option) *Client {
+	c := &Client{
+		transport: transport,
+		info:      Implementation{Name: "openllm", Version: "1.0.0"},
+		pending:   make(map[int64]chan *rpcMessage),
+		closed:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	go c.readLoop()
+	return c
+}
+
+// Initialize performs the MCP handshake.
+func (c *Client) Initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      c.info,
+	}
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	c.server = result.ServerInfo
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ServerInfo returns the server identity reported during Initialize.
+func (c *Client) ServerInfo() Implementation {
+	return c.server
+}
+
+// Close shuts down the transport and fails all pending requests.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// rpcMessage is a JSON-RPC 2.0 request, notification or response.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is an error returned by the server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: rpc error %d: %s", e.Code, e.Message)
+}
+
+// call issues a request and decodes its result into result (if non-nil).
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	id := c.nextID.Add(1)
+	ch := make(chan *rpcMessage, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(&rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if err := c.transport.Send(ctx, data); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return c.err
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	}
+}
+
+// notify sends a notification, which has no response.
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	data, err := json.Marshal(&rpcMessage{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// readLoop dispatches server messages until the transport fails.
+func (c *Client) readLoop() {
+	for {
+		data, err := c.transport.Recv()
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("%w: %v", ErrClosed, err)
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+
+		var msg struct {
+			rpcMessage
+			Params json.RawMessage `json:"params,omitempty"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			// Server-initiated request: answer pings, reject everything else
+			c.respond(*msg.ID, msg.Method)
+		case msg.Method != "":
+			// Notifications are not used by this client
+		case msg.ID != nil:
+			c.mu.Lock()
+			ch, found := c.pending[*msg.ID]
+			c.mu.Unlock()
+			if found {
+				resp := msg.rpcMessage
+				ch <- &resp
+			}
+		}
+	}
+}
+
+// respond answers a server-initiated request.
+func (c *Client) respond(id int64, method string) {
+	resp := &rpcMessage{JSONRPC: "2.0", ID: &id}
+	if method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &RPCError{Code: -32601, Message: "method not found: " + method}
+	}
+	if data, err := json.Marshal(resp); err == nil {
+		_ = c.transport.Send(context.Background(), data)
+	}
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SSETransport implements the MCP HTTP+SSE transport: server messages arrive on a
+// long-lived event stream, client messages are POSTed to the endpoint announced by
+// the server in its first "endpoint" event.
+type SSETransport struct {
+	client   *http.Client
+	endpoint string
+	body     io.ReadCloser
+	messages chan []byte
+	// err records why the event stream ended; it is set before messages is closed.
+	err error
+}
+
+// NewSSETransport opens the event stream at sseURL and waits for the endpoint event.
+// A nil httpClient uses http.DefaultClient.
+func NewSSETransport(ctx context.Context, sseURL string, httpClient *http.Client) (*SSETransport, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	base, err := url.Parse(sseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, sseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp: sse connect: unexpected status %s", resp.Status)
+	}
+
+	t := &SSETransport{
+		client:   httpClient,
+		body:     resp.Body,
+		messages: make(chan []byte, 16),
+	}
+	endpoint := make(chan string, 1)
+	go t.readEvents(endpoint)
+
+	select {
+	case <-ctx.Done():
+		t.Close()
+		return nil, ctx.Err()
+	case _, ok := <-t.messages:
+		t.Close()
+		if !ok {
+			return nil, t.err
+		}
+		return nil, errors.New("mcp: sse: message received before endpoint event")
+	case ep := <-endpoint:
+		ref, err := url.Parse(ep)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+		return t, nil
+	}
+}
+
+// readEvents parses the event stream until it ends.
+func (t *SSETransport) readEvents(endpoint chan<- string) {
+	reader := bufio.NewReader(t.body)
+	var (
+		event string
+		data  bytes.Buffer
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.err = err
+			close(t.messages)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			// Dispatch the event
+			payload := bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+			switch event {
+			case "endpoint":
+				select {
+				case endpoint <- string(payload):
+				default:
+				}
+			case "", "message":
+				if len(payload) > 0 {
+					t.messages <- append([]byte(nil), payload...)
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			data.WriteByte('\n')
+		}
+	}
+}
+
+// Send implements Transport.
+func (t *SSETransport) Send(ctx context.Context, message []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("mcp: post message: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Recv implements Transport.
+func (t *SSETransport) Recv() ([]byte, error) {
+	msg, ok := <-t.messages
+	if !ok {
+		return nil, t.err
+	}
+	return msg, nil
+}
+
+// Close implements Transport.
+func (t *SSETransport) Close() error {
+	return t.body.Close()
+}
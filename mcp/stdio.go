@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioCloseTimeout is how long Close waits for the server to exit after its
+// stdin is closed before killing it.
+const stdioCloseTimeout = 5 * time.Second
+
+// StdioTransport talks to an MCP server subprocess over newline-delimited JSON on stdin/stdout.
+type StdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	mu     sync.Mutex
+	// closeTimeout is the grace period of Close.
+	closeTimeout time.Duration
+}
+
+// NewStdioTransport starts command with args and connects to its standard streams.
+func NewStdioTransport(command string, args ...string) (*StdioTransport, error) {
+	return NewStdioTransportCmd(exec.Command(command, args...))
+}
+
+// NewStdioTransportCmd starts a prepared command (allowing a custom environment,
+// working directory or stderr) and connects to its standard streams.
+func NewStdioTransportCmd(cmd *exec.Cmd) (*StdioTransport, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &StdioTransport{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+
+		closeTimeout: stdioCloseTimeout,
+	}, nil
+}
+
+// Send implements Transport.
+func (t *StdioTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.stdin.Write(append(message, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Recv implements Transport.
+func (t *StdioTransport) Recv() ([]byte, error) {
+	for {
+		line, err := t.stdout.ReadBytes('\n')
+		if len(line) > 1 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close implements Transport by closing stdin and waiting for the subprocess to exit.
+// A server that is still running after a grace period of 5 seconds is killed.
+func (t *StdioTransport) Close() error {
+	t.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- t.cmd.Wait() }()
+
+	timer := time.NewTimer(t.closeTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		_ = t.cmd.Process.Kill()
+		return <-done
+	}
+}
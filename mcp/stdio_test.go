@@ -0,0 +1,26 @@
+package mcp
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStdioCloseKillsStuckServer(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	transport, err := NewStdioTransport("sleep", "60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.closeTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if err := transport.Close(); err == nil {
+		t.Error("Close of a killed server returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close took %v, want the server killed after the grace period", elapsed)
+	}
+}
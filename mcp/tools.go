@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/thecxx/openllm"
+)
+
+// ToolInfo describes a tool advertised by an MCP server.
+type ToolInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// Content is an item of a tool result.
+type Content struct {
+	// Type is "text", "image", "audio" or "resource".
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// CallToolResult is the outcome of a tools/call request.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text flattens the result into the text sent back to the model:
+// text items verbatim, other items JSON-encoded, separated by newlines.
+func (r *CallToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+			continue
+		}
+		if data, err := json.Marshal(c); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ListTools returns every tool advertised by the server, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var (
+		tools  []ToolInfo
+		cursor string
+	)
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor,omitempty"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server with JSON arguments.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	params := map[string]any{
+		"name":      name,
+		"arguments": args,
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Tools lists the server tools and wraps each as an openllm.Tool whose execution is
+// proxied to the server. Results flow back through the standard tool-result path:
+// a result flagged isError by the server is returned as an error, which openllm.Runner
+// reports to the model as a failed tool invocation.
+func (c *Client) Tools(ctx context.Context) ([]openllm.Tool, error) {
+	infos, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]openllm.Tool, 0, len(infos))
+	for _, info := range infos {
+		tools = append(tools, c.tool(info))
+	}
+	return tools, nil
+}
+
+// tool wraps a server tool as an openllm.Tool.
+func (c *Client) tool(info ToolInfo) openllm.Tool {
+	name := info.Name
+	invoke := func(ctx context.Context, args json.RawMessage) (string, error) {
+		result, err := c.CallTool(ctx, name, args)
+		if err != nil {
+			return "", err
+		}
+		if result.IsError {
+			return "", errors.New(result.Text())
+		}
+		return result.Text(), nil
+	}
+
+	if len(info.InputSchema) > 0 {
+		if tool, err := openllm.DefineFunctionFromJSONSchema(info.Name, info.Description, info.InputSchema, openllm.WithFunction(invoke)); err == nil {
+			return tool
+		}
+	}
+	return openllm.DefineFunction(info.Name, info.Description, openllm.WithFunction(invoke))
+}
@@ -0,0 +1,66 @@
+package openllm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+)
+
+// defaultImageMediaType is assumed when an image's format cannot be detected.
+const defaultImageMediaType = "image/jpeg"
+
+// sniffImageType detects PNG, JPEG, GIF and WebP images by their magic numbers.
+// It returns "" for other formats.
+func sniffImageType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "image/webp"
+	}
+	return ""
+}
+
+// imageDataURL encodes image bytes as a base64 data URL with the detected media type.
+func imageDataURL(data []byte) string {
+	mediaType := sniffImageType(data)
+	if mediaType == "" {
+		mediaType = defaultImageMediaType
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// parseImageURL splits an image reference into its parts. Remote URLs are reported
+// with isURL; data URLs and raw base64 strings yield the media type and base64 data,
+// sniffing the type of raw base64 from its first bytes.
+func parseImageURL(imageURL string) (mediaType, data string, isURL bool) {
+	if strings.HasPrefix(imageURL, "http://") || strings.HasPrefix(imageURL, "https://") {
+		return "", imageURL, true
+	}
+	if idx := strings.Index(imageURL, ";base64,"); idx != -1 {
+		mediaType = defaultImageMediaType
+		if prefix := imageURL[:idx]; strings.HasPrefix(prefix, "data:") {
+			mediaType = strings.TrimPrefix(prefix, "data:")
+		}
+		return mediaType, imageURL[idx+len(";base64,"):], false
+	}
+
+	// Raw base64: decode a short prefix to look at the magic number
+	mediaType = defaultImageMediaType
+	if len(imageURL) > 15 {
+		prefix := imageURL
+		if len(prefix) > 64 {
+			prefix = prefix[:64]
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(prefix); err == nil {
+			if sniffed := sniffImageType(decoded); sniffed != "" {
+				mediaType = sniffed
+			}
+		}
+	}
+	return mediaType, imageURL, false
+}
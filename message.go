@@ -1,7 +1,9 @@
 package openllm
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/thecxx/openllm/constants"
@@ -12,6 +14,12 @@ import (
 type MessageOptions struct {
 	// imageURLs is the set of image parts to attach to a user message.
 	imageURLs []ImageURL
+	// documents is the set of document parts to attach to a user message.
+	documents []Document
+	// videos is the set of video parts to attach to a user message.
+	videos []Video
+	// name identifies the participant who authored the message.
+	name string
 }
 
 // ImageURL represents an image URL with detail level for multi-modal messages.
@@ -29,6 +37,20 @@ func WithImageURL(imageURL string) MessageOption {
 	return WithImageURLDetail(imageURL, constants.ImageURLDetailAuto)
 }
 
+// WithParticipantName attributes the message to a named participant, keeping speaker
+// identity in multi-user conversations. OpenAI receives it as the message name
+// (letters, digits, '_' and '-' only); Anthropic, which has no such field, receives
+// the text prefixed with "name: ".
+func WithParticipantName(name string) MessageOption {
+	return func(opts *MessageOptions) { opts.name = name }
+}
+
+// WithImageBytes adds an image given by its raw bytes. PNG, JPEG, GIF and WebP
+// are detected automatically and the image is sent as base64 data.
+func WithImageBytes(data []byte) MessageOption {
+	return WithImageURL(imageDataURL(data))
+}
+
 // WithImageURLDetail adds an image URL with an explicit detail level for OpenAI.
 func WithImageURLDetail(imageURL string, detail string) MessageOption {
 	if detail != constants.ImageURLDetailHigh &&
@@ -44,8 +66,61 @@ func WithImageURLDetail(imageURL string, detail string) MessageOption {
 	}
 }
 
+// Document is a file attached to a message, such as a PDF contract.
+// Exactly one of Data or URL is set.
+type Document struct {
+	// Data holds the file content; it is base64-encoded in JSON.
+	Data []byte `json:"data,omitempty"`
+	// URL points to a file the provider fetches itself.
+	URL string `json:"url,omitempty"`
+	// MIMEType is the media type of Data, e.g. "application/pdf" or "text/plain".
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+// WithDocument attaches a document given by its content and MIME type.
+// Anthropic accepts PDFs and plain text; OpenAI receives text documents inline
+// (see ErrUnsupportedContent).
+func WithDocument(data []byte, mimeType string) MessageOption {
+	return func(opts *MessageOptions) {
+		opts.documents = append(opts.documents, Document{Data: data, MIMEType: mimeType})
+	}
+}
+
+// WithDocumentURL attaches a PDF document by URL.
+func WithDocumentURL(url string) MessageOption {
+	return func(opts *MessageOptions) {
+		opts.documents = append(opts.documents, Document{URL: url, MIMEType: "application/pdf"})
+	}
+}
+
+// Video is a video clip attached to a message. Exactly one of Data or URL is set.
+// Only video-capable backends accept it; OpenAI and Anthropic return ErrUnsupportedContent.
+type Video struct {
+	// Data holds the clip content; it is base64-encoded in JSON.
+	Data []byte `json:"data,omitempty"`
+	// URL points to a clip the provider fetches itself.
+	URL string `json:"url,omitempty"`
+	// MIMEType is the media type, e.g. "video/mp4".
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+// WithVideo attaches a video clip given by its content and MIME type.
+func WithVideo(data []byte, mimeType string) MessageOption {
+	return func(opts *MessageOptions) {
+		opts.videos = append(opts.videos, Video{Data: data, MIMEType: mimeType})
+	}
+}
+
+// WithVideoURL attaches a video clip by URL.
+func WithVideoURL(url, mimeType string) MessageOption {
+	return func(opts *MessageOptions) {
+		opts.videos = append(opts.videos, Video{URL: url, MIMEType: mimeType})
+	}
+}
+
 // Message represents a minimal conversational unit.
-// It exposes only the role and textual content.
+// It exposes only the role and textual content. Messages created by this package
+// are immutable and safe for concurrent use.
 type Message interface {
 	// Role returns the logical role of the message
 	// (e.g. system, assistant, user).
@@ -58,6 +133,25 @@ type Message interface {
 	Reasoning() string
 }
 
+// RichMessage extends Message with its structured content.
+// Messages created by this package, including Response.Answer(), implement it.
+type RichMessage interface {
+	Message
+
+	// Parts returns the content parts (text, images, server tool blocks) in order.
+	Parts() []ContentPart
+
+	// ToolCalls returns the tool calls requested by an assistant message.
+	ToolCalls() []ToolCall
+
+	// ToolCallID returns the ID of the call a tool message answers, or "".
+	ToolCallID() string
+
+	// Refusal returns the explanation of an answer the model refused to give
+	// (OpenAI), or "".
+	Refusal() string
+}
+
 // NewUserMessage creates a user-role message suitable for any model.
 func NewUserMessage(content string, opts ...MessageOption) Message {
 	var options MessageOptions
@@ -66,20 +160,33 @@ func NewUserMessage(content string, opts ...MessageOption) Message {
 	}
 	msg := &llmmsg{
 		role: constants.RoleUser,
+		name: options.name,
 	}
 
-	if len(options.imageURLs) == 0 {
+	if len(options.imageURLs) == 0 && len(options.documents) == 0 && len(options.videos) == 0 {
 		msg.content = []ContentPart{
 			{Type: constants.ContentPartTypeText, Text: content},
 		}
 	} else {
-		// Mixed content: Text + Images
+		// Mixed content: Text + Images + Documents + Videos
 		for _, img := range options.imageURLs {
 			msg.content = append(msg.content, ContentPart{
 				Type:     constants.ContentPartTypeImageURL,
 				ImageURL: &img,
 			})
 		}
+		for _, doc := range options.documents {
+			msg.content = append(msg.content, ContentPart{
+				Type:     constants.ContentPartTypeDocument,
+				Document: &doc,
+			})
+		}
+		for _, video := range options.videos {
+			msg.content = append(msg.content, ContentPart{
+				Type:  constants.ContentPartTypeVideo,
+				Video: &video,
+			})
+		}
 		if content != "" {
 			msg.content = append(msg.content, ContentPart{
 				Type: constants.ContentPartTypeText,
@@ -101,10 +208,39 @@ func NewToolMessage(tool ToolCall, result string) Message {
 	}
 }
 
+// NewToolResultMessage creates a tool result message for the call identified by toolCallID.
+// Strings and byte slices are sent verbatim, errors as their message, and any other value
+// is JSON-encoded. isError marks the invocation as failed; Anthropic receives it as the
+// tool_result is_error flag, while OpenAI (which has no such flag) receives the content only.
+func NewToolResultMessage(toolCallID string, result any, isError bool) Message {
+	var content string
+	if err, ok := result.(error); ok {
+		content = err.Error()
+	} else if text, err := encodeResult(result); err != nil {
+		content, isError = err.Error(), true
+	} else {
+		content = text
+	}
+	return &llmmsg{
+		role:       constants.RoleTool,
+		toolCallID: toolCallID,
+		isError:    isError,
+		content: []ContentPart{
+			{Type: constants.ContentPartTypeText, Text: content},
+		},
+	}
+}
+
 // NewSystemMessage creates a system-role message suitable for any model.
-func NewSystemMessage(content string) Message {
+// Only WithParticipantName applies to system messages.
+func NewSystemMessage(content string, opts ...MessageOption) Message {
+	var options MessageOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	return &llmmsg{
 		role: constants.RoleSystem,
+		name: options.name,
 		content: []ContentPart{
 			{Type: constants.ContentPartTypeText, Text: content},
 		},
@@ -122,9 +258,14 @@ func NewAssistantMessage(content string, toolCalls ...ToolCall) Message {
 		}
 	}
 	if len(toolCalls) > 0 {
-		for _, tc := range toolCalls {
+		for i, tc := range toolCalls {
+			index := tc.Index()
+			if index == 0 {
+				// Calls built with NewToolCall take their position
+				index = i
+			}
 			msg.toolCalls = append(msg.toolCalls, &toolcall{
-				index: tc.Index(),
+				index: index,
 				id:    tc.ID(),
 				type_: tc.Type(),
 				fcall: funcall{
@@ -137,19 +278,93 @@ func NewAssistantMessage(content string, toolCalls ...ToolCall) Message {
 	return msg
 }
 
+// NewAssistantMessageWithToolCalls creates an assistant-role message that requests
+// the given tool calls, e.g. to replay a conversation built outside this package.
+// Use NewToolCall to create the calls.
+func NewAssistantMessageWithToolCalls(content string, toolCalls []ToolCall) Message {
+	return NewAssistantMessage(content, toolCalls...)
+}
+
+// NewToolCall creates a function tool call with the given ID, tool name and JSON arguments.
+// The index of each call is its position in the assistant message.
+func NewToolCall(id, name, arguments string) ToolCall {
+	return &toolcall{
+		id:    id,
+		type_: constants.ToolTypeFunction,
+		fcall: funcall{name: name, args: arguments},
+	}
+}
+
 // ContentPart represents a part of a multi-modal message.
 type ContentPart struct {
-	Type     string    `json:"type"`
-	Text     string    `json:"text,omitempty"`
-	ImageURL *ImageURL `json:"image_url,omitempty"`
+	Type            string           `json:"type"`
+	Text            string           `json:"text,omitempty"`
+	ImageURL        *ImageURL        `json:"image_url,omitempty"`
+	Document        *Document        `json:"document,omitempty"`
+	Video           *Video           `json:"video,omitempty"`
+	ServerToolUse   *ServerToolUse   `json:"server_tool_use,omitempty"`
+	WebSearchResult *WebSearchResult `json:"web_search_result,omitempty"`
+
+	// Citations lists the sources supporting a text part, when the provider returns them.
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation links a span of generated text to the source it quotes.
+type Citation struct {
+	// Type is the kind of location (see constants.CitationType*).
+	Type string `json:"type"`
+	// CitedText is the quoted span of the source.
+	CitedText string `json:"cited_text"`
+	// Title is the title of the cited document or web page.
+	Title string `json:"title,omitempty"`
+	// URL is the address of a cited web page or the source of a search result.
+	URL string `json:"url,omitempty"`
+	// DocumentIndex is the index of the cited document or search result in the request.
+	DocumentIndex int `json:"document_index,omitempty"`
+	// Start and End delimit the location within the document: character indexes,
+	// page numbers or content block indexes depending on Type.
+	Start int `json:"start,omitempty"`
+	End   int `json:"end,omitempty"`
+	// EncryptedIndex must be passed back unchanged for web search citations.
+	EncryptedIndex string `json:"encrypted_index,omitempty"`
+}
+
+// ServerToolUse records a tool invocation executed by the provider itself
+// (e.g., Anthropic web search). It is informational and needs no tool result.
+type ServerToolUse struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// WebSearchResult holds the results of a provider-executed web search.
+type WebSearchResult struct {
+	// ToolUseID references the ServerToolUse that produced the results.
+	ToolUseID string `json:"tool_use_id"`
+	// Results lists the pages found; empty when ErrorCode is set.
+	Results []WebSearchResultItem `json:"results,omitempty"`
+	// ErrorCode is set when the search failed (e.g., "max_uses_exceeded").
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// WebSearchResultItem is a single web search hit.
+type WebSearchResultItem struct {
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	PageAge string `json:"page_age,omitempty"`
+	// EncryptedContent must be passed back unchanged in multi-turn conversations.
+	EncryptedContent string `json:"encrypted_content,omitempty"`
 }
 
 // llmmsg implements Message interface using a unified structure.
+// It is the canonical representation every provider converts from and to, so a
+// message produced by one backend can be sent to any other.
 type llmmsg struct {
 	role       string
 	content    []ContentPart
 	toolCalls  []*toolcall
 	toolCallID string
+	isError    bool
 	reasoning  string
 	refusal    string
 	name       string
@@ -176,6 +391,33 @@ func (m *llmmsg) Reasoning() string {
 	return m.reasoning
 }
 
+// Parts implements RichMessage. The returned slice is a copy.
+func (m *llmmsg) Parts() []ContentPart {
+	return append([]ContentPart(nil), m.content...)
+}
+
+// ToolCalls implements RichMessage.
+func (m *llmmsg) ToolCalls() []ToolCall {
+	if len(m.toolCalls) == 0 {
+		return nil
+	}
+	tcalls := make([]ToolCall, 0, len(m.toolCalls))
+	for _, tc := range m.toolCalls {
+		tcalls = append(tcalls, tc)
+	}
+	return tcalls
+}
+
+// ToolCallID implements RichMessage.
+func (m *llmmsg) ToolCallID() string {
+	return m.toolCallID
+}
+
+// Refusal implements RichMessage.
+func (m *llmmsg) Refusal() string {
+	return m.refusal
+}
+
 // MarshalJSON implements json.Marshaler.
 func (m *llmmsg) MarshalJSON() ([]byte, error) {
 	// We'll use a structure compatible with our previous WireMessage but cleaner.
@@ -187,6 +429,7 @@ func (m *llmmsg) MarshalJSON() ([]byte, error) {
 		Reasoning  string        `json:"reasoning,omitempty"`
 		Refusal    string        `json:"refusal,omitempty"`
 		Name       string        `json:"name,omitempty"`
+		IsError    bool          `json:"is_error,omitempty"`
 	}
 	return json.Marshal(&alias{
 		Role:       m.role,
@@ -196,6 +439,7 @@ func (m *llmmsg) MarshalJSON() ([]byte, error) {
 		Reasoning:  m.reasoning,
 		Refusal:    m.refusal,
 		Name:       m.name,
+		IsError:    m.isError,
 	})
 }
 
@@ -209,6 +453,7 @@ func (m *llmmsg) UnmarshalJSON(data []byte) error {
 		Reasoning  string        `json:"reasoning,omitempty"`
 		Refusal    string        `json:"refusal,omitempty"`
 		Name       string        `json:"name,omitempty"`
+		IsError    bool          `json:"is_error,omitempty"`
 	}
 	var tmp alias
 	if err := json.Unmarshal(data, &tmp); err != nil {
@@ -221,9 +466,51 @@ func (m *llmmsg) UnmarshalJSON(data []byte) error {
 	m.reasoning = tmp.Reasoning
 	m.refusal = tmp.Refusal
 	m.name = tmp.Name
+	m.isError = tmp.IsError
 	return nil
 }
 
+// asLLMMessage returns the canonical representation of message. Other Message
+// implementations are copied through the RichMessage accessors when available,
+// and otherwise reduced to their role, text and reasoning.
+func asLLMMessage(message Message) *llmmsg {
+	if msg, ok := message.(*llmmsg); ok {
+		return msg
+	}
+	msg := &llmmsg{
+		role:      message.Role(),
+		reasoning: message.Reasoning(),
+	}
+	rich, ok := message.(RichMessage)
+	if !ok {
+		msg.content = []ContentPart{{Type: constants.ContentPartTypeText, Text: message.Content()}}
+		return msg
+	}
+	msg.content = rich.Parts()
+	msg.toolCallID = rich.ToolCallID()
+	msg.refusal = rich.Refusal()
+	for i, tc := range rich.ToolCalls() {
+		index := tc.Index()
+		if index == 0 {
+			index = i
+		}
+		msg.toolCalls = append(msg.toolCalls, &toolcall{
+			index: index,
+			id:    tc.ID(),
+			type_: tc.Type(),
+			fcall: funcall{name: tc.Function().Name(), args: tc.Function().Arguments()},
+		})
+	}
+	return msg
+}
+
+// IsToolError reports whether msg is a tool result flagged as a failed invocation
+// (see NewToolResultMessage).
+func IsToolError(msg Message) bool {
+	m, ok := msg.(*llmmsg)
+	return ok && m.role == constants.RoleTool && m.isError
+}
+
 // EncodeMessage serializes a Message into a JSON-encoded byte slice.
 func EncodeMessage(msg Message) ([]byte, error) {
 	if m, ok := msg.(json.Marshaler); ok {
@@ -240,3 +527,49 @@ func DecodeMessage(data []byte) (Message, error) {
 	}
 	return &m, nil
 }
+
+// conversationVersion is the format version written by EncodeConversation.
+const conversationVersion = 1
+
+// conversationJSON is the serialized form of a conversation.
+type conversationJSON struct {
+	Version  int       `json:"version"`
+	Messages []*llmmsg `json:"messages"`
+}
+
+// EncodeConversation serializes messages, including their content parts, tool
+// calls, tool results and reasoning, into a versioned JSON document. Messages
+// are provider-neutral, so the result can be decoded for any model.
+func EncodeConversation(messages []Message) ([]byte, error) {
+	conv := conversationJSON{Version: conversationVersion, Messages: make([]*llmmsg, len(messages))}
+	for i, msg := range messages {
+		conv.Messages[i] = asLLMMessage(msg)
+	}
+	return json.Marshal(&conv)
+}
+
+// DecodeConversation deserializes messages encoded by EncodeConversation. A plain
+// JSON array of messages is accepted as well.
+func DecodeConversation(data []byte) ([]Message, error) {
+	var conv conversationJSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &conv.Messages); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(data, &conv); err != nil {
+			return nil, err
+		}
+		if conv.Version > conversationVersion {
+			return nil, fmt.Errorf("unsupported conversation version %d", conv.Version)
+		}
+	}
+	messages := make([]Message, 0, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		if msg == nil {
+			return nil, fmt.Errorf("conversation message %d is null", i)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
@@ -0,0 +1,233 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// defaultLatencyBuckets are the upper bounds, in seconds, of the latency histogram.
+var defaultLatencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80}
+
+// Metrics collects request metrics from the models wrapped by its Middleware.
+// It is a prometheus.Collector; register it on the application's registry with
+// Register (or prometheus.MustRegister), or serve it on its own:
+//
+//	openllm_requests_total{model,provider}             requests made
+//	openllm_request_errors_total{model,provider,type}  failed requests by ErrorType
+//	openllm_request_duration_seconds{model,provider}   latency histogram
+//	openllm_tokens_total{model,provider,direction}     input and output tokens
+//	openllm_cost_usd_total{model,provider}             cost of priced models
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	tokens   *prometheus.CounterVec
+	cost     *prometheus.CounterVec
+
+	// registry holds only these metrics, for ServeHTTP and WritePrometheus.
+	registry *prometheus.Registry
+
+	mu sync.Mutex
+	// providers remembers the provider of each model for requests that fail without a response.
+	providers map[string]string
+}
+
+// NewMetrics creates an empty Metrics. Custom latency buckets, in seconds, replace
+// the defaults (0.1s to 80s).
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openllm_requests_total",
+			Help: "Chat completion requests made.",
+		}, []string{"model", "provider"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openllm_request_errors_total",
+			Help: "Chat completion requests that failed, by error type.",
+		}, []string{"model", "provider", "type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "openllm_request_duration_seconds",
+			Help:    "Chat completion request latency.",
+			Buckets: buckets,
+		}, []string{"model", "provider"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openllm_tokens_total",
+			Help: "Tokens used, by direction.",
+		}, []string{"model", "provider", "direction"}),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openllm_cost_usd_total",
+			Help: "Cost in USD of models with a known price.",
+		}, []string{"model", "provider"}),
+
+		registry:  prometheus.NewRegistry(),
+		providers: make(map[string]string),
+	}
+	m.registry.MustRegister(m)
+	return m
+}
+
+// Register registers the metrics on reg, e.g. prometheus.DefaultRegisterer.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	return reg.Register(m)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.errors.Describe(ch)
+	m.duration.Describe(ch)
+	m.tokens.Describe(ch)
+	m.cost.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.errors.Collect(ch)
+	m.duration.Collect(ch)
+	m.tokens.Collect(ch)
+	m.cost.Collect(ch)
+}
+
+// Middleware returns a Middleware that records the requests of the wrapped models.
+func (m *Metrics) Middleware() Middleware {
+	return func(next Model) Model {
+		return &metricsModel{Model: next, metrics: m}
+	}
+}
+
+// metricsModel is the Model returned by Metrics.Middleware.
+type metricsModel struct {
+	Model
+	metrics *Metrics
+}
+
+// Unwrap returns the wrapped model.
+func (m *metricsModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *metricsModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	start := time.Now()
+	resp, err := m.Model.ChatCompletion(ctx, messages, opts...)
+	m.metrics.observe(m.Name(), resp, err, time.Since(start))
+	return resp, err
+}
+
+// ChatCompletionStream implements Model.
+func (m *metricsModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	start := time.Now()
+	resp, err := m.Model.ChatCompletionStream(ctx, messages, opts...)
+	m.metrics.observe(m.Name(), resp, err, time.Since(start))
+	return resp, err
+}
+
+// observe records one request.
+func (m *Metrics) observe(model string, resp Response, err error, elapsed time.Duration) {
+	m.mu.Lock()
+	provider := m.providers[model]
+	if resp != nil && resp.Meta().Provider != "" {
+		provider = resp.Meta().Provider
+		m.providers[model] = provider
+	}
+	m.mu.Unlock()
+
+	m.requests.WithLabelValues(model, provider).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(model, provider, ErrorType(err)).Inc()
+	}
+	if resp != nil {
+		usage := resp.Usage()
+		m.tokens.WithLabelValues(model, provider, "input").Add(float64(max(usage.InputTokens, 0)))
+		m.tokens.WithLabelValues(model, provider, "output").Add(float64(max(usage.OutputTokens, 0)))
+		if usage.CostUSD != nil {
+			m.cost.WithLabelValues(model, provider).Add(max(usage.CostUSD.Total, 0))
+		}
+	}
+	m.duration.WithLabelValues(model, provider).Observe(elapsed.Seconds())
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler and serves the exposition of these metrics
+// alone, for applications without a Prometheus registry of their own.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// errorKindTypes names the provider error kinds for ErrorType.
+var errorKindTypes = []struct {
+	kind error
+	name string
+}{
+	{ErrRateLimited, "rate_limit"},
+	{ErrQuotaExceeded, "quota"},
+	{ErrContextLengthExceeded, "context_length"},
+	{ErrAuthentication, "authentication"},
+	{ErrPermissionDenied, "permission"},
+	{ErrContentFiltered, "content_filter"},
+	{ErrOverloaded, "overloaded"},
+	{ErrInvalidRequest, "invalid_request"},
+}
+
+// ErrorType classifies err for metrics and logs. Provider failures are named
+// after their error kind: "rate_limit" (ErrRateLimited), "quota", "context_length",
+// "authentication", "permission", "content_filter", "overloaded" and
+// "invalid_request"; other failures are "canceled", "timeout" (deadlines, network
+// and idle timeouts, HTTP 408), "circuit_open", "shed", "blocked", "server"
+// (other 5xx), "client" (other 4xx) or "other".
+func ErrorType(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrRequestShed):
+		return "shed"
+	case errors.Is(err, ErrContentBlocked):
+		return "blocked"
+	}
+
+	normalized := normalizeProviderError("", err)
+	for _, k := range errorKindTypes {
+		if errors.Is(normalized, k.kind) {
+			return k.name
+		}
+	}
+
+	var netErr net.Error
+	switch code := httpStatusCode(err); {
+	case code == http.StatusRequestTimeout || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrStreamIdleTimeout) || errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case code >= 500:
+		return "server"
+	case code >= 400:
+		return "client"
+	}
+	return "other"
+}
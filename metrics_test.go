@@ -0,0 +1,73 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	metrics := NewMetrics()
+	reg := prometheus.NewRegistry()
+	if err := metrics.Register(reg); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	echo := Wrap(NewEchoModel(), metrics.Middleware())
+	if _, err := echo.ChatCompletion(context.Background(), []Message{NewUserMessage("hello")}); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	quota := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Code: "insufficient_quota", Message: "You exceeded your current quota."}
+	failing := Wrap(failingModel{err: quota}, metrics.Middleware())
+	if _, err := failing.ChatCompletion(context.Background(), []Message{NewUserMessage("hello")}); err == nil {
+		t.Fatal("ChatCompletion succeeded")
+	}
+
+	if n := testutil.ToFloat64(metrics.requests.WithLabelValues("echo", "fake")); n != 1 {
+		t.Errorf("echo requests = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(metrics.errors.WithLabelValues("failing", "", "quota")); n != 1 {
+		t.Errorf("failing quota errors = %v, want 1", n)
+	}
+	if n, err := testutil.GatherAndCount(reg, "openllm_request_duration_seconds"); err != nil || n != 2 {
+		t.Errorf("duration series = %d, %v, want 2", n, err)
+	}
+
+	var b strings.Builder
+	if err := metrics.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	if want := `openllm_request_errors_total{model="failing",provider="",type="quota"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf("exposition does not contain %s:\n%s", want, b.String())
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.Canceled, "canceled"},
+		{fmt.Errorf("request: %w", context.DeadlineExceeded), "timeout"},
+		{ErrStreamIdleTimeout, "timeout"},
+		{&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Code: "rate_limit_exceeded"}, "rate_limit"},
+		{&openai.APIError{HTTPStatusCode: http.StatusForbidden, Message: "forbidden"}, "permission"},
+		{&openai.APIError{HTTPStatusCode: http.StatusUnauthorized, Code: "invalid_api_key"}, "authentication"},
+		{&HTTPError{StatusCode: 529, Body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`}, "overloaded"},
+		{&openai.APIError{HTTPStatusCode: http.StatusBadGateway, Message: "bad gateway"}, "server"},
+		{&openai.APIError{HTTPStatusCode: http.StatusConflict, Message: "conflict"}, "client"},
+		{io.ErrUnexpectedEOF, "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorType(tt.err); got != tt.want {
+			t.Errorf("ErrorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
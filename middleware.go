@@ -0,0 +1,37 @@
+package openllm
+
+// Middleware wraps a Model to add behavior around every request, such as retries,
+// caching or logging. The returned Model must forward any method it does not change,
+// and should implement Unwrap() Model, as the built-in middleware do, so that
+// optional interfaces of the wrapped model such as TokenCounter stay reachable.
+type Middleware func(next Model) Model
+
+// Wrap applies middleware to model so that the first middleware is the outermost,
+// i.e. Wrap(m, a, b) calls a, then b, then m.
+func Wrap(model Model, middleware ...Middleware) Model {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		model = middleware[i](model)
+	}
+	return model
+}
+
+// UnwrapModel returns the model wrapped by a middleware, or nil when model does
+// not implement Unwrap() Model.
+func UnwrapModel(model Model) Model {
+	if u, ok := model.(interface{ Unwrap() Model }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// asModel finds the first model in the chain of wrapped models that implements T.
+func asModel[T any](model Model) (T, bool) {
+	for model != nil {
+		if t, ok := model.(T); ok {
+			return t, true
+		}
+		model = UnwrapModel(model)
+	}
+	var zero T
+	return zero, false
+}
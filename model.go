@@ -5,6 +5,8 @@ import (
 )
 
 // StreamWatcher handles events emitted during LLM generation.
+// Any callback may return ErrStopStreaming to end the stream early and
+// receive the partial Response; other errors abort the request.
 type StreamWatcher interface {
 	// OnRefusal is invoked when the model explicitly refuses to answer (e.g., safety filters).
 	// The delta parameter contains the partial refusal message.
@@ -26,6 +28,49 @@ type StreamWatcher interface {
 	OnStop() error
 }
 
+// UsageWatcher is an optional extension of StreamWatcher.
+// When the watcher passed via WithStreamWatcher also implements UsageWatcher,
+// usage and metadata updates are pushed as soon as the provider reports them.
+type UsageWatcher interface {
+	// OnUsage is invoked whenever the provider reports token usage during the stream.
+	// The usage parameter contains the cumulative counts known so far.
+	OnUsage(usage Usage) error
+
+	// OnMeta is invoked whenever request metadata becomes available or changes
+	// (e.g., request ID at stream start, stop reason at stream end).
+	OnMeta(meta Meta) error
+}
+
+// ToolCallDoneWatcher is an optional extension of StreamWatcher that is notified
+// once the arguments of a tool call have been fully streamed.
+type ToolCallDoneWatcher interface {
+	// OnToolCallDone is invoked exactly once per tool call after its last argument delta.
+	// For Anthropic this corresponds to content_block_stop; for OpenAI it fires when the
+	// next tool call starts or the choice finishes.
+	OnToolCallDone(ctx context.Context, tcall ToolCall) error
+}
+
+// FieldWatcher is an optional extension of StreamWatcher that receives structured
+// output field by field. When a response format of JSON is requested (see
+// WithResponseFormat and WithResponseSchema), the streamed answer is parsed as it
+// arrives and every value is reported once complete, innermost first, so UIs can
+// render structured results progressively.
+type FieldWatcher interface {
+	// OnField is invoked with the path of a completed value, e.g. "$.days[0].city",
+	// and the value decoded as by json.Unmarshal into any. The root value has path "$".
+	OnField(path string, value any) error
+}
+
+// RawEventWatcher is an optional extension of StreamWatcher that observes
+// provider-native stream events before they are translated into unified callbacks.
+// The event type depends on the backend:
+//   - OpenAI: openai.ChatCompletionStreamResponse (one per chunk)
+//   - Anthropic: anthropic.MessageStreamEventUnion (use AsAny to switch on the concrete event)
+type RawEventWatcher interface {
+	// OnRawEvent is invoked once for every event received from the provider.
+	OnRawEvent(providerEvent any) error
+}
+
 // Model defines the abstract interface for an LLM engine.
 type Model interface {
 	// Name returns the unique, human-readable name of the LLM core.
@@ -43,5 +88,7 @@ type Model interface {
 	// ChatCompletionStream performs a streaming chat completion request.
 	// It takes a context, conversation history, and ChatOption (which must include a StreamWatcher).
 	// Partial outputs are pushed to the watcher; the returned Response contains final metadata.
+	// If the stream fails once it is open, even before any output, the partial Response is
+	// returned together with a *PartialResponseError wrapping the cause.
 	ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error)
 }
@@ -0,0 +1,355 @@
+// Package modeltest is a conformance suite for openllm.Model implementations.
+// TestModel exercises blocking and streaming requests, the stream watcher
+// contract, tool calls, images, reasoning and error paths against a live or
+// recorded backend, so third-party provider adapters can prove they behave like
+// the built-in ones. Prompts are simple enough for any capable model; use the
+// vcr subpackage to run the suite without network access.
+package modeltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thecxx/openllm"
+	"github.com/thecxx/openllm/constants"
+)
+
+// Factory creates the Model under test. It is called once per subtest.
+type Factory func(t *testing.T) openllm.Model
+
+// Option configures TestModel.
+type Option func(c *config)
+
+// config holds the settings of a TestModel run.
+type config struct {
+	tools     bool
+	images    bool
+	reasoning bool
+	timeout   time.Duration
+	opts      []openllm.ChatOption
+}
+
+// WithoutTools skips the tool call tests, for models without function calling.
+func WithoutTools() Option {
+	return func(c *config) { c.tools = false }
+}
+
+// WithoutImages skips the image input test, for models without vision.
+func WithoutImages() Option {
+	return func(c *config) { c.images = false }
+}
+
+// WithoutReasoning skips the reasoning test, for models that do not stream reasoning.
+func WithoutReasoning() Option {
+	return func(c *config) { c.reasoning = false }
+}
+
+// WithTimeout bounds each request (default 60s).
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) { c.timeout = timeout }
+}
+
+// WithChatOptions applies opts to every request, e.g. a low temperature.
+func WithChatOptions(opts ...openllm.ChatOption) Option {
+	return func(c *config) { c.opts = append(c.opts, opts...) }
+}
+
+// TestModel runs the conformance suite against the models built by factory,
+// each check in its own subtest.
+func TestModel(t *testing.T, factory Factory, opts ...Option) {
+	c := &config{tools: true, images: true, reasoning: true, timeout: time.Minute}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s := &suite{factory: factory, config: c}
+
+	t.Run("Name", s.testName)
+	t.Run("Blocking", s.testBlocking)
+	t.Run("Streaming", s.testStreaming)
+	t.Run("StopStreaming", s.testStopStreaming)
+	t.Run("WatcherError", s.testWatcherError)
+	t.Run("Canceled", s.testCanceled)
+	if c.tools {
+		t.Run("ToolCall", func(t *testing.T) { s.testToolCall(t, false) })
+		t.Run("StreamingToolCall", func(t *testing.T) { s.testToolCall(t, true) })
+		t.Run("ToolResult", s.testToolResult)
+	}
+	if c.images {
+		t.Run("Image", s.testImage)
+	}
+	if c.reasoning {
+		t.Run("Reasoning", s.testReasoning)
+	}
+}
+
+// suite runs the checks of one TestModel call.
+type suite struct {
+	factory Factory
+	*config
+}
+
+// request sends messages with the suite options followed by opts.
+func (s *suite) request(t *testing.T, model openllm.Model, stream bool, messages []openllm.Message, opts ...openllm.ChatOption) (openllm.Response, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	all := append(append([]openllm.ChatOption(nil), s.opts...), opts...)
+	if stream {
+		return model.ChatCompletionStream(ctx, messages, all...)
+	}
+	return model.ChatCompletion(ctx, messages, all...)
+}
+
+// pongMessages asks for a known one-word answer.
+func pongMessages() []openllm.Message {
+	return []openllm.Message{openllm.NewUserMessage("Reply with the single word pong and nothing else.")}
+}
+
+// checkAnswer fails t unless resp carries an assistant answer.
+func checkAnswer(t *testing.T, resp openllm.Response) {
+	t.Helper()
+	if resp == nil {
+		t.Fatal("response is nil")
+	}
+	answer := resp.Answer()
+	if answer == nil {
+		t.Fatal("response has no answer")
+	}
+	if answer.Role() != constants.RoleAssistant {
+		t.Errorf("answer role = %q, want %q", answer.Role(), constants.RoleAssistant)
+	}
+	if usage := resp.Usage(); usage.InputTokens < 0 || usage.OutputTokens < 0 || usage.TotalTokens < 0 {
+		t.Errorf("usage has negative counts: %+v", usage)
+	}
+	if resp.Meta().Provider == "" {
+		t.Error("Meta().Provider is empty")
+	}
+}
+
+// testName checks the model identity.
+func (s *suite) testName(t *testing.T) {
+	if s.factory(t).Name() == "" {
+		t.Error("Name() is empty")
+	}
+}
+
+// testBlocking checks a blocking request.
+func (s *suite) testBlocking(t *testing.T) {
+	resp, err := s.request(t, s.factory(t), false, pongMessages())
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	checkAnswer(t, resp)
+	if !strings.Contains(strings.ToLower(resp.Answer().Content()), "pong") {
+		t.Errorf("answer = %q, want it to contain pong", resp.Answer().Content())
+	}
+}
+
+// testStreaming checks that the watcher sees exactly the answer, then one OnStop.
+func (s *suite) testStreaming(t *testing.T) {
+	w := newRecorder()
+	resp, err := s.request(t, s.factory(t), true, pongMessages(), openllm.WithStreamWatcher(w))
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	checkAnswer(t, resp)
+	if got, want := w.content.String(), resp.Answer().Content(); got != want {
+		t.Errorf("streamed content = %q, answer = %q", got, want)
+	}
+	if !strings.Contains(strings.ToLower(resp.Answer().Content()), "pong") {
+		t.Errorf("answer = %q, want it to contain pong", resp.Answer().Content())
+	}
+	if w.stops != 1 {
+		t.Errorf("OnStop called %d times, want 1", w.stops)
+	}
+	if len(w.late) > 0 {
+		t.Errorf("callbacks after OnStop: %v", w.late)
+	}
+}
+
+// testStopStreaming checks that ErrStopStreaming ends the stream without an error.
+func (s *suite) testStopStreaming(t *testing.T) {
+	w := newRecorder()
+	w.stopAfterContent = true
+	messages := []openllm.Message{openllm.NewUserMessage("Count from 1 to 50, separated by spaces.")}
+	resp, err := s.request(t, s.factory(t), true, messages, openllm.WithStreamWatcher(w))
+	if err != nil {
+		t.Fatalf("ChatCompletionStream stopped by the watcher: %v, want nil error", err)
+	}
+	if resp == nil || resp.Answer() == nil {
+		t.Fatal("stopped stream returned no partial response")
+	}
+	if got, want := resp.Answer().Content(), w.content.String(); got != want {
+		t.Errorf("partial answer = %q, streamed content = %q", got, want)
+	}
+}
+
+// errWatcher is returned by the watcher of testWatcherError.
+var errWatcher = errors.New("modeltest: watcher failure")
+
+// testWatcherError checks that a watcher error aborts the request and is returned.
+func (s *suite) testWatcherError(t *testing.T) {
+	w := newRecorder()
+	w.failWith = errWatcher
+	_, err := s.request(t, s.factory(t), true, pongMessages(), openllm.WithStreamWatcher(w))
+	if !errors.Is(err, errWatcher) {
+		t.Errorf("ChatCompletionStream error = %v, want one matching the watcher's error", err)
+	}
+}
+
+// testCanceled checks that a canceled context fails both kinds of request.
+func (s *suite) testCanceled(t *testing.T) {
+	model := s.factory(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := model.ChatCompletion(ctx, pongMessages(), s.opts...); !errors.Is(err, context.Canceled) {
+		t.Errorf("ChatCompletion with canceled context: error = %v, want context.Canceled", err)
+	}
+	if _, err := model.ChatCompletionStream(ctx, pongMessages(), s.opts...); !errors.Is(err, context.Canceled) {
+		t.Errorf("ChatCompletionStream with canceled context: error = %v, want context.Canceled", err)
+	}
+}
+
+// weatherArgs are the arguments of the test tool.
+type weatherArgs struct {
+	City string `openllm:"city,required,desc=City name"`
+}
+
+// weatherTool is the tool offered by the tool tests.
+var weatherTool = openllm.DefineTypedFunction("get_weather", "Returns the current weather for a city.",
+	func(ctx context.Context, args weatherArgs) (string, error) {
+		return "It is 21 degrees and sunny in " + args.City + ".", nil
+	})
+
+// weatherMessages asks a question that requires the test tool.
+func weatherMessages() []openllm.Message {
+	return []openllm.Message{openllm.NewUserMessage("What is the weather in Paris right now? Use the get_weather tool.")}
+}
+
+// checkWeatherCall fails t unless resp calls the test tool for Paris, and returns the call.
+func checkWeatherCall(t *testing.T, resp openllm.Response) openllm.ToolCall {
+	t.Helper()
+	calls := resp.ToolCalls()
+	if len(calls) == 0 {
+		t.Fatalf("no tool call; answer = %q", resp.Answer().Content())
+	}
+	call := calls[0]
+	if call.ID() == "" {
+		t.Error("tool call has no ID")
+	}
+	if name := call.Function().Name(); name != "get_weather" {
+		t.Fatalf("tool call name = %q, want get_weather", name)
+	}
+	var args weatherArgs
+	if err := json.Unmarshal([]byte(call.Function().Arguments()), &args); err != nil {
+		t.Fatalf("tool call arguments %q: %v", call.Function().Arguments(), err)
+	}
+	if !strings.Contains(strings.ToLower(args.City), "paris") {
+		t.Errorf("tool call city = %q, want Paris", args.City)
+	}
+	if rich, ok := resp.Answer().(openllm.RichMessage); !ok || len(rich.ToolCalls()) != len(calls) {
+		t.Errorf("answer does not carry the %d tool calls of the response", len(calls))
+	}
+	return call
+}
+
+// testToolCall checks that the model calls an offered tool and, when streaming,
+// that the watcher sees the full arguments and one completion per call. Run with
+// -race, it also checks that streamed calls can be read from other goroutines.
+func (s *suite) testToolCall(t *testing.T, stream bool) {
+	w := newRecorder()
+	resp, err := s.request(t, s.factory(t), stream, weatherMessages(), openllm.WithTool(weatherTool), openllm.WithStreamWatcher(w))
+	w.finish()
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	checkAnswer(t, resp)
+	call := checkWeatherCall(t, resp)
+	if !stream {
+		return
+	}
+	args, ok := w.args[call.ID()]
+	if !ok {
+		t.Fatalf("watcher saw no OnToolCall for %s", call.ID())
+	}
+	if got, want := args.String(), call.Function().Arguments(); got != want {
+		t.Errorf("streamed arguments = %q, final = %q", got, want)
+	}
+	if n := w.done[call.ID()]; n != 1 {
+		t.Errorf("OnToolCallDone called %d times for %s, want 1", n, call.ID())
+	}
+}
+
+// testToolResult checks that the model answers from a tool result.
+func (s *suite) testToolResult(t *testing.T) {
+	model := s.factory(t)
+	resp, err := s.request(t, model, false, weatherMessages(), openllm.WithTool(weatherTool))
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	call := checkWeatherCall(t, resp)
+	messages := append(weatherMessages(), resp.Answer(),
+		openllm.NewToolMessage(call, "It is 21 degrees and sunny in Paris."))
+	resp, err = s.request(t, model, false, messages, openllm.WithTool(weatherTool))
+	if err != nil {
+		t.Fatalf("request with tool result: %v", err)
+	}
+	checkAnswer(t, resp)
+	if !strings.Contains(resp.Answer().Content(), "21") {
+		t.Errorf("answer = %q, want it to use the tool result", resp.Answer().Content())
+	}
+}
+
+// testImage checks that the model reads an image.
+func (s *suite) testImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	messages := []openllm.Message{openllm.NewUserMessage(
+		"What color is this image? Answer with one lowercase color name.", openllm.WithImageBytes(buf.Bytes()))}
+	resp, err := s.request(t, s.factory(t), false, messages)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	checkAnswer(t, resp)
+	if !strings.Contains(strings.ToLower(resp.Answer().Content()), "red") {
+		t.Errorf("answer = %q, want red", resp.Answer().Content())
+	}
+}
+
+// testReasoning checks that streamed reasoning matches the answer's reasoning
+// and that reasoning is reported at all.
+func (s *suite) testReasoning(t *testing.T) {
+	w := newRecorder()
+	messages := []openllm.Message{openllm.NewUserMessage("What is 17 * 23? Think it through, then give the number.")}
+	resp, err := s.request(t, s.factory(t), true, messages,
+		openllm.WithReasoningEffort(constants.ReasoningEffortLow), openllm.WithStreamWatcher(w))
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	checkAnswer(t, resp)
+	if got, want := w.reasoning.String(), resp.Answer().Reasoning(); got != want {
+		t.Errorf("streamed reasoning = %q, answer reasoning = %q", got, want)
+	}
+	if resp.Answer().Reasoning() == "" && resp.Usage().ReasoningTokens == 0 {
+		t.Error("no reasoning reported; use WithoutReasoning for models that do not reason")
+	}
+	if !strings.Contains(resp.Answer().Content(), "391") {
+		t.Errorf("answer = %q, want 391", resp.Answer().Content())
+	}
+}
@@ -0,0 +1,32 @@
+package modeltest
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/thecxx/openllm"
+)
+
+func TestEchoModel(t *testing.T) {
+	TestModel(t, func(t *testing.T) openllm.Model {
+		return openllm.NewEchoModel(
+			openllm.WithEchoAnswers(map[string]string{
+				"Reply with the single word pong and nothing else.":               "pong",
+				"What color is this image? Answer with one lowercase color name.": "red",
+			}),
+			openllm.WithEchoToolRule(regexp.MustCompile(`weather in (\w+)`), "get_weather", `{"city":{{json (index .Groups 1)}}}`),
+			openllm.WithEchoPacing(2, time.Millisecond),
+		)
+	}, WithoutReasoning())
+}
+
+func TestFakeStreamModel(t *testing.T) {
+	TestModel(t, func(t *testing.T) openllm.Model {
+		return openllm.NewFakeStreamModel("fake", openllm.FakeStream{
+			Content:    "pong, and a few more words so the stream has several deltas",
+			ChunkRunes: 3,
+			Delay:      time.Millisecond,
+		})
+	}, WithoutTools(), WithoutImages(), WithoutReasoning())
+}
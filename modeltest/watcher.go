@@ -0,0 +1,128 @@
+package modeltest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thecxx/openllm"
+)
+
+// recorder is a StreamWatcher that records the callbacks it receives and checks
+// their order.
+type recorder struct {
+	// stopAfterContent makes OnContent return ErrStopStreaming.
+	stopAfterContent bool
+	// failWith makes OnContent return this error.
+	failWith error
+
+	mu        sync.Mutex
+	content   strings.Builder
+	reasoning strings.Builder
+	// args holds the argument deltas by tool call ID.
+	args map[string]*strings.Builder
+	// done counts the OnToolCallDone calls by tool call ID.
+	done  map[string]int
+	stops int
+	// late records callbacks received after OnStop.
+	late []string
+
+	// readers read the tool calls from other goroutines while they stream, so
+	// the race detector catches calls that are unsafe to share; finished stops them.
+	readers  sync.WaitGroup
+	finished chan struct{}
+}
+
+// newRecorder creates an empty recorder.
+func newRecorder() *recorder {
+	return &recorder{args: map[string]*strings.Builder{}, done: map[string]int{}, finished: make(chan struct{})}
+}
+
+// finish stops the tool call readers once the request has returned.
+func (r *recorder) finish() {
+	close(r.finished)
+	r.readers.Wait()
+}
+
+// OnRefusal implements openllm.StreamWatcher.
+func (r *recorder) OnRefusal(delta string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkLate("OnRefusal")
+	return nil
+}
+
+// OnReasoning implements openllm.StreamWatcher.
+func (r *recorder) OnReasoning(delta string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkLate("OnReasoning")
+	r.reasoning.WriteString(delta)
+	return nil
+}
+
+// OnContent implements openllm.StreamWatcher.
+func (r *recorder) OnContent(delta string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkLate("OnContent")
+	r.content.WriteString(delta)
+	if r.failWith != nil {
+		return r.failWith
+	}
+	if r.stopAfterContent {
+		return openllm.ErrStopStreaming
+	}
+	return nil
+}
+
+// OnToolCall implements openllm.StreamWatcher.
+func (r *recorder) OnToolCall(ctx context.Context, tcall openllm.ToolCall, args string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkLate("OnToolCall")
+	id := tcall.ID()
+	if _, ok := r.args[id]; !ok {
+		r.args[id] = &strings.Builder{}
+		r.readers.Add(1)
+		go func() {
+			defer r.readers.Done()
+			for {
+				_ = tcall.Function().Arguments()
+				_ = tcall.ArgumentsValid()
+				select {
+				case <-r.finished:
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}()
+	}
+	r.args[id].WriteString(args)
+	return nil
+}
+
+// OnToolCallDone implements openllm.ToolCallDoneWatcher.
+func (r *recorder) OnToolCallDone(ctx context.Context, tcall openllm.ToolCall) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkLate("OnToolCallDone")
+	r.done[tcall.ID()]++
+	return nil
+}
+
+// OnStop implements openllm.StreamWatcher.
+func (r *recorder) OnStop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stops++
+	return nil
+}
+
+// checkLate records a callback received after OnStop; r.mu must be held.
+func (r *recorder) checkLate(callback string) {
+	if r.stops > 0 {
+		r.late = append(r.late, callback)
+	}
+}
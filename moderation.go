@@ -0,0 +1,295 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+// ModerationResult is the classification of one text.
+type ModerationResult struct {
+	// Flagged reports whether the classifier considers the text in violation of
+	// any category.
+	Flagged bool
+	// Categories holds the classifier's verdict per category (see constants.Moderation*).
+	Categories map[string]bool
+	// Scores holds the confidence per category, between 0 and 1.
+	Scores map[string]float64
+}
+
+// Moderator classifies texts against safety categories.
+type Moderator interface {
+	// Moderate returns one result per input, in order.
+	Moderate(ctx context.Context, inputs []string) ([]ModerationResult, error)
+}
+
+// ModeratorFunc adapts a classifier of single texts, such as a local model, to Moderator.
+type ModeratorFunc func(ctx context.Context, text string) (ModerationResult, error)
+
+// Moderate implements Moderator.
+func (f ModeratorFunc) Moderate(ctx context.Context, inputs []string) ([]ModerationResult, error) {
+	results := make([]ModerationResult, len(inputs))
+	for i, input := range inputs {
+		result, err := f(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// NewPatternModerator returns a local Moderator that flags a category with score 1
+// when its pattern matches the text, e.g. for blocklists that need no API call.
+func NewPatternModerator(patterns map[string]*regexp.Regexp) Moderator {
+	return ModeratorFunc(func(_ context.Context, text string) (ModerationResult, error) {
+		result := ModerationResult{Categories: map[string]bool{}, Scores: map[string]float64{}}
+		for category, pattern := range patterns {
+			matched := pattern.MatchString(text)
+			result.Categories[category] = matched
+			if matched {
+				result.Scores[category] = 1
+				result.Flagged = true
+			} else {
+				result.Scores[category] = 0
+			}
+		}
+		return result, nil
+	})
+}
+
+type openAIModerator struct {
+	model  string
+	client *openai.Client
+}
+
+// NewModerator creates a Moderator for an OpenAI moderation model such as
+// "omni-moderation-latest"; an empty model uses the API default. The illicit
+// categories are not reported, as go-openai does not decode them.
+func NewModerator(model string, client *openai.Client) Moderator {
+	return &openAIModerator{model: model, client: client}
+}
+
+// NewModeratorWithAPIKey creates an OpenAI Moderator with an auth token.
+// Client options can set the HTTP client, proxy and endpoint.
+func NewModeratorWithAPIKey(model, authToken string, opts ...ClientOption) Moderator {
+	return &openAIModerator{model: model, client: newOpenAIClient(authToken, opts)}
+}
+
+// Moderate implements Moderator, with one request per input.
+func (m *openAIModerator) Moderate(ctx context.Context, inputs []string) ([]ModerationResult, error) {
+	results := make([]ModerationResult, len(inputs))
+	for i, input := range inputs {
+		resp, err := m.client.Moderations(ctx, openai.ModerationRequest{Input: input, Model: m.model})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Results) == 0 {
+			continue
+		}
+		r := resp.Results[0]
+		results[i] = ModerationResult{Flagged: r.Flagged}
+		// The category structs are keyed by their JSON names, which are the category names
+		if err := remarshal(r.Categories, &results[i].Categories); err != nil {
+			return nil, err
+		}
+		if err := remarshal(r.CategoryScores, &results[i].Scores); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// remarshal converts v to out through JSON.
+func remarshal(v, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// ModerationStage selects what WithModeration checks.
+type ModerationStage int
+
+const (
+	// ModerateInput checks the new user messages of a request.
+	ModerateInput ModerationStage = 1 << iota
+	// ModerateOutput checks the answer of a response.
+	ModerateOutput
+)
+
+// ModerationAction is what WithModeration does with flagged content.
+type ModerationAction int
+
+const (
+	// ModerationBlock fails the request with a *ModerationError.
+	ModerationBlock ModerationAction = iota
+	// ModerationFlag lets the request through and records the categories in Meta.Moderation.
+	ModerationFlag
+)
+
+// FlaggedCategory is a category flagged by WithModeration.
+type FlaggedCategory struct {
+	// Stage is "input" or "output".
+	Stage    string  `json:"stage"`
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
+}
+
+// ModerationConfig configures WithModeration.
+type ModerationConfig struct {
+	// Stages selects the checks; zero checks both input and output.
+	Stages ModerationStage
+	// Action is what to do with flagged content (default ModerationBlock).
+	Action ModerationAction
+	// Thresholds flags a category when its score reaches the threshold. Categories
+	// not listed are ignored; a nil map follows the moderator's own verdict.
+	Thresholds map[string]float64
+}
+
+// WithModeration returns a Middleware that runs the request's new user messages
+// and the response's answer through moderator, blocking or flagging content
+// whose categories reach their thresholds. Streamed output can only be checked
+// once complete, after the watcher has seen it; blocking then withholds the
+// response from the caller but not from the watcher.
+func WithModeration(moderator Moderator, cfg ModerationConfig) Middleware {
+	if cfg.Stages == 0 {
+		cfg.Stages = ModerateInput | ModerateOutput
+	}
+	return func(next Model) Model {
+		return &moderationModel{Model: next, moderator: moderator, cfg: cfg}
+	}
+}
+
+// moderationModel is the Model returned by WithModeration.
+type moderationModel struct {
+	Model
+	moderator Moderator
+	cfg       ModerationConfig
+}
+
+// Unwrap returns the wrapped model.
+func (m *moderationModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *moderationModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *moderationModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do moderates the input, runs request and moderates the output.
+func (m *moderationModel) do(ctx context.Context, messages []Message, request func() (Response, error)) (Response, error) {
+	var flags []FlaggedCategory
+	if m.cfg.Stages&ModerateInput != 0 {
+		if texts := newUserTexts(messages); len(texts) > 0 {
+			inputFlags, err := m.check(ctx, "input", texts)
+			if err != nil {
+				return nil, err
+			}
+			flags = append(flags, inputFlags...)
+		}
+	}
+
+	resp, err := request()
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if m.cfg.Stages&ModerateOutput != 0 {
+		if answer := resp.Answer(); answer != nil && answer.Content() != "" {
+			outputFlags, err := m.check(ctx, "output", []string{answer.Content()})
+			if err != nil {
+				return nil, err
+			}
+			flags = append(flags, outputFlags...)
+		}
+	}
+	if len(flags) == 0 {
+		return resp, nil
+	}
+	return withMeta(resp, func(meta *Meta) { meta.Moderation = append(meta.Moderation, flags...) }), nil
+}
+
+// check moderates texts and returns the flagged categories, or a *ModerationError
+// when the action is to block.
+func (m *moderationModel) check(ctx context.Context, stage string, texts []string) ([]FlaggedCategory, error) {
+	results, err := m.moderator.Moderate(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	scores := map[string]float64{}
+	for _, result := range results {
+		for category, score := range flaggedCategories(result, m.cfg.Thresholds) {
+			scores[category] = max(scores[category], score)
+		}
+	}
+	if len(scores) == 0 {
+		return nil, nil
+	}
+	categories := make([]string, 0, len(scores))
+	for category := range scores {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	if m.cfg.Action == ModerationBlock {
+		return nil, &ModerationError{Stage: stage, Categories: categories}
+	}
+	flags := make([]FlaggedCategory, len(categories))
+	for i, category := range categories {
+		flags[i] = FlaggedCategory{Stage: stage, Category: category, Score: scores[category]}
+	}
+	return flags, nil
+}
+
+// flaggedCategories returns the categories of result that reach their thresholds,
+// with their scores; nil thresholds follow the moderator's own verdict.
+func flaggedCategories(result ModerationResult, thresholds map[string]float64) map[string]float64 {
+	flagged := map[string]float64{}
+	if thresholds == nil {
+		for category, on := range result.Categories {
+			if on {
+				flagged[category] = result.Scores[category]
+			}
+		}
+		return flagged
+	}
+	for category, threshold := range thresholds {
+		if score, ok := result.Scores[category]; ok && score >= threshold {
+			flagged[category] = score
+		}
+	}
+	return flagged
+}
+
+// newUserTexts returns the text of the user messages after the last assistant
+// message, i.e. the input new to this turn; earlier turns were checked before.
+func newUserTexts(messages []Message) []string {
+	var texts []string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role() == constants.RoleAssistant {
+			break
+		}
+		if messages[i].Role() == constants.RoleUser {
+			if text := strings.TrimSpace(messages[i].Content()); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return texts
+}
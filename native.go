@@ -0,0 +1,263 @@
+package openllm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+// ExportOpenAIMessages encodes messages as an OpenAI Chat Completions messages
+// array, as sent in the "messages" field of a request. Content OpenAI cannot
+// express is rejected with ErrUnsupportedContent, as when sending a request.
+func ExportOpenAIMessages(messages []Message) ([]byte, error) {
+	l := &llm{}
+	raw := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, message := range messages {
+		msg, err := l.convertMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		// Indexes only exist in stream deltas
+		for i := range msg.ToolCalls {
+			msg.ToolCalls[i].Index = nil
+		}
+		raw = append(raw, msg)
+	}
+	return json.Marshal(raw)
+}
+
+// ImportOpenAIMessages decodes an OpenAI Chat Completions messages array, or a
+// request body holding one in its "messages" field. Developer messages become
+// system messages; content parts other than text and images are dropped.
+func ImportOpenAIMessages(data []byte) ([]Message, error) {
+	var raw []openai.ChatCompletionMessage
+	if err := unmarshalNativeMessages(data, &raw); err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(raw))
+	for _, m := range raw {
+		msg := convertOpenAIAnswer(m)
+		msg.name = m.Name
+		msg.toolCallID = m.ToolCallID
+		switch m.Role {
+		case openai.ChatMessageRoleDeveloper:
+			msg.role = constants.RoleSystem
+		case openai.ChatMessageRoleFunction:
+			msg.role = constants.RoleTool
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// anthropicPayload is the part of an Anthropic Messages request holding the conversation.
+type anthropicPayload struct {
+	System   []anthropic.TextBlockParam `json:"system,omitempty"`
+	Messages []anthropic.MessageParam   `json:"messages"`
+}
+
+// ExportAnthropicMessages encodes messages as the "system" and "messages" fields
+// of an Anthropic Messages request, normalized the way requests are: system
+// messages are hoisted, tool results become user content and consecutive
+// messages of the same role are merged.
+func ExportAnthropicMessages(messages []Message) ([]byte, error) {
+	a := &anthropicLLM{}
+	var payload anthropicPayload
+	var converted []anthropic.MessageParam
+	for _, message := range messages {
+		if message.Role() == constants.RoleSystem {
+			if content := message.Content(); content != "" {
+				payload.System = append(payload.System, anthropic.TextBlockParam{Text: content})
+			}
+			continue
+		}
+		msg, err := a.convertMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, msg)
+	}
+	payload.Messages = normalizeAnthropicMessages(converted)
+	if payload.Messages == nil {
+		payload.Messages = []anthropic.MessageParam{}
+	}
+	return json.Marshal(payload)
+}
+
+// anthropicNativeMessage is an Anthropic message whose content is a string or blocks.
+type anthropicNativeMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// anthropicNativeBlock holds the fields of the user content blocks ImportAnthropicMessages reads.
+type anthropicNativeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+	Source    *struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+		URL       string `json:"url"`
+	} `json:"source"`
+}
+
+// ImportAnthropicMessages decodes an Anthropic messages array, or a request body
+// with "system" and "messages" fields. Tool results become tool messages ahead of
+// the rest of their user turn; assistant content is read like a response, so
+// thinking, tool use and citations are kept.
+func ImportAnthropicMessages(data []byte) ([]Message, error) {
+	var payload struct {
+		System json.RawMessage `json:"system"`
+	}
+	var raw []anthropicNativeMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '[' {
+		if err := json.Unmarshal(trimmed, &payload); err != nil {
+			return nil, err
+		}
+	}
+	if err := unmarshalNativeMessages(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	if len(payload.System) > 0 {
+		system, err := anthropicText(payload.System)
+		if err != nil {
+			return nil, fmt.Errorf("anthropic system prompt: %w", err)
+		}
+		if system != "" {
+			messages = append(messages, NewSystemMessage(system))
+		}
+	}
+	for i, m := range raw {
+		content := m.Content
+		if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '"' {
+			// Plain text is shorthand for a single text block
+			var text string
+			if err := json.Unmarshal(trimmed, &text); err != nil {
+				return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+			}
+			content, _ = json.Marshal([]anthropicNativeBlock{{Type: "text", Text: text}})
+		}
+
+		if m.Role == string(anthropic.MessageParamRoleAssistant) {
+			var blocks []anthropic.ContentBlockUnion
+			if err := json.Unmarshal(content, &blocks); err != nil {
+				return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+			}
+			answer, err := convertAnthropicAnswer(blocks)
+			if err != nil {
+				return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+			}
+			messages = append(messages, answer)
+			continue
+		}
+
+		var blocks []anthropicNativeBlock
+		if err := json.Unmarshal(content, &blocks); err != nil {
+			return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+		}
+		user := &llmmsg{role: constants.RoleUser}
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				user.content = append(user.content, ContentPart{Type: constants.ContentPartTypeText, Text: b.Text})
+			case "tool_result":
+				text, err := anthropicText(b.Content)
+				if err != nil {
+					return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+				}
+				messages = append(messages, &llmmsg{
+					role:       constants.RoleTool,
+					content:    []ContentPart{{Type: constants.ContentPartTypeText, Text: text}},
+					toolCallID: b.ToolUseID,
+					isError:    b.IsError,
+				})
+			case "image":
+				if b.Source == nil {
+					continue
+				}
+				url := b.Source.URL
+				if b.Source.Type == "base64" {
+					url = "data:" + b.Source.MediaType + ";base64," + b.Source.Data
+				}
+				user.content = append(user.content, ContentPart{Type: constants.ContentPartTypeImageURL, ImageURL: &ImageURL{URL: url}})
+			case "document":
+				if b.Source == nil {
+					continue
+				}
+				doc := &Document{URL: b.Source.URL, MIMEType: b.Source.MediaType}
+				switch b.Source.Type {
+				case "base64":
+					decoded, err := base64.StdEncoding.DecodeString(b.Source.Data)
+					if err != nil {
+						return nil, fmt.Errorf("anthropic message %d: %w", i, err)
+					}
+					doc.Data = decoded
+				case "text":
+					doc.Data = []byte(b.Source.Data)
+				}
+				user.content = append(user.content, ContentPart{Type: constants.ContentPartTypeDocument, Document: doc})
+			}
+		}
+		if len(user.content) > 0 {
+			messages = append(messages, user)
+		}
+	}
+	return messages, nil
+}
+
+// unmarshalNativeMessages decodes a messages array, or the "messages" field of
+// a request body, into v.
+func unmarshalNativeMessages(data []byte, v any) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, v)
+	}
+	var body struct {
+		Messages json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(trimmed, &body); err != nil {
+		return err
+	}
+	if len(body.Messages) == 0 {
+		return errors.New("no messages field")
+	}
+	return json.Unmarshal(body.Messages, v)
+}
+
+// anthropicText returns the text of Anthropic content given as a string or as
+// blocks, joining the text blocks.
+func anthropicText(raw json.RawMessage) (string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return "", nil
+	}
+	if trimmed[0] == '"' {
+		var text string
+		err := json.Unmarshal(trimmed, &text)
+		return text, err
+	}
+	var blocks []anthropicNativeBlock
+	if err := json.Unmarshal(trimmed, &blocks); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, b := range blocks {
+		if b.Type == "text" {
+			sb.WriteString(b.Text)
+		}
+	}
+	return sb.String(), nil
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"sort"
+	"net/http"
 	"strings"
 	"time"
 
@@ -17,6 +18,8 @@ type llm struct {
 	name        string
 	description string
 	client      *openai.Client
+	// constraintBackend is the server enforcing WithConstraint (see WithConstraintBackend).
+	constraintBackend string
 }
 
 // NewLLM creates a new Model implementation for a specific model name and client.
@@ -25,9 +28,40 @@ func NewLLM(name, description string, client *openai.Client) Model {
 }
 
 // NewLLMWithAPIKey creates a new Model implementation with an auth token.
-func NewLLMWithAPIKey(name, description, authToken string) Model {
-	client := openai.NewClient(authToken)
-	return &llm{name: name, description: description, client: client}
+// Client options can set the HTTP client, proxy, endpoint and, for compatible
+// servers, the constraint backend.
+func NewLLMWithAPIKey(name, description, authToken string, opts ...ClientOption) Model {
+	return &llm{
+		name:              name,
+		description:       description,
+		client:            newOpenAIClient(authToken, opts),
+		constraintBackend: newClientOptions(opts).constraintBackend,
+	}
+}
+
+// newOpenAIClient builds an OpenAI client with the client options applied.
+func newOpenAIClient(authToken string, opts []ClientOption) *openai.Client {
+	options := newClientOptions(opts)
+	config := openai.DefaultConfig(authToken)
+	client := http.DefaultClient
+	if options.httpClient != nil {
+		client = options.httpClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if options.constraintBackend != "" {
+		base = &constraintTransport{base: base}
+	}
+	// Record response headers, which the client's errors leave out, for Retry-After
+	wrapped := *client
+	wrapped.Transport = &headerTransport{base: base}
+	config.HTTPClient = &wrapped
+	if options.baseURL != "" {
+		config.BaseURL = options.baseURL
+	}
+	return openai.NewClientWithConfig(config)
 }
 
 // Name returns the model identifier string.
@@ -44,21 +78,31 @@ func (l *llm) Description() string {
 // It builds the request from messages and options, executes the call,
 // and returns the final assistant message together with any tool-calls.
 func (l *llm) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, l.name, false, messages)
+	defer func() { end(resp, err) }()
+
 	options := &ChatOptions{}
 	// Set chat options
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.history != nil {
+		if messages, err = options.history.Trim(ctx, l, messages, opts...); err != nil {
+			return nil, err
+		}
+	}
 
 	req, err := l.makeRequest(options, messages)
 	if err != nil {
 		return nil, err
 	}
+	ctx = l.constrain(ctx, options)
 
+	ctx, header := withResponseHeader(ctx)
 	start := time.Now()
 	chatResp, err := l.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, openAIError(cancelCause(ctx, err), *header)
 	}
 
 	// Defensive: ensure we have at least one choice
@@ -67,40 +111,9 @@ func (l *llm) ChatCompletion(ctx context.Context, messages []Message, opts ...Ch
 	}
 
 	choice := chatResp.Choices[0]
-	var tcalls []ToolCall
-	if n := len(choice.Message.ToolCalls); n > 0 {
-		tcalls = make([]ToolCall, 0, n)
-	}
-
-	for _, call := range choice.Message.ToolCalls {
-		if call.Index == nil {
-			continue
-		}
-		index := copyInt(*call.Index)
-		if call.Type == openai.ToolTypeFunction && call.Function.Name != "" {
-			tcalls = append(tcalls, &toolcall{
-				index: index,
-				id:    call.ID,
-				type_: constants.ToolTypeFunction,
-				fcall: funcall{
-					name: call.Function.Name,
-					args: call.Function.Arguments,
-				},
-			})
-		}
-	}
+	answer := convertOpenAIAnswer(choice.Message)
 
-	usage := Usage{
-		InputTokens:  chatResp.Usage.PromptTokens,
-		OutputTokens: chatResp.Usage.CompletionTokens,
-		TotalTokens:  chatResp.Usage.TotalTokens,
-	}
-	if chatResp.Usage.PromptTokensDetails != nil {
-		usage.CachedTokens = chatResp.Usage.PromptTokensDetails.CachedTokens
-	}
-	if chatResp.Usage.CompletionTokensDetails != nil {
-		usage.ReasoningTokens = chatResp.Usage.CompletionTokensDetails.ReasoningTokens
-	}
+	usage := convertOpenAIUsage(chatResp.Usage)
 
 	meta := Meta{
 		Provider:          constants.ProviderOpenAI,
@@ -108,58 +121,27 @@ func (l *llm) ChatCompletion(ctx context.Context, messages []Message, opts ...Ch
 		RequestID:         chatResp.ID,
 		SystemFingerprint: chatResp.SystemFingerprint,
 		StopReason:        string(choice.FinishReason),
+		FinishReason:      finishReasonOf(string(choice.FinishReason), choice.Message.Refusal != ""),
+		RateLimit:         parseOpenAIRateLimit(chatResp.Header()),
 	}
+	usage = usage.withCost(meta.Model)
 	duration := time.Since(start)
+	meta.Latency = blockingLatency(usage, duration)
 
-	return &response{
-		answer: &llmmsg{
-			role:      choice.Message.Role,
-			reasoning: choice.Message.ReasoningContent,
-			refusal:   choice.Message.Refusal,
-			content: func() []ContentPart {
-				if choice.Message.Content != "" {
-					return []ContentPart{{Type: constants.ContentPartTypeText, Text: choice.Message.Content}}
-				}
-				var parts []ContentPart
-				for _, p := range choice.Message.MultiContent {
-					if p.Type == openai.ChatMessagePartTypeText {
-						parts = append(parts, ContentPart{Type: constants.ContentPartTypeText, Text: p.Text})
-					} else if p.Type == openai.ChatMessagePartTypeImageURL && p.ImageURL != nil {
-						parts = append(parts, ContentPart{
-							Type: constants.ContentPartTypeImageURL,
-							ImageURL: &ImageURL{
-								URL:    p.ImageURL.URL,
-								Detail: string(p.ImageURL.Detail),
-							},
-						})
-					}
-				}
-				return parts
-			}(),
-			toolCalls: func() []*toolcall {
-				if len(tcalls) == 0 {
-					return nil
-				}
-				var gtc []*toolcall
-				for _, tc := range tcalls {
-					gtc = append(gtc, &toolcall{
-						index: tc.Index(),
-						id:    tc.ID(),
-						type_: tc.Type(),
-						fcall: funcall{
-							name: tc.Function().Name(),
-							args: tc.Function().Arguments(),
-						},
-					})
-				}
-				return gtc
-			}(),
-		},
-		tcalls:   tcalls,
+	var logprobs []TokenLogProb
+	if choice.LogProbs != nil {
+		logprobs = convertOpenAILogProbs(choice.LogProbs.Content)
+	}
+
+	resp = &response{
+		answer:   answer,
+		tcalls:   answer.ToolCalls(),
 		usage:    usage,
 		meta:     meta,
 		duration: duration,
-	}, nil
+		logprobs: logprobs,
+	}
+	return resp, validateAnswer(options, resp)
 }
 
 // ChatCompletionStream performs a streaming chat completion request.
@@ -167,40 +149,48 @@ func (l *llm) ChatCompletion(ctx context.Context, messages []Message, opts ...Ch
 // collects streamed tool-call arguments, and returns the assembled answer
 // and ordered tool-calls once the stream finishes.
 func (l *llm) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (resp Response, err error) {
+	end := startRequest(ctx, l.name, true, messages)
+	defer func() { end(resp, err) }()
+
 	options := &ChatOptions{}
 	// Set chat options
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.history != nil {
+		if messages, err = options.history.Trim(ctx, l, messages, opts...); err != nil {
+			return nil, err
+		}
+	}
 
 	req, err := l.makeRequest(options, messages)
 	if err != nil {
 		return nil, err
 	}
+	ctx = l.constrain(ctx, options)
 
-	start := time.Now()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	// Ask the server to append a final chunk carrying token usage.
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	acc := newStreamAccumulator(options, Meta{Provider: constants.ProviderOpenAI, Model: l.name})
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	idle := startIdleTimer(options.streamIdleTimeout, cancel)
+	defer idle.stop()
 
+	ctx, header := withResponseHeader(ctx)
 	stream, err := l.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, openAIError(cancelCause(ctx, err), *header)
 	}
 	defer stream.Close()
-
-	var (
-		role      string
-		content   strings.Builder
-		reasoning strings.Builder
-		refusal   strings.Builder
-		rawmsg    openai.ChatCompletionMessage
-		callm     = make(map[int]*toolcall)
-	)
+	acc.meta.RateLimit = parseOpenAIRateLimit(stream.Header())
 
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return acc.fail(context.Cause(ctx))
 		default:
 		}
 
@@ -209,7 +199,31 @@ func (l *llm) ChatCompletionStream(ctx context.Context, messages []Message, opts
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return nil, err
+			return acc.fail(normalizeProviderError(constants.ProviderOpenAI, cancelCause(ctx, err)))
+		}
+		idle.reset()
+
+		if err = acc.onRawEvent(resp); err != nil {
+			return acc.fail(err)
+		}
+
+		// Capture request metadata from the first chunk
+		if acc.meta.RequestID == "" && resp.ID != "" {
+			acc.meta.RequestID = resp.ID
+			if resp.Model != "" {
+				acc.meta.Model = resp.Model
+			}
+			acc.meta.SystemFingerprint = resp.SystemFingerprint
+			if err = acc.onMeta(); err != nil {
+				return acc.fail(err)
+			}
+		}
+
+		// The usage chunk (if requested) arrives last with empty choices
+		if resp.Usage != nil {
+			if err = acc.onUsage(convertOpenAIUsage(*resp.Usage)); err != nil {
+				return acc.fail(err)
+			}
 		}
 
 		// Ignore empty payloads defensively
@@ -219,167 +233,114 @@ func (l *llm) ChatCompletionStream(ctx context.Context, messages []Message, opts
 		choice := resp.Choices[0]
 
 		// Set role
-		if choice.Delta.Role != "" && role == "" {
-			role = choice.Delta.Role
+		if choice.Delta.Role != "" {
+			acc.setRole(choice.Delta.Role)
 		}
 
 		if choice.Delta.ReasoningContent != "" {
-			if options.watcher != nil {
-				if err = options.watcher.OnReasoning(choice.Delta.ReasoningContent); err != nil {
-					return nil, err
-				}
+			if err = acc.onReasoning(choice.Delta.ReasoningContent); err != nil {
+				return acc.fail(err)
 			}
-			reasoning.WriteString(choice.Delta.ReasoningContent)
 		}
 
 		if choice.Delta.Content != "" {
-			if options.watcher != nil {
-				if err = options.watcher.OnContent(choice.Delta.Content); err != nil {
-					return nil, err
+			if err = acc.onContent(choice.Delta.Content); err != nil {
+				return acc.fail(err)
+			}
+		}
+
+		if choice.Logprobs != nil {
+			for _, lp := range choice.Logprobs.Content {
+				tlp := TokenLogProb{Token: lp.Token, LogProb: lp.Logprob}
+				for _, top := range lp.TopLogprobs {
+					tlp.TopLogProbs = append(tlp.TopLogProbs, TokenLogProb{Token: top.Token, LogProb: top.Logprob})
 				}
+				acc.logprobs = append(acc.logprobs, tlp)
 			}
-			content.WriteString(choice.Delta.Content)
 		}
 
 		if choice.Delta.Refusal != "" {
-			if options.watcher != nil {
-				if err = options.watcher.OnRefusal(choice.Delta.Refusal); err != nil {
-					return nil, err
-				}
+			if err = acc.onRefusal(choice.Delta.Refusal); err != nil {
+				return acc.fail(err)
 			}
-			refusal.WriteString(choice.Delta.Refusal)
 		}
 
-		if len(choice.Delta.ToolCalls) > 0 {
-			for _, call := range choice.Delta.ToolCalls {
-				if call.Index == nil {
-					continue
-				}
-				index := copyInt(*call.Index)
-				if call.Type == openai.ToolTypeFunction && call.Function.Name != "" {
-					tcall := &toolcall{
-						index: index,
-						id:    call.ID,
-						type_: constants.ToolTypeFunction,
-						fcall: funcall{
-							name: call.Function.Name,
-							args: call.Function.Arguments,
-						},
-					}
-					if options.watcher != nil {
-						if err = options.watcher.OnToolCall(ctx, tcall, ""); err != nil {
-							return nil, err
-						}
+		for _, call := range choice.Delta.ToolCalls {
+			if call.Index == nil {
+				continue
+			}
+			index := copyInt(*call.Index)
+			if call.Type == openai.ToolTypeFunction && call.Function.Name != "" {
+				// A new call implies the previous one has received all its arguments
+				if acc.last >= 0 && acc.last != index {
+					if err = acc.onToolCallDone(ctx, acc.last); err != nil {
+						return acc.fail(err)
 					}
-					callm[index] = tcall
 				}
+				tcall := &toolcall{
+					index: index,
+					id:    call.ID,
+					type_: constants.ToolTypeFunction,
+					fcall: funcall{
+						name: call.Function.Name,
+					},
+				}
+				if err = acc.onToolCallStart(ctx, tcall); err != nil {
+					return acc.fail(err)
+				}
+			}
 
-				if call.Function.Arguments != "" {
-					tcall, found := callm[index]
-					if found {
-						if options.watcher != nil {
-							if err = options.watcher.OnToolCall(ctx, tcall, call.Function.Arguments); err != nil {
-								return nil, err
-							}
-						}
-						tcall.fcall.writeArgs(call.Function.Arguments)
-					}
+			if call.Function.Arguments != "" {
+				if err = acc.onToolCallArgs(ctx, index, call.Function.Arguments); err != nil {
+					return acc.fail(err)
 				}
 			}
 		}
-	}
 
-	if options.watcher != nil {
-		if err := options.watcher.OnStop(); err != nil {
-			return nil, err
+		if choice.FinishReason != "" {
+			if err = acc.finishToolCalls(ctx); err != nil {
+				return acc.fail(err)
+			}
+			acc.meta.StopReason = string(choice.FinishReason)
+			acc.meta.FinishReason = finishReasonOf(acc.meta.StopReason, acc.refusal.Len() > 0)
+			if err = acc.onMeta(); err != nil {
+				return acc.fail(err)
+			}
 		}
 	}
 
-	rawmsg.Role = role
-	rawmsg.Content = content.String()
-	rawmsg.ReasoningContent = reasoning.String()
-	rawmsg.Refusal = refusal.String()
+	if err := acc.finishToolCalls(ctx); err != nil {
+		return acc.fail(err)
+	}
 
-	var tcalls = make([]ToolCall, 0)
-	if len(callm) > 0 {
-		for _, tcall := range callm {
-			tcalls = append(tcalls, tcall)
-		}
-		sort.Slice(tcalls, func(i, j int) bool {
-			return tcalls[i].Index() < tcalls[j].Index()
-		})
-		for _, tcall := range tcalls {
-			index := tcall.Index()
-			rawmsg.ToolCalls = append(rawmsg.ToolCalls, openai.ToolCall{
-				Index: &index,
-				ID:    tcall.ID(),
-				Type:  openai.ToolTypeFunction,
-				Function: openai.FunctionCall{
-					Name:      tcall.Function().Name(),
-					Arguments: tcall.Function().Arguments(),
-				},
-			})
-		}
+	if err := acc.onStop(); err != nil {
+		return acc.fail(err)
 	}
 
-	return &response{
-		answer: &llmmsg{
-			role: rawmsg.Role,
-			content: func() []ContentPart {
-				if rawmsg.Content != "" {
-					return []ContentPart{{Type: constants.ContentPartTypeText, Text: rawmsg.Content}}
-				}
-				var parts []ContentPart
-				for _, p := range rawmsg.MultiContent {
-					if p.Type == openai.ChatMessagePartTypeText {
-						parts = append(parts, ContentPart{Type: constants.ContentPartTypeText, Text: p.Text})
-					} else if p.Type == openai.ChatMessagePartTypeImageURL && p.ImageURL != nil {
-						parts = append(parts, ContentPart{
-							Type: constants.ContentPartTypeImageURL,
-							ImageURL: &ImageURL{
-								URL:    p.ImageURL.URL,
-								Detail: string(p.ImageURL.Detail),
-							},
-						})
-					}
-				}
-				return parts
-			}(),
-			reasoning: rawmsg.ReasoningContent,
-			refusal:   rawmsg.Refusal,
-			toolCalls: func() []*toolcall {
-				if len(tcalls) == 0 {
-					return nil
-				}
-				var gtc []*toolcall
-				for _, tc := range tcalls {
-					gtc = append(gtc, &toolcall{
-						index: tc.Index(),
-						id:    tc.ID(),
-						type_: tc.Type(),
-						fcall: funcall{
-							name: tc.Function().Name(),
-							args: tc.Function().Arguments(),
-						},
-					})
-				}
-				return gtc
-			}(),
-		},
-		tcalls:   tcalls,
-		usage:    Usage{},
-		duration: time.Since(start),
-		meta: Meta{
-			Provider: constants.ProviderOpenAI,
-			Model:    l.name,
-		},
-	}, nil
+	return acc.result()
+}
+
+// constrain returns ctx carrying the constraint fields of the request for
+// constraintTransport; makeRequest has already rejected unsupported constraints.
+func (l *llm) constrain(ctx context.Context, opts *ChatOptions) context.Context {
+	if opts.constraint == nil {
+		return ctx
+	}
+	fields, _ := opts.constraint.fields(l.constraintBackend)
+	return withConstraintFields(ctx, fields)
 }
 
 // makeRequest builds an OpenAI ChatCompletionRequest from ChatOptions and Message list.
 // It converts messages to the OpenAI format, applies system prompt and temperature,
 // and attaches tool definitions when provided.
 func (l *llm) makeRequest(opts *ChatOptions, messages []Message) (req openai.ChatCompletionRequest, err error) {
+	// Option: ValidateMessages
+	if opts.validateMessages {
+		if err := ValidateMessages(constants.ProviderOpenAI, messages); err != nil {
+			return req, err
+		}
+	}
+
 	req.Model = l.name
 	// Option: MaxTokens
 	if opts.maxTokens != nil {
@@ -394,6 +355,11 @@ func (l *llm) makeRequest(opts *ChatOptions, messages []Message) (req openai.Cha
 	if opts.topP != nil {
 		req.TopP = float32(*opts.topP)
 	}
+	// Option: Seed
+	if opts.seed != nil {
+		seed := *opts.seed
+		req.Seed = &seed
+	}
 
 	// Option: ReasoningEffort
 	if opts.reasoningEffort != nil {
@@ -406,10 +372,49 @@ func (l *llm) makeRequest(opts *ChatOptions, messages []Message) (req openai.Cha
 		}
 	}
 
-	if opts.prompt != "" {
+	// Option: LogProbs
+	if opts.logProbs != nil {
+		req.LogProbs = true
+		req.TopLogProbs = *opts.logProbs
+	}
+
+	// Option: Constraint; schemas are sent as the response format, the others as
+	// fields of the constraint backend, added by constraintTransport
+	if c := opts.constraint; c != nil {
+		if _, err := c.fields(l.constraintBackend); err != nil {
+			return req, err
+		}
+	}
+
+	// Option: ResponseFormat
+	switch opts.responseFormat {
+	case constants.ResponseFormatJSONObject:
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	case constants.ResponseFormatJSONSchema:
+		if opts.responseSchema == nil {
+			break
+		}
+		schema, err := opts.responseSchema.raw()
+		if err != nil {
+			return req, err
+		}
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   opts.responseSchema.name,
+				Schema: schema,
+				Strict: opts.responseSchema.strict,
+			},
+		}
+	case constants.ResponseFormatText:
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeText}
+	}
+
+	prompts, messages := opts.redact(messages)
+	for _, prompt := range prompts {
 		req.Messages = append(req.Messages, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: opts.prompt,
+			Content: prompt,
 		})
 	}
 
@@ -425,95 +430,134 @@ func (l *llm) makeRequest(opts *ChatOptions, messages []Message) (req openai.Cha
 		req.Messages = append(req.Messages, openaiMsg)
 	}
 
-	for _, tool := range opts.tools {
-		var fn *openai.FunctionDefinition
-		if def, ok := tool.Definition().(*openai.FunctionDefinition); ok {
-			fn = def
-		} else if def, ok := tool.Definition().(*FunctionDefinition); ok {
-			fn = &openai.FunctionDefinition{
-				Name:        def.Name,
-				Description: def.Description,
-				Parameters:  def.Parameters,
-				Strict:      def.Strict,
-			}
-		} else {
-			// Try JSON round-trip conversion for compatibility
-			data, err := json.Marshal(tool.Definition())
-			if err == nil {
-				var def openai.FunctionDefinition
-				if err := json.Unmarshal(data, &def); err == nil {
-					fn = &def
+	for _, tool := range opts.filterTools(opts.tools) {
+		if t, ok := convertOpenAITool(tool); ok {
+			req.Tools = append(req.Tools, t)
+		}
+	}
+
+	// ToolSet conversions are cached until the set changes
+	if opts.toolset != nil {
+		convert := func(tools []Tool) any {
+			var out []openai.Tool
+			for _, tool := range tools {
+				if t, ok := convertOpenAITool(tool); ok {
+					out = append(out, t)
 				}
 			}
+			return out
 		}
-
-		if fn != nil {
-			req.Tools = append(req.Tools, openai.Tool{
-				Type:     openai.ToolType(tool.Type()),
-				Function: fn,
-			})
+		var converted any
+		if opts.toolFilter != nil {
+			// Filtered views differ per request and bypass the cache
+			converted = convert(opts.filterTools(opts.toolset.Tools()))
+		} else {
+			converted = opts.toolset.converted(constants.ProviderOpenAI, convert)
 		}
+		req.Tools = append(req.Tools, converted.([]openai.Tool)...)
 	}
 
 	return req, nil
 }
 
+// convertOpenAITool converts a Tool into OpenAI's tool format.
+// It reports false when the definition cannot be interpreted as a function.
+func convertOpenAITool(tool Tool) (openai.Tool, bool) {
+	// Provider-specific built-in tools (e.g., Anthropic web search) have no OpenAI equivalent
+	if tool.Type() != constants.ToolTypeFunction {
+		return openai.Tool{}, false
+	}
+
+	var fn *openai.FunctionDefinition
+	if def, ok := tool.Definition().(*openai.FunctionDefinition); ok {
+		fn = def
+	} else if def, ok := tool.Definition().(*FunctionDefinition); ok {
+		fn = &openai.FunctionDefinition{
+			Name:        def.Name,
+			Description: def.Description,
+			Parameters:  def.Parameters,
+			Strict:      def.Strict,
+		}
+	} else {
+		// Try JSON round-trip conversion for compatibility
+		data, err := json.Marshal(tool.Definition())
+		if err == nil {
+			var def openai.FunctionDefinition
+			if err := json.Unmarshal(data, &def); err == nil {
+				fn = &def
+			}
+		}
+	}
+
+	if fn == nil {
+		return openai.Tool{}, false
+	}
+	return openai.Tool{
+		Type:     openai.ToolType(tool.Type()),
+		Function: fn,
+	}, true
+}
+
 // convertMessage transforms the unified Message (llmmsg) into OpenAI's ChatCompletionMessage.
 func (l *llm) convertMessage(message Message) (openai.ChatCompletionMessage, error) {
-	// Cast to llmmsg to access internal structure
-	msg, ok := message.(*llmmsg)
-	if !ok {
-		// Fallback for custom implementations
-		return openai.ChatCompletionMessage{
-			Role:    message.Role(),
-			Content: message.Content(),
-		}, nil
-	}
+	msg := asLLMMessage(message)
 
 	raw := openai.ChatCompletionMessage{
 		Role:             msg.role,
+		Name:             msg.name,
 		ReasoningContent: msg.reasoning,
+		Refusal:          msg.refusal,
 		ToolCallID:       msg.toolCallID,
 	}
 
-	// Handle Content (Text + Images)
-	if len(msg.content) > 0 {
-		// If simple text (length 1 and type text), can use Content field,
-		// but MultiContent is more robust for general cases.
-		// However, standard OpenAI client might prefer Content field for simple text.
-		// Let's check if it's pure text.
-		isPureText := true
-		for _, part := range msg.content {
-			if part.Type != constants.ContentPartTypeText {
-				isPureText = false
-				break
+	// Handle Content (Text + Images). Parts OpenAI has no equivalent for, such as
+	// Anthropic server tool blocks, are dropped so histories can cross providers.
+	pureText := true
+	for _, part := range msg.content {
+		switch part.Type {
+		case constants.ContentPartTypeText:
+			raw.MultiContent = append(raw.MultiContent, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: part.Text,
+			})
+		case constants.ContentPartTypeImageURL:
+			if part.ImageURL != nil {
+				pureText = false
+				raw.MultiContent = append(raw.MultiContent, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{
+						URL:    part.ImageURL.URL,
+						Detail: openai.ImageURLDetail(part.ImageURL.Detail),
+					},
+				})
 			}
-		}
-
-		if isPureText && len(msg.content) == 1 {
-			raw.Content = msg.content[0].Text
-		} else {
-			for _, part := range msg.content {
-				switch part.Type {
-				case constants.ContentPartTypeText:
-					raw.MultiContent = append(raw.MultiContent, openai.ChatMessagePart{
-						Type: openai.ChatMessagePartTypeText,
-						Text: part.Text,
-					})
-				case constants.ContentPartTypeImageURL:
-					if part.ImageURL != nil {
-						raw.MultiContent = append(raw.MultiContent, openai.ChatMessagePart{
-							Type: openai.ChatMessagePartTypeImageURL,
-							ImageURL: &openai.ChatMessageImageURL{
-								URL:    part.ImageURL.URL,
-								Detail: openai.ImageURLDetail(part.ImageURL.Detail),
-							},
-						})
-					}
-				}
+		case constants.ContentPartTypeDocument:
+			if part.Document == nil {
+				continue
 			}
+			// Chat Completions file inputs cannot be expressed with the client types,
+			// so only text documents are supported (inlined)
+			if part.Document.URL != "" || !strings.HasPrefix(part.Document.MIMEType, "text/") {
+				return raw, fmt.Errorf("%w: document of type %q for OpenAI", ErrUnsupportedContent, part.Document.MIMEType)
+			}
+			pureText = false
+			raw.MultiContent = append(raw.MultiContent, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: string(part.Document.Data),
+			})
+		case constants.ContentPartTypeVideo:
+			return raw, fmt.Errorf("%w: video input for OpenAI", ErrUnsupportedContent)
 		}
 	}
+	// Plain text is sent as a string, which every role accepts
+	if pureText {
+		var text strings.Builder
+		for _, part := range raw.MultiContent {
+			text.WriteString(part.Text)
+		}
+		raw.Content = text.String()
+		raw.MultiContent = nil
+	}
 
 	// Handle ToolCalls
 	if len(msg.toolCalls) > 0 {
@@ -534,6 +578,82 @@ func (l *llm) convertMessage(message Message) (openai.ChatCompletionMessage, err
 	return raw, nil
 }
 
+// convertOpenAIAnswer converts a response message into the unified message.
+func convertOpenAIAnswer(message openai.ChatCompletionMessage) *llmmsg {
+	answer := &llmmsg{
+		role:      message.Role,
+		reasoning: message.ReasoningContent,
+		refusal:   message.Refusal,
+	}
+
+	if message.Content != "" {
+		answer.content = []ContentPart{{Type: constants.ContentPartTypeText, Text: message.Content}}
+	}
+	for _, p := range message.MultiContent {
+		if p.Type == openai.ChatMessagePartTypeText {
+			answer.content = append(answer.content, ContentPart{Type: constants.ContentPartTypeText, Text: p.Text})
+		} else if p.Type == openai.ChatMessagePartTypeImageURL && p.ImageURL != nil {
+			answer.content = append(answer.content, ContentPart{
+				Type: constants.ContentPartTypeImageURL,
+				ImageURL: &ImageURL{
+					URL:    p.ImageURL.URL,
+					Detail: string(p.ImageURL.Detail),
+				},
+			})
+		}
+	}
+
+	for _, call := range message.ToolCalls {
+		if call.Type != openai.ToolTypeFunction || call.Function.Name == "" {
+			continue
+		}
+		// Blocking responses may omit the index; fall back to the position
+		index := len(answer.toolCalls)
+		if call.Index != nil {
+			index = *call.Index
+		}
+		answer.toolCalls = append(answer.toolCalls, &toolcall{
+			index: index,
+			id:    call.ID,
+			type_: constants.ToolTypeFunction,
+			fcall: funcall{
+				name: call.Function.Name,
+				args: call.Function.Arguments,
+			},
+		})
+	}
+	return answer
+}
+
+// convertOpenAIUsage maps OpenAI usage statistics to the unified Usage structure.
+func convertOpenAIUsage(u openai.Usage) Usage {
+	usage := Usage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+		TotalTokens:  u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		usage.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return usage
+}
+
 // copyInt returns a value copy of the provided int.
 // It exists mainly to document the intent when copying pointer-based indices.
 func copyInt(i int) int { return i }
+
+// convertOpenAILogProbs maps OpenAI token log probabilities to TokenLogProb.
+func convertOpenAILogProbs(content []openai.LogProb) []TokenLogProb {
+	logprobs := make([]TokenLogProb, 0, len(content))
+	for _, lp := range content {
+		tlp := TokenLogProb{Token: lp.Token, LogProb: lp.LogProb}
+		for _, top := range lp.TopLogProbs {
+			tlp.TopLogProbs = append(tlp.TopLogProbs, TokenLogProb{Token: top.Token, LogProb: top.LogProb})
+		}
+		logprobs = append(logprobs, tlp)
+	}
+	return logprobs
+}
@@ -0,0 +1,206 @@
+package openllm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+type openAIImageModel struct {
+	model  string
+	client *openai.Client
+}
+
+// NewImageModel creates an ImageModel for an OpenAI image model: "gpt-image-1",
+// "dall-e-3" or "dall-e-2". Edits and variations are only available with dall-e-2.
+func NewImageModel(model string, client *openai.Client) ImageModel {
+	return &openAIImageModel{model: model, client: client}
+}
+
+// NewImageModelWithAPIKey creates an OpenAI ImageModel with an auth token.
+// Client options can set the HTTP client, proxy and endpoint.
+func NewImageModelWithAPIKey(model, authToken string, opts ...ClientOption) ImageModel {
+	return &openAIImageModel{model: model, client: newOpenAIClient(authToken, opts)}
+}
+
+// Name implements ImageModel.
+func (m *openAIImageModel) Name() string {
+	return m.model
+}
+
+// Generate implements ImageModel.
+func (m *openAIImageModel) Generate(ctx context.Context, prompt string, opts ...ImageOption) (*ImageResult, error) {
+	options := &ImageOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	req := openai.ImageRequest{
+		Prompt:       prompt,
+		Model:        m.model,
+		N:            options.n,
+		Quality:      options.quality,
+		Size:         options.size,
+		Style:        options.style,
+		Background:   options.background,
+		OutputFormat: options.outputFormat,
+	}
+	// gpt-image models always return base64 and reject response_format
+	if !m.gptImage() {
+		req.ResponseFormat = m.responseFormat(options)
+	}
+	resp, err := m.client.CreateImage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return m.result(resp, options)
+}
+
+// Edit implements ImageModel. go-openai sends edits without a model, so the API
+// edits with dall-e-2; other models return ErrUnsupportedImageOperation rather
+// than silently switching. The size defaults to 1024x1024.
+func (m *openAIImageModel) Edit(ctx context.Context, image []byte, prompt string, opts ...ImageOption) (*ImageResult, error) {
+	if m.model != openai.CreateImageModelDallE2 {
+		return nil, fmt.Errorf("%w: %s edits", ErrUnsupportedImageOperation, m.model)
+	}
+	options := &ImageOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	// go-openai always sends the size field, and the API rejects it empty
+	if options.size == "" {
+		options.size = constants.ImageSize1024x1024
+	}
+	req := openai.ImageEditRequest{
+		Image:          imageFile(image, "image"),
+		Prompt:         prompt,
+		Model:          m.model,
+		N:              max(options.n, 1),
+		Size:           options.size,
+		ResponseFormat: m.responseFormat(options),
+	}
+	if options.mask != nil {
+		req.Mask = imageFile(options.mask, "mask")
+	}
+	resp, err := m.client.CreateEditImage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return m.result(resp, options)
+}
+
+// Variation implements ImageModel; only dall-e-2 supports variations. Like Edit,
+// it defaults the size to 1024x1024.
+func (m *openAIImageModel) Variation(ctx context.Context, image []byte, opts ...ImageOption) (*ImageResult, error) {
+	if m.model != openai.CreateImageModelDallE2 {
+		return nil, fmt.Errorf("%w: %s variations", ErrUnsupportedImageOperation, m.model)
+	}
+	options := &ImageOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.size == "" {
+		options.size = constants.ImageSize1024x1024
+	}
+	req := openai.ImageVariRequest{
+		Image:          imageFile(image, "image"),
+		Model:          m.model,
+		N:              max(options.n, 1),
+		Size:           options.size,
+		ResponseFormat: m.responseFormat(options),
+	}
+	resp, err := m.client.CreateVariImage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return m.result(resp, options)
+}
+
+// gptImage reports whether the model is token-billed gpt-image rather than DALL·E.
+func (m *openAIImageModel) gptImage() bool {
+	return strings.HasPrefix(m.model, "gpt-image")
+}
+
+// responseFormat returns the DALL·E response format for options.
+func (m *openAIImageModel) responseFormat(options *ImageOptions) string {
+	if options.url {
+		return openai.CreateImageResponseFormatURL
+	}
+	return openai.CreateImageResponseFormatB64JSON
+}
+
+// result converts an OpenAI image response, decoding base64 images.
+func (m *openAIImageModel) result(resp openai.ImageResponse, options *ImageOptions) (*ImageResult, error) {
+	result := &ImageResult{}
+	for _, data := range resp.Data {
+		image := Image{URL: data.URL, RevisedPrompt: data.RevisedPrompt}
+		if data.B64JSON != "" {
+			decoded, err := base64.StdEncoding.DecodeString(data.B64JSON)
+			if err != nil {
+				return nil, fmt.Errorf("decode image: %w", err)
+			}
+			image.Data = decoded
+			image.MIMEType = imageMIMEType(decoded)
+		}
+		result.Images = append(result.Images, image)
+	}
+
+	if m.gptImage() {
+		result.Usage = Usage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		}.withCost(m.model)
+	} else if price := dallEImagePrice(m.model, options); price > 0 {
+		total := price * float64(len(result.Images))
+		result.Usage.CostUSD = &Cost{Output: total, Total: total}
+	}
+	return result, nil
+}
+
+// dallEImagePrice returns the list price in USD of one DALL·E image, or zero when unknown.
+func dallEImagePrice(model string, options *ImageOptions) float64 {
+	size := options.size
+	if size == "" {
+		size = constants.ImageSize1024x1024
+	}
+	switch model {
+	case openai.CreateImageModelDallE3:
+		large := size != constants.ImageSize1024x1024
+		switch {
+		case options.quality == constants.ImageQualityHD && large:
+			return 0.12
+		case options.quality == constants.ImageQualityHD || large:
+			return 0.08
+		}
+		return 0.04
+	case openai.CreateImageModelDallE2, "":
+		switch size {
+		case constants.ImageSize256x256:
+			return 0.016
+		case constants.ImageSize512x512:
+			return 0.018
+		}
+		return 0.02
+	}
+	return 0
+}
+
+// imageFile wraps image data as a multipart file named after its type, which
+// the API uses to recognize the format.
+func imageFile(data []byte, name string) io.Reader {
+	mimeType := imageMIMEType(data)
+	ext := ".png"
+	switch mimeType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/webp":
+		ext = ".webp"
+	}
+	return openai.WrapReader(bytes.NewReader(data), name+ext, mimeType)
+}
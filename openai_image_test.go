@@ -0,0 +1,37 @@
+package openllm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestImageEditDefaultSize(t *testing.T) {
+	var size string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size = r.FormValue("size")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"created":1,"data":[{"url":"https://example.com/image.png"}]}`)
+	}))
+	defer srv.Close()
+
+	model := NewImageModelWithAPIKey(openai.CreateImageModelDallE2, "key", WithBaseURL(srv.URL))
+	image := []byte("\x89PNG\r\n\x1a\n")
+	if _, err := model.Edit(context.Background(), image, "add a hat"); err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if size != constants.ImageSize1024x1024 {
+		t.Errorf("edit size = %q, want %s", size, constants.ImageSize1024x1024)
+	}
+	if _, err := model.Variation(context.Background(), image, WithImageSize(constants.ImageSize512x512)); err != nil {
+		t.Fatalf("Variation: %v", err)
+	}
+	if size != constants.ImageSize512x512 {
+		t.Errorf("variation size = %q, want %s", size, constants.ImageSize512x512)
+	}
+}
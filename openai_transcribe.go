@@ -0,0 +1,81 @@
+package openllm
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type openAITranscriber struct {
+	model  string
+	client *openai.Client
+}
+
+// NewTranscriber creates a Transcriber for an OpenAI speech-to-text model such as
+// "whisper-1" or "gpt-4o-transcribe". Only whisper models report the language,
+// duration and timestamps; the others return the text alone.
+func NewTranscriber(model string, client *openai.Client) Transcriber {
+	return &openAITranscriber{model: model, client: client}
+}
+
+// NewTranscriberWithAPIKey creates an OpenAI Transcriber with an auth token.
+// Client options can set the HTTP client, proxy and endpoint.
+func NewTranscriberWithAPIKey(model, authToken string, opts ...ClientOption) Transcriber {
+	return &openAITranscriber{model: model, client: newOpenAIClient(authToken, opts)}
+}
+
+// Transcribe implements Transcriber.
+func (t *openAITranscriber) Transcribe(ctx context.Context, r io.Reader, name string, opts ...TranscribeOption) (*Transcription, error) {
+	options := &TranscribeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	req := openai.AudioRequest{
+		Model:    t.model,
+		FilePath: name,
+		Reader:   r,
+		Prompt:   options.prompt,
+		Language: options.language,
+		Format:   openai.AudioResponseFormatJSON,
+	}
+	if options.temperature != nil {
+		req.Temperature = *options.temperature
+	}
+	// Timestamps need verbose_json, which only whisper models support
+	verbose := strings.HasPrefix(t.model, "whisper")
+	if verbose {
+		req.Format = openai.AudioResponseFormatVerboseJSON
+		req.TimestampGranularities = []openai.TranscriptionTimestampGranularity{openai.TranscriptionTimestampGranularitySegment}
+		if options.words {
+			req.TimestampGranularities = append(req.TimestampGranularities, openai.TranscriptionTimestampGranularityWord)
+		}
+	}
+	resp, err := t.client.CreateTranscription(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Transcription{
+		Text:     resp.Text,
+		Language: resp.Language,
+		Duration: seconds(resp.Duration),
+	}
+	if result.Language == "" {
+		result.Language = options.language
+	}
+	for _, s := range resp.Segments {
+		result.Segments = append(result.Segments, TranscriptionSegment{Start: seconds(s.Start), End: seconds(s.End), Text: s.Text})
+	}
+	for _, w := range resp.Words {
+		result.Words = append(result.Words, TranscriptionWord{Start: seconds(w.Start), End: seconds(w.End), Word: w.Word})
+	}
+	return result, nil
+}
+
+// seconds converts fractional seconds to a Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
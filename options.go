@@ -1,5 +1,10 @@
 package openllm
 
+import (
+	"slices"
+	"time"
+)
+
 // ChatOption represents a functional option to configure a single chat request.
 // Options are applied in order and only affect the specific call where they are passed.
 type ChatOption func(*ChatOptions)
@@ -7,10 +12,16 @@ type ChatOption func(*ChatOptions)
 // ChatOptions holds per-request configuration used to build the OpenAI chat completion.
 // Fields are intentionally unexported; use With* helpers to set them.
 type ChatOptions struct {
-	// prompt is the system prompt included at the beginning of the conversation.
-	prompt string
+	// prompts are the system prompts included, in order, at the beginning of the conversation.
+	prompts []string
 	// tools is the list of function tools available for the model to call.
 	tools []Tool
+	// toolset is a shared registry whose enabled tools are offered to the model.
+	toolset *ToolSet
+	// toolFilter narrows tools and toolset to those it accepts; nil offers all of them.
+	toolFilter func(Tool) bool
+	// redactors mask sensitive text in prompts and messages before they are sent.
+	redactors []Redactor
 	// watcher handles streaming events during ChatCompletionStream; ignored for blocking calls.
 	watcher StreamWatcher
 
@@ -24,10 +35,59 @@ type ChatOptions struct {
 	topK *int
 	// topP controls nucleus sampling, keeping the top tokens with cumulative probability >= topP.
 	topP *float64
+	// seed requests deterministic sampling where supported; nil leaves it random.
+	seed *int
 
 	// reasoningEffort controls the reasoning effort/budget.
 	// Values should be one of "low", "medium", "high" (see constants/reasoning.go).
 	reasoningEffort *string
+
+	// responseFormat is the requested output format (see constants/response.go); empty means text.
+	responseFormat string
+	// responseSchema is the schema of the answer when responseFormat is json_schema.
+	responseSchema *responseSchema
+	// constraint restricts decoding to a schema, regex, grammar or choice list.
+	constraint *constraint
+	// parseRetries bounds the re-asks made by Complete; nil uses the default.
+	parseRetries *int
+	// logProbs requests token log probabilities with the given number of alternatives.
+	logProbs *int
+	// validateOutput checks structured answers and tool arguments against their schemas.
+	validateOutput bool
+	// validateMessages checks the history before it is sent (see ValidateMessages).
+	validateMessages bool
+	// cacheMode controls how WithCache serves the request.
+	cacheMode CacheMode
+	// usageKeys attribute the usage of the request in a UsageTracker.
+	usageKeys []string
+	// priority orders the request in WithScheduler.
+	priority Priority
+	// idempotencyKey identifies the request across retries (see WithIdempotencyKey).
+	idempotencyKey string
+	// history trims the messages before the request is sent; nil sends them all.
+	history *HistoryManager
+	// experimentKey assigns the request to an experiment variant (see WithExperimentKey).
+	experimentKey string
+	// noProviderRetries turns off the retries built into provider SDKs; WithRetry sets it.
+	noProviderRetries bool
+
+	// fineGrainedToolStreaming enables provider betas that stream tool arguments with less buffering.
+	fineGrainedToolStreaming bool
+
+	// bufferMinBytes and bufferFlushEvery coalesce small text deltas before they reach the watcher.
+	bufferMinBytes   int
+	bufferFlushEvery time.Duration
+
+	// eventBufferSize and overflowPolicy configure the channel used by StreamEvents.
+	eventBufferSize int
+	overflowPolicy  OverflowPolicy
+
+	// streamIdleTimeout aborts a stream when no event arrives within the window; zero disables it.
+	streamIdleTimeout time.Duration
+	// keepStreamOnWatcherError detaches a failing watcher instead of cancelling the stream.
+	keepStreamOnWatcherError bool
+	// streamLimits bounds the size of streamed output.
+	streamLimits StreamLimits
 }
 
 // WithReasoningEffort sets the reasoning effort.
@@ -37,9 +97,23 @@ func WithReasoningEffort(effort string) ChatOption {
 	return func(opts *ChatOptions) { opts.reasoningEffort = &effort }
 }
 
-// WithSystemPrompt sets the system prompt for the current chat request.
+// WithSystemPrompt adds a system prompt for the current chat request.
+// Calling it several times keeps the prompts separate and in order, e.g. persona,
+// policy and context, so stable prefixes remain cacheable.
 func WithSystemPrompt(prompt string) ChatOption {
-	return func(opts *ChatOptions) { opts.prompt = prompt }
+	return WithSystemPrompts(prompt)
+}
+
+// WithSystemPrompts adds several system prompts, in order. OpenAI receives one system
+// message per prompt; Anthropic receives them as an array of system blocks.
+func WithSystemPrompts(prompts ...string) ChatOption {
+	return func(opts *ChatOptions) {
+		for _, prompt := range prompts {
+			if prompt != "" {
+				opts.prompts = append(opts.prompts, prompt)
+			}
+		}
+	}
 }
 
 // WithTool sets the function tools the model may call during generation.
@@ -47,6 +121,28 @@ func WithTool(tools ...Tool) ChatOption {
 	return func(opts *ChatOptions) { opts.tools = append(opts.tools, tools...) }
 }
 
+// WithToolSet offers the enabled tools of a ToolSet to the model.
+// Provider-specific conversions are cached on the set, so large shared sets are cheap to reuse.
+func WithToolSet(toolset *ToolSet) ChatOption {
+	return func(opts *ChatOptions) { opts.toolset = toolset }
+}
+
+// WithToolFilter offers only the tools (from WithTool and WithToolSet) for which
+// filter returns true, so a large shared set can be narrowed per request.
+// Filtered ToolSet views are converted per request instead of using the set's cache.
+func WithToolFilter(filter func(Tool) bool) ChatOption {
+	return func(opts *ChatOptions) { opts.toolFilter = filter }
+}
+
+// WithToolNames offers only the tools with the given names; see WithToolFilter.
+func WithToolNames(names ...string) ChatOption {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return WithToolFilter(func(tool Tool) bool { return allowed[ToolName(tool)] })
+}
+
 // StreamWatcher sets the handler used to receive streamed deltas and tool-call updates.
 func WithStreamWatcher(watcher StreamWatcher) ChatOption {
 	return func(opts *ChatOptions) { opts.watcher = watcher }
@@ -73,3 +169,109 @@ func WithTopK(topK int) ChatOption {
 func WithTopP(topP float64) ChatOption {
 	return func(opts *ChatOptions) { opts.topP = &topP }
 }
+
+// WithSeed asks the provider to sample deterministically for the given seed, so
+// repeated requests with the same seed and parameters tend to return the same
+// answer. OpenAI supports it on a best-effort basis; Anthropic ignores it.
+func WithSeed(seed int) ChatOption {
+	return func(opts *ChatOptions) { opts.seed = &seed }
+}
+
+// WithResponseFormat sets the output format, one of the constants.ResponseFormat* values.
+// With "json_object" OpenAI uses JSON mode (the messages must mention JSON); Anthropic,
+// which has no JSON mode, is instructed to answer with a JSON object, the answer is
+// prefilled with "{" and generation stops at a closing code fence. The prefill is
+// skipped when reasoning is enabled, as Anthropic does not allow it with thinking.
+func WithResponseFormat(format string) ChatOption {
+	return func(opts *ChatOptions) { opts.responseFormat = format }
+}
+
+// WithLogProbs requests the log probabilities of the answer tokens, with up to top
+// alternatives per position (0 to 20), available from LogProbs.
+// Only OpenAI returns log probabilities; Anthropic ignores the option.
+func WithLogProbs(top int) ChatOption {
+	return func(opts *ChatOptions) { opts.logProbs = &top }
+}
+
+// WithStreamIdleTimeout aborts ChatCompletionStream with ErrStreamIdleTimeout when no
+// event arrives from the provider within d. The partial response is still returned
+// via PartialResponseError. A zero or negative duration disables the check.
+func WithStreamIdleTimeout(d time.Duration) ChatOption {
+	return func(opts *ChatOptions) { opts.streamIdleTimeout = d }
+}
+
+// WithCancelOnWatcherError sets what happens when a StreamWatcher callback returns
+// an error other than ErrStopStreaming. By default (true) the request is cancelled
+// and the partial response is returned via PartialResponseError. With false the
+// watcher is detached and the stream runs to completion, so the caller still gets
+// the complete response, returned with a *WatcherError carrying the callback's error.
+func WithCancelOnWatcherError(cancel bool) ChatOption {
+	return func(opts *ChatOptions) { opts.keepStreamOnWatcherError = !cancel }
+}
+
+// StreamLimits bounds the output of a stream, in bytes. A zero limit is not enforced.
+type StreamLimits struct {
+	// MaxToolArgumentBytes bounds the arguments of each tool call.
+	MaxToolArgumentBytes int
+	// MaxOutputBytes bounds the content, reasoning, refusal and tool arguments together.
+	MaxOutputBytes int
+}
+
+// WithStreamLimits aborts ChatCompletionStream with an *OutputLimitError as soon as a
+// delta would exceed limits, protecting services from runaway generations. The
+// delta is dropped and the partial response is returned via PartialResponseError.
+// Blocking requests are not checked; bound them with WithMaxTokens.
+func WithStreamLimits(limits StreamLimits) ChatOption {
+	return func(opts *ChatOptions) { opts.streamLimits = limits }
+}
+
+// WithFineGrainedToolStreaming enables fine-grained tool streaming where supported.
+// For Anthropic this sends the fine-grained-tool-streaming beta header so tool argument
+// deltas arrive earlier and in smaller chunks; note that the partial JSON is not validated
+// by the server and may be incomplete if generation stops early. Other providers ignore it.
+func WithFineGrainedToolStreaming(enabled bool) ChatOption {
+	return func(opts *ChatOptions) { opts.fineGrainedToolStreaming = enabled }
+}
+
+// WithStreamBuffering coalesces small content and reasoning deltas before they are passed
+// to the watcher. Pending text is delivered once it reaches minBytes or once flushEvery has
+// elapsed since the previous delivery (checked when the next delta arrives), and always
+// before any other watcher event. Zero values disable the respective threshold.
+func WithStreamBuffering(minBytes int, flushEvery time.Duration) ChatOption {
+	return func(opts *ChatOptions) {
+		opts.bufferMinBytes = minBytes
+		opts.bufferFlushEvery = flushEvery
+	}
+}
+
+// WithEventBuffer bounds the channel used by StreamEvents to size events and sets
+// the policy applied when a slow consumer lets it fill up.
+func WithEventBuffer(size int, policy OverflowPolicy) ChatOption {
+	return func(opts *ChatOptions) {
+		opts.eventBufferSize = size
+		opts.overflowPolicy = policy
+	}
+}
+
+// filterTools returns the tools accepted by the tool filter.
+func (opts *ChatOptions) filterTools(tools []Tool) []Tool {
+	if opts.toolFilter == nil {
+		return tools
+	}
+	filtered := make([]Tool, 0, len(tools))
+	for _, tool := range tools {
+		if opts.toolFilter(tool) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+// allTools returns the tools and the toolset tools that pass the tool filter.
+func (opts *ChatOptions) allTools() []Tool {
+	tools := slices.Clip(opts.filterTools(opts.tools))
+	if opts.toolset != nil {
+		tools = append(tools, opts.filterTools(opts.toolset.Tools())...)
+	}
+	return tools
+}
@@ -0,0 +1,215 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Step is a stage of a Pipeline: it receives the output of the previous step
+// and returns its own. Steps that call models report their usage through ctx,
+// as the built-in steps do, so it is attributed to the step.
+type Step func(ctx context.Context, input any) (any, error)
+
+// Pipeline runs steps in sequence, feeding each step the output of the one
+// before, for multi-step workflows such as extract, validate and rewrite.
+// A Pipeline is built once and may be run concurrently.
+type Pipeline struct {
+	steps []pipelineStep
+}
+
+// pipelineStep is a named Step of a Pipeline.
+type pipelineStep struct {
+	name string
+	step Step
+}
+
+// NewPipeline creates an empty Pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Then appends a step named name and returns p.
+func (p *Pipeline) Then(name string, step Step) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{name: name, step: step})
+	return p
+}
+
+// StepResult is the outcome of one step of a pipeline run.
+type StepResult struct {
+	Name     string
+	Output   any
+	Usage    Usage
+	Duration time.Duration
+}
+
+// PipelineResult is the outcome of Pipeline.Run.
+type PipelineResult struct {
+	// Output is the output of the last step.
+	Output any
+	// Steps are the steps run, in order; on failure the last one is the step that failed.
+	Steps []StepResult
+	// Usage is the token usage of all steps.
+	Usage Usage
+}
+
+// Run runs the steps on input. On failure, the result holds the steps run so
+// far and the error names the failed step.
+func (p *Pipeline) Run(ctx context.Context, input any) (*PipelineResult, error) {
+	result := &PipelineResult{Output: input}
+	for _, s := range p.steps {
+		acc := &usageAccumulator{}
+		start := time.Now()
+		output, err := s.step(context.WithValue(ctx, usageAccumulatorKey{}, acc), input)
+		step := StepResult{Name: s.name, Output: output, Usage: acc.total(), Duration: time.Since(start)}
+		result.Steps = append(result.Steps, step)
+		result.Usage = result.Usage.Add(step.Usage)
+		recordStepUsage(ctx, step.Usage)
+		if err != nil {
+			return result, fmt.Errorf("pipeline step %s: %w", s.name, err)
+		}
+		input = output
+		result.Output = output
+	}
+	return result, nil
+}
+
+// Step returns p as a Step, so pipelines can be nested or used as branches.
+func (p *Pipeline) Step() Step {
+	return func(ctx context.Context, input any) (any, error) {
+		result, err := p.Run(ctx, input)
+		return result.Output, err
+	}
+}
+
+// usageAccumulatorKey is the context key of the usage of the running step.
+type usageAccumulatorKey struct{}
+
+// usageAccumulator sums the usage reported by a step, which may call models concurrently.
+type usageAccumulator struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// total returns the usage recorded so far.
+func (a *usageAccumulator) total() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.usage
+}
+
+// recordStepUsage adds usage to the step running in ctx, if any.
+func recordStepUsage(ctx context.Context, usage Usage) {
+	if acc, ok := ctx.Value(usageAccumulatorKey{}).(*usageAccumulator); ok {
+		acc.mu.Lock()
+		acc.usage = acc.usage.Add(usage)
+		acc.mu.Unlock()
+	}
+}
+
+// stepText formats a step input for a prompt: strings and fmt.Stringers as
+// text, other values as JSON.
+func stepText(input any) (string, error) {
+	switch v := input.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	data, err := json.Marshal(input)
+	return string(data), err
+}
+
+// PromptStep returns a Step that sends its input as the user message to model,
+// with instructions as the system prompt, and outputs the answer text.
+func PromptStep(model Model, instructions string, opts ...ChatOption) Step {
+	return func(ctx context.Context, input any) (any, error) {
+		text, err := stepText(input)
+		if err != nil {
+			return nil, err
+		}
+		all := append([]ChatOption{WithSystemPrompt(instructions)}, opts...)
+		resp, err := model.ChatCompletion(ctx, []Message{NewUserMessage(text)}, all...)
+		if resp != nil {
+			recordStepUsage(ctx, resp.Usage())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp.Answer().Content(), nil
+	}
+}
+
+// TemplateStep returns a Step that renders tmpl with its input as data, sends
+// the messages to model and outputs the answer text.
+func TemplateStep(model Model, tmpl *PromptTemplate, opts ...ChatOption) Step {
+	return func(ctx context.Context, input any) (any, error) {
+		messages, err := tmpl.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := model.ChatCompletion(ctx, messages, opts...)
+		if resp != nil {
+			recordStepUsage(ctx, resp.Usage())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp.Answer().Content(), nil
+	}
+}
+
+// ExtractStep returns a Step that asks model for a structured answer of type T
+// about its input (see Complete), with instructions as the system prompt, and
+// outputs the decoded T.
+func ExtractStep[T any](model Model, instructions string, opts ...ChatOption) Step {
+	return func(ctx context.Context, input any) (any, error) {
+		text, err := stepText(input)
+		if err != nil {
+			return nil, err
+		}
+		all := append([]ChatOption{WithSystemPrompt(instructions)}, opts...)
+		value, resp, err := Complete[T](ctx, model, []Message{NewUserMessage(text)}, all...)
+		if resp != nil {
+			recordStepUsage(ctx, resp.Usage())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// ParseStep returns a Step that decodes the first JSON value in its string
+// input into a T, ignoring code fences and surrounding prose. Failures are
+// reported as *OutputParseError.
+func ParseStep[T any]() Step {
+	return func(ctx context.Context, input any) (any, error) {
+		text, ok := input.(string)
+		if !ok {
+			return nil, fmt.Errorf("parse step: input is %T, not string", input)
+		}
+		var value T
+		if err := decodeJSONText(text, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// BranchStep returns a Step that runs then when cond holds for its input and
+// otherwise runs otherwise; a nil step passes the input through unchanged.
+func BranchStep(cond func(input any) bool, then, otherwise Step) Step {
+	return func(ctx context.Context, input any) (any, error) {
+		step := otherwise
+		if cond(input) {
+			step = then
+		}
+		if step == nil {
+			return input, nil
+		}
+		return step(ctx, input)
+	}
+}
@@ -0,0 +1,262 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// PoolStrategy selects the backend of a ModelPool for each request.
+type PoolStrategy int
+
+const (
+	// PoolRoundRobin cycles through the backends in order.
+	PoolRoundRobin PoolStrategy = iota
+	// PoolLeastLatency picks the backend with the lowest average latency. Backends
+	// without measurements are tried first; backend failures (see WithPoolHealth)
+	// count as slow requests (at least 10s), so a backend that keeps failing is not
+	// preferred.
+	PoolLeastLatency
+	// PoolWeighted picks backends at random in proportion to their weights (see WithPoolWeights).
+	PoolWeighted
+)
+
+const (
+	// defaultPoolMaxFailures is the number of consecutive failures that mark a backend unhealthy.
+	defaultPoolMaxFailures = 3
+	// defaultPoolCooldown is how long an unhealthy backend is skipped.
+	defaultPoolCooldown = 30 * time.Second
+	// latencyDecay is the weight of the newest sample in the latency average.
+	latencyDecay = 0.2
+	// poolFailureLatency is the least latency sample recorded for a failed request.
+	poolFailureLatency = 10 * time.Second
+)
+
+// PoolOption configures a ModelPool.
+type PoolOption func(p *ModelPool)
+
+// WithPoolWeights sets the relative weights of the backends, in order, for PoolWeighted.
+// Missing or non-positive weights count as 1.
+func WithPoolWeights(weights ...int) PoolOption {
+	return func(p *ModelPool) {
+		for i := range p.backends {
+			if i < len(weights) && weights[i] > 0 {
+				p.backends[i].weight = weights[i]
+			}
+		}
+	}
+}
+
+// WithPoolHealth marks a backend unhealthy after maxFailures consecutive failures
+// and skips it for cooldown (defaults 3 and 30s). Only errors for which IsRetryable
+// reports true, such as server errors, rate limits and timeouts, count as failures;
+// errors any backend would return, such as ErrInvalidRequest or
+// ErrContextLengthExceeded, and requests canceled by the caller's context do not.
+func WithPoolHealth(maxFailures int, cooldown time.Duration) PoolOption {
+	return func(p *ModelPool) {
+		p.maxFailures = maxFailures
+		p.cooldown = cooldown
+	}
+}
+
+// ModelPool is a Model that spreads requests over several backends serving the
+// same model, such as deployments in different regions or separate API keys.
+// A failed request is not retried on another backend; wrap the pool with WithRetry
+// to do so, as each attempt selects a backend afresh.
+type ModelPool struct {
+	strategy    PoolStrategy
+	maxFailures int
+	cooldown    time.Duration
+
+	mu       sync.Mutex
+	backends []*poolBackend
+	next     int
+}
+
+// poolBackend tracks one model of a pool.
+type poolBackend struct {
+	model  Model
+	weight int
+	// latency is the moving average of request durations; failures count as at
+	// least poolFailureLatency.
+	latency time.Duration
+	// measured reports whether latency holds a sample.
+	measured bool
+	// failures counts consecutive failures.
+	failures int
+	// downUntil is the end of the cooldown of an unhealthy backend.
+	downUntil time.Time
+	requests  int64
+	errors    int64
+}
+
+// NewModelPool creates a pool over models using strategy. It fails if models is empty.
+func NewModelPool(models []Model, strategy PoolStrategy, opts ...PoolOption) (*ModelPool, error) {
+	if len(models) == 0 {
+		return nil, errors.New("model pool: no models")
+	}
+	p := &ModelPool{
+		strategy:    strategy,
+		maxFailures: defaultPoolMaxFailures,
+		cooldown:    defaultPoolCooldown,
+	}
+	for _, model := range models {
+		p.backends = append(p.backends, &poolBackend{model: model, weight: 1})
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// MustNewModelPool is like NewModelPool but panics on error.
+func MustNewModelPool(models []Model, strategy PoolStrategy, opts ...PoolOption) *ModelPool {
+	p, err := NewModelPool(models, strategy, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Name implements Model and returns the name of the first backend.
+func (p *ModelPool) Name() string {
+	return p.backends[0].model.Name()
+}
+
+// Description implements Model and returns the description of the first backend.
+func (p *ModelPool) Description() string {
+	return p.backends[0].model.Description()
+}
+
+// ChatCompletion implements Model.
+func (p *ModelPool) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	b := p.pick()
+	start := time.Now()
+	resp, err := b.model.ChatCompletion(ctx, messages, opts...)
+	p.record(ctx, b, time.Since(start), err)
+	return resp, err
+}
+
+// ChatCompletionStream implements Model.
+func (p *ModelPool) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	b := p.pick()
+	start := time.Now()
+	resp, err := b.model.ChatCompletionStream(ctx, messages, opts...)
+	p.record(ctx, b, time.Since(start), err)
+	return resp, err
+}
+
+// PoolBackendStats describes a backend of a ModelPool.
+type PoolBackendStats struct {
+	// Name is the backend model name.
+	Name string
+	// Healthy is false while the backend is skipped after repeated failures.
+	Healthy bool
+	// Latency is the moving average duration of requests, failures counting as at least 10s.
+	Latency time.Duration
+	// Requests and Errors count the requests sent to the backend and those that
+	// failed, not counting requests canceled by the caller.
+	Requests int64
+	Errors   int64
+}
+
+// Stats returns the current state of each backend, in order.
+func (p *ModelPool) Stats() []PoolBackendStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	stats := make([]PoolBackendStats, len(p.backends))
+	for i, b := range p.backends {
+		stats[i] = PoolBackendStats{
+			Name:     b.model.Name(),
+			Healthy:  !now.Before(b.downUntil),
+			Latency:  b.latency,
+			Requests: b.requests,
+			Errors:   b.errors,
+		}
+	}
+	return stats
+}
+
+// pick selects the backend for a request among the healthy ones, or among all
+// of them when none is healthy.
+func (p *ModelPool) pick() *poolBackend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]*poolBackend, 0, len(p.backends))
+	for _, b := range p.backends {
+		if !now.Before(b.downUntil) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.backends
+	}
+
+	var chosen *poolBackend
+	switch p.strategy {
+	case PoolLeastLatency:
+		for _, b := range candidates {
+			if !b.measured {
+				chosen = b
+				break
+			}
+			if chosen == nil || b.latency < chosen.latency {
+				chosen = b
+			}
+		}
+	case PoolWeighted:
+		total := 0
+		for _, b := range candidates {
+			total += b.weight
+		}
+		n := rand.IntN(total)
+		for _, b := range candidates {
+			if n -= b.weight; n < 0 {
+				chosen = b
+				break
+			}
+		}
+	default:
+		chosen = candidates[p.next%len(candidates)]
+		p.next++
+	}
+	chosen.requests++
+	return chosen
+}
+
+// record updates the health and latency of b after a request made with ctx.
+func (p *ModelPool) record(ctx context.Context, b *poolBackend, elapsed time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// requests canceled by the caller say nothing about the backend
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		b.errors++
+		// nor do requests that every backend would reject
+		if !IsRetryable(err) {
+			return
+		}
+		elapsed = max(elapsed, poolFailureLatency)
+	}
+	if !b.measured {
+		b.latency, b.measured = elapsed, true
+	} else {
+		b.latency += time.Duration(latencyDecay * float64(elapsed-b.latency))
+	}
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	if b.failures++; p.maxFailures > 0 && b.failures >= p.maxFailures {
+		b.failures = 0
+		b.downUntil = time.Now().Add(p.cooldown)
+	}
+}
@@ -0,0 +1,59 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestModelPoolEmpty(t *testing.T) {
+	if _, err := NewModelPool(nil, PoolRoundRobin); err == nil {
+		t.Fatal("NewModelPool(nil) succeeded")
+	}
+}
+
+func TestModelPoolLeastLatencyAvoidsFailingBackend(t *testing.T) {
+	broken := failingModel{err: &HTTPError{StatusCode: http.StatusBadGateway}}
+	healthy := NewEchoModel(WithEchoName("healthy"))
+	pool := MustNewModelPool([]Model{broken, healthy}, PoolLeastLatency, WithPoolHealth(0, 0))
+
+	messages := []Message{NewUserMessage("hello")}
+	for i := 0; i < 5; i++ {
+		_, _ = pool.ChatCompletion(context.Background(), messages)
+	}
+	stats := pool.Stats()
+	if stats[0].Requests != 1 || stats[1].Requests != 4 {
+		t.Fatalf("requests = %d/%d, want the failing backend tried once", stats[0].Requests, stats[1].Requests)
+	}
+	if stats[0].Latency < poolFailureLatency {
+		t.Errorf("failing backend latency = %v, want at least %v", stats[0].Latency, poolFailureLatency)
+	}
+}
+
+func TestModelPoolHealthCountsBackendFailures(t *testing.T) {
+	messages := []Message{NewUserMessage("hello")}
+
+	invalid := failingModel{err: &ProviderError{Kind: ErrInvalidRequest, StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}}
+	pool := MustNewModelPool([]Model{invalid, NewEchoModel()}, PoolLeastLatency, WithPoolHealth(1, time.Minute))
+	_, _ = pool.ChatCompletion(context.Background(), messages)
+	if stats := pool.Stats()[0]; !stats.Healthy || stats.Errors != 1 || stats.Latency >= poolFailureLatency {
+		t.Errorf("after an invalid request: %+v, want a healthy backend without latency penalty", stats)
+	}
+
+	down := failingModel{err: &HTTPError{StatusCode: http.StatusServiceUnavailable}}
+	pool = MustNewModelPool([]Model{down, NewEchoModel()}, PoolRoundRobin, WithPoolHealth(1, time.Minute))
+	_, _ = pool.ChatCompletion(context.Background(), messages)
+	if pool.Stats()[0].Healthy {
+		t.Error("backend still healthy after a server error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool = MustNewModelPool([]Model{down}, PoolRoundRobin, WithPoolHealth(1, time.Minute))
+	_, _ = pool.ChatCompletion(ctx, messages)
+	if stats := pool.Stats()[0]; !stats.Healthy || stats.Errors != 0 {
+		t.Errorf("after a canceled request: %+v, want a healthy backend without errors", stats)
+	}
+}
@@ -0,0 +1,222 @@
+package openllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// PromptTemplate renders a sequence of messages from text/template sources, so
+// prompts with variables, conditionals and shared partials are written once
+// instead of assembled with fmt.Sprintf. Templates are parsed when created and
+// may be rendered concurrently.
+type PromptTemplate struct {
+	name string
+	// root holds the partials and one associated template per message.
+	root     *template.Template
+	messages []promptMessage
+	examples []PromptExample
+}
+
+// promptMessage is a message of a PromptTemplate.
+type promptMessage struct {
+	role string
+	// tmpl is the name of the associated template rendering the content.
+	tmpl string
+}
+
+// PromptExample is a few-shot example: an input and the answer expected for it.
+type PromptExample struct {
+	Input  string
+	Output string
+}
+
+// PromptOption configures a PromptTemplate.
+type PromptOption func(b *promptBuilder)
+
+// promptBuilder collects the options of a PromptTemplate before parsing.
+type promptBuilder struct {
+	messages []promptSource
+	partials []promptSource
+	examples []PromptExample
+}
+
+// promptSource is an unparsed message or partial.
+type promptSource struct {
+	// name is the role of a message or the name of a partial.
+	name string
+	text string
+}
+
+// WithPromptMessage appends a message of role (system, user or assistant) whose
+// content is rendered from text. Messages that render to blank text are left
+// out, so a whole message can be made conditional.
+func WithPromptMessage(role, text string) PromptOption {
+	return func(b *promptBuilder) {
+		b.messages = append(b.messages, promptSource{name: role, text: text})
+	}
+}
+
+// WithPromptPartial defines a partial that messages include with
+// {{template "name" .}}.
+func WithPromptPartial(name, text string) PromptOption {
+	return func(b *promptBuilder) {
+		b.partials = append(b.partials, promptSource{name: name, text: text})
+	}
+}
+
+// WithPromptExamples injects few-shot examples as user and assistant message
+// pairs after the leading system messages. Examples are used verbatim.
+func WithPromptExamples(examples ...PromptExample) PromptOption {
+	return func(b *promptBuilder) { b.examples = append(b.examples, examples...) }
+}
+
+// promptFuncs are the functions available to prompt templates besides the
+// text/template builtins.
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewPromptTemplate parses a prompt template. Besides the text/template
+// builtins, templates may use join, trim and json. Referencing a missing map
+// key fails when rendering rather than producing "<no value>".
+func NewPromptTemplate(name string, opts ...PromptOption) (*PromptTemplate, error) {
+	b := &promptBuilder{}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	p := &PromptTemplate{
+		name:     name,
+		root:     template.New(name).Funcs(promptFuncs).Option("missingkey=error"),
+		examples: b.examples,
+	}
+	for _, partial := range b.partials {
+		if _, err := p.root.New(partial.name).Parse(partial.text); err != nil {
+			return nil, fmt.Errorf("prompt %s: partial %s: %w", name, partial.name, err)
+		}
+	}
+	for i, msg := range b.messages {
+		switch msg.name {
+		case constants.RoleSystem, constants.RoleUser, constants.RoleAssistant:
+		default:
+			return nil, fmt.Errorf("prompt %s: message %d: unsupported role %q", name, i, msg.name)
+		}
+		tmpl := fmt.Sprintf("%s#%d", name, i)
+		if _, err := p.root.New(tmpl).Parse(msg.text); err != nil {
+			return nil, fmt.Errorf("prompt %s: message %d: %w", name, i, err)
+		}
+		p.messages = append(p.messages, promptMessage{role: msg.name, tmpl: tmpl})
+	}
+	return p, nil
+}
+
+// Name returns the template name.
+func (p *PromptTemplate) Name() string {
+	return p.name
+}
+
+// Render executes the template with data and returns the messages.
+func (p *PromptTemplate) Render(data any) ([]Message, error) {
+	messages := make([]Message, 0, len(p.messages)+2*len(p.examples))
+	examples := p.examples
+	for i, msg := range p.messages {
+		if len(examples) > 0 && msg.role != constants.RoleSystem {
+			messages = appendExamples(messages, examples)
+			examples = nil
+		}
+		var buf bytes.Buffer
+		if err := p.root.ExecuteTemplate(&buf, msg.tmpl, data); err != nil {
+			return nil, fmt.Errorf("prompt %s: message %d: %w", p.name, i, err)
+		}
+		content := strings.TrimSpace(buf.String())
+		if content == "" {
+			continue
+		}
+		switch msg.role {
+		case constants.RoleSystem:
+			messages = append(messages, NewSystemMessage(content))
+		case constants.RoleUser:
+			messages = append(messages, NewUserMessage(content))
+		default:
+			messages = append(messages, NewAssistantMessage(content))
+		}
+	}
+	return appendExamples(messages, examples), nil
+}
+
+// appendExamples appends examples as user and assistant message pairs.
+func appendExamples(messages []Message, examples []PromptExample) []Message {
+	for _, ex := range examples {
+		messages = append(messages, NewUserMessage(ex.Input), NewAssistantMessage(ex.Output))
+	}
+	return messages
+}
+
+// PromptRegistry holds named prompt templates, parsed once when registered,
+// and partials shared by all of them. It is safe for concurrent use.
+type PromptRegistry struct {
+	mu        sync.RWMutex
+	partials  []PromptOption
+	templates map[string]*PromptTemplate
+}
+
+// NewPromptRegistry creates an empty PromptRegistry.
+func NewPromptRegistry() *PromptRegistry {
+	return &PromptRegistry{templates: map[string]*PromptTemplate{}}
+}
+
+// AddPartial defines a partial for the templates registered after it.
+func (r *PromptRegistry) AddPartial(name, text string) error {
+	if _, err := template.New(name).Funcs(promptFuncs).Parse(text); err != nil {
+		return fmt.Errorf("prompt partial %s: %w", name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partials = append(r.partials, WithPromptPartial(name, text))
+	return nil
+}
+
+// Register parses a template with the registry's partials and stores it under
+// name, replacing any template of the same name.
+func (r *PromptRegistry) Register(name string, opts ...PromptOption) (*PromptTemplate, error) {
+	r.mu.RLock()
+	all := append(append([]PromptOption(nil), r.partials...), opts...)
+	r.mu.RUnlock()
+
+	p, err := NewPromptTemplate(name, all...)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[name] = p
+	return p, nil
+}
+
+// Lookup returns the template registered under name.
+func (r *PromptRegistry) Lookup(name string) (*PromptTemplate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.templates[name]
+	return p, ok
+}
+
+// Render renders the template registered under name with data.
+func (r *PromptRegistry) Render(name string, data any) ([]Message, error) {
+	p, ok := r.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("prompt %s: not registered", name)
+	}
+	return p.Render(data)
+}
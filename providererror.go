@@ -0,0 +1,168 @@
+package openllm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// normalizeProviderError wraps a provider SDK error in a *ProviderError of the
+// matching kind. Errors of no known kind, context errors and errors already
+// normalized are returned unchanged.
+func normalizeProviderError(provider string, err error) error {
+	var normalized *ProviderError
+	if err == nil || errors.As(err, &normalized) {
+		return err
+	}
+
+	var status int
+	var typ, message string
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var anthErr *anthropic.Error
+	switch {
+	case errors.As(err, &apiErr):
+		status, typ, message = apiErr.HTTPStatusCode, apiErr.Type, apiErr.Message
+		if code, ok := apiErr.Code.(string); ok && code != "" {
+			typ = code
+		}
+		if apiErr.InnerError != nil && apiErr.InnerError.Code != "" {
+			typ = apiErr.InnerError.Code
+		}
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+		typ, message = parseErrorBody(string(reqErr.Body))
+	case errors.As(err, &anthErr):
+		status = anthErr.StatusCode
+		typ, message = parseErrorBody(anthErr.RawJSON())
+	default:
+		// Errors reported mid-stream carry the error event as text
+		text := err.Error()
+		i := strings.IndexByte(text, '{')
+		if i < 0 {
+			return err
+		}
+		if typ, message = parseErrorBody(text[i:]); typ == "" {
+			return err
+		}
+	}
+
+	kind := errorKind(status, typ, message)
+	if kind == nil {
+		return err
+	}
+	retryAfter := RetryAfter(err)
+	if retryAfter == 0 && kind == ErrRateLimited {
+		retryAfter = retryAfterFromMessage(message)
+	}
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       kind,
+		StatusCode: status,
+		Type:       typ,
+		Message:    message,
+		RetryAfter: retryAfter,
+		Err:        err,
+	}
+}
+
+// openAIError normalizes an error of the OpenAI client, whose errors carry no
+// response headers, taking RetryAfter from the header recorded by headerTransport
+// in preference to the delay suggested by the message.
+func openAIError(err error, header http.Header) error {
+	err = normalizeProviderError(constants.ProviderOpenAI, err)
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		if after := parseRetryAfter(header); after > 0 {
+			providerErr.RetryAfter = after
+		}
+	}
+	return err
+}
+
+// parseErrorBody extracts the error type (or code) and message from a provider
+// error body, in the OpenAI ({"error": {"code", "type", "message"}}) or Anthropic
+// ({"type": "error", "error": {"type", "message"}}) shape, or a flat object.
+func parseErrorBody(body string) (typ, message string) {
+	type errorFields struct {
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+		Message string `json:"message"`
+	}
+	var parsed struct {
+		errorFields
+		Error *errorFields `json:"error"`
+	}
+	if json.Unmarshal([]byte(body), &parsed) != nil {
+		return "", body
+	}
+	fields := parsed.errorFields
+	if parsed.Error != nil {
+		fields = *parsed.Error
+	}
+	typ = fields.Type
+	if code, ok := fields.Code.(string); ok && code != "" {
+		typ = code
+	}
+	return typ, fields.Message
+}
+
+// errorKind classifies a provider failure from its HTTP status, error type or
+// code, and message. It returns nil for failures of no known kind, such as
+// internal server errors.
+func errorKind(status int, typ, message string) error {
+	lower := strings.ToLower(message)
+	switch {
+	case typ == "context_length_exceeded" || strings.Contains(lower, "maximum context length") ||
+		strings.Contains(lower, "prompt is too long") || strings.Contains(lower, "context window"):
+		return ErrContextLengthExceeded
+	case typ == "content_filter" || typ == "content_policy_violation" ||
+		strings.Contains(lower, "content management policy"):
+		return ErrContentFiltered
+	case typ == "insufficient_quota" || typ == "billing_hard_limit_reached" ||
+		strings.Contains(lower, "exceeded your current quota"):
+		return ErrQuotaExceeded
+	case status == http.StatusTooManyRequests || typ == "rate_limit_error" || typ == "rate_limit_exceeded":
+		return ErrRateLimited
+	case status == http.StatusUnauthorized || typ == "authentication_error" || typ == "invalid_api_key":
+		return ErrAuthentication
+	case status == http.StatusForbidden || typ == "permission_error":
+		return ErrPermissionDenied
+	case status == http.StatusServiceUnavailable || status == 529 || typ == "overloaded_error":
+		return ErrOverloaded
+	case status == http.StatusBadRequest || status == http.StatusNotFound ||
+		status == http.StatusRequestEntityTooLarge || status == http.StatusUnprocessableEntity ||
+		typ == "invalid_request_error" || typ == "not_found_error" || typ == "request_too_large":
+		return ErrInvalidRequest
+	}
+	return nil
+}
+
+// retryInPattern matches the delay in OpenAI rate limit messages, such as
+// "Please try again in 1.898s" or "try again in 20ms".
+var retryInPattern = regexp.MustCompile(`try again in (\d+(?:\.\d+)?)(ms|s)\b`)
+
+// retryAfterFromMessage returns the delay suggested by a rate limit message, or zero.
+func retryAfterFromMessage(message string) time.Duration {
+	m := retryInPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+	if m[2] == "ms" {
+		return time.Duration(value * float64(time.Millisecond))
+	}
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,47 @@
+package openllm
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		status  int
+		typ     string
+		message string
+		want    error
+	}{
+		{http.StatusTooManyRequests, "rate_limit_exceeded", "Rate limit reached. Please try again in 20ms.", ErrRateLimited},
+		{http.StatusTooManyRequests, "insufficient_quota", "You exceeded your current quota.", ErrQuotaExceeded},
+		{http.StatusUnauthorized, "authentication_error", "invalid x-api-key", ErrAuthentication},
+		{http.StatusForbidden, "permission_error", "not allowed to use this model", ErrPermissionDenied},
+		{http.StatusForbidden, "", "forbidden", ErrPermissionDenied},
+		{529, "overloaded_error", "Overloaded", ErrOverloaded},
+		{http.StatusBadRequest, "invalid_request_error", "prompt is too long: 210000 tokens", ErrContextLengthExceeded},
+		{http.StatusInternalServerError, "", "internal error", nil},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.status, tt.typ, tt.message); got != tt.want {
+			t.Errorf("errorKind(%d, %q) = %v, want %v", tt.status, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestQuotaNotRetryable(t *testing.T) {
+	quota := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Code: "insufficient_quota", Message: "You exceeded your current quota."}
+	if IsRetryable(quota) {
+		t.Error("IsRetryable(insufficient_quota) = true")
+	}
+	if err := normalizeProviderError("openai", quota); !errors.Is(err, ErrQuotaExceeded) || IsRetryable(err) {
+		t.Errorf("normalized quota error = %v, retryable %v", err, IsRetryable(err))
+	}
+
+	limited := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Code: "rate_limit_exceeded", Message: "Rate limit reached."}
+	if !IsRetryable(limited) || !IsRetryable(normalizeProviderError("openai", limited)) {
+		t.Error("IsRetryable(rate_limit_exceeded) = false")
+	}
+}
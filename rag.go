@@ -0,0 +1,170 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// defaultRAGTopK is the number of documents a RAGChain retrieves by default.
+const defaultRAGTopK = 4
+
+// defaultRAGInstructions introduces the retrieved sources in the system prompt.
+const defaultRAGInstructions = "Answer using the sources below. Cite the sources you use by their number " +
+	"in square brackets, e.g. [1]. If the sources do not contain the answer, say so."
+
+// RAGChain answers questions from documents in a VectorStore: it embeds the last
+// user message, retrieves the closest documents, adds them as numbered sources
+// to the system prompt, calls the model and reports which sources the answer cites.
+type RAGChain struct {
+	model    Model
+	embedder Embedder
+	store    VectorStore
+	// topK is the number of documents retrieved per question.
+	topK int
+	// minScore drops documents less similar to the question.
+	minScore float64
+	// instructions precede the sources in the system prompt.
+	instructions string
+}
+
+// RAGOption configures a RAGChain.
+type RAGOption func(c *RAGChain)
+
+// WithRetrievalTopK sets the number of documents retrieved per question (default 4).
+func WithRetrievalTopK(k int) RAGOption {
+	return func(c *RAGChain) { c.topK = k }
+}
+
+// WithRetrievalMinScore drops retrieved documents scoring below score.
+func WithRetrievalMinScore(score float64) RAGOption {
+	return func(c *RAGChain) { c.minScore = score }
+}
+
+// WithRAGInstructions replaces the instructions that precede the sources in the
+// system prompt. They should ask for citations as bracketed source numbers for
+// RAGResult.Cited to be filled.
+func WithRAGInstructions(instructions string) RAGOption {
+	return func(c *RAGChain) { c.instructions = instructions }
+}
+
+// NewRAGChain creates a RAGChain answering with model from documents in store,
+// which must be indexed with embedder (see IndexDocuments).
+func NewRAGChain(model Model, embedder Embedder, store VectorStore, opts ...RAGOption) *RAGChain {
+	c := &RAGChain{
+		model:        model,
+		embedder:     embedder,
+		store:        store,
+		topK:         defaultRAGTopK,
+		instructions: defaultRAGInstructions,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// RAGResult is the outcome of RAGChain.Run.
+type RAGResult struct {
+	// Response is the model response.
+	Response Response
+	// Sources are the retrieved documents, numbered from 1 in the prompt.
+	Sources []ScoredDocument
+	// Cited are the sources the answer cites, in order of first citation.
+	Cited []ScoredDocument
+	// RetrievalUsage is the tokens spent embedding the question.
+	RetrievalUsage Usage
+}
+
+// Run answers the last user message of messages. The model is called with
+// ChatCompletionStream when opts include a stream watcher, ChatCompletion otherwise.
+func (c *RAGChain) Run(ctx context.Context, messages []Message, opts ...ChatOption) (*RAGResult, error) {
+	question := lastUserText(messages)
+	if question == "" {
+		return nil, errors.New("rag: no user message to answer")
+	}
+	vectors, usage, err := c.embedder.Embed(ctx, []string{question}, WithInputType(constants.EmbedInputQuery))
+	if err != nil {
+		return nil, fmt.Errorf("rag: embed question: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, errors.New("rag: embedder returned no vector")
+	}
+	docs, err := c.store.Query(ctx, vectors[0], c.topK)
+	if err != nil {
+		return nil, fmt.Errorf("rag: query store: %w", err)
+	}
+	result := &RAGResult{RetrievalUsage: usage}
+	for _, doc := range docs {
+		if doc.Score >= c.minScore {
+			result.Sources = append(result.Sources, doc)
+		}
+	}
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	opts = append(opts[:len(opts):len(opts)], WithSystemPrompt(c.prompt(result.Sources)))
+	if options.watcher != nil {
+		result.Response, err = c.model.ChatCompletionStream(ctx, messages, opts...)
+	} else {
+		result.Response, err = c.model.ChatCompletion(ctx, messages, opts...)
+	}
+	if result.Response != nil && result.Response.Answer() != nil {
+		for _, n := range citedSources(result.Response.Answer().Content(), len(result.Sources)) {
+			result.Cited = append(result.Cited, result.Sources[n-1])
+		}
+	}
+	return result, err
+}
+
+// prompt renders the instructions and numbered sources.
+func (c *RAGChain) prompt(sources []ScoredDocument) string {
+	var b strings.Builder
+	b.WriteString(c.instructions)
+	b.WriteString("\n\nSources:")
+	if len(sources) == 0 {
+		b.WriteString("\n(none found)")
+	}
+	for i, doc := range sources {
+		fmt.Fprintf(&b, "\n\n[%d] %s", i+1, doc.Text)
+	}
+	return b.String()
+}
+
+// citationPattern matches bracketed source numbers such as [1] or [1, 3].
+var citationPattern = regexp.MustCompile(`\[\s*\d+(?:\s*,\s*\d+)*\s*\]`)
+
+// citedSources returns the source numbers between 1 and n cited in text, in
+// order of first citation.
+func citedSources(text string, n int) []int {
+	var cited []int
+	seen := map[int]bool{}
+	for _, match := range citationPattern.FindAllString(text, -1) {
+		for _, field := range strings.Split(strings.Trim(match, "[] "), ",") {
+			num, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || num < 1 || num > n || seen[num] {
+				continue
+			}
+			seen[num] = true
+			cited = append(cited, num)
+		}
+	}
+	return cited
+}
+
+// lastUserText returns the content of the last user message, or "".
+func lastUserText(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role() == constants.RoleUser {
+			return strings.TrimSpace(messages[i].Content())
+		}
+	}
+	return ""
+}
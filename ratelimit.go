@@ -0,0 +1,204 @@
+package openllm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit configures WithRateLimit. A zero limit is not enforced.
+type RateLimit struct {
+	// RequestsPerMinute bounds the number of requests started per minute.
+	RequestsPerMinute int
+	// TokensPerMinute bounds the input plus output tokens per minute.
+	TokensPerMinute int
+	// EstimateTokens estimates the tokens a request will use before it is sent;
+	// nil uses EstimateTokens. The estimate is corrected with the actual usage
+	// once the response arrives.
+	EstimateTokens func(messages []Message, opts *ChatOptions) int
+}
+
+// WithRateLimit returns a Middleware that keeps requests within per-minute request
+// and token budgets, waiting (or failing when ctx ends) instead of tripping provider
+// 429s. Budgets refill continuously and allow bursts of up to one minute's worth.
+// All models wrapped by the same returned Middleware share its budgets, so one
+// value can enforce a provider-wide quota.
+func WithRateLimit(limit RateLimit) Middleware {
+	if limit.EstimateTokens == nil {
+		limit.EstimateTokens = EstimateTokens
+	}
+	l := &rateLimiter{
+		requests: newTokenBucket(limit.RequestsPerMinute),
+		tokens:   newTokenBucket(limit.TokensPerMinute),
+		estimate: limit.EstimateTokens,
+	}
+	return func(next Model) Model {
+		return &rateLimitModel{Model: next, limiter: l}
+	}
+}
+
+// EstimateTokens roughly estimates the tokens of a request: about four characters
+// per token for the prompts and messages, a small overhead per message, plus the
+// maximum output tokens when set.
+func EstimateTokens(messages []Message, opts *ChatOptions) int {
+	chars, n := 0, 0
+	for _, prompt := range opts.prompts {
+		chars += len(prompt)
+		n++
+	}
+	for _, message := range messages {
+		chars += len(message.Content()) + len(message.Reasoning())
+		if rich, ok := message.(RichMessage); ok {
+			for _, tc := range rich.ToolCalls() {
+				chars += len(tc.Function().Name()) + len(tc.Function().Arguments())
+			}
+		}
+		n++
+	}
+	tokens := (chars+3)/4 + 4*n
+	if opts.maxTokens != nil {
+		tokens += *opts.maxTokens
+	}
+	return tokens
+}
+
+// rateLimitModel is the Model returned by WithRateLimit.
+type rateLimitModel struct {
+	Model
+	limiter *rateLimiter
+}
+
+// Unwrap returns the wrapped model.
+func (m *rateLimitModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *rateLimitModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *rateLimitModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do waits for budget, runs request and settles the token estimate against the usage.
+func (m *rateLimitModel) do(ctx context.Context, messages []Message, opts []ChatOption, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	estimate := 0
+	if m.limiter.tokens != nil {
+		estimate = m.limiter.estimate(messages, options)
+	}
+	if err := m.limiter.wait(ctx, estimate); err != nil {
+		return nil, err
+	}
+	resp, err := request()
+	if resp != nil && m.limiter.tokens != nil {
+		if used := resp.Usage().TotalTokens; used > 0 {
+			m.limiter.settle(estimate - used)
+		}
+	}
+	return resp, err
+}
+
+// rateLimiter holds the budgets shared by the models of one WithRateLimit.
+type rateLimiter struct {
+	mu sync.Mutex
+	// requests and tokens are nil when the respective limit is not enforced.
+	requests *tokenBucket
+	tokens   *tokenBucket
+	estimate func(messages []Message, opts *ChatOptions) int
+}
+
+// wait blocks until one request and n tokens are available and takes them.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		delay := max(l.requests.delay(now, 1), l.tokens.delay(now, float64(n)))
+		if delay == 0 {
+			l.requests.take(1)
+			l.tokens.take(float64(n))
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// settle returns the difference between estimated and used tokens to the budget.
+func (l *rateLimiter) settle(delta int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.adjust(float64(delta))
+}
+
+// tokenBucket is a continuously refilling budget of one minute's capacity.
+// Methods on a nil bucket behave as an unlimited budget.
+type tokenBucket struct {
+	capacity float64
+	// available may become negative when actual usage exceeds the estimate.
+	available float64
+	// rate is the refill rate per second.
+	rate float64
+	last time.Time
+}
+
+// newTokenBucket returns a full bucket for perMinute, or nil when it is not positive.
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		rate:      float64(perMinute) / 60,
+		last:      time.Now(),
+	}
+}
+
+// delay refills the bucket and returns how long to wait until n is available.
+// Requests larger than the capacity wait for a full bucket.
+func (b *tokenBucket) delay(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.available = math.Min(b.capacity, b.available+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	n = math.Min(n, b.capacity)
+	if b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.rate * float64(time.Second))
+}
+
+// take removes n from the bucket.
+func (b *tokenBucket) take(n float64) {
+	if b != nil {
+		b.available -= n
+	}
+}
+
+// adjust returns delta to the bucket (negative to charge more).
+func (b *tokenBucket) adjust(delta float64) {
+	if b != nil {
+		b.available = math.Min(b.capacity, b.available+delta)
+	}
+}
@@ -0,0 +1,84 @@
+package openllm
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitInfo is the rate-limit state reported by the provider's response
+// headers. Counts are -1 and reset times zero when the provider did not report them.
+type RateLimitInfo struct {
+	LimitRequests     int       `json:"limit_requests"`
+	RemainingRequests int       `json:"remaining_requests"`
+	ResetRequests     time.Time `json:"reset_requests"`
+	LimitTokens       int       `json:"limit_tokens"`
+	RemainingTokens   int       `json:"remaining_tokens"`
+	ResetTokens       time.Time `json:"reset_tokens"`
+	// (Claude) separate input and output token limits.
+	LimitInputTokens      int `json:"limit_input_tokens,omitempty"`
+	RemainingInputTokens  int `json:"remaining_input_tokens,omitempty"`
+	LimitOutputTokens     int `json:"limit_output_tokens,omitempty"`
+	RemainingOutputTokens int `json:"remaining_output_tokens,omitempty"`
+}
+
+// parseOpenAIRateLimit reads the x-ratelimit-* headers, whose reset values are
+// durations such as "6m0s"; it returns nil when there are none.
+func parseOpenAIRateLimit(header http.Header) *RateLimitInfo {
+	if header.Get("X-Ratelimit-Limit-Requests") == "" && header.Get("X-Ratelimit-Limit-Tokens") == "" {
+		return nil
+	}
+	now := time.Now()
+	reset := func(name string) time.Time {
+		if d, err := time.ParseDuration(header.Get(name)); err == nil {
+			return now.Add(d)
+		}
+		return time.Time{}
+	}
+	return &RateLimitInfo{
+		LimitRequests:     headerInt(header, "X-Ratelimit-Limit-Requests"),
+		RemainingRequests: headerInt(header, "X-Ratelimit-Remaining-Requests"),
+		ResetRequests:     reset("X-Ratelimit-Reset-Requests"),
+		LimitTokens:       headerInt(header, "X-Ratelimit-Limit-Tokens"),
+		RemainingTokens:   headerInt(header, "X-Ratelimit-Remaining-Tokens"),
+		ResetTokens:       reset("X-Ratelimit-Reset-Tokens"),
+	}
+}
+
+// parseAnthropicRateLimit reads the anthropic-ratelimit-* headers, whose reset
+// values are RFC 3339 times; it returns nil when there are none.
+func parseAnthropicRateLimit(header http.Header) *RateLimitInfo {
+	if header.Get("Anthropic-Ratelimit-Requests-Limit") == "" && header.Get("Anthropic-Ratelimit-Tokens-Limit") == "" {
+		return nil
+	}
+	reset := func(name string) time.Time {
+		t, _ := time.Parse(time.RFC3339, header.Get(name))
+		return t
+	}
+	info := &RateLimitInfo{
+		LimitRequests:     headerInt(header, "Anthropic-Ratelimit-Requests-Limit"),
+		RemainingRequests: headerInt(header, "Anthropic-Ratelimit-Requests-Remaining"),
+		ResetRequests:     reset("Anthropic-Ratelimit-Requests-Reset"),
+		LimitTokens:       headerInt(header, "Anthropic-Ratelimit-Tokens-Limit"),
+		RemainingTokens:   headerInt(header, "Anthropic-Ratelimit-Tokens-Remaining"),
+		ResetTokens:       reset("Anthropic-Ratelimit-Tokens-Reset"),
+	}
+	if header.Get("Anthropic-Ratelimit-Input-Tokens-Limit") != "" {
+		info.LimitInputTokens = headerInt(header, "Anthropic-Ratelimit-Input-Tokens-Limit")
+		info.RemainingInputTokens = headerInt(header, "Anthropic-Ratelimit-Input-Tokens-Remaining")
+	}
+	if header.Get("Anthropic-Ratelimit-Output-Tokens-Limit") != "" {
+		info.LimitOutputTokens = headerInt(header, "Anthropic-Ratelimit-Output-Tokens-Limit")
+		info.RemainingOutputTokens = headerInt(header, "Anthropic-Ratelimit-Output-Tokens-Remaining")
+	}
+	return info
+}
+
+// headerInt parses an integer header, or returns -1.
+func headerInt(header http.Header, name string) int {
+	n, err := strconv.Atoi(header.Get(name))
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package openllm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redactor masks sensitive text, such as e-mail addresses or card numbers,
+// before it leaves the process. Implementations must be safe for concurrent use.
+type Redactor interface {
+	Redact(text string) string
+}
+
+// RedactorFunc adapts an ordinary function to the Redactor interface.
+type RedactorFunc func(text string) string
+
+// Redact implements Redactor.
+func (f RedactorFunc) Redact(text string) string {
+	return f(text)
+}
+
+// ChainRedactors returns a Redactor that applies redactors in order.
+func ChainRedactors(redactors ...Redactor) Redactor {
+	return RedactorFunc(func(text string) string {
+		for _, r := range redactors {
+			text = r.Redact(text)
+		}
+		return text
+	})
+}
+
+// NewPatternRedactor returns a Redactor that replaces every match of pattern with
+// replacement. Replacement may refer to submatches as in regexp.ReplaceAllString.
+func NewPatternRedactor(pattern *regexp.Regexp, replacement string) Redactor {
+	return RedactorFunc(func(text string) string {
+		return pattern.ReplaceAllString(text, replacement)
+	})
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+var (
+	// RedactEmails replaces e-mail addresses with "[EMAIL]".
+	RedactEmails Redactor = NewPatternRedactor(emailPattern, "[EMAIL]")
+
+	// RedactCardNumbers replaces payment card numbers (13 to 19 digits, optionally
+	// separated by spaces or dashes, passing the Luhn check) with "[CARD]".
+	RedactCardNumbers Redactor = RedactorFunc(func(text string) string {
+		return cardPattern.ReplaceAllStringFunc(text, func(match string) string {
+			if !luhnValid(match) {
+				return match
+			}
+			return "[CARD]"
+		})
+	})
+)
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// WithRedactor redacts the system prompts and the text of every message with the
+// given redactors, in order, before the request is sent. The caller's messages are
+// not modified. Tool-call arguments, images and documents are sent unchanged.
+func WithRedactor(redactors ...Redactor) ChatOption {
+	return func(opts *ChatOptions) { opts.redactors = append(opts.redactors, redactors...) }
+}
+
+// redact returns the system prompts and messages as they should be sent.
+func (opts *ChatOptions) redact(messages []Message) ([]string, []Message) {
+	if len(opts.redactors) == 0 {
+		return opts.prompts, messages
+	}
+	r := ChainRedactors(opts.redactors...)
+	prompts := make([]string, len(opts.prompts))
+	for i, prompt := range opts.prompts {
+		prompts[i] = r.Redact(prompt)
+	}
+	return prompts, RedactMessages(r, messages)
+}
+
+// RedactMessages returns copies of messages with their text passed through r,
+// e.g. to mask a transcript before it is logged. The originals are not modified.
+func RedactMessages(r Redactor, messages []Message) []Message {
+	redacted := make([]Message, len(messages))
+	for i, message := range messages {
+		redacted[i] = RedactMessage(r, message)
+	}
+	return redacted
+}
+
+// RedactMessage returns a copy of message with its text content, refusal and
+// reasoning passed through r.
+func RedactMessage(r Redactor, message Message) Message {
+	src := asLLMMessage(message)
+	msg := *src
+	msg.content = make([]ContentPart, len(src.content))
+	for i, part := range src.content {
+		if part.Text != "" {
+			part.Text = r.Redact(part.Text)
+		}
+		msg.content[i] = part
+	}
+	msg.reasoning = redactNonEmpty(r, src.reasoning)
+	msg.refusal = redactNonEmpty(r, src.refusal)
+	return &msg
+}
+
+// redactNonEmpty redacts text unless it is blank.
+func redactNonEmpty(r Redactor, text string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	return r.Redact(text)
+}
@@ -0,0 +1,112 @@
+package openllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ArgumentRepairPolicy controls what a Runner does with tool-call arguments
+// that are not valid JSON (see WithArgumentRepair).
+type ArgumentRepairPolicy int
+
+const (
+	// RepairReask repairs malformed arguments and, when that fails, answers the
+	// call with an error tool result asking the model to call the tool again.
+	RepairReask ArgumentRepairPolicy = iota + 1
+	// RepairFail repairs malformed arguments and aborts the run with an
+	// *ArgumentsError when that fails.
+	RepairFail
+)
+
+// RepairArguments fixes common defects in model-generated JSON: Markdown code
+// fences, trailing commas, single-quoted strings, raw control characters such as
+// newlines inside strings, and Python literals (True, False, None).
+// It reports whether the result is valid JSON; valid input is returned unchanged.
+func RepairArguments(args string) (string, bool) {
+	if json.Valid([]byte(args)) {
+		return args, true
+	}
+
+	s := strings.TrimSpace(args)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimPrefix(s, "json")
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+
+	var out strings.Builder
+	var quote byte // current string delimiter, 0 outside strings
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && i+1 < len(s):
+				i++
+				if s[i] == '\'' {
+					// \' is not a JSON escape
+					out.WriteByte('\'')
+				} else {
+					out.WriteByte('\\')
+					out.WriteByte(s[i])
+				}
+			case c == quote:
+				out.WriteByte('"')
+				quote = 0
+			case c == '"':
+				out.WriteString(`\"`)
+			case c == '\n':
+				out.WriteString(`\n`)
+			case c == '\r':
+				out.WriteString(`\r`)
+			case c == '\t':
+				out.WriteString(`\t`)
+			case c < 0x20:
+				fmt.Fprintf(&out, `\u%04x`, c)
+			default:
+				out.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			quote = c
+			out.WriteByte('"')
+		case ',':
+			j := i + 1
+			for j < len(s) && strings.IndexByte(" \t\r\n", s[j]) >= 0 {
+				j++
+			}
+			if j < len(s) && (s[j] == '}' || s[j] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			if lit, n := pythonLiteral(s[i:]); n > 0 {
+				out.WriteString(lit)
+				i += n - 1
+				continue
+			}
+			out.WriteByte(c)
+		}
+	}
+
+	repaired := out.String()
+	return repaired, json.Valid([]byte(repaired))
+}
+
+// pythonLiteral maps a leading True/False/None to its JSON literal and reports its length.
+func pythonLiteral(s string) (string, int) {
+	for word, lit := range map[string]string{"True": "true", "False": "false", "None": "null"} {
+		if strings.HasPrefix(s, word) && (len(s) == len(word) || !isIdentByte(s[len(word)])) {
+			return lit, len(word)
+		}
+	}
+	return "", 0
+}
+
+// isIdentByte reports whether c may continue an identifier.
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
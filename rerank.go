@@ -0,0 +1,59 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// RerankOption represents a functional option to configure a single rerank request.
+type RerankOption func(*RerankOptions)
+
+// RerankOptions holds per-request configuration for Reranker.Rerank.
+// Fields are intentionally unexported; use With* helpers to set them.
+type RerankOptions struct {
+	// topN limits the results to the most relevant documents; zero returns all.
+	topN int
+}
+
+// WithTopN returns only the n most relevant documents.
+func WithTopN(n int) RerankOption {
+	return func(opts *RerankOptions) { opts.topN = n }
+}
+
+// RerankResult is the relevance of one document to a query.
+type RerankResult struct {
+	// Index is the position of the document in the input.
+	Index int
+	// Document is the document text.
+	Document string
+	// Score is the relevance reported by the model, higher is more relevant.
+	// Scores are comparable within one response only.
+	Score float64
+}
+
+// Reranker orders documents by relevance to a query, typically to refine the
+// candidates of an embedding search in a retrieval pipeline.
+type Reranker interface {
+	// Rerank returns the documents from most to least relevant and the tokens used.
+	Rerank(ctx context.Context, query string, documents []string, opts ...RerankOption) ([]RerankResult, Usage, error)
+}
+
+// rerankScore is a provider result: a document index and its relevance.
+type rerankScore struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+// rerankResults resolves provider scores against documents, sorted by descending score.
+func rerankResults(documents []string, scores []rerankScore) ([]RerankResult, error) {
+	results := make([]RerankResult, 0, len(scores))
+	for _, score := range scores {
+		if score.Index < 0 || score.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank returned index %d for %d documents", score.Index, len(documents))
+		}
+		results = append(results, RerankResult{Index: score.Index, Document: documents[score.Index], Score: score.RelevanceScore})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
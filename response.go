@@ -1,9 +1,17 @@
 package openllm
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Response wraps the final assistant message and any tool calls produced by the model.
-// Both blocking and streaming APIs return a Response upon completion.
+// Both blocking and streaming APIs return a Response upon completion. Responses are
+// immutable once returned and safe for concurrent use, as are their messages and
+// tool calls.
 type Response interface {
 	// Answer returns the final assistant message after generation finishes.
 	Answer() Message
@@ -12,7 +20,8 @@ type Response interface {
 	// Usage returns the token usage statistics.
 	// Notes:
 	// - Blocking requests usually provide complete Usage (input/output tokens and cache-related metrics).
-	// - Streaming requests in current SDKs typically do not expose Usage; usually empty or partial.
+	// - Streaming requests report Usage when the provider emits it (OpenAI final usage chunk,
+	//   Anthropic message_start/message_delta events); see UsageWatcher for live updates.
 	Usage() Usage
 	// Meta returns the request metadata (provider, model, request ID, etc.).
 	Meta() Meta
@@ -20,6 +29,33 @@ type Response interface {
 	Duration() time.Duration
 }
 
+// LogProbsResponse is implemented by responses that carry the log probabilities
+// of their answer tokens. Responses of this package implement it; see LogProbs.
+type LogProbsResponse interface {
+	Response
+	// LogProbs returns the log probabilities of the answer tokens, or nil.
+	LogProbs() []TokenLogProb
+}
+
+// LogProbs returns the log probabilities of the answer tokens of resp when they
+// were requested with WithLogProbs and the provider returns them; otherwise nil.
+func LogProbs(resp Response) []TokenLogProb {
+	if r, ok := resp.(LogProbsResponse); ok {
+		return r.LogProbs()
+	}
+	return nil
+}
+
+// TokenLogProb is the log probability of a generated token.
+type TokenLogProb struct {
+	// Token is the token text.
+	Token string `json:"token"`
+	// LogProb is the natural logarithm of the token's probability.
+	LogProb float64 `json:"logprob"`
+	// TopLogProbs lists the most likely alternatives at this position.
+	TopLogProbs []TokenLogProb `json:"top_logprobs,omitempty"`
+}
+
 // response is the concrete implementation of Response.
 type response struct {
 	// answer is the final assistant message constructed from the model output.
@@ -32,6 +68,8 @@ type response struct {
 	meta Meta
 	// duration captures the elapsed time from request start to completion.
 	duration time.Duration
+	// logprobs holds the token log probabilities, if requested.
+	logprobs []TokenLogProb
 }
 
 // Answer implements Response by returning the final assistant message.
@@ -40,8 +78,9 @@ func (resp *response) Answer() Message {
 }
 
 // ToolCalls implements Response by returning the collected tool calls.
+// The returned slice is a copy.
 func (resp *response) ToolCalls() []ToolCall {
-	return resp.tcalls
+	return slices.Clone(resp.tcalls)
 }
 
 // Usage implements Response.
@@ -59,34 +98,303 @@ func (resp *response) Duration() time.Duration {
 	return resp.duration
 }
 
+// LogProbs implements LogProbsResponse. The returned slice is a copy.
+func (resp *response) LogProbs() []TokenLogProb {
+	return slices.Clone(resp.logprobs)
+}
+
+// IsTruncated reports whether the answer of resp was cut off by the output token
+// limit or the context window, i.e. Meta().FinishReason is FinishLength.
+func IsTruncated(resp Response) bool {
+	return resp != nil && resp.Meta().FinishReason == FinishLength
+}
+
+// Refusal returns the model's explanation when it refused to answer, in which
+// case Meta().FinishReason is FinishRefusal; otherwise "". The explanation is the
+// refusal text reported by the provider (OpenAI), or the answer text when the
+// model stopped to refuse (Anthropic).
+func Refusal(resp Response) string {
+	if resp == nil {
+		return ""
+	}
+	answer, meta := resp.Answer(), resp.Meta()
+	if rich, ok := answer.(RichMessage); ok && rich.Refusal() != "" {
+		return rich.Refusal()
+	}
+	if meta.FinishReason == FinishRefusal && answer != nil {
+		return answer.Content()
+	}
+	return ""
+}
+
+// DecodeJSON unmarshals the first JSON object or array in the answer text of resp
+// into v, ignoring Markdown code fences and surrounding prose. Failures, including
+// a response without an answer, are reported as *OutputParseError.
+func DecodeJSON(resp Response, v any) error {
+	if resp == nil || resp.Answer() == nil {
+		return &OutputParseError{Err: errors.New("no answer")}
+	}
+	return decodeJSONText(resp.Answer().Content(), v)
+}
+
+// decodeJSONText unmarshals the first JSON value found in text into v.
+func decodeJSONText(text string, v any) error {
+	raw, ok := ExtractJSON(text)
+	if !ok {
+		err := errors.New("no JSON object or array found")
+		if trimmed := strings.TrimSpace(text); trimmed != "" {
+			// Report the syntax error of text that looks like JSON
+			if c := trimmed[0]; c == '{' || c == '[' {
+				err = json.Unmarshal([]byte(trimmed), new(json.RawMessage))
+			}
+		}
+		return &OutputParseError{Text: text, Err: err}
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return &OutputParseError{Text: text, Err: err}
+	}
+	return nil
+}
+
 // Usage captures token and cache-related consumption metrics.
 type Usage struct {
 	// number of input tokens (system, history, and user messages).
-	InputTokens int
+	InputTokens int `json:"input_tokens"`
 	// number of output tokens generated by the model.
-	OutputTokens int
+	OutputTokens int `json:"output_tokens"`
 	// sum of input and output tokens.
-	TotalTokens int
+	TotalTokens int `json:"total_tokens"`
 	// (OpenAI) tokens used for internal chain-of-thought processing before final answer.
-	ReasoningTokens int
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
 	// (OpenAI) total input tokens that were retrieved from the server-side cache.
-	CachedTokens int
+	CachedTokens int `json:"cached_tokens,omitempty"`
 	// (Claude) input tokens charged for prompt cache creation (higher price).
-	CacheCreationInputTokens int
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
 	// (Claude) input tokens charged when reading from prompt cache (discounted).
-	CacheReadInputTokens int
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+	// price of the tokens in USD, when the model is in the pricing table (see SetPrice).
+	CostUSD *Cost `json:"cost_usd,omitempty"`
+}
+
+// Add returns the element-wise sum of u and other. The cost is summed when
+// either side has one.
+func (u Usage) Add(other Usage) Usage {
+	sum := Usage{
+		InputTokens:              u.InputTokens + other.InputTokens,
+		OutputTokens:             u.OutputTokens + other.OutputTokens,
+		TotalTokens:              u.TotalTokens + other.TotalTokens,
+		ReasoningTokens:          u.ReasoningTokens + other.ReasoningTokens,
+		CachedTokens:             u.CachedTokens + other.CachedTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens + other.CacheReadInputTokens,
+	}
+	if u.CostUSD != nil || other.CostUSD != nil {
+		var cost Cost
+		if u.CostUSD != nil {
+			cost = *u.CostUSD
+		}
+		if other.CostUSD != nil {
+			cost = cost.Add(*other.CostUSD)
+		}
+		sum.CostUSD = &cost
+	}
+	return sum
 }
 
 // Meta contains request metadata:
 type Meta struct {
 	// backend provider (e.g., openai, anthropic).
-	Provider string
+	Provider string `json:"provider"`
 	// model name.
-	Model string
+	Model string `json:"model"`
 	// request ID (useful for troubleshooting/auditing).
-	RequestID string
+	RequestID string `json:"request_id,omitempty"`
 	// (OpenAI) server fingerprint to distinguish backend versions.
-	SystemFingerprint string
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
 	// reason the generation stopped (e.g., stop_sequence, max_tokens, tool_use).
-	StopReason string
+	StopReason string `json:"stop_reason,omitempty"`
+	// StopReason normalized across providers.
+	FinishReason FinishReason `json:"finish_reason,omitempty"`
+	// number of requests made to produce the response when retries are enabled (see WithRetry).
+	Attempts int `json:"attempts,omitempty"`
+	// number of continuation requests made for a truncated answer (see WithAutoContinue).
+	Continuations int `json:"continuations,omitempty"`
+	// whether the response was served from a cache (see WithCache).
+	CacheHit bool `json:"cache_hit,omitempty"`
+	// whether the response came from a hedged request rather than the first one (see WithHedging).
+	Hedged bool `json:"hedged,omitempty"`
+	// rate-limit state reported by the provider's response headers, if any.
+	RateLimit *RateLimitInfo `json:"rate_limit,omitempty"`
+	// latency breakdown of the generation, set once the response is complete.
+	Latency *Latency `json:"latency,omitempty"`
+	// categories flagged by moderation that were let through (see WithModeration).
+	Moderation []FlaggedCategory `json:"moderation,omitempty"`
+	// experiment and variant that served the request (see NewExperiment).
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	// guardrail decisions that redacted or annotated the request or response (see WithGuardrails).
+	Guardrails []GuardrailDecision `json:"guardrails,omitempty"`
+}
+
+// FinishReason is the reason a generation stopped, normalized across providers.
+type FinishReason string
+
+const (
+	// FinishStop is a natural end of the answer or a stop sequence.
+	FinishStop FinishReason = "stop"
+	// FinishLength is the output token limit or the context window being reached.
+	FinishLength FinishReason = "length"
+	// FinishToolUse is the model waiting for the results of its tool calls.
+	FinishToolUse FinishReason = "tool_use"
+	// FinishContentFilter is the provider's content filter withholding output.
+	FinishContentFilter FinishReason = "content_filter"
+	// FinishRefusal is the model refusing to answer (see Refusal).
+	FinishRefusal FinishReason = "refusal"
+	// FinishPause is the provider pausing a long-running turn (Anthropic pause_turn);
+	// send the answer back as is to let the model continue.
+	FinishPause FinishReason = "pause"
+)
+
+// finishReasonOf normalizes a provider stop reason; refused reports whether the
+// answer carries a refusal. Unknown reasons are passed through unchanged.
+func finishReasonOf(stopReason string, refused bool) FinishReason {
+	if refused {
+		return FinishRefusal
+	}
+	switch stopReason {
+	case "":
+		return ""
+	case "stop", "end_turn", "stop_sequence":
+		return FinishStop
+	case "pause_turn":
+		return FinishPause
+	case "length", "max_tokens", "model_context_window_exceeded":
+		return FinishLength
+	case "tool_calls", "function_call", "tool_use":
+		return FinishToolUse
+	case "content_filter":
+		return FinishContentFilter
+	case "refusal":
+		return FinishRefusal
+	}
+	return FinishReason(stopReason)
+}
+
+// Latency breaks down the time spent generating a response.
+type Latency struct {
+	// (streaming) time from the request to the first output delta.
+	TTFT time.Duration `json:"ttft,omitempty"`
+	// output tokens per second of generation; for streams measured from the first
+	// delta, for blocking calls over the whole request.
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	// (streaming) percentiles of the gaps between successive output deltas.
+	InterTokenP50 time.Duration `json:"inter_token_p50,omitempty"`
+	InterTokenP90 time.Duration `json:"inter_token_p90,omitempty"`
+	InterTokenP99 time.Duration `json:"inter_token_p99,omitempty"`
+}
+
+// blockingLatency returns the latency of a blocking call that took duration.
+func blockingLatency(usage Usage, duration time.Duration) *Latency {
+	latency := &Latency{}
+	if duration > 0 {
+		latency.TokensPerSecond = float64(usage.OutputTokens) / duration.Seconds()
+	}
+	return latency
+}
+
+// metaResponse overrides the metadata of a Response implemented outside this package.
+type metaResponse struct {
+	Response
+	meta Meta
+}
+
+// Meta implements Response.
+func (resp *metaResponse) Meta() Meta {
+	return resp.meta
+}
+
+// LogProbs implements LogProbsResponse.
+func (resp *metaResponse) LogProbs() []TokenLogProb {
+	return LogProbs(resp.Response)
+}
+
+// withMeta returns a copy of resp whose metadata has been changed by update.
+func withMeta(resp Response, update func(meta *Meta)) Response {
+	if resp == nil {
+		return nil
+	}
+	if r, ok := resp.(*response); ok {
+		cp := *r
+		update(&cp.meta)
+		return &cp
+	}
+	meta := resp.Meta()
+	update(&meta)
+	return &metaResponse{Response: resp, meta: meta}
+}
+
+// answerResponse overrides the answer of a Response implemented outside this package.
+type answerResponse struct {
+	Response
+	answer Message
+}
+
+// Answer implements Response.
+func (resp *answerResponse) Answer() Message {
+	return resp.answer
+}
+
+// LogProbs implements LogProbsResponse.
+func (resp *answerResponse) LogProbs() []TokenLogProb {
+	return LogProbs(resp.Response)
+}
+
+// withAnswer returns a copy of resp with answer as its answer.
+func withAnswer(resp Response, answer Message) Response {
+	if r, ok := resp.(*response); ok {
+		cp := *r
+		cp.answer = answer
+		return &cp
+	}
+	return &answerResponse{Response: resp, answer: answer}
+}
+
+// responseJSON is the serialized form of a Response.
+type responseJSON struct {
+	Answer   *llmmsg        `json:"answer"`
+	Usage    Usage          `json:"usage"`
+	Meta     Meta           `json:"meta"`
+	Duration time.Duration  `json:"duration"`
+	LogProbs []TokenLogProb `json:"logprobs,omitempty"`
+}
+
+// EncodeResponse serializes a Response, including its answer, tool calls, usage
+// and metadata, into JSON. Tool calls are stored on the answer.
+func EncodeResponse(resp Response) ([]byte, error) {
+	return json.Marshal(&responseJSON{
+		Answer:   asLLMMessage(resp.Answer()),
+		Usage:    resp.Usage(),
+		Meta:     resp.Meta(),
+		Duration: resp.Duration(),
+		LogProbs: LogProbs(resp),
+	})
+}
+
+// DecodeResponse restores a Response serialized by EncodeResponse.
+func DecodeResponse(data []byte) (Response, error) {
+	var r responseJSON
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Answer == nil {
+		return nil, errors.New("serialized response has no answer")
+	}
+	return &response{
+		answer:   r.Answer,
+		tcalls:   r.Answer.ToolCalls(),
+		usage:    r.Usage,
+		meta:     r.Meta,
+		duration: r.Duration,
+		logprobs: r.LogProbs,
+	}, nil
 }
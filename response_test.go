@@ -0,0 +1,81 @@
+package openllm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	var v struct {
+		Label string `json:"label"`
+	}
+	resp := &response{answer: NewAssistantMessage("Sure:\n```json\n{\"label\": \"spam\"}\n```")}
+	if err := DecodeJSON(resp, &v); err != nil || v.Label != "spam" {
+		t.Fatalf("DecodeJSON = %q, %v; want spam", v.Label, err)
+	}
+
+	var perr *OutputParseError
+	if err := DecodeJSON(&response{answer: NewAssistantMessage("no json here")}, &v); !errors.As(err, &perr) {
+		t.Errorf("DecodeJSON without JSON = %v, want *OutputParseError", err)
+	}
+	if err := DecodeJSON(&response{}, &v); !errors.As(err, &perr) {
+		t.Errorf("DecodeJSON without an answer = %v, want *OutputParseError", err)
+	}
+}
+
+func TestLogProbs(t *testing.T) {
+	logprobs := []TokenLogProb{{Token: "yes", LogProb: -0.1}}
+	resp := &response{answer: NewAssistantMessage("yes"), logprobs: logprobs}
+	if got := LogProbs(resp); len(got) != 1 || got[0].Token != "yes" {
+		t.Fatalf("LogProbs = %v, want %v", got, logprobs)
+	}
+	if got := LogProbs(&metaResponse{Response: resp}); len(got) != 1 {
+		t.Errorf("LogProbs through a wrapped response = %v, want %v", got, logprobs)
+	}
+	if got := LogProbs(&answerResponse{Response: plainResponse{resp}}); got != nil {
+		t.Errorf("LogProbs of a response without log probabilities = %v, want nil", got)
+	}
+}
+
+// plainResponse hides every method of a Response outside the interface.
+type plainResponse struct {
+	Response
+}
+
+func TestRefusal(t *testing.T) {
+	refused := &response{answer: NewAssistantMessage("I can't help with that."), meta: Meta{FinishReason: FinishRefusal}}
+	if got := Refusal(refused); got != "I can't help with that." {
+		t.Errorf("Refusal = %q, want the answer text", got)
+	}
+	if got := Refusal(&metaResponse{Response: refused, meta: Meta{FinishReason: FinishStop}}); got != "" {
+		t.Errorf("Refusal of a completed answer = %q, want empty", got)
+	}
+	if got := Refusal(nil); got != "" {
+		t.Errorf("Refusal(nil) = %q, want empty", got)
+	}
+}
+
+func TestIsTruncated(t *testing.T) {
+	if !IsTruncated(&response{meta: Meta{FinishReason: FinishLength}}) {
+		t.Error("IsTruncated = false for FinishLength")
+	}
+	if IsTruncated(&response{meta: Meta{FinishReason: FinishStop}}) || IsTruncated(nil) {
+		t.Error("IsTruncated = true for a completed answer")
+	}
+}
+
+func TestFinishReasonOf(t *testing.T) {
+	tests := map[string]FinishReason{
+		"end_turn":       FinishStop,
+		"max_tokens":     FinishLength,
+		"tool_calls":     FinishToolUse,
+		"pause_turn":     FinishPause,
+		"new_reason":     "new_reason",
+		"content_filter": FinishContentFilter,
+	}
+	for stop, want := range tests {
+		if got := finishReasonOf(stop, false); got != want {
+			t.Errorf("finishReasonOf(%q) = %q, want %q", stop, got, want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// RetryPolicy configures WithRetry. Zero fields take the values of
+// DefaultRetryPolicy, except Jitter and Budget, for which zero disables them.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of requests, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier scales the delay after each retry.
+	Multiplier float64
+	// Jitter randomly shortens each delay by up to this fraction (0 to 1),
+	// so clients that failed together do not retry together.
+	Jitter float64
+	// Budget bounds the total time spent on a request, including waits;
+	// no retry is started that would exceed it.
+	Budget time.Duration
+	// Retryable reports whether a failed request should be retried; nil uses IsRetryable.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns the policy used for zero RetryPolicy fields:
+// 3 attempts, 500ms initial backoff doubling up to 30s, and 20% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetry returns a Middleware that retries failed requests with exponential
+// backoff. Rate limits (429), server errors (5xx) and timeouts are retried by
+// default, waiting at least as long as the provider's Retry-After header asks.
+// Streams are only retried when the failed attempt produced no output, so the
+// watcher never sees content twice. The retries built into provider SDKs
+// (Anthropic's) are turned off for wrapped requests, so Meta.Attempts reports the
+// number of requests made. Optional interfaces of the wrapped model, such as
+// TokenCounter, remain reachable through Unwrap.
+func WithRetry(policy RetryPolicy) Middleware {
+	def := DefaultRetryPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = def.MaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = def.InitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = def.MaxBackoff
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = def.Multiplier
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	return func(next Model) Model {
+		return &retryModel{Model: next, policy: policy}
+	}
+}
+
+// retryModel is the Model returned by WithRetry.
+type retryModel struct {
+	Model
+	policy RetryPolicy
+}
+
+// Unwrap returns the wrapped model.
+func (m *retryModel) Unwrap() Model {
+	return m.Model
+}
+
+// withoutProviderRetries turns off the retries of provider SDKs, so that only
+// WithRetry decides how often a request is sent.
+func withoutProviderRetries() ChatOption {
+	return func(opts *ChatOptions) { opts.noProviderRetries = true }
+}
+
+// ChatCompletion implements Model.
+func (m *retryModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	opts = append(opts[:len(opts):len(opts)], withoutProviderRetries())
+	return m.do(ctx, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *retryModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	opts = append(opts[:len(opts):len(opts)], withoutProviderRetries())
+	return m.do(ctx, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do calls request until it succeeds or the policy gives up.
+func (m *retryModel) do(ctx context.Context, request func() (Response, error)) (Response, error) {
+	start := time.Now()
+	backoff := m.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := request()
+		if err == nil || attempt >= m.policy.MaxAttempts || ctx.Err() != nil ||
+			outputStarted(resp) || !m.policy.Retryable(err) {
+			return withMeta(resp, func(meta *Meta) { meta.Attempts = attempt }), err
+		}
+
+		delay := backoff
+		if m.policy.Jitter > 0 {
+			delay -= time.Duration(rand.Float64() * m.policy.Jitter * float64(delay))
+		}
+		if after := RetryAfter(err); after > delay {
+			delay = after
+		}
+		if m.policy.Budget > 0 && time.Since(start)+delay > m.policy.Budget {
+			return withMeta(resp, func(meta *Meta) { meta.Attempts = attempt }), err
+		}
+		emitRetry(ctx, RetryEvent{Model: m.Name(), Attempt: attempt, Err: err, Delay: delay})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return withMeta(resp, func(meta *Meta) { meta.Attempts = attempt }), err
+		case <-timer.C:
+		}
+		backoff = min(time.Duration(float64(backoff)*m.policy.Multiplier), m.policy.MaxBackoff)
+	}
+}
+
+// outputStarted reports whether a partial response already carries output that
+// a streaming watcher has seen.
+func outputStarted(resp Response) bool {
+	if resp == nil {
+		return false
+	}
+	answer := resp.Answer()
+	if rich, ok := answer.(RichMessage); ok && rich.Refusal() != "" {
+		return true
+	}
+	return len(resp.ToolCalls()) > 0 || answer != nil && (answer.Content() != "" || answer.Reasoning() != "")
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limiting (429, but not ErrQuotaExceeded), request timeouts (408), server errors (5xx), network
+// timeouts and connections dropped mid-response.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	// exhausted quotas come with a 429 but do not recover by waiting
+	if errors.Is(normalizeProviderError("", err), ErrQuotaExceeded) {
+		return false
+	}
+	if code := httpStatusCode(err); code != 0 {
+		return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, ErrStreamIdleTimeout)
+}
+
+// httpStatusCode extracts the HTTP status of a provider error, or 0.
+func httpStatusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	var anthErr *anthropic.Error
+	if errors.As(err, &anthErr) {
+		return anthErr.StatusCode
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// RetryAfter returns the delay requested by the Retry-After (or retry-after-ms)
+// header of a provider error, or zero when there is none. Anthropic errors and
+// *HTTPError carry the header. The OpenAI client's errors do not, so the header of
+// a failed chat request is recorded in the RetryAfter of its *ProviderError, which
+// otherwise takes the delay from the error message; OpenAI failures of no known
+// kind, such as a 500, are not normalized and carry no delay.
+func RetryAfter(err error) time.Duration {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.RetryAfter > 0 {
+		return providerErr.RetryAfter
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Header != nil {
+		return parseRetryAfter(httpErr.Header)
+	}
+	var anthErr *anthropic.Error
+	if !errors.As(err, &anthErr) || anthErr.Response == nil {
+		return 0
+	}
+	return parseRetryAfter(anthErr.Response.Header)
+}
+
+// parseRetryAfter reads retry-after-ms, then Retry-After as seconds or an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDisablesSDKRetries(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"type":"error","error":{"type":"api_error","message":"boom"}}`))
+	}))
+	defer srv.Close()
+
+	base := NewAnthropicLLMWithAPIKey("claude-sonnet-4-5", "", "key", WithBaseURL(srv.URL))
+	model := Wrap(base, WithRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	_, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("hi")})
+	if err == nil {
+		t.Fatal("ChatCompletion succeeded against a failing server")
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want 2 (one per attempt)", n)
+	}
+}
+
+func TestOpenAIRetryAfterHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"Rate limit reached. Please try again in 20ms.","type":"requests","code":"rate_limit_exceeded"}}`))
+	}))
+	defer srv.Close()
+
+	model := NewLLMWithAPIKey("gpt-4o", "", "key", WithBaseURL(srv.URL))
+	for _, stream := range []bool{false, true} {
+		request := model.ChatCompletion
+		if stream {
+			request = model.ChatCompletionStream
+		}
+		_, err := request(context.Background(), []Message{NewUserMessage("hi")})
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("stream %v: err = %v, want ErrRateLimited", stream, err)
+		}
+		if got := RetryAfter(err); got != 7*time.Second {
+			t.Errorf("stream %v: RetryAfter = %v, want 7s from the header", stream, got)
+		}
+	}
+}
+
+func TestRetryKeepsStreamsWithRefusal(t *testing.T) {
+	base := &refusingModel{Model: NewEchoModel()}
+	model := Wrap(base, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	watcher := &refusalCounter{}
+	_, err := model.ChatCompletionStream(context.Background(), []Message{NewUserMessage("hi")},
+		WithStreamWatcher(watcher))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want the stream failure", err)
+	}
+	if base.calls != 1 || watcher.refusals != 1 {
+		t.Errorf("%d requests, watcher saw %d refusals, want 1 and 1", base.calls, watcher.refusals)
+	}
+}
+
+// refusingModel streams a refusal, then fails with a retryable error.
+type refusingModel struct {
+	Model
+	calls int
+}
+
+func (m *refusingModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	m.calls++
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	acc := newStreamAccumulator(options, Meta{Model: m.Name()})
+	if err := acc.onRefusal("I can't help with that."); err != nil {
+		return acc.fail(err)
+	}
+	return acc.fail(io.ErrUnexpectedEOF)
+}
+
+// refusalCounter counts the refusal deltas it receives.
+type refusalCounter struct {
+	refusals int
+}
+
+func (w *refusalCounter) OnRefusal(string) error   { w.refusals++; return nil }
+func (w *refusalCounter) OnReasoning(string) error { return nil }
+func (w *refusalCounter) OnContent(string) error   { return nil }
+func (w *refusalCounter) OnStop() error            { return nil }
+
+func (w *refusalCounter) OnToolCall(context.Context, ToolCall, string) error { return nil }
@@ -0,0 +1,312 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMaxIterations bounds the number of model calls made by a Runner.
+const defaultMaxIterations = 10
+
+// Runner drives the tool-calling loop: it calls the model, executes the requested
+// tools through the Go functions bound with WithFunction, feeds the results back,
+// and repeats until the model produces a final answer.
+type Runner struct {
+	model Model
+	tools []Tool
+	// maxIterations bounds the number of model calls per Run.
+	maxIterations int
+	// concurrency bounds the number of tool calls executed in parallel.
+	concurrency int
+	// middleware wraps every tool execution, outside any ToolSet middleware.
+	middleware []ToolMiddleware
+	// repair is the policy for malformed tool-call arguments; zero disables repair.
+	repair ArgumentRepairPolicy
+	// toolStore records tool results of runs with an idempotency key; nil disables it.
+	toolStore    CacheStore
+	toolStoreTTL time.Duration
+	// history trims or compacts the conversation before each model call; nil keeps it whole.
+	history *HistoryManager
+	// onCheckpoint receives a checkpoint after each step; nil disables checkpoints.
+	onCheckpoint func(ctx context.Context, cp *RunCheckpoint) error
+	// stopTools names the tools whose calls end the run once executed.
+	stopTools map[string]bool
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(r *Runner)
+
+// WithRunnerTools registers tools that are offered to the model and executed by the Runner.
+func WithRunnerTools(tools ...Tool) RunnerOption {
+	return func(r *Runner) { r.tools = append(r.tools, tools...) }
+}
+
+// WithMaxIterations sets the maximum number of model calls per Run (default 10).
+func WithMaxIterations(n int) RunnerOption {
+	return func(r *Runner) { r.maxIterations = n }
+}
+
+// WithToolConcurrency sets how many tool calls from a single model response may run
+// in parallel (default 1, i.e. serially). Results are always appended in call order.
+func WithToolConcurrency(n int) RunnerOption {
+	return func(r *Runner) { r.concurrency = n }
+}
+
+// WithToolMiddleware wraps every tool execution of the Runner with middleware.
+// Runner middleware runs outside the middleware of a ToolSet passed with WithToolSet.
+func WithToolMiddleware(middleware ...ToolMiddleware) RunnerOption {
+	return func(r *Runner) { r.middleware = append(r.middleware, middleware...) }
+}
+
+// WithArgumentRepair makes the Runner repair malformed tool-call arguments
+// (see RepairArguments) before executing them, and sets what happens when repair fails.
+func WithArgumentRepair(policy ArgumentRepairPolicy) RunnerOption {
+	return func(r *Runner) { r.repair = policy }
+}
+
+// WithRunnerHistory trims the conversation with manager before each model call.
+// The trimmed conversation replaces RunResult.Messages, so with a summarizer
+// (see WithHistorySummarizer) a long tool loop is compacted once and continues
+// from the summary rather than being summarized again on every call.
+func WithRunnerHistory(manager *HistoryManager) RunnerOption {
+	return func(r *Runner) { r.history = manager }
+}
+
+// WithStopTools makes a call to any of the named tools end the run once the
+// calls of that response have been executed, instead of asking the model again.
+// The call is reported in RunResult.StopCall. This suits tools that deliver the
+// final answer or hand the conversation over to another agent.
+func WithStopTools(names ...string) RunnerOption {
+	return func(r *Runner) {
+		if r.stopTools == nil {
+			r.stopTools = map[string]bool{}
+		}
+		for _, name := range names {
+			r.stopTools[name] = true
+		}
+	}
+}
+
+// NewRunner creates a Runner for model.
+func NewRunner(model Model, opts ...RunnerOption) *Runner {
+	r := &Runner{model: model, maxIterations: defaultMaxIterations, concurrency: 1}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RunResult is the outcome of Runner.Run.
+type RunResult struct {
+	// Messages is the full conversation: the input messages followed by every
+	// assistant and tool message produced during the run, as trimmed by
+	// WithRunnerHistory if set.
+	Messages []Message
+	// Response is the last model response.
+	Response Response
+	// Iterations is the number of model calls made.
+	Iterations int
+	// Usage is the token usage accumulated over all model calls.
+	Usage Usage
+	// StopCall is the call that ended the run, if it was ended by a tool
+	// registered with WithStopTools.
+	StopCall ToolCall
+}
+
+// Run executes the tool loop starting from messages. Tools passed through opts with
+// WithTool or WithToolSet are executed as well as those registered on the Runner.
+// If the model keeps requesting tools, Run stops after the configured number of
+// iterations and returns the result so far together with ErrMaxIterations.
+func (r *Runner) Run(ctx context.Context, messages []Message, opts ...ChatOption) (*RunResult, error) {
+	result := &RunResult{
+		Messages: append([]Message(nil), messages...),
+	}
+	return r.run(ctx, result, nil, opts)
+}
+
+// run continues the tool loop from result, first executing the pending tool calls
+// of the last response.
+func (r *Runner) run(ctx context.Context, result *RunResult, pending []ToolCall, opts []ChatOption) (*RunResult, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	tools := options.filterTools(append(append([]Tool(nil), r.tools...), options.tools...))
+	toolset := options.toolset
+	middleware := r.middleware
+	if options.validateOutput {
+		// Validation runs innermost, after middleware that may rewrite arguments
+		middleware = append(middleware[:len(middleware):len(middleware)], ValidateToolArguments)
+	}
+	if r.toolStore != nil && options.idempotencyKey != "" {
+		// Replay runs outermost, so recorded results skip every other middleware
+		middleware = append([]ToolMiddleware{idempotentTool(r.toolStore, r.toolStoreTTL, options.idempotencyKey)}, middleware...)
+	}
+
+	if len(r.tools) > 0 {
+		opts = append(opts, WithTool(r.tools...))
+	}
+
+	for {
+		if len(pending) > 0 {
+			results, err := r.executeAll(ctx, tools, toolset, options.toolFilter, middleware, pending)
+			if err != nil {
+				return result, err
+			}
+			result.Messages = append(result.Messages, results...)
+			stop := r.stopCall(pending)
+			pending = nil
+			if err := r.checkpoint(ctx, result, nil); err != nil {
+				return result, err
+			}
+			if stop != nil {
+				result.StopCall = stop
+				return result, nil
+			}
+		}
+		if result.Iterations >= r.maxIterations {
+			return result, ErrMaxIterations
+		}
+
+		callOpts := opts
+		if options.idempotencyKey != "" {
+			// Each model call of the run gets its own key
+			key := options.idempotencyKey + "#" + strconv.Itoa(result.Iterations)
+			callOpts = append(opts[:len(opts):len(opts)], WithIdempotencyKey(key))
+		}
+		if r.history != nil {
+			trimmed, err := r.history.Trim(ctx, r.model, result.Messages, callOpts...)
+			if err != nil {
+				return result, err
+			}
+			result.Messages = trimmed
+		}
+		resp, err := r.model.ChatCompletion(ctx, result.Messages, callOpts...)
+		if err != nil {
+			return result, err
+		}
+		result.Iterations++
+		result.Response = resp
+		result.Usage = result.Usage.Add(resp.Usage())
+		result.Messages = append(result.Messages, resp.Answer())
+
+		pending = resp.ToolCalls()
+		if err := r.checkpoint(ctx, result, pending); err != nil {
+			return result, err
+		}
+		if len(pending) == 0 {
+			return result, nil
+		}
+	}
+}
+
+// stopCall returns the first of tcalls naming a stop tool, or nil.
+func (r *Runner) stopCall(tcalls []ToolCall) ToolCall {
+	for _, tcall := range tcalls {
+		if r.stopTools[tcall.Function().Name()] {
+			return tcall
+		}
+	}
+	return nil
+}
+
+// executeAll runs the tool calls of one response with bounded parallelism and
+// returns their result messages in call order.
+func (r *Runner) executeAll(ctx context.Context, tools []Tool, toolset *ToolSet, filter func(Tool) bool, middleware []ToolMiddleware, tcalls []ToolCall) ([]Message, error) {
+	results := make([]Message, len(tcalls))
+	errs := make([]error, len(tcalls))
+
+	workers := r.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, tcall := range tcalls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, tcall ToolCall) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			tcall, err := r.repairArguments(tcall)
+			if err != nil {
+				if r.repair == RepairFail {
+					errs[i] = err
+					return
+				}
+				results[i] = NewToolResultMessage(tcall.ID(),
+					fmt.Sprintf("%v. Call the tool again with valid JSON arguments.", err), true)
+				return
+			}
+			output, err := r.executeToolCall(ctx, tools, toolset, filter, middleware, tcall)
+			if err != nil {
+				// Report the failure to the model so it can recover
+				results[i] = NewToolResultMessage(tcall.ID(), err, true)
+				return
+			}
+			results[i] = NewToolResultMessage(tcall.ID(), output, false)
+		}(i, tcall)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// repairArguments applies the Runner's repair policy to the arguments of tcall.
+// It returns the call to execute, or an error wrapping *ArgumentsError if the
+// arguments remain malformed.
+func (r *Runner) repairArguments(tcall ToolCall) (ToolCall, error) {
+	if r.repair == 0 {
+		return tcall, nil
+	}
+	verr := tcall.ArgumentsValid()
+	if verr == nil {
+		return tcall, nil
+	}
+	if fixed, ok := RepairArguments(tcall.Function().Arguments()); ok {
+		return withArguments(tcall, fixed), nil
+	}
+	return tcall, fmt.Errorf("invalid arguments for tool %s: %w", tcall.Function().Name(), verr)
+}
+
+// executeToolCall finds the tool requested by tcall and runs it through middleware.
+// Toolset tools rejected by filter are treated as missing. A panicking tool is
+// reported as an error, since it runs on a goroutine the caller of Run cannot recover.
+func (r *Runner) executeToolCall(ctx context.Context, tools []Tool, toolset *ToolSet, filter func(Tool) bool, middleware []ToolMiddleware, tcall ToolCall) (result string, err error) {
+	start := time.Now()
+	defer func() {
+		emitToolExecuted(ctx, ToolExecutedEvent{Call: tcall, Result: result, Err: err, Duration: time.Since(start)})
+	}()
+
+	name := tcall.Function().Name()
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = "", fmt.Errorf("tool %s panicked: %v", name, p)
+		}
+	}()
+	for _, tool := range tools {
+		if def, ok := tool.Definition().(*FunctionDefinition); ok && def.Name == name {
+			return chainToolMiddleware(ExecuteToolCall, middleware)(ctx, tool, tcall)
+		}
+	}
+	if toolset != nil {
+		if tool, handler, found := toolset.resolve(name); found && (filter == nil || filter(tool)) {
+			return chainToolMiddleware(handler, middleware)(ctx, tool, tcall)
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrToolNotFound, name)
+}
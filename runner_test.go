@@ -0,0 +1,39 @@
+package openllm
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestRunnerRecoversToolPanic(t *testing.T) {
+	crash := DefineFunction("crash", "Always panics.", WithFunction(func() (string, error) {
+		var m map[string]int
+		m["boom"]++
+		return "", nil
+	}))
+	model := NewEchoModel(WithEchoToolRule(regexp.MustCompile(`crash`), "crash", `{}`))
+
+	for _, concurrency := range []int{1, 4} {
+		runner := NewRunner(model, WithRunnerTools(crash), WithToolConcurrency(concurrency))
+		result, err := runner.Run(context.Background(), []Message{NewUserMessage("crash")})
+		if err != nil {
+			t.Fatalf("concurrency %d: Run: %v", concurrency, err)
+		}
+		var tool Message
+		for _, msg := range result.Messages {
+			if msg.Role() == constants.RoleTool {
+				tool = msg
+			}
+		}
+		if tool == nil {
+			t.Fatalf("concurrency %d: no tool result in %d messages", concurrency, len(result.Messages))
+		}
+		if !strings.Contains(tool.Content(), "tool crash panicked") {
+			t.Errorf("concurrency %d: tool result = %q, want the panic reported", concurrency, tool.Content())
+		}
+	}
+}
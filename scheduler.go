@@ -0,0 +1,270 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Priority orders requests queued by WithScheduler; higher values go first.
+type Priority int
+
+const (
+	// PriorityBatch is for background work such as bulk summarization. It cannot
+	// use the reserved part of the budgets and may be shed under load.
+	PriorityBatch Priority = -1
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 0
+	// PriorityInteractive is for requests a user is waiting on.
+	PriorityInteractive Priority = 1
+)
+
+// WithPriority sets the priority of the request for WithScheduler (default PriorityNormal).
+func WithPriority(priority Priority) ChatOption {
+	return func(opts *ChatOptions) { opts.priority = priority }
+}
+
+// SchedulerConfig configures WithScheduler. A zero limit is not enforced.
+type SchedulerConfig struct {
+	// MaxConcurrent bounds the number of requests in flight.
+	MaxConcurrent int
+	// RequestsPerMinute and TokensPerMinute are budgets as in RateLimit.
+	RequestsPerMinute int
+	TokensPerMinute   int
+	// EstimateTokens estimates the tokens of a request; nil uses EstimateTokens.
+	EstimateTokens func(messages []Message, opts *ChatOptions) int
+	// Reserve is the fraction of each budget (0 to 1) that PriorityBatch requests
+	// leave untouched, so interactive traffic still finds room when budgets run low.
+	Reserve float64
+	// MaxQueue bounds the number of waiting PriorityBatch requests; further ones
+	// fail immediately with ErrRequestShed.
+	MaxQueue int
+	// MaxWait sheds PriorityBatch requests that waited longer, with ErrRequestShed.
+	MaxWait time.Duration
+}
+
+// WithScheduler returns a Middleware that admits requests by priority within
+// concurrency and per-minute budgets. Waiting requests are served highest priority
+// first, in arrival order within a priority, so background work cannot starve
+// user-facing chat. All models wrapped by the same returned Middleware share its
+// budgets and queue.
+func WithScheduler(cfg SchedulerConfig) Middleware {
+	if cfg.EstimateTokens == nil {
+		cfg.EstimateTokens = EstimateTokens
+	}
+	s := &scheduler{
+		cfg:      cfg,
+		requests: newTokenBucket(cfg.RequestsPerMinute),
+		tokens:   newTokenBucket(cfg.TokensPerMinute),
+		changed:  make(chan struct{}),
+	}
+	return func(next Model) Model {
+		return &schedulerModel{Model: next, scheduler: s}
+	}
+}
+
+// schedulerModel is the Model returned by WithScheduler.
+type schedulerModel struct {
+	Model
+	scheduler *scheduler
+}
+
+// Unwrap returns the wrapped model.
+func (m *schedulerModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *schedulerModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *schedulerModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do waits for admission, runs request and releases its slot.
+func (m *schedulerModel) do(ctx context.Context, messages []Message, opts []ChatOption, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	s := m.scheduler
+	estimate := 0
+	if s.tokens != nil {
+		estimate = s.cfg.EstimateTokens(messages, options)
+	}
+	if err := s.admit(ctx, options.priority, estimate); err != nil {
+		return nil, err
+	}
+	resp, err := request()
+	used := estimate
+	if resp != nil && s.tokens != nil && resp.Usage().TotalTokens > 0 {
+		used = resp.Usage().TotalTokens
+	}
+	s.release(estimate - used)
+	return resp, err
+}
+
+// scheduler holds the budgets and queue shared by the models of one WithScheduler.
+type scheduler struct {
+	cfg SchedulerConfig
+
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+	running  int
+	queue    []*schedWaiter
+	seq      int64
+	// changed is closed and replaced whenever waiters may become admissible.
+	changed chan struct{}
+}
+
+// schedWaiter is a request waiting for admission.
+type schedWaiter struct {
+	priority Priority
+	seq      int64
+	tokens   int
+}
+
+// admit blocks until the request may proceed, ctx ends, or it is shed.
+func (s *scheduler) admit(ctx context.Context, priority Priority, tokens int) error {
+	s.mu.Lock()
+	if priority <= PriorityBatch && s.cfg.MaxQueue > 0 && s.queued(PriorityBatch) >= s.cfg.MaxQueue {
+		s.mu.Unlock()
+		return ErrRequestShed
+	}
+	s.seq++
+	w := &schedWaiter{priority: priority, seq: s.seq, tokens: tokens}
+	s.enqueue(w)
+
+	var deadline <-chan time.Time
+	if priority <= PriorityBatch && s.cfg.MaxWait > 0 {
+		timer := time.NewTimer(s.cfg.MaxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		delay, ok := s.tryAdmit(w)
+		if ok {
+			s.mu.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		var retry <-chan time.Time
+		var timer *time.Timer
+		if delay > 0 {
+			timer = time.NewTimer(delay)
+			retry = timer.C
+		}
+		var err error
+		select {
+		case <-changed:
+		case <-retry:
+		case <-deadline:
+			err = ErrRequestShed
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		s.mu.Lock()
+		if err != nil {
+			s.remove(w)
+			s.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// tryAdmit admits w if it heads the queue and fits the limits; otherwise it
+// returns how long to wait for the budgets, or zero to wait for a change.
+// The lock must be held.
+func (s *scheduler) tryAdmit(w *schedWaiter) (time.Duration, bool) {
+	if s.queue[0] != w {
+		return 0, false
+	}
+	if s.cfg.MaxConcurrent > 0 && s.running >= s.cfg.MaxConcurrent {
+		return 0, false
+	}
+	now := time.Now()
+	reserve := 0.0
+	if w.priority <= PriorityBatch {
+		reserve = s.cfg.Reserve
+	}
+	delay := max(s.requests.delay(now, 1+s.reserved(s.requests, reserve)),
+		s.tokens.delay(now, float64(w.tokens)+s.reserved(s.tokens, reserve)))
+	if delay > 0 {
+		return delay, false
+	}
+	s.requests.take(1)
+	s.tokens.take(float64(w.tokens))
+	s.running++
+	s.remove(w)
+	return 0, true
+}
+
+// reserved returns the part of bucket held back at the reserve fraction.
+func (s *scheduler) reserved(bucket *tokenBucket, reserve float64) float64 {
+	if bucket == nil || reserve <= 0 {
+		return 0
+	}
+	return min(reserve, 1) * bucket.capacity
+}
+
+// release frees the slot of a finished request and returns delta tokens to the budget.
+func (s *scheduler) release(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.tokens.adjust(float64(delta))
+	s.notify()
+}
+
+// enqueue inserts w after the waiters of the same or higher priority. The lock must be held.
+func (s *scheduler) enqueue(w *schedWaiter) {
+	i := len(s.queue)
+	for i > 0 && s.queue[i-1].priority < w.priority {
+		i--
+	}
+	s.queue = append(s.queue, nil)
+	copy(s.queue[i+1:], s.queue[i:])
+	s.queue[i] = w
+	s.notify()
+}
+
+// remove drops w from the queue. The lock must be held.
+func (s *scheduler) remove(w *schedWaiter) {
+	for i, q := range s.queue {
+		if q == w {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			break
+		}
+	}
+	s.notify()
+}
+
+// queued counts the waiters at or below priority. The lock must be held.
+func (s *scheduler) queued(priority Priority) int {
+	n := 0
+	for _, w := range s.queue {
+		if w.priority <= priority {
+			n++
+		}
+	}
+	return n
+}
+
+// notify wakes the waiters to re-check admission. The lock must be held.
+func (s *scheduler) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,197 @@
+package openllm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// Schema is the JSON Schema generated from Go parameter structs.
+// Unlike jsonschema.Definition it carries the validation keywords
+// (format, default, bounds) that strict mode relies on.
+type Schema struct {
+	Type                 jsonschema.DataType `json:"type,omitempty"`
+	Description          string              `json:"description,omitempty"`
+	Format               string              `json:"format,omitempty"`
+	Enum                 []any               `json:"enum,omitempty"`
+	Default              any                 `json:"default,omitempty"`
+	Minimum              *float64            `json:"minimum,omitempty"`
+	Maximum              *float64            `json:"maximum,omitempty"`
+	MinLength            *int                `json:"minLength,omitempty"`
+	MaxLength            *int                `json:"maxLength,omitempty"`
+	MinItems             *int                `json:"minItems,omitempty"`
+	MaxItems             *int                `json:"maxItems,omitempty"`
+	MinProperties        *int                `json:"minProperties,omitempty"`
+	MaxProperties        *int                `json:"maxProperties,omitempty"`
+	Properties           map[string]*Schema  `json:"properties,omitempty"`
+	Required             []string            `json:"required,omitempty"`
+	Items                *Schema             `json:"items,omitempty"`
+	AdditionalProperties any                 `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema           `json:"allOf,omitempty"`
+	AnyOf                []*Schema           `json:"anyOf,omitempty"`
+	OneOf                []*Schema           `json:"oneOf,omitempty"`
+	Ref                  string              `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema  `json:"$defs,omitempty"`
+	Definitions          map[string]*Schema  `json:"definitions,omitempty"`
+}
+
+// isSchemaPassthrough reports whether parameters are sent to providers as-is
+// instead of being normalized to a jsonschema.Definition.
+func isSchemaPassthrough(parameters any) bool {
+	switch parameters.(type) {
+	case *Schema, json.RawMessage:
+		return true
+	}
+	return false
+}
+
+// parseStructToDefinition generates the object schema of a parameter struct.
+func parseStructToDefinition(t reflect.Type) *Schema {
+	return structSchema(t, make(map[reflect.Type]bool))
+}
+
+// structSchema builds an object schema from the parameter fields of t.
+// seen guards against recursive types, which are emitted as plain objects.
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	def := &Schema{
+		Type:       jsonschema.Object,
+		Properties: make(map[string]*Schema),
+		Required:   []string{},
+	}
+	if seen[t] {
+		return def
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for _, pf := range structFields(t) {
+		field, tag := pf.field, pf.tag
+
+		fieldDef := typeSchema(field.Type, seen)
+		fieldDef.Description = tag.desc
+		applyFieldTag(fieldDef, field.Type, tag)
+
+		def.Properties[tag.name] = fieldDef
+		if tag.required {
+			def.Required = append(def.Required, tag.name)
+		}
+	}
+
+	return def
+}
+
+// typeSchema maps a Go type to its JSON Schema.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: jsonschema.String, Format: "date-time"}
+	case rawMessageType:
+		// Any JSON value
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: jsonschema.String}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: jsonschema.Integer}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: jsonschema.Number}
+	case reflect.Bool:
+		return &Schema{Type: jsonschema.Boolean}
+	case reflect.Struct:
+		return structSchema(t, seen)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes byte slices as base64 strings
+			return &Schema{Type: jsonschema.String}
+		}
+		return &Schema{Type: jsonschema.Array, Items: typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return &Schema{Type: jsonschema.Object}
+		}
+		return &Schema{Type: jsonschema.Object, AdditionalProperties: typeSchema(t.Elem(), seen)}
+	}
+	// Interfaces and other kinds accept any value
+	return &Schema{}
+}
+
+// applyFieldTag adds the enum, default, bound and format options of tag to def.
+// Bounds apply to the value for numbers, the length for strings, the item count
+// for arrays and the entry count for maps.
+func applyFieldTag(def *Schema, t reflect.Type, tag fieldTag) {
+	if tag.format != "" {
+		def.Format = tag.format
+	}
+	for _, value := range tag.enum {
+		def.Enum = append(def.Enum, parseTagValue(def.Type, value))
+	}
+	if tag.hasDef {
+		def.Default = parseTagValue(def.Type, tag.def)
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	setBound := func(value string, number **float64, length, items, props **int) {
+		if value == "" {
+			return
+		}
+		switch def.Type {
+		case jsonschema.Integer, jsonschema.Number:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				*number = &f
+			}
+		case jsonschema.String:
+			if n, err := strconv.Atoi(value); err == nil {
+				*length = &n
+			}
+		case jsonschema.Array:
+			if n, err := strconv.Atoi(value); err == nil {
+				*items = &n
+			}
+		case jsonschema.Object:
+			if t.Kind() == reflect.Map {
+				if n, err := strconv.Atoi(value); err == nil {
+					*props = &n
+				}
+			}
+		}
+	}
+	setBound(tag.min, &def.Minimum, &def.MinLength, &def.MinItems, &def.MinProperties)
+	setBound(tag.max, &def.Maximum, &def.MaxLength, &def.MaxItems, &def.MaxProperties)
+}
+
+// parseTagValue converts a tag literal to the JSON type of the schema.
+// Literals that don't parse are kept as strings.
+func parseTagValue(typ jsonschema.DataType, value string) any {
+	switch typ {
+	case jsonschema.Integer:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case jsonschema.Number:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case jsonschema.Boolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,183 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// SemanticCacheConfig configures WithSemanticCache. Zero fields take the defaults noted.
+type SemanticCacheConfig struct {
+	// Embedder embeds the prompts; it is required.
+	Embedder Embedder
+	// Threshold is the minimum cosine similarity for a cached prompt to match (default 0.95).
+	Threshold float64
+	// Capacity is the maximum number of entries; the least recently used is evicted (default 1000).
+	Capacity int
+	// TTL bounds the age of entries; zero keeps them until evicted.
+	TTL time.Duration
+}
+
+// WithSemanticCache returns a Middleware that answers a request from a previous
+// response when its final user message is semantically close to a cached one, for
+// FAQ-style workloads where users phrase the same question differently. Only the
+// final message is compared; the model, earlier messages and options must match
+// exactly. Requests whose final message is not from the user pass through.
+// WithCacheMode applies as for WithCache, and Meta.CacheHit reports cached responses.
+// All models wrapped by the same returned Middleware share its entries.
+// Embedding errors are ignored and the request goes to the model.
+func WithSemanticCache(cfg SemanticCacheConfig) Middleware {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.95
+	}
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1000
+	}
+	c := &semanticCache{cfg: cfg}
+	return func(next Model) Model {
+		return &semanticCacheModel{Model: next, cache: c}
+	}
+}
+
+// semanticCacheModel is the Model returned by WithSemanticCache.
+type semanticCacheModel struct {
+	Model
+	cache *semanticCache
+}
+
+// Unwrap returns the wrapped model.
+func (m *semanticCacheModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *semanticCacheModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, false, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *semanticCacheModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(ctx, messages, opts, true, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do looks up a similar prompt, calling request on a miss.
+func (m *semanticCacheModel) do(ctx context.Context, messages []Message, opts []ChatOption, stream bool, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	n := len(messages)
+	if options.cacheMode == CacheBypass || n == 0 || messages[n-1].Role() != constants.RoleUser {
+		return request()
+	}
+	// The scope covers everything but the final message, which is compared by meaning
+	scope, err := cacheKey(m.Name(), messages[:n-1], options)
+	if err != nil {
+		return request()
+	}
+	_, redacted := options.redact(messages[n-1:])
+	prompt := redacted[0].Content()
+	if prompt == "" {
+		return request()
+	}
+	vectors, _, err := m.cache.cfg.Embedder.Embed(ctx, []string{prompt})
+	if err != nil || len(vectors) != 1 {
+		return request()
+	}
+	vector := vectors[0]
+
+	if options.cacheMode != CacheRefresh {
+		if data, ok := m.cache.lookup(scope, vector); ok {
+			if resp, err := DecodeResponse(data); err == nil {
+				resp = withMeta(resp, func(meta *Meta) { meta.CacheHit = true })
+				if stream && options.watcher != nil {
+					return resp, replayResponse(ctx, options.watcher, resp)
+				}
+				return resp, nil
+			}
+		}
+	}
+
+	resp, err := request()
+	if err != nil {
+		return resp, err
+	}
+	if data, err := EncodeResponse(resp); err == nil {
+		m.cache.store(scope, vector, data)
+	}
+	return resp, nil
+}
+
+// semanticCache holds the entries shared by the models of one WithSemanticCache.
+type semanticCache struct {
+	cfg SemanticCacheConfig
+
+	mu sync.Mutex
+	// entries are ordered from least to most recently used.
+	entries []*semanticEntry
+}
+
+// semanticEntry is a cached response and the embedding of its prompt.
+type semanticEntry struct {
+	scope   string
+	vector  []float32
+	data    []byte
+	created time.Time
+}
+
+// lookup returns the response of the most similar live entry in scope, if it
+// reaches the threshold, and marks it recently used.
+func (c *semanticCache) lookup(scope string, vector []float32) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire()
+	best, bestScore := -1, c.cfg.Threshold
+	for i, entry := range c.entries {
+		if entry.scope != scope {
+			continue
+		}
+		if score := CosineSimilarity(entry.vector, vector); score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+	entry := c.entries[best]
+	c.entries = append(append(c.entries[:best], c.entries[best+1:]...), entry)
+	return entry.data, true
+}
+
+// store adds an entry, evicting the least recently used ones beyond capacity.
+func (c *semanticCache) store(scope string, vector []float32, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = append(c.entries, &semanticEntry{scope: scope, vector: vector, data: data, created: time.Now()})
+	if over := len(c.entries) - c.cfg.Capacity; over > 0 {
+		c.entries = append(c.entries[:0], c.entries[over:]...)
+	}
+}
+
+// expire drops entries older than the TTL.
+func (c *semanticCache) expire() {
+	if c.cfg.TTL <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-c.cfg.TTL)
+	live := c.entries[:0]
+	for _, entry := range c.entries {
+		if entry.created.After(deadline) {
+			live = append(live, entry)
+		}
+	}
+	clear(c.entries[len(live):])
+	c.entries = live
+}
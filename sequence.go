@@ -0,0 +1,156 @@
+package openllm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// MessageError describes an invalid message in a conversation history.
+type MessageError struct {
+	// Index is the position of the offending message.
+	Index int
+	// Reason describes the problem.
+	Reason string
+}
+
+// Error implements error.
+func (e *MessageError) Error() string {
+	return fmt.Sprintf("message %d: %s", e.Index, e.Reason)
+}
+
+// MessageErrors collects every problem found in a history. It matches
+// ErrInvalidRequest, the error the provider would have returned.
+type MessageErrors []*MessageError
+
+// Error implements error.
+func (errs MessageErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "invalid message sequence: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrInvalidRequest.
+func (errs MessageErrors) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// WithValidateMessages checks the history with ValidateMessages before it is sent,
+// failing the request with MessageErrors instead of an opaque provider error.
+func WithValidateMessages(validate bool) ChatOption {
+	return func(opts *ChatOptions) { opts.validateMessages = validate }
+}
+
+// ValidateMessages checks that a history is acceptable to provider (see
+// constants/provider.go) and returns MessageErrors listing every problem, or nil:
+//   - messages must be non-nil, of a known role and carry content, tool calls or
+//     reasoning; tool results need the ID of the call they answer,
+//   - every tool result must answer a call of the last assistant message that has
+//     not been answered yet,
+//   - every tool call must be answered before the conversation moves on: before
+//     the next non-tool message for OpenAI, and before the next assistant message
+//     for Anthropic, whose user turns merge tool results with the following text.
+//
+// Consecutive messages of the same role are not errors: Anthropic histories are
+// merged into alternating turns before they are sent.
+func ValidateMessages(provider string, messages []Message) error {
+	var errs MessageErrors
+	fail := func(index int, format string, args ...any) {
+		errs = append(errs, &MessageError{Index: index, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// pending holds the unanswered calls of the last assistant message, by ID
+	pending := map[string]bool{}
+	caller := -1
+	// unanswered reports the calls still pending when the conversation moves on
+	unanswered := func() {
+		for _, tcall := range asLLMMessage(messages[caller]).toolCalls {
+			if pending[tcall.id] {
+				fail(caller, "tool call %q (%s) has no result", tcall.id, tcall.fcall.name)
+			}
+		}
+		clear(pending)
+	}
+
+	for i, message := range messages {
+		if message == nil {
+			fail(i, "message is nil")
+			continue
+		}
+		msg := asLLMMessage(message)
+		role := msg.role
+		switch role {
+		case constants.RoleSystem, constants.RoleUser, constants.RoleAssistant, constants.RoleTool:
+		default:
+			fail(i, "unknown role %q", role)
+			continue
+		}
+
+		if len(pending) > 0 {
+			moveOn := role == constants.RoleAssistant
+			if provider != constants.ProviderAnthropic {
+				moveOn = role != constants.RoleTool
+			}
+			if moveOn {
+				unanswered()
+			}
+		}
+
+		switch role {
+		case constants.RoleTool:
+			switch id := msg.toolCallID; {
+			case id == "":
+				fail(i, "tool result has no tool call ID")
+			case pending[id]:
+				delete(pending, id)
+			default:
+				fail(i, "tool result answers unknown or already answered tool call %q", id)
+			}
+		case constants.RoleAssistant:
+			caller = i
+			for _, tcall := range msg.toolCalls {
+				switch {
+				case tcall.id == "":
+					fail(i, "tool call %s has no ID", tcall.fcall.name)
+				case tcall.fcall.name == "":
+					fail(i, "tool call %q has no function name", tcall.id)
+				case pending[tcall.id]:
+					fail(i, "duplicate tool call ID %q", tcall.id)
+				default:
+					pending[tcall.id] = true
+				}
+			}
+			if len(msg.toolCalls) == 0 && isEmptyMessage(msg) {
+				fail(i, "assistant message is empty")
+			}
+		default:
+			if isEmptyMessage(msg) {
+				fail(i, "%s message is empty", role)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		unanswered()
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isEmptyMessage reports whether msg carries no text, media or reasoning.
+func isEmptyMessage(msg *llmmsg) bool {
+	if strings.TrimSpace(msg.reasoning) != "" || msg.refusal != "" {
+		return false
+	}
+	for _, part := range msg.content {
+		if part.Type != constants.ContentPartTypeText || strings.TrimSpace(part.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/thecxx/openllm/constants"
+)
+
+// StreamEvent is the unified, provider-agnostic representation of a single stream event.
+type StreamEvent struct {
+	// Type is one of the constants.StreamEvent* values.
+	Type string `json:"type"`
+	// Delta carries the text for content, reasoning and refusal events,
+	// and the partial JSON arguments for tool_call events.
+	Delta string `json:"delta,omitempty"`
+	// ToolCall identifies the tool call for tool_call and tool_call_done events.
+	ToolCall *StreamToolCall `json:"tool_call,omitempty"`
+	// Path and Value are set for field events (see FieldWatcher).
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+	// Usage is set for usage events.
+	Usage *Usage `json:"usage,omitempty"`
+	// Meta is set for meta events.
+	Meta *Meta `json:"meta,omitempty"`
+	// Error is set for error events.
+	Error string `json:"error,omitempty"`
+}
+
+// StreamToolCall identifies a tool call within a StreamEvent.
+type StreamToolCall struct {
+	Index int    `json:"index"`
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+}
+
+// newStreamToolCall captures the identity of tcall for a StreamEvent.
+func newStreamToolCall(tcall ToolCall) *StreamToolCall {
+	return &StreamToolCall{
+		Index: tcall.Index(),
+		ID:    tcall.ID(),
+		Type:  tcall.Type(),
+		Name:  tcall.Function().Name(),
+	}
+}
+
+// HTTPStreamEncoding selects how events are framed on the wire.
+type HTTPStreamEncoding int
+
+const (
+	// HTTPStreamSSE frames events as Server-Sent Events (text/event-stream).
+	HTTPStreamSSE HTTPStreamEncoding = iota
+	// HTTPStreamNDJSON writes one JSON document per line (application/x-ndjson).
+	HTTPStreamNDJSON
+)
+
+// HTTPStreamFormat selects the payload schema of each event.
+type HTTPStreamFormat int
+
+const (
+	// HTTPStreamUnified writes StreamEvent objects.
+	HTTPStreamUnified HTTPStreamFormat = iota
+	// HTTPStreamOpenAI writes OpenAI chat.completion.chunk objects, terminated by
+	// "data: [DONE]" when using SSE, so existing OpenAI clients can consume the stream.
+	HTTPStreamOpenAI
+)
+
+// HTTPStreamOption configures an HTTPStreamWatcher.
+type HTTPStreamOption func(w *HTTPStreamWatcher)
+
+// WithHTTPStreamEncoding sets the wire framing (default HTTPStreamSSE).
+func WithHTTPStreamEncoding(encoding HTTPStreamEncoding) HTTPStreamOption {
+	return func(w *HTTPStreamWatcher) { w.encoding = encoding }
+}
+
+// WithHTTPStreamFormat sets the payload schema (default HTTPStreamUnified).
+func WithHTTPStreamFormat(format HTTPStreamFormat) HTTPStreamOption {
+	return func(w *HTTPStreamWatcher) { w.format = format }
+}
+
+// HTTPStreamWatcher is a StreamWatcher that relays every event to an http.ResponseWriter,
+// making it straightforward to expose a model as a streaming HTTP endpoint:
+//
+//	model.ChatCompletionStream(ctx, messages, openllm.WithStreamWatcher(openllm.NewHTTPStreamWatcher(w)))
+//
+// Write failures (e.g., the client disconnected) are returned from the callbacks and abort the stream.
+type HTTPStreamWatcher struct {
+	w        http.ResponseWriter
+	encoding HTTPStreamEncoding
+	format   HTTPStreamFormat
+
+	// started reports whether response headers have been written.
+	started bool
+	// created is the timestamp reported in OpenAI chunks.
+	created int64
+	// meta is the latest metadata reported by the provider.
+	meta Meta
+	// usage is the latest usage reported by the provider, if any.
+	usage *Usage
+	// toolCalls reports whether any tool call was relayed.
+	toolCalls bool
+}
+
+// NewHTTPStreamWatcher creates a watcher that writes stream events to w.
+func NewHTTPStreamWatcher(w http.ResponseWriter, opts ...HTTPStreamOption) *HTTPStreamWatcher {
+	hw := &HTTPStreamWatcher{w: w, created: time.Now().Unix()}
+	for _, opt := range opts {
+		opt(hw)
+	}
+	return hw
+}
+
+// OnRefusal implements StreamWatcher.
+func (hw *HTTPStreamWatcher) OnRefusal(delta string) error {
+	if hw.format == HTTPStreamOpenAI {
+		return hw.writeChunk(openai.ChatCompletionStreamChoiceDelta{Refusal: delta}, "")
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventRefusal, Delta: delta})
+}
+
+// OnReasoning implements StreamWatcher.
+func (hw *HTTPStreamWatcher) OnReasoning(delta string) error {
+	if hw.format == HTTPStreamOpenAI {
+		return hw.writeChunk(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: delta}, "")
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventReasoning, Delta: delta})
+}
+
+// OnContent implements StreamWatcher.
+func (hw *HTTPStreamWatcher) OnContent(delta string) error {
+	if hw.format == HTTPStreamOpenAI {
+		return hw.writeChunk(openai.ChatCompletionStreamChoiceDelta{Content: delta}, "")
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventContent, Delta: delta})
+}
+
+// OnToolCall implements StreamWatcher.
+func (hw *HTTPStreamWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	hw.toolCalls = true
+	if hw.format == HTTPStreamOpenAI {
+		index := tcall.Index()
+		call := openai.ToolCall{Index: &index}
+		if args == "" {
+			// First event of a call carries its identity
+			call.ID = tcall.ID()
+			call.Type = openai.ToolType(tcall.Type())
+			call.Function.Name = tcall.Function().Name()
+		}
+		call.Function.Arguments = args
+		return hw.writeChunk(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{call}}, "")
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventToolCall, Delta: args, ToolCall: newStreamToolCall(tcall)})
+}
+
+// OnToolCallDone implements ToolCallDoneWatcher.
+func (hw *HTTPStreamWatcher) OnToolCallDone(ctx context.Context, tcall ToolCall) error {
+	if hw.format == HTTPStreamOpenAI {
+		// OpenAI chunks have no per-call completion marker
+		return nil
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventToolCallDone, ToolCall: newStreamToolCall(tcall)})
+}
+
+// OnField implements FieldWatcher.
+func (hw *HTTPStreamWatcher) OnField(path string, value any) error {
+	if hw.format == HTTPStreamOpenAI {
+		// The fields are already part of the streamed content
+		return nil
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventField, Path: path, Value: value})
+}
+
+// OnUsage implements UsageWatcher.
+func (hw *HTTPStreamWatcher) OnUsage(usage Usage) error {
+	hw.usage = &usage
+	if hw.format == HTTPStreamOpenAI {
+		// Reported once in the final chunk
+		return nil
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventUsage, Usage: &usage})
+}
+
+// OnMeta implements UsageWatcher.
+func (hw *HTTPStreamWatcher) OnMeta(meta Meta) error {
+	hw.meta = meta
+	if hw.format == HTTPStreamOpenAI {
+		return nil
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventMeta, Meta: &meta})
+}
+
+// OnStop implements StreamWatcher.
+func (hw *HTTPStreamWatcher) OnStop() error {
+	if hw.format == HTTPStreamOpenAI {
+		reason := openai.FinishReasonStop
+		if hw.toolCalls {
+			reason = openai.FinishReasonToolCalls
+		}
+		if err := hw.writeChunk(openai.ChatCompletionStreamChoiceDelta{}, reason); err != nil {
+			return err
+		}
+		if hw.usage != nil {
+			chunk := hw.chunk()
+			chunk.Choices = []openai.ChatCompletionStreamChoice{}
+			chunk.Usage = &openai.Usage{
+				PromptTokens:     hw.usage.InputTokens,
+				CompletionTokens: hw.usage.OutputTokens,
+				TotalTokens:      hw.usage.TotalTokens,
+			}
+			if err := hw.write("", chunk); err != nil {
+				return err
+			}
+		}
+		if hw.encoding == HTTPStreamSSE {
+			return hw.writeRaw("data: [DONE]\n\n")
+		}
+		return nil
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventStop})
+}
+
+// WriteError reports a failure to the client, typically after ChatCompletionStream returned an error.
+func (hw *HTTPStreamWatcher) WriteError(err error) error {
+	if hw.format == HTTPStreamOpenAI {
+		return hw.write("", map[string]any{
+			"error": map[string]string{"message": err.Error()},
+		})
+	}
+	return hw.writeEvent(StreamEvent{Type: constants.StreamEventError, Error: err.Error()})
+}
+
+// chunk returns an empty OpenAI chunk populated with the current metadata.
+func (hw *HTTPStreamWatcher) chunk() openai.ChatCompletionStreamResponse {
+	return openai.ChatCompletionStreamResponse{
+		ID:                hw.meta.RequestID,
+		Object:            "chat.completion.chunk",
+		Created:           hw.created,
+		Model:             hw.meta.Model,
+		SystemFingerprint: hw.meta.SystemFingerprint,
+	}
+}
+
+// writeChunk writes a single-choice OpenAI chunk.
+func (hw *HTTPStreamWatcher) writeChunk(delta openai.ChatCompletionStreamChoiceDelta, reason openai.FinishReason) error {
+	chunk := hw.chunk()
+	chunk.Choices = []openai.ChatCompletionStreamChoice{{
+		Index:        0,
+		Delta:        delta,
+		FinishReason: reason,
+	}}
+	return hw.write("", chunk)
+}
+
+// writeEvent writes a unified event, naming the SSE event after its type.
+func (hw *HTTPStreamWatcher) writeEvent(event StreamEvent) error {
+	return hw.write(event.Type, event)
+}
+
+// write encodes v and frames it according to the configured encoding.
+func (hw *HTTPStreamWatcher) write(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if hw.encoding == HTTPStreamNDJSON {
+		return hw.writeRaw(string(data) + "\n")
+	}
+	if name != "" {
+		return hw.writeRaw(fmt.Sprintf("event: %s\ndata: %s\n\n", name, data))
+	}
+	return hw.writeRaw(fmt.Sprintf("data: %s\n\n", data))
+}
+
+// writeRaw writes headers on first use, then the payload, and flushes it to the client.
+func (hw *HTTPStreamWatcher) writeRaw(payload string) error {
+	if !hw.started {
+		hw.started = true
+		header := hw.w.Header()
+		if hw.encoding == HTTPStreamNDJSON {
+			header.Set("Content-Type", "application/x-ndjson")
+		} else {
+			header.Set("Content-Type", "text/event-stream")
+			header.Set("Connection", "keep-alive")
+		}
+		header.Set("Cache-Control", "no-cache")
+		hw.w.WriteHeader(http.StatusOK)
+	}
+	if _, err := hw.w.Write([]byte(payload)); err != nil {
+		return err
+	}
+	if flusher, ok := hw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,649 @@
+package openllm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// streamAccumulator collects streamed deltas, forwards them to the watcher,
+// and assembles the final Response. It is shared by all streaming backends so
+// that partial output can be recovered when the stream stops early.
+type streamAccumulator struct {
+	// watcher receives events as they are accumulated; may be nil.
+	watcher StreamWatcher
+	// options are the request options, against which the final answer is validated.
+	options *ChatOptions
+	// start is the time the request was issued.
+	start time.Time
+
+	role      string
+	content   strings.Builder
+	reasoning strings.Builder
+	refusal   strings.Builder
+	// callm indexes tool calls by their provider-assigned position.
+	callm map[int]*toolcall
+	// done records tool calls whose arguments are complete.
+	done map[int]bool
+	// last is the index of the most recently started tool call, or -1.
+	last  int
+	usage Usage
+	meta  Meta
+	// extras holds non-text parts with the content offset at which they occurred.
+	extras []extraPart
+	// spans holds citations attached to ranges of the content.
+	spans []citedSpan
+	// fields parses structured output for a FieldWatcher; nil when not needed.
+	fields *fieldStream
+	// logprobs holds the log probabilities of the content tokens, if requested.
+	logprobs []TokenLogProb
+	// firstOutput and lastOutput are the times of the first and latest output deltas.
+	firstOutput time.Time
+	lastOutput  time.Time
+	// gaps holds the time between successive output deltas.
+	gaps []time.Duration
+	// limits bounds the streamed output; outputBytes counts it.
+	limits      StreamLimits
+	outputBytes int
+
+	// bufferMinBytes and bufferFlushEvery configure delta coalescing (see WithStreamBuffering).
+	bufferMinBytes   int
+	bufferFlushEvery time.Duration
+	// pending holds coalesced text not yet delivered to the watcher.
+	pending strings.Builder
+	// pendingReasoning reports whether pending holds reasoning rather than content.
+	pendingReasoning bool
+	// lastFlush is the time pending was last delivered.
+	lastFlush time.Time
+}
+
+// newStreamAccumulator creates an accumulator for a stream started now.
+func newStreamAccumulator(options *ChatOptions, meta Meta) *streamAccumulator {
+	now := time.Now()
+	watcher := options.watcher
+	if watcher != nil && options.keepStreamOnWatcherError {
+		watcher = &detachingWatcher{watcher: watcher}
+	}
+	acc := &streamAccumulator{
+		watcher:          watcher,
+		options:          options,
+		start:            now,
+		callm:            make(map[int]*toolcall),
+		done:             make(map[int]bool),
+		last:             -1,
+		meta:             meta,
+		limits:           options.streamLimits,
+		bufferMinBytes:   options.bufferMinBytes,
+		bufferFlushEvery: options.bufferFlushEvery,
+		lastFlush:        now,
+	}
+	if _, ok := options.watcher.(FieldWatcher); ok && options.responseFormat != "" &&
+		options.responseFormat != constants.ResponseFormatText {
+		acc.fields = newFieldStream()
+	}
+	return acc
+}
+
+// buffering reports whether delta coalescing is enabled.
+func (acc *streamAccumulator) buffering() bool {
+	return acc.watcher != nil && (acc.bufferMinBytes > 0 || acc.bufferFlushEvery > 0)
+}
+
+// buffer queues a text delta and delivers the pending text once a threshold is reached.
+func (acc *streamAccumulator) buffer(delta string, reasoning bool) error {
+	if acc.pending.Len() > 0 && acc.pendingReasoning != reasoning {
+		if err := acc.flush(); err != nil {
+			return err
+		}
+	}
+	acc.pending.WriteString(delta)
+	acc.pendingReasoning = reasoning
+
+	if acc.bufferMinBytes > 0 && acc.pending.Len() >= acc.bufferMinBytes {
+		return acc.flush()
+	}
+	if acc.bufferFlushEvery > 0 && time.Since(acc.lastFlush) >= acc.bufferFlushEvery {
+		return acc.flush()
+	}
+	return nil
+}
+
+// flush delivers any pending coalesced text to the watcher.
+func (acc *streamAccumulator) flush() error {
+	if acc.pending.Len() == 0 {
+		return nil
+	}
+	text := acc.pending.String()
+	acc.pending.Reset()
+	acc.lastFlush = time.Now()
+	if acc.pendingReasoning {
+		return acc.watcher.OnReasoning(text)
+	}
+	return acc.watcher.OnContent(text)
+}
+
+// setRole records the role of the streamed message (first one wins).
+func (acc *streamAccumulator) setRole(role string) {
+	if acc.role == "" {
+		acc.role = role
+	}
+}
+
+// markOutput records the arrival time of an output delta.
+func (acc *streamAccumulator) markOutput() {
+	now := time.Now()
+	if acc.firstOutput.IsZero() {
+		acc.firstOutput = now
+	} else {
+		acc.gaps = append(acc.gaps, now.Sub(acc.lastOutput))
+	}
+	acc.lastOutput = now
+}
+
+// latency computes the latency breakdown of the stream once it has ended.
+func (acc *streamAccumulator) latency(duration time.Duration) *Latency {
+	if acc.firstOutput.IsZero() {
+		return blockingLatency(acc.usage, duration)
+	}
+	latency := &Latency{TTFT: acc.firstOutput.Sub(acc.start)}
+	if generation := acc.lastOutput.Sub(acc.firstOutput); generation > 0 {
+		latency.TokensPerSecond = float64(acc.usage.OutputTokens) / generation.Seconds()
+	}
+	if len(acc.gaps) > 0 {
+		gaps := append([]time.Duration(nil), acc.gaps...)
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		percentile := func(p int) time.Duration { return gaps[(len(gaps)-1)*p/100] }
+		latency.InterTokenP50 = percentile(50)
+		latency.InterTokenP90 = percentile(90)
+		latency.InterTokenP99 = percentile(99)
+	}
+	return latency
+}
+
+// limit accounts for an output delta, failing with an *OutputLimitError when it
+// would exceed the stream limits. tcall is the call receiving argument deltas, or nil.
+func (acc *streamAccumulator) limit(delta string, tcall *toolcall) error {
+	if maxBytes := acc.limits.MaxToolArgumentBytes; tcall != nil && maxBytes > 0 &&
+		len(tcall.fcall.Arguments())+len(delta) > maxBytes {
+		return &OutputLimitError{Limit: maxBytes, ToolCall: tcall}
+	}
+	if maxBytes := acc.limits.MaxOutputBytes; maxBytes > 0 && acc.outputBytes+len(delta) > maxBytes {
+		return &OutputLimitError{Limit: maxBytes}
+	}
+	acc.outputBytes += len(delta)
+	return nil
+}
+
+// onContent appends a content delta and notifies the watcher.
+func (acc *streamAccumulator) onContent(delta string) error {
+	if err := acc.limit(delta, nil); err != nil {
+		return err
+	}
+	acc.markOutput()
+	return acc.writeContent(delta)
+}
+
+// onPrefill appends text the request put in the model's mouth, such as the
+// Anthropic JSON prefill. It is delivered like content, but it is not generated
+// output and does not count toward latency.
+func (acc *streamAccumulator) onPrefill(prefix string) error {
+	if err := acc.limit(prefix, nil); err != nil {
+		return err
+	}
+	return acc.writeContent(prefix)
+}
+
+// writeContent appends answer text and notifies the watcher.
+func (acc *streamAccumulator) writeContent(delta string) error {
+	acc.content.WriteString(delta)
+	var err error
+	if acc.buffering() {
+		err = acc.buffer(delta, false)
+	} else if acc.watcher != nil {
+		err = acc.watcher.OnContent(delta)
+	}
+	if err != nil || acc.fields == nil {
+		return err
+	}
+	return acc.onFields(acc.fields.write(delta))
+}
+
+// onFields delivers completed structured output values to the FieldWatcher.
+func (acc *streamAccumulator) onFields(events []fieldEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	w := acc.watcher.(FieldWatcher)
+	for _, ev := range events {
+		if err := w.OnField(ev.path, ev.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onReasoning appends a reasoning delta and notifies the watcher.
+func (acc *streamAccumulator) onReasoning(delta string) error {
+	if err := acc.limit(delta, nil); err != nil {
+		return err
+	}
+	acc.markOutput()
+	acc.reasoning.WriteString(delta)
+	if acc.buffering() {
+		return acc.buffer(delta, true)
+	}
+	if acc.watcher != nil {
+		return acc.watcher.OnReasoning(delta)
+	}
+	return nil
+}
+
+// onRefusal appends a refusal delta and notifies the watcher.
+func (acc *streamAccumulator) onRefusal(delta string) error {
+	if err := acc.limit(delta, nil); err != nil {
+		return err
+	}
+	acc.markOutput()
+	acc.refusal.WriteString(delta)
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	if acc.watcher != nil {
+		return acc.watcher.OnRefusal(delta)
+	}
+	return nil
+}
+
+// onToolCallStart registers a new tool call and notifies the watcher.
+func (acc *streamAccumulator) onToolCallStart(ctx context.Context, tcall *toolcall) error {
+	acc.markOutput()
+	acc.callm[tcall.index] = tcall
+	acc.last = tcall.index
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	if acc.watcher != nil {
+		return acc.watcher.OnToolCall(ctx, tcall, "")
+	}
+	return nil
+}
+
+// onToolCallArgs appends an argument delta to the tool call at index and notifies the watcher.
+// Deltas for unknown indices are ignored.
+func (acc *streamAccumulator) onToolCallArgs(ctx context.Context, index int, delta string) error {
+	tcall, found := acc.callm[index]
+	if !found {
+		return nil
+	}
+	if err := acc.limit(delta, tcall); err != nil {
+		return err
+	}
+	acc.markOutput()
+	tcall.fcall.writeArgs(delta)
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	if acc.watcher != nil {
+		return acc.watcher.OnToolCall(ctx, tcall, delta)
+	}
+	return nil
+}
+
+// onToolCallDone marks the tool call at index as complete and notifies the watcher once.
+func (acc *streamAccumulator) onToolCallDone(ctx context.Context, index int) error {
+	tcall, found := acc.callm[index]
+	if !found || acc.done[index] {
+		return nil
+	}
+	acc.done[index] = true
+	tcall.fcall.close()
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	if w, ok := acc.watcher.(ToolCallDoneWatcher); ok {
+		return w.OnToolCallDone(ctx, tcall)
+	}
+	return nil
+}
+
+// finishToolCalls completes every tool call that has not been marked done yet, in index order.
+func (acc *streamAccumulator) finishToolCalls(ctx context.Context) error {
+	for _, tcall := range acc.toolCalls() {
+		if err := acc.onToolCallDone(ctx, tcall.index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPart records a non-text content part at the current position in the content.
+func (acc *streamAccumulator) addPart(part ContentPart) {
+	acc.extras = append(acc.extras, extraPart{offset: acc.content.Len(), part: part})
+}
+
+// addCitations attaches citations to the content written since offset start.
+func (acc *streamAccumulator) addCitations(start int, citations []Citation) {
+	if len(citations) == 0 {
+		return
+	}
+	acc.spans = append(acc.spans, citedSpan{start: start, end: acc.content.Len(), citations: citations})
+}
+
+// onUsage records the latest usage and notifies the watcher after pending text.
+func (acc *streamAccumulator) onUsage(usage Usage) error {
+	usage = usage.withCost(acc.meta.Model)
+	acc.usage = usage
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	return notifyUsage(acc.watcher, usage)
+}
+
+// onMeta notifies the watcher of the current metadata after pending text.
+// Callers mutate acc.meta directly before invoking it.
+func (acc *streamAccumulator) onMeta() error {
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	return notifyMeta(acc.watcher, acc.meta)
+}
+
+// onRawEvent forwards a provider-native event to the watcher after pending text.
+func (acc *streamAccumulator) onRawEvent(event any) error {
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	return notifyRawEvent(acc.watcher, event)
+}
+
+// onStop delivers pending text and notifies the watcher that the stream completed.
+func (acc *streamAccumulator) onStop() error {
+	if err := acc.flush(); err != nil {
+		return err
+	}
+	if acc.watcher != nil {
+		return acc.watcher.OnStop()
+	}
+	return nil
+}
+
+// result returns the response assembled at the end of a stream, with a
+// *WatcherError if a detached watcher failed along the way, or the validation
+// errors of the answer (see WithValidateOutput).
+func (acc *streamAccumulator) result() (Response, error) {
+	resp := acc.response()
+	if w, ok := acc.watcher.(*detachingWatcher); ok && w.err != nil {
+		return resp, &WatcherError{Response: resp, Err: w.err}
+	}
+	return resp, validateAnswer(acc.options, resp)
+}
+
+// fail converts an error raised while streaming into the values returned to the caller.
+// ErrStopStreaming is not a failure: the partial response is returned with a nil error.
+// Every other error, including one raised before any output, is wrapped in a
+// PartialResponseError carrying the same partial response.
+func (acc *streamAccumulator) fail(err error) (Response, error) {
+	resp := acc.response()
+	if errors.Is(err, ErrStopStreaming) {
+		return resp, nil
+	}
+	return resp, &PartialResponseError{Response: resp, Err: err}
+}
+
+// toolCalls returns the accumulated tool calls ordered by index.
+func (acc *streamAccumulator) toolCalls() []*toolcall {
+	tcalls := make([]*toolcall, 0, len(acc.callm))
+	for _, tcall := range acc.callm {
+		tcalls = append(tcalls, tcall)
+	}
+	sort.Slice(tcalls, func(i, j int) bool {
+		return tcalls[i].index < tcalls[j].index
+	})
+	return tcalls
+}
+
+// response assembles a Response from everything accumulated so far.
+func (acc *streamAccumulator) response() Response {
+	role := acc.role
+	if role == "" {
+		role = constants.RoleAssistant
+	}
+	answer := &llmmsg{
+		role:      role,
+		reasoning: acc.reasoning.String(),
+		refusal:   acc.refusal.String(),
+	}
+	answer.content = interleaveParts(acc.content.String(), acc.extras, acc.spans)
+	if len(answer.content) == 0 {
+		// An empty answer keeps a single empty text part
+		answer.content = []ContentPart{{Type: constants.ContentPartTypeText}}
+	}
+
+	var tcalls = make([]ToolCall, 0)
+	for _, tc := range acc.toolCalls() {
+		tcalls = append(tcalls, tc)
+		answer.toolCalls = append(answer.toolCalls, &toolcall{
+			index: tc.index,
+			id:    tc.id,
+			type_: tc.type_,
+			fcall: funcall{
+				name: tc.fcall.Name(),
+				args: tc.fcall.Arguments(),
+			},
+		})
+	}
+
+	duration := time.Since(acc.start)
+	meta := acc.meta
+	meta.Latency = acc.latency(duration)
+	return &response{
+		answer:   answer,
+		tcalls:   tcalls,
+		usage:    acc.usage,
+		meta:     meta,
+		duration: duration,
+		logprobs: acc.logprobs,
+	}
+}
+
+// extraPart is a non-text content part positioned within the streamed text.
+type extraPart struct {
+	// offset is the length of the text content when the part was received.
+	offset int
+	part   ContentPart
+}
+
+// citedSpan is a range of the streamed text supported by citations.
+type citedSpan struct {
+	start, end int
+	citations  []Citation
+}
+
+// interleaveParts splits text at the offsets of extras and the bounds of cited spans,
+// and returns the parts in stream order with citations attached to their text.
+func interleaveParts(text string, extras []extraPart, spans []citedSpan) []ContentPart {
+	var parts []ContentPart
+	prev := 0
+	emitText := func(end int) {
+		for prev < end {
+			next, citations := end, []Citation(nil)
+			for _, span := range spans {
+				if span.end <= prev {
+					continue
+				}
+				if span.start <= prev {
+					next, citations = min(span.end, end), span.citations
+				} else if span.start < end {
+					next = span.start
+				}
+				break
+			}
+			parts = append(parts, ContentPart{Type: constants.ContentPartTypeText, Text: text[prev:next], Citations: citations})
+			prev = next
+		}
+	}
+	for _, extra := range extras {
+		emitText(extra.offset)
+		parts = append(parts, extra.part)
+	}
+	emitText(len(text))
+	return parts
+}
+
+// notifyUsage forwards a usage update to the watcher if it implements UsageWatcher.
+func notifyUsage(watcher StreamWatcher, usage Usage) error {
+	if w, ok := watcher.(UsageWatcher); ok {
+		return w.OnUsage(usage)
+	}
+	return nil
+}
+
+// notifyMeta forwards a metadata update to the watcher if it implements UsageWatcher.
+func notifyMeta(watcher StreamWatcher, meta Meta) error {
+	if w, ok := watcher.(UsageWatcher); ok {
+		return w.OnMeta(meta)
+	}
+	return nil
+}
+
+// notifyRawEvent forwards a provider-native event to the watcher if it implements RawEventWatcher.
+func notifyRawEvent(watcher StreamWatcher, event any) error {
+	if w, ok := watcher.(RawEventWatcher); ok {
+		return w.OnRawEvent(event)
+	}
+	return nil
+}
+
+// detachingWatcher forwards events to a watcher until one of its callbacks fails,
+// then records the error and drops the remaining events, so the stream runs to
+// completion instead of being cancelled (see WithCancelOnWatcherError).
+type detachingWatcher struct {
+	watcher StreamWatcher
+	// err is the first error returned by the watcher.
+	err error
+}
+
+// forward delivers an event unless the watcher has failed, detaching it on failure.
+// ErrStopStreaming is passed through so the watcher can still stop the stream.
+func (w *detachingWatcher) forward(deliver func() error) error {
+	if w.err != nil {
+		return nil
+	}
+	err := deliver()
+	if err != nil && !errors.Is(err, ErrStopStreaming) {
+		w.err = err
+		return nil
+	}
+	return err
+}
+
+// OnRefusal implements StreamWatcher.
+func (w *detachingWatcher) OnRefusal(delta string) error {
+	return w.forward(func() error { return w.watcher.OnRefusal(delta) })
+}
+
+// OnReasoning implements StreamWatcher.
+func (w *detachingWatcher) OnReasoning(delta string) error {
+	return w.forward(func() error { return w.watcher.OnReasoning(delta) })
+}
+
+// OnContent implements StreamWatcher.
+func (w *detachingWatcher) OnContent(delta string) error {
+	return w.forward(func() error { return w.watcher.OnContent(delta) })
+}
+
+// OnToolCall implements StreamWatcher.
+func (w *detachingWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	return w.forward(func() error { return w.watcher.OnToolCall(ctx, tcall, args) })
+}
+
+// OnStop implements StreamWatcher.
+func (w *detachingWatcher) OnStop() error {
+	return w.forward(w.watcher.OnStop)
+}
+
+// OnUsage implements UsageWatcher.
+func (w *detachingWatcher) OnUsage(usage Usage) error {
+	return w.forward(func() error { return notifyUsage(w.watcher, usage) })
+}
+
+// OnMeta implements UsageWatcher.
+func (w *detachingWatcher) OnMeta(meta Meta) error {
+	return w.forward(func() error { return notifyMeta(w.watcher, meta) })
+}
+
+// OnToolCallDone implements ToolCallDoneWatcher.
+func (w *detachingWatcher) OnToolCallDone(ctx context.Context, tcall ToolCall) error {
+	return w.forward(func() error {
+		if dw, ok := w.watcher.(ToolCallDoneWatcher); ok {
+			return dw.OnToolCallDone(ctx, tcall)
+		}
+		return nil
+	})
+}
+
+// OnField implements FieldWatcher.
+func (w *detachingWatcher) OnField(path string, value any) error {
+	return w.forward(func() error {
+		if fw, ok := w.watcher.(FieldWatcher); ok {
+			return fw.OnField(path, value)
+		}
+		return nil
+	})
+}
+
+// OnRawEvent implements RawEventWatcher.
+func (w *detachingWatcher) OnRawEvent(providerEvent any) error {
+	return w.forward(func() error { return notifyRawEvent(w.watcher, providerEvent) })
+}
+
+// idleTimer cancels a stream's context with ErrStreamIdleTimeout when it is
+// not reset within the configured timeout. A nil *idleTimer is a no-op.
+type idleTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+// startIdleTimer arms an idle timer; it returns nil when timeout is not positive.
+func startIdleTimer(timeout time.Duration, cancel context.CancelCauseFunc) *idleTimer {
+	if timeout <= 0 {
+		return nil
+	}
+	return &idleTimer{
+		timeout: timeout,
+		timer:   time.AfterFunc(timeout, func() { cancel(ErrStreamIdleTimeout) }),
+	}
+}
+
+// reset restarts the idle window after an event has been received.
+func (t *idleTimer) reset() {
+	if t != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+// stop disarms the timer.
+func (t *idleTimer) stop() {
+	if t != nil {
+		t.timer.Stop()
+	}
+}
+
+// cancelCause prefers the cancellation cause recorded on ctx (such as
+// ErrStreamIdleTimeout or context.DeadlineExceeded) over the transport error it
+// produced, so cancelled requests consistently fail with the context's error.
+func cancelCause(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+	}
+	return err
+}
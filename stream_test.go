@@ -0,0 +1,106 @@
+package openllm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestStreamBufferingFlushesBeforeUsage(t *testing.T) {
+	watcher := &orderWatcher{}
+	_, err := FakeStream{Content: "hello world", ChunkRunes: 1}.Play(context.Background(),
+		WithStreamWatcher(watcher), WithStreamBuffering(1<<10, 0))
+	if err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	want := []string{"meta", "content:hello world", "usage", "meta", "stop"}
+	if !slices.Equal(watcher.events, want) {
+		t.Errorf("events = %q, want %q", watcher.events, want)
+	}
+}
+
+func TestAnthropicPrefillIsNotFirstOutput(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4-5\",\"content\":[],\"usage\":{\"input_tokens\":5,\"output_tokens\":1}}}\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		io.WriteString(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"+
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"\\\"a\\\":1}\"}}\n\n"+
+			"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"+
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":4}}\n\n"+
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer srv.Close()
+
+	model := NewAnthropicLLMWithAPIKey("claude-sonnet-4-5", "", "key", WithBaseURL(srv.URL))
+	resp, err := model.ChatCompletionStream(context.Background(), []Message{NewUserMessage("json please")},
+		WithStreamWatcher(&orderWatcher{}), WithResponseFormat(constants.ResponseFormatJSONObject))
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	if got := resp.Answer().Content(); got != `{"a":1}` {
+		t.Errorf("answer = %s, want {\"a\":1}", got)
+	}
+	if latency := resp.Meta().Latency; latency == nil || latency.TTFT < delay {
+		t.Errorf("latency = %+v, want a TTFT of at least %v", latency, delay)
+	}
+}
+
+// orderWatcher records the order of the callbacks it receives.
+type orderWatcher struct {
+	events []string
+}
+
+func (w *orderWatcher) OnRefusal(delta string) error {
+	w.events = append(w.events, "refusal:"+delta)
+	return nil
+}
+
+func (w *orderWatcher) OnReasoning(delta string) error {
+	w.events = append(w.events, "reasoning:"+delta)
+	return nil
+}
+
+func (w *orderWatcher) OnContent(delta string) error {
+	w.events = append(w.events, "content:"+delta)
+	return nil
+}
+
+func (w *orderWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	return nil
+}
+
+func (w *orderWatcher) OnStop() error {
+	w.events = append(w.events, "stop")
+	return nil
+}
+
+func (w *orderWatcher) OnUsage(usage Usage) error {
+	w.events = append(w.events, "usage")
+	return nil
+}
+
+func (w *orderWatcher) OnMeta(meta Meta) error {
+	w.events = append(w.events, "meta")
+	return nil
+}
+
+func TestStreamEmptyAnswerHasTextPart(t *testing.T) {
+	script := FakeStream{ToolCalls: []FakeToolCall{{Name: "get_time", Arguments: `{}`}}}
+	resp, err := script.Play(context.Background(), WithStreamWatcher(&orderWatcher{}))
+	if err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	parts := resp.Answer().(RichMessage).Parts()
+	if len(parts) != 1 || parts[0].Type != constants.ContentPartTypeText || parts[0].Text != "" {
+		t.Errorf("parts = %+v, want one empty text part", parts)
+	}
+}
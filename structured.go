@@ -0,0 +1,310 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"github.com/thecxx/openllm/constants"
+)
+
+// responseSchema is the structured output requested with WithResponseSchema.
+type responseSchema struct {
+	name   string
+	schema any
+	strict bool
+}
+
+// WithResponseSchema asks the model to answer with JSON matching schema.
+// The schema may be a Go struct value or pointer (or its reflect.Type), whose
+// schema is generated like tool parameters, or a ready schema such as *Schema,
+// json.RawMessage, jsonschema.Definition or map[string]any.
+//
+// OpenAI receives it as response_format json_schema. Anthropic is forced to call a
+// single tool named name whose input is the answer; the arguments are returned as
+// the answer text rather than as a tool call. With strict, generated schemas also
+// disallow unknown properties and require every property, as OpenAI strict mode
+// expects. name may only contain letters, digits, '_' and '-'.
+func WithResponseSchema(name string, schema any, strict bool) ChatOption {
+	return func(opts *ChatOptions) {
+		opts.responseFormat = constants.ResponseFormatJSONSchema
+		opts.responseSchema = &responseSchema{name: name, schema: schema, strict: strict}
+	}
+}
+
+// raw returns the JSON encoding of the schema.
+func (rs *responseSchema) raw() (json.RawMessage, error) {
+	var schema any
+	switch s := rs.schema.(type) {
+	case json.RawMessage:
+		return s, nil
+	case *Schema, jsonschema.Definition, *jsonschema.Definition, map[string]any:
+		schema = s
+	case reflect.Type:
+		schema = rs.generate(s)
+	default:
+		t := reflect.TypeOf(s)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("response schema %q: unsupported schema type %T", rs.name, rs.schema)
+		}
+		schema = rs.generate(t)
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("response schema %q: %w", rs.name, err)
+	}
+	return data, nil
+}
+
+// generate builds the schema of a Go type, tightened for strict mode if requested.
+func (rs *responseSchema) generate(t reflect.Type) *Schema {
+	schema := typeSchema(t, make(map[reflect.Type]bool))
+	if rs.strict {
+		strictSchema(schema)
+	}
+	return schema
+}
+
+// strictSchema closes every object schema in s and marks all of its properties
+// required, as OpenAI strict mode demands.
+func strictSchema(s *Schema) {
+	if s == nil {
+		return
+	}
+	if s.Type == jsonschema.Object && s.Properties != nil {
+		s.AdditionalProperties = false
+		s.Required = s.Required[:0]
+		for name, prop := range s.Properties {
+			s.Required = append(s.Required, name)
+			strictSchema(prop)
+		}
+		sort.Strings(s.Required)
+	}
+	if items, ok := s.AdditionalProperties.(*Schema); ok {
+		strictSchema(items)
+	}
+	strictSchema(s.Items)
+}
+
+// validate checks the JSON in an answer text against the schema.
+func (rs *responseSchema) validate(text string) error {
+	schema, err := rs.raw()
+	if err != nil {
+		return err
+	}
+	raw, ok := ExtractJSON(text)
+	if !ok {
+		return &OutputParseError{Text: text, Err: errors.New("no JSON object or array found")}
+	}
+	return ValidateJSON(schema, []byte(raw))
+}
+
+// tool returns the function tool used to emulate structured output with tool use.
+func (rs *responseSchema) tool() (Tool, error) {
+	schema, err := rs.raw()
+	if err != nil {
+		return nil, err
+	}
+	return DefineFunction(rs.name, "Respond with the final answer using this schema.",
+		WithFunctionParameters(schema),
+		WithFunctionStrict(rs.strict),
+	), nil
+}
+
+// constraint is the constrained decoding requested with WithConstraint.
+type constraint struct {
+	kind string
+	spec string
+}
+
+// WithConstraint constrains decoding so the output is valid by construction.
+// kind is one of the constants.Constraint* values:
+//   - json_schema: spec is a JSON Schema, sent like WithResponseSchema (OpenAI and
+//     compatible servers such as vLLM and llama.cpp; a forced tool call on Anthropic).
+//   - choice: spec lists the allowed outputs separated by '|', sent as vLLM
+//     guided_choice or as a llama.cpp grammar.
+//   - regex: a regular expression, sent as vLLM guided_regex.
+//   - grammar: a grammar in the backend's syntax, sent as vLLM guided_grammar or
+//     llama.cpp grammar (GBNF).
+//
+// Choices, regexes and grammars need a model built with WithConstraintBackend.
+// Backends that cannot enforce the constraint fail the request with
+// ErrUnsupportedConstraint rather than silently generating unconstrained output.
+func WithConstraint(kind, spec string) ChatOption {
+	return func(opts *ChatOptions) {
+		if kind == constants.ConstraintJSONSchema {
+			WithResponseSchema("output", json.RawMessage(spec), false)(opts)
+		}
+		opts.constraint = &constraint{kind: kind, spec: spec}
+	}
+}
+
+// unsupported returns the error reported when provider cannot enforce c.
+func (c *constraint) unsupported(provider string) error {
+	return fmt.Errorf("%w: %s is not supported by %s", ErrUnsupportedConstraint, c.kind, provider)
+}
+
+// choices returns the allowed outputs of a choice constraint.
+func (c *constraint) choices() []string {
+	return strings.Split(c.spec, "|")
+}
+
+// fields returns the request fields enforcing c on an OpenAI-compatible backend
+// (see WithConstraintBackend), or nil for JSON schemas, which are sent as the
+// response format. The plain OpenAI API, backend "", only accepts schemas.
+func (c *constraint) fields(backend string) (map[string]any, error) {
+	if c.kind == constants.ConstraintJSONSchema {
+		return nil, nil
+	}
+	switch backend {
+	case constants.ConstraintBackendVLLM:
+		switch c.kind {
+		case constants.ConstraintChoice:
+			return map[string]any{"guided_choice": c.choices()}, nil
+		case constants.ConstraintRegex:
+			return map[string]any{"guided_regex": c.spec}, nil
+		case constants.ConstraintGrammar:
+			return map[string]any{"guided_grammar": c.spec}, nil
+		}
+	case constants.ConstraintBackendLlamaCpp:
+		switch c.kind {
+		case constants.ConstraintChoice:
+			return map[string]any{"grammar": choiceGrammar(c.choices())}, nil
+		case constants.ConstraintGrammar:
+			return map[string]any{"grammar": c.spec}, nil
+		}
+	case "":
+		return nil, c.unsupported(constants.ProviderOpenAI)
+	}
+	return nil, c.unsupported(backend)
+}
+
+// choiceGrammar returns a GBNF grammar matching exactly one of choices.
+func choiceGrammar(choices []string) string {
+	quoted := make([]string, len(choices))
+	for i, choice := range choices {
+		quoted[i] = strconv.Quote(choice)
+	}
+	return "root ::= " + strings.Join(quoted, " | ")
+}
+
+// defaultParseRetries is the number of times Complete re-asks for a parsable answer.
+const defaultParseRetries = 2
+
+// WithParseRetries sets how many times Complete re-asks the model, quoting the
+// parse error, when the answer cannot be decoded (default 2).
+func WithParseRetries(n int) ChatOption {
+	return func(opts *ChatOptions) { opts.parseRetries = &n }
+}
+
+// Complete asks model for a structured answer of type T, which should be a struct.
+// The response schema is generated from T (see WithResponseSchema) and the answer is
+// decoded into T. When decoding fails, the invalid answer and the error are sent
+// back and the model is asked again, up to WithParseRetries times; the last
+// Response is returned along with the *OutputParseError. With WithValidateOutput
+// the answer must also satisfy the schema, and violations are reported as
+// ValidationErrors.
+func Complete[T any](ctx context.Context, model Model, messages []Message, opts ...ChatOption) (T, Response, error) {
+	var zero T
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	opts = append([]ChatOption{WithResponseSchema(schemaName(t), t, true)}, opts...)
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	retries := defaultParseRetries
+	if options.parseRetries != nil {
+		retries = *options.parseRetries
+	}
+
+	// Validation happens here, where failures can be fed back to the model.
+	opts = append(opts, WithValidateOutput(false))
+
+	history := append([]Message(nil), messages...)
+	for attempt := 0; ; attempt++ {
+		resp, err := model.ChatCompletion(ctx, history, opts...)
+		if err != nil {
+			return zero, resp, err
+		}
+		var value T
+		err = DecodeJSON(resp, &value)
+		if err == nil && options.validateOutput {
+			err = options.responseSchema.validate(resp.Answer().Content())
+		}
+		if err == nil {
+			return value, resp, nil
+		}
+		if attempt >= retries {
+			return zero, resp, err
+		}
+		feedback := err
+		var perr *OutputParseError
+		if errors.As(err, &perr) {
+			feedback = perr.Err
+		}
+		history = append(history, resp.Answer(), NewUserMessage(fmt.Sprintf(
+			"Your previous answer is invalid: %v. Respond again with only valid JSON matching the schema.", feedback)))
+	}
+}
+
+// schemaName derives a response schema name from a Go type, e.g. "WeatherReport".
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, t.Name())
+	if name == "" {
+		return "response"
+	}
+	return name
+}
+
+// ExtractJSON returns the first complete JSON object or array in text, looking
+// inside a Markdown code fence first when there is one.
+func ExtractJSON(text string) (string, bool) {
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			// Skip the info string, e.g. "json"
+			body = body[nl+1:]
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			if raw, ok := firstJSONValue(body[:end]); ok {
+				return raw, true
+			}
+		}
+	}
+	return firstJSONValue(text)
+}
+
+// firstJSONValue returns the first '{' or '[' in text that starts a valid JSON value.
+func firstJSONValue(text string) (string, bool) {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(text[i:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == nil {
+			return string(raw), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// constraintServer is an OpenAI-compatible server recording the last request body.
+func constraintServer(t *testing.T) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"local","choices":[{"index":0,"message":{"role":"assistant","content":"yes"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &body
+}
+
+func TestConstraintBackendFields(t *testing.T) {
+	srv, body := constraintServer(t)
+	messages := []Message{NewUserMessage("Is the sky blue?")}
+	tests := []struct {
+		backend, kind, spec string
+		field               string
+		want                any
+	}{
+		{constants.ConstraintBackendVLLM, constants.ConstraintChoice, "yes|no", "guided_choice", []any{"yes", "no"}},
+		{constants.ConstraintBackendVLLM, constants.ConstraintRegex, "yes|no", "guided_regex", "yes|no"},
+		{constants.ConstraintBackendVLLM, constants.ConstraintGrammar, `root ::= "yes" | "no"`, "guided_grammar", `root ::= "yes" | "no"`},
+		{constants.ConstraintBackendLlamaCpp, constants.ConstraintGrammar, `root ::= "yes" | "no"`, "grammar", `root ::= "yes" | "no"`},
+		{constants.ConstraintBackendLlamaCpp, constants.ConstraintChoice, "yes|no", "grammar", `root ::= "yes" | "no"`},
+	}
+	for _, tt := range tests {
+		model := NewLLMWithAPIKey("local", "", "key", WithBaseURL(srv.URL), WithConstraintBackend(tt.backend))
+		for _, stream := range []bool{false, true} {
+			request := model.ChatCompletion
+			if stream {
+				request = model.ChatCompletionStream
+			}
+			// the stream fails on the blocking answer; only the request body matters
+			_, _ = request(context.Background(), messages, WithConstraint(tt.kind, tt.spec))
+			got, err := json.Marshal((*body)[tt.field])
+			want, _ := json.Marshal(tt.want)
+			if err != nil || string(got) != string(want) {
+				t.Errorf("%s %s (stream %v): %s = %s, want %s", tt.backend, tt.kind, stream, tt.field, got, want)
+			}
+		}
+	}
+}
+
+func TestConstraintUnsupported(t *testing.T) {
+	srv, body := constraintServer(t)
+	messages := []Message{NewUserMessage("Is the sky blue?")}
+
+	openaiModel := NewLLMWithAPIKey("gpt-4o-mini", "", "key", WithBaseURL(srv.URL))
+	for _, kind := range []string{constants.ConstraintChoice, constants.ConstraintRegex, constants.ConstraintGrammar} {
+		if _, err := openaiModel.ChatCompletion(context.Background(), messages, WithConstraint(kind, "yes|no")); !errors.Is(err, ErrUnsupportedConstraint) {
+			t.Errorf("OpenAI %s: error = %v, want ErrUnsupportedConstraint", kind, err)
+		}
+	}
+	if _, err := openaiModel.ChatCompletion(context.Background(), messages, WithConstraint(constants.ConstraintJSONSchema, `{"type":"object"}`)); err != nil {
+		t.Fatalf("OpenAI json_schema: %v", err)
+	}
+	if _, ok := (*body)["guided_choice"]; ok {
+		t.Error("OpenAI request carries guided_choice")
+	}
+
+	llama := NewLLMWithAPIKey("local", "", "key", WithBaseURL(srv.URL), WithConstraintBackend(constants.ConstraintBackendLlamaCpp))
+	if _, err := llama.ChatCompletion(context.Background(), messages, WithConstraint(constants.ConstraintRegex, "yes|no")); !errors.Is(err, ErrUnsupportedConstraint) {
+		t.Errorf("llama.cpp regex: error = %v, want ErrUnsupportedConstraint", err)
+	}
+}
@@ -0,0 +1,146 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// TokenCounter is implemented by models that can count the input tokens of a
+// request before sending it. Counts are exact when the provider counts them, as
+// Anthropic does, and estimates otherwise.
+type TokenCounter interface {
+	// CountTokens returns the input tokens of a request with messages and opts.
+	CountTokens(ctx context.Context, messages []Message, opts ...ChatOption) (int, error)
+}
+
+// CountTokens returns the input tokens a request with messages and tools would use,
+// so prompts can be checked against the context window before they are sent.
+// Anthropic models ask the count_tokens endpoint and are exact. OpenAI models
+// estimate locally, without the tokenizer's vocabulary, so their count is an
+// estimate that can be off by a few percent; leave a margin below the context
+// window. Models wrapped by middleware are counted by the model they wrap (see
+// UnwrapModel); other models fall back to EstimateTokens.
+func CountTokens(ctx context.Context, model Model, messages []Message, tools ...Tool) (int, error) {
+	var opts []ChatOption
+	if len(tools) > 0 {
+		opts = append(opts, WithTool(tools...))
+	}
+	if counter, ok := asModel[TokenCounter](model); ok {
+		return counter.CountTokens(ctx, messages, opts...)
+	}
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return EstimateTokens(messages, options), nil
+}
+
+// CountTokens implements TokenCounter with the count_tokens endpoint.
+func (a *anthropicLLM) CountTokens(ctx context.Context, messages []Message, opts ...ChatOption) (int, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	req, err := a.makeRequest(options, messages)
+	if err != nil {
+		return 0, err
+	}
+	params := anthropic.MessageCountTokensParams{
+		Messages:   req.Messages,
+		Model:      req.Model,
+		System:     anthropic.MessageCountTokensParamsSystemUnion{OfTextBlockArray: req.System},
+		Thinking:   req.Thinking,
+		ToolChoice: req.ToolChoice,
+	}
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, anthropic.MessageCountTokensToolUnionParam{
+			OfTool:                  tool.OfTool,
+			OfBashTool20250124:      tool.OfBashTool20250124,
+			OfTextEditor20250124:    tool.OfTextEditor20250124,
+			OfTextEditor20250429:    tool.OfTextEditor20250429,
+			OfTextEditor20250728:    tool.OfTextEditor20250728,
+			OfWebSearchTool20250305: tool.OfWebSearchTool20250305,
+		})
+	}
+	count, err := a.client.Messages.CountTokens(ctx, params, anthropicRequestOptions(options)...)
+	if err != nil {
+		return 0, err
+	}
+	return int(count.InputTokens), nil
+}
+
+// OpenAI message framing overheads, as documented for the chat format.
+const (
+	openAITokensPerMessage = 3
+	openAITokensPerName    = 1
+	openAITokensPerReply   = 3
+	// openAITokensPerImage approximates a high-detail 1024x1024 image.
+	openAITokensPerImage = 765
+)
+
+// CountTokens implements TokenCounter with a local estimate. The tokenizer's
+// vocabulary is not bundled, so text is split as the tokenizer pre-splits it and
+// each piece is estimated from its length; the estimate is typically within a few
+// percent for English and less accurate for other scripts and code.
+func (l *llm) CountTokens(_ context.Context, messages []Message, opts ...ChatOption) (int, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	req, err := l.makeRequest(options, messages)
+	if err != nil {
+		return 0, err
+	}
+	tokens := openAITokensPerReply
+	for _, msg := range req.Messages {
+		tokens += openAITokensPerMessage + countTextTokens(msg.Role) + countTextTokens(msg.Content)
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeImageURL {
+				tokens += openAITokensPerImage
+			} else {
+				tokens += countTextTokens(part.Text)
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			tokens += countTextTokens(tc.Function.Name) + countTextTokens(tc.Function.Arguments)
+		}
+		if msg.Name != "" {
+			tokens += openAITokensPerName + countTextTokens(msg.Name)
+		}
+	}
+	if len(req.Tools) > 0 {
+		data, err := json.Marshal(req.Tools)
+		if err != nil {
+			return 0, err
+		}
+		tokens += countTextTokens(string(data))
+	}
+	return tokens, nil
+}
+
+// pretokenPattern approximates the pre-tokenization of OpenAI's BPE tokenizers.
+var pretokenPattern = regexp.MustCompile(`'(?:s|t|re|ve|m|ll|d)| ?\pL+| ?\pN{1,3}| ?[^\s\pL\pN]+|\s+`)
+
+// countTextTokens estimates the BPE tokens of text: ASCII pieces of up to ten bytes,
+// such as common words with their leading space, are one token, longer ones about
+// one per five bytes, and other scripts about one per rune.
+func countTextTokens(text string) int {
+	tokens := 0
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		if n := utf8.RuneCountInString(piece); n != len(piece) {
+			tokens += n
+			continue
+		}
+		if len(piece) <= 10 {
+			tokens++
+		} else {
+			tokens += (len(piece) + 4) / 5
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,23 @@
+package openllm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountTokensThroughMiddleware(t *testing.T) {
+	counter := &countingModel{Model: NewEchoModel()}
+	model := Wrap(counter, WithRetry(RetryPolicy{}), NewMetrics().Middleware(), WithAutoContinue(AutoContinueConfig{}))
+	messages := []Message{NewUserMessage("hello"), NewAssistantMessage("hi")}
+
+	n, err := CountTokens(context.Background(), model, messages)
+	if err != nil {
+		t.Fatalf("CountTokens: %v", err)
+	}
+	if n != 30 || counter.calls != 1 {
+		t.Fatalf("CountTokens = %d with %d counter calls, want 30 from the wrapped counter", n, counter.calls)
+	}
+	if UnwrapModel(counter) != nil {
+		t.Error("UnwrapModel of an unwrapped model is not nil")
+	}
+}
@@ -3,6 +3,7 @@ package openllm
 import (
 	"encoding/json"
 	"strings"
+	"sync"
 )
 
 // Tool describes a callable capability the model may invoke during generation.
@@ -19,6 +20,9 @@ type Tool interface {
 }
 
 // ToolCall represents a single tool invocation emitted by the model.
+// Implementations in this package are safe for concurrent use: a call handed to a
+// StreamWatcher may be kept and read from other goroutines while its arguments
+// are still streaming, and Arguments returns a consistent snapshot.
 type ToolCall interface {
 	// Index returns the zero-based position of this tool call in a sequence of calls.
 	Index() int
@@ -31,6 +35,12 @@ type ToolCall interface {
 
 	// Function returns details of the function call (name and arguments).
 	Function() FunctionCall
+
+	// ArgumentsValid reports whether the arguments are well-formed JSON.
+	// While arguments are still streaming it only reports syntax errors seen so far;
+	// once the call is complete it also reports truncation. It returns an
+	// *ArgumentsError on failure.
+	ArgumentsValid() error
 }
 
 // FunctionCall contains the details of a function-style tool invocation.
@@ -42,6 +52,28 @@ type FunctionCall interface {
 	Arguments() string
 }
 
+// ToolName returns the name a tool is exposed under, or "" if it cannot be determined.
+func ToolName(tool Tool) string {
+	switch def := tool.Definition().(type) {
+	case *FunctionDefinition:
+		return def.Name
+	case interface{ ToolName() string }:
+		return def.ToolName()
+	}
+	// Fall back to the "name" field of the serialized definition
+	data, err := json.Marshal(tool.Definition())
+	if err != nil {
+		return ""
+	}
+	var named struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return ""
+	}
+	return named.Name
+}
+
 type tool struct {
 	type_      string
 	definition any
@@ -101,7 +133,8 @@ func (tc *toolcall) UnmarshalJSON(data []byte) error {
 	tc.id = tmp.ID
 	tc.type_ = tmp.Type
 	if tmp.Function != nil {
-		tc.fcall = *tmp.Function
+		tc.fcall.name = tmp.Function.name
+		tc.fcall.args = tmp.Function.args
 	}
 	return nil
 }
@@ -126,15 +159,27 @@ func (tcall *toolcall) Function() FunctionCall {
 	return &tcall.fcall
 }
 
+// ArgumentsValid implements ToolCall.
+func (tcall *toolcall) ArgumentsValid() error {
+	return tcall.fcall.validate()
+}
+
 // funcall accumulates the function call arguments, supporting both
-// complete argument payloads and incremental streaming deltas.
+// complete argument payloads and incremental streaming deltas. The streamed
+// state is guarded by mu, so the call can be read while a stream writes to it.
 type funcall struct {
 	// name is the function/tool name.
 	name string
 	// args holds the complete serialized arguments when provided at once.
 	args string
+
+	mu sync.Mutex
 	// buff accumulates streamed argument deltas until completion.
 	buff strings.Builder
+	// scan validates streamed argument deltas incrementally.
+	scan jsonScanner
+	// closed reports whether all streamed deltas have been received.
+	closed bool
 }
 
 // MarshalJSON implements json.Marshaler for funcall.
@@ -175,10 +220,37 @@ func (fcall *funcall) Arguments() string {
 	if fcall.args != "" {
 		return fcall.args
 	}
+	fcall.mu.Lock()
+	defer fcall.mu.Unlock()
 	return fcall.buff.String()
 }
 
 // writeArgs appends an incremental delta to the argument buffer during streaming.
 func (fcall *funcall) writeArgs(delta string) {
+	fcall.mu.Lock()
+	defer fcall.mu.Unlock()
 	fcall.buff.WriteString(delta)
+	fcall.scan.write(delta)
+}
+
+// close marks the streamed arguments as complete.
+func (fcall *funcall) close() {
+	fcall.mu.Lock()
+	defer fcall.mu.Unlock()
+	fcall.closed = true
+}
+
+// validate checks the arguments for JSON syntax errors.
+func (fcall *funcall) validate() error {
+	if fcall.args != "" {
+		var scan jsonScanner
+		scan.write(fcall.args)
+		return scan.finish()
+	}
+	fcall.mu.Lock()
+	defer fcall.mu.Unlock()
+	if fcall.closed {
+		return fcall.scan.finish()
+	}
+	return fcall.scan.invalid()
 }
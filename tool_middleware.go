@@ -0,0 +1,143 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ToolHandler executes a tool call and returns the text sent back to the model.
+// ExecuteToolCall is the innermost handler of every chain.
+type ToolHandler func(ctx context.Context, tool Tool, tcall ToolCall) (string, error)
+
+// ToolMiddleware wraps a ToolHandler, like HTTP middleware, to add behavior such as
+// logging, authorization checks, argument redaction or per-tool timeouts.
+// A middleware may short-circuit by returning without calling next.
+type ToolMiddleware func(next ToolHandler) ToolHandler
+
+// chainToolMiddleware wraps handler so that the first middleware is the outermost.
+func chainToolMiddleware(handler ToolHandler, middleware []ToolMiddleware) ToolHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// ToolTimeout returns a middleware that bounds the execution time of the named tools,
+// or of every tool when no names are given.
+func ToolTimeout(timeout time.Duration, names ...string) ToolMiddleware {
+	only := make(map[string]bool, len(names))
+	for _, name := range names {
+		only[name] = true
+	}
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+			if len(only) > 0 && !only[tcall.Function().Name()] {
+				return next(ctx, tool, tcall)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, tool, tcall)
+		}
+	}
+}
+
+// TruncateFunc shortens a tool result to at most maxBytes bytes.
+type TruncateFunc func(ctx context.Context, result string, maxBytes int) (string, error)
+
+// LimitToolResult returns a middleware that passes tool results longer than
+// maxBytes through truncate (TruncateHead if nil) before they reach the model.
+func LimitToolResult(maxBytes int, truncate TruncateFunc) ToolMiddleware {
+	if truncate == nil {
+		truncate = TruncateHead
+	}
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+			result, err := next(ctx, tool, tcall)
+			if err != nil || maxBytes <= 0 || len(result) <= maxBytes {
+				return result, err
+			}
+			return truncate(ctx, result, maxBytes)
+		}
+	}
+}
+
+// TruncateHead keeps the beginning of the result and appends a truncation notice.
+// The notice is left out when maxBytes is too small to hold it.
+func TruncateHead(_ context.Context, result string, maxBytes int) (string, error) {
+	if len(result) <= maxBytes {
+		return result, nil
+	}
+	notice := fmt.Sprintf("\n[truncated %d bytes]", len(result))
+	if len(notice) >= maxBytes {
+		return utf8Prefix(result, maxBytes), nil
+	}
+	keep := utf8Prefix(result, maxBytes-len(notice))
+	return keep + fmt.Sprintf("\n[truncated %d bytes]", len(result)-len(keep)), nil
+}
+
+// TruncateTail keeps the end of the result, where logs and command output
+// usually carry the relevant part, and prepends a truncation notice. The notice
+// is left out when maxBytes is too small to hold it.
+func TruncateTail(_ context.Context, result string, maxBytes int) (string, error) {
+	if len(result) <= maxBytes {
+		return result, nil
+	}
+	notice := fmt.Sprintf("[truncated %d bytes]\n", len(result))
+	if len(notice) >= maxBytes {
+		return utf8Suffix(result, maxBytes), nil
+	}
+	keep := utf8Suffix(result, maxBytes-len(notice))
+	return fmt.Sprintf("[truncated %d bytes]\n", len(result)-len(keep)) + keep, nil
+}
+
+// TruncateSummarize returns a TruncateFunc that asks model to summarize oversized
+// results. If the call fails or the summary is still too long, it falls back to TruncateHead.
+func TruncateSummarize(model Model, opts ...ChatOption) TruncateFunc {
+	return func(ctx context.Context, result string, maxBytes int) (string, error) {
+		messages := []Message{
+			NewSystemMessage(fmt.Sprintf("Summarize the following tool output in at most %d bytes. "+
+				"Keep identifiers, numbers and error messages verbatim. Reply with the summary only.", maxBytes)),
+			NewUserMessage(result),
+		}
+		resp, err := model.ChatCompletion(ctx, messages, opts...)
+		if err != nil {
+			return TruncateHead(ctx, result, maxBytes)
+		}
+		summary := resp.Answer().Content()
+		if summary == "" || len(summary) > maxBytes {
+			return TruncateHead(ctx, result, maxBytes)
+		}
+		return summary, nil
+	}
+}
+
+// utf8Prefix returns the longest prefix of s of at most n bytes that does not split a rune.
+func utf8Prefix(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// utf8Suffix returns the longest suffix of s of at most n bytes that does not split a rune.
+func utf8Suffix(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return s[i:]
+}
@@ -0,0 +1,25 @@
+package openllm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTruncateFitsMaxBytes(t *testing.T) {
+	result := strings.Repeat("héllo wörld ", 50)
+	for _, truncate := range []TruncateFunc{TruncateHead, TruncateTail} {
+		for _, maxBytes := range []int{0, 1, 5, 20, 21, 40, 100} {
+			got, err := truncate(context.Background(), result, maxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) > maxBytes {
+				t.Errorf("truncated to %d bytes, want at most %d: %q", len(got), maxBytes, got)
+			}
+		}
+	}
+	if got, _ := TruncateHead(context.Background(), result, 100); !strings.HasSuffix(got, "bytes]") {
+		t.Errorf("TruncateHead = %q, want a truncation notice", got)
+	}
+}
@@ -0,0 +1,73 @@
+package openllm
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestToolCallConcurrentReads(t *testing.T) {
+	script := FakeStream{
+		Content: "checking",
+		ToolCalls: []FakeToolCall{
+			{Name: "get_weather", Arguments: `{"city":"Paris","unit":"celsius"}`},
+			{Name: "get_time", Arguments: `{"zone":"Europe/Paris"}`},
+		},
+		ChunkRunes: 1,
+	}
+	watcher := &readingWatcher{done: make(chan struct{}), seen: map[string]bool{}}
+	resp, err := NewFakeStreamModel("fake", script).ChatCompletionStream(context.Background(),
+		[]Message{NewUserMessage("weather and time in Paris")}, WithStreamWatcher(watcher))
+	close(watcher.done)
+	watcher.readers.Wait()
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+
+	tcalls := resp.ToolCalls()
+	if len(tcalls) != len(script.ToolCalls) {
+		t.Fatalf("got %d tool calls, want %d", len(tcalls), len(script.ToolCalls))
+	}
+	for i, tcall := range tcalls {
+		if got, want := tcall.Function().Arguments(), script.ToolCalls[i].Arguments; got != want {
+			t.Errorf("tool call %d arguments = %s, want %s", i, got, want)
+		}
+		if err := tcall.ArgumentsValid(); err != nil {
+			t.Errorf("tool call %d: %v", i, err)
+		}
+	}
+}
+
+// readingWatcher reads the arguments of every streamed tool call from another
+// goroutine while they are being written, until done is closed.
+type readingWatcher struct {
+	done    chan struct{}
+	readers sync.WaitGroup
+	seen    map[string]bool
+}
+
+func (w *readingWatcher) OnRefusal(string) error   { return nil }
+func (w *readingWatcher) OnReasoning(string) error { return nil }
+func (w *readingWatcher) OnContent(string) error   { return nil }
+func (w *readingWatcher) OnStop() error            { return nil }
+
+func (w *readingWatcher) OnToolCall(ctx context.Context, tcall ToolCall, args string) error {
+	if w.seen[tcall.ID()] {
+		return nil
+	}
+	w.seen[tcall.ID()] = true
+	w.readers.Add(1)
+	go func() {
+		defer w.readers.Done()
+		for {
+			_ = tcall.Function().Arguments()
+			_ = tcall.ArgumentsValid()
+			select {
+			case <-w.done:
+				return
+			default:
+			}
+		}
+	}()
+	return nil
+}
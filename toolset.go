@@ -0,0 +1,233 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ToolNamespaceSeparator joins a namespace and a tool name (e.g., "github__search").
+// Provider tool names only allow letters, digits, '_' and '-'.
+const ToolNamespaceSeparator = "__"
+
+// ToolSet is a registry of named tools shared across requests.
+// It rejects duplicate names, supports namespacing and enabling/disabling tools by tag,
+// and caches provider-specific conversions until the set changes.
+// A ToolSet is safe for concurrent use.
+type ToolSet struct {
+	mu sync.RWMutex
+	// entries keeps tools in registration order.
+	entries []*toolEntry
+	// byName indexes entries by exposed name.
+	byName map[string]*toolEntry
+	// disabled holds the tags whose tools are currently hidden.
+	disabled map[string]bool
+	// cache holds converted tool lists keyed by provider.
+	cache map[string]any
+	// middleware wraps the execution of every tool in the set.
+	middleware []ToolMiddleware
+}
+
+// toolEntry is a tool registered in a ToolSet.
+type toolEntry struct {
+	name string
+	tool Tool
+	tags []string
+}
+
+// NewToolSet creates a ToolSet holding tools.
+// It returns ErrToolConflict when two tools share a name.
+func NewToolSet(tools ...Tool) (*ToolSet, error) {
+	ts := &ToolSet{
+		byName:   make(map[string]*toolEntry),
+		disabled: make(map[string]bool),
+	}
+	for _, tool := range tools {
+		if err := ts.Add(tool); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+// MustNewToolSet is like NewToolSet but panics on error.
+func MustNewToolSet(tools ...Tool) *ToolSet {
+	ts, err := NewToolSet(tools...)
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+// Add registers a tool under its own name with optional tags.
+// It returns ErrToolConflict if the name is already taken.
+func (ts *ToolSet) Add(tool Tool, tags ...string) error {
+	name := ToolName(tool)
+	if name == "" {
+		return fmt.Errorf("cannot determine tool name for %T", tool.Definition())
+	}
+	return ts.add(name, tool, tags)
+}
+
+// AddNamespaced registers a function tool as namespace + ToolNamespaceSeparator + name,
+// so tools from different sources (e.g., several MCP servers) cannot collide.
+// Only tools created with DefineFunction can be renamed.
+func (ts *ToolSet) AddNamespaced(namespace string, t Tool, tags ...string) error {
+	def, ok := t.Definition().(*FunctionDefinition)
+	if !ok {
+		return fmt.Errorf("cannot namespace tool definition %T", t.Definition())
+	}
+	renamed := *def
+	renamed.Name = namespace + ToolNamespaceSeparator + def.Name
+	return ts.add(renamed.Name, &tool{type_: t.Type(), definition: &renamed}, tags)
+}
+
+// add registers a tool under name.
+func (ts *ToolSet) add(name string, tool Tool, tags []string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, found := ts.byName[name]; found {
+		return fmt.Errorf("%w: %s", ErrToolConflict, name)
+	}
+	entry := &toolEntry{name: name, tool: tool, tags: tags}
+	ts.entries = append(ts.entries, entry)
+	ts.byName[name] = entry
+	ts.cache = nil
+	return nil
+}
+
+// Remove unregisters the tool with the given name, if present.
+func (ts *ToolSet) Remove(name string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, found := ts.byName[name]; !found {
+		return
+	}
+	delete(ts.byName, name)
+	for i, entry := range ts.entries {
+		if entry.name == name {
+			ts.entries = append(ts.entries[:i], ts.entries[i+1:]...)
+			break
+		}
+	}
+	ts.cache = nil
+}
+
+// Get returns the tool registered under name, regardless of whether it is enabled.
+func (ts *ToolSet) Get(name string) (Tool, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	entry, found := ts.byName[name]
+	if !found {
+		return nil, false
+	}
+	return entry.tool, true
+}
+
+// Use appends middleware applied when tools of the set are executed,
+// either through Execute or by a Runner.
+func (ts *ToolSet) Use(middleware ...ToolMiddleware) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.middleware = append(ts.middleware, middleware...)
+}
+
+// Execute runs the enabled tool requested by tcall through the set's middleware.
+// It returns ErrToolNotFound if no enabled tool has that name.
+func (ts *ToolSet) Execute(ctx context.Context, tcall ToolCall) (string, error) {
+	tool, handler, found := ts.resolve(tcall.Function().Name())
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrToolNotFound, tcall.Function().Name())
+	}
+	return handler(ctx, tool, tcall)
+}
+
+// resolve returns the enabled tool registered under name and the handler executing it.
+func (ts *ToolSet) resolve(name string) (Tool, ToolHandler, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	entry, found := ts.byName[name]
+	if !found || ts.isDisabled(entry) {
+		return nil, nil, false
+	}
+	return entry.tool, chainToolMiddleware(ExecuteToolCall, ts.middleware), true
+}
+
+// Enable makes tools carrying any of the tags available again.
+func (ts *ToolSet) Enable(tags ...string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, tag := range tags {
+		delete(ts.disabled, tag)
+	}
+	ts.cache = nil
+}
+
+// Disable hides tools carrying any of the tags from Tools and from requests.
+func (ts *ToolSet) Disable(tags ...string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, tag := range tags {
+		ts.disabled[tag] = true
+	}
+	ts.cache = nil
+}
+
+// Tools returns the enabled tools in registration order.
+func (ts *ToolSet) Tools() []Tool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return ts.enabled()
+}
+
+// enabled returns the enabled tools; the caller must hold the lock.
+func (ts *ToolSet) enabled() []Tool {
+	tools := make([]Tool, 0, len(ts.entries))
+	for _, entry := range ts.entries {
+		if !ts.isDisabled(entry) {
+			tools = append(tools, entry.tool)
+		}
+	}
+	return tools
+}
+
+// isDisabled reports whether any tag of entry is disabled.
+func (ts *ToolSet) isDisabled(entry *toolEntry) bool {
+	for _, tag := range entry.tags {
+		if ts.disabled[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// converted returns the enabled tools converted for provider, computing and caching
+// the conversion on first use after any change to the set.
+func (ts *ToolSet) converted(provider string, convert func(tools []Tool) any) any {
+	ts.mu.RLock()
+	if v, found := ts.cache[provider]; found {
+		ts.mu.RUnlock()
+		return v
+	}
+	ts.mu.RUnlock()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if v, found := ts.cache[provider]; found {
+		return v
+	}
+	v := convert(ts.enabled())
+	if ts.cache == nil {
+		ts.cache = make(map[string]any)
+	}
+	ts.cache[provider] = v
+	return v
+}
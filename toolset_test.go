@@ -0,0 +1,18 @@
+package openllm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewToolSetConflict(t *testing.T) {
+	a := DefineFunction("lookup", "Looks up a record.")
+	b := DefineFunction("lookup", "Looks up another record.")
+	if _, err := NewToolSet(a, b); !errors.Is(err, ErrToolConflict) {
+		t.Fatalf("NewToolSet error = %v, want ErrToolConflict", err)
+	}
+	ts, err := NewToolSet(a)
+	if err != nil || len(ts.Tools()) != 1 {
+		t.Fatalf("NewToolSet = %v, %v", ts, err)
+	}
+}
@@ -0,0 +1,144 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WithUsageKey attributes the usage of the request to the given keys of a
+// UsageTracker, e.g. a tenant ID and a conversation ID.
+func WithUsageKey(keys ...string) ChatOption {
+	return func(opts *ChatOptions) { opts.usageKeys = append(opts.usageKeys, keys...) }
+}
+
+// Budget limits the usage accumulated under a UsageTracker key. Zero fields are not enforced.
+type Budget struct {
+	// MaxTokens bounds the total input plus output tokens.
+	MaxTokens int
+	// MaxCostUSD bounds the cost of priced models (see Usage.CostUSD).
+	MaxCostUSD float64
+}
+
+// UsageTracker accumulates Usage per key across the requests of the models wrapped
+// by its Middleware. The empty key holds the total of all requests. Keys with a
+// Budget fail further requests with ErrBudgetExceeded once it is used up; the
+// request that crosses the limit still completes, as its usage is only known afterwards.
+type UsageTracker struct {
+	mu      sync.Mutex
+	usage   map[string]Usage
+	budgets map[string]Budget
+}
+
+// NewUsageTracker creates an empty UsageTracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		usage:   make(map[string]Usage),
+		budgets: make(map[string]Budget),
+	}
+}
+
+// SetBudget sets the budget of key; the empty key limits all requests together.
+func (t *UsageTracker) SetBudget(key string, budget Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets[key] = budget
+}
+
+// Usage returns the usage accumulated under key.
+func (t *UsageTracker) Usage(key string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage[key]
+}
+
+// Record adds usage to the total and to each key. Requests made through the
+// Middleware are recorded automatically; Record accounts for usage made elsewhere.
+func (t *UsageTracker) Record(usage Usage, keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage[""] = t.usage[""].Add(usage)
+	for _, key := range keys {
+		if key != "" {
+			t.usage[key] = t.usage[key].Add(usage)
+		}
+	}
+}
+
+// Reset clears the usage accumulated under key, e.g. at the start of a billing period.
+func (t *UsageTracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.usage, key)
+}
+
+// Check returns an error wrapping ErrBudgetExceeded if the total or any of keys
+// has used up its budget.
+func (t *UsageTracker) Check(keys ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range append([]string{""}, keys...) {
+		budget, ok := t.budgets[key]
+		if !ok {
+			continue
+		}
+		usage := t.usage[key]
+		if budget.MaxTokens > 0 && usage.TotalTokens >= budget.MaxTokens {
+			return fmt.Errorf("%w: %q used %d of %d tokens", ErrBudgetExceeded, key, usage.TotalTokens, budget.MaxTokens)
+		}
+		if budget.MaxCostUSD > 0 && usage.CostUSD != nil && usage.CostUSD.Total >= budget.MaxCostUSD {
+			return fmt.Errorf("%w: %q spent $%.4f of $%.4f", ErrBudgetExceeded, key, usage.CostUSD.Total, budget.MaxCostUSD)
+		}
+	}
+	return nil
+}
+
+// Middleware returns a Middleware that checks the budgets before each request and
+// records its usage, including that of partial responses, under the keys set with
+// WithUsageKey.
+func (t *UsageTracker) Middleware() Middleware {
+	return func(next Model) Model {
+		return &trackedModel{Model: next, tracker: t}
+	}
+}
+
+// trackedModel is the Model returned by UsageTracker.Middleware.
+type trackedModel struct {
+	Model
+	tracker *UsageTracker
+}
+
+// Unwrap returns the wrapped model.
+func (m *trackedModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *trackedModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(opts, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *trackedModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(opts, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do checks the budgets, runs request and records its usage.
+func (m *trackedModel) do(opts []ChatOption, request func() (Response, error)) (Response, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := m.tracker.Check(options.usageKeys...); err != nil {
+		return nil, err
+	}
+	resp, err := request()
+	if resp != nil {
+		m.tracker.Record(resp.Usage(), options.usageKeys...)
+	}
+	return resp, err
+}
@@ -0,0 +1,181 @@
+package openllm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TranscribeOption represents a functional option to configure a single transcription request.
+type TranscribeOption func(*TranscribeOptions)
+
+// TranscribeOptions holds per-request configuration for Transcriber.Transcribe.
+// Fields are intentionally unexported; use With* helpers to set them.
+type TranscribeOptions struct {
+	// language is the ISO-639-1 code of the spoken language; empty detects it.
+	language string
+	// prompt guides spelling and style, or continues a previous segment.
+	prompt string
+	// temperature is the sampling temperature; nil uses the provider default.
+	temperature *float32
+	// words requests word-level timestamps in addition to segments.
+	words bool
+}
+
+// WithLanguage hints the spoken language as an ISO-639-1 code such as "en",
+// which improves accuracy and latency over automatic detection.
+func WithLanguage(language string) TranscribeOption {
+	return func(opts *TranscribeOptions) { opts.language = language }
+}
+
+// WithTranscriptionPrompt guides the transcription with text such as the
+// preceding transcript or the spelling of names and jargon.
+func WithTranscriptionPrompt(prompt string) TranscribeOption {
+	return func(opts *TranscribeOptions) { opts.prompt = prompt }
+}
+
+// WithTranscriptionTemperature sets the sampling temperature, between 0 and 1.
+func WithTranscriptionTemperature(temperature float32) TranscribeOption {
+	return func(opts *TranscribeOptions) { opts.temperature = &temperature }
+}
+
+// WithWordTimestamps requests the timing of every word in Transcription.Words.
+func WithWordTimestamps() TranscribeOption {
+	return func(opts *TranscribeOptions) { opts.words = true }
+}
+
+// Transcription is the text of an audio recording with its timing.
+type Transcription struct {
+	// Text is the full transcript.
+	Text string `json:"text"`
+	// Language is the spoken language, detected or as hinted.
+	Language string `json:"language,omitempty"`
+	// Duration is the length of the audio.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Segments are the transcript split into phrases, with timestamps when the
+	// provider reports them.
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+	// Words holds word timestamps when requested with WithWordTimestamps.
+	Words []TranscriptionWord `json:"words,omitempty"`
+}
+
+// TranscriptionSegment is a phrase of a transcript.
+type TranscriptionSegment struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+}
+
+// TranscriptionWord is a word of a transcript.
+type TranscriptionWord struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Word  string        `json:"word"`
+}
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	// Transcribe transcribes the audio read from r. The name, such as
+	// "meeting.mp3", tells the provider the audio format.
+	Transcribe(ctx context.Context, r io.Reader, name string, opts ...TranscribeOption) (*Transcription, error)
+}
+
+// TranscribeFile transcribes the audio file at path.
+func TranscribeFile(ctx context.Context, t Transcriber, path string, opts ...TranscribeOption) (*Transcription, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return t.Transcribe(ctx, f, filepath.Base(path), opts...)
+}
+
+// transcriptionContext is the length of the previous text passed as the
+// prompt of the next chunk in TranscribeStream.
+const transcriptionContext = 200
+
+// TranscribeStream transcribes audio that arrives as a sequence of chunks, such
+// as utterances cut by voice activity detection, until chunks is closed. Each
+// chunk must be a complete audio file in the format given by name. The tail of
+// the previous text is passed as the prompt of the next chunk for continuity,
+// unless a prompt is set. onChunk, if not nil, receives each chunk's
+// transcription as it completes, with timestamps relative to the start of the
+// stream; an error from it stops the stream. The merged transcription is returned.
+func TranscribeStream(ctx context.Context, t Transcriber, chunks <-chan []byte, name string, onChunk func(*Transcription) error, opts ...TranscribeOption) (*Transcription, error) {
+	options := &TranscribeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	merged := &Transcription{}
+	var text strings.Builder
+	for {
+		var chunk []byte
+		select {
+		case <-ctx.Done():
+			return merged, ctx.Err()
+		case data, ok := <-chunks:
+			if !ok {
+				merged.Text = text.String()
+				return merged, nil
+			}
+			chunk = data
+		}
+
+		chunkOpts := opts
+		if options.prompt == "" && text.Len() > 0 {
+			tail := text.String()
+			if len(tail) > transcriptionContext {
+				tail = strings.ToValidUTF8(tail[len(tail)-transcriptionContext:], "")
+			}
+			chunkOpts = append(opts[:len(opts):len(opts)], WithTranscriptionPrompt(tail))
+		}
+		part, err := t.Transcribe(ctx, bytes.NewReader(chunk), name, chunkOpts...)
+		if err != nil {
+			merged.Text = text.String()
+			return merged, err
+		}
+		part.shift(merged.Duration)
+
+		if s := strings.TrimSpace(part.Text); s != "" {
+			if text.Len() > 0 {
+				text.WriteByte(' ')
+			}
+			text.WriteString(s)
+		}
+		if merged.Language == "" {
+			merged.Language = part.Language
+		}
+		merged.Segments = append(merged.Segments, part.Segments...)
+		merged.Words = append(merged.Words, part.Words...)
+		if part.Duration > 0 {
+			merged.Duration += part.Duration
+		} else if n := len(part.Segments); n > 0 {
+			merged.Duration = part.Segments[n-1].End
+		}
+		if onChunk != nil {
+			if err := onChunk(part); err != nil {
+				merged.Text = text.String()
+				return merged, err
+			}
+		}
+	}
+}
+
+// shift moves the timestamps of t later by offset.
+func (t *Transcription) shift(offset time.Duration) {
+	if offset == 0 {
+		return
+	}
+	for i := range t.Segments {
+		t.Segments[i].Start += offset
+		t.Segments[i].End += offset
+	}
+	for i := range t.Words {
+		t.Words[i].Start += offset
+		t.Words[i].End += offset
+	}
+}
@@ -0,0 +1,240 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transcript is the complete record of one request written by WithTranscript.
+type Transcript struct {
+	Time     time.Time         `json:"time"`
+	Model    string            `json:"model"`
+	Stream   bool              `json:"stream"`
+	Prompts  []string          `json:"prompts,omitempty"`
+	Messages []Message         `json:"messages"`
+	Options  TranscriptOptions `json:"options"`
+	Answer   Message           `json:"answer,omitempty"`
+	Usage    Usage             `json:"usage"`
+	Meta     Meta              `json:"meta"`
+	Duration time.Duration     `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// TranscriptOptions records the options of a request that shape its answer.
+// The same summary keys WithCache and is logged by WithLogging.
+type TranscriptOptions struct {
+	MaxTokens       *int            `json:"max_tokens,omitempty"`
+	Temperature     *float64        `json:"temperature,omitempty"`
+	TopK            *int            `json:"top_k,omitempty"`
+	TopP            *float64        `json:"top_p,omitempty"`
+	Seed            *int            `json:"seed,omitempty"`
+	ReasoningEffort *string         `json:"reasoning_effort,omitempty"`
+	ResponseFormat  string          `json:"response_format,omitempty"`
+	SchemaName      string          `json:"schema_name,omitempty"`
+	Schema          json.RawMessage `json:"schema,omitempty"`
+	SchemaStrict    bool            `json:"schema_strict,omitempty"`
+	ConstraintKind  string          `json:"constraint_kind,omitempty"`
+	Constraint      string          `json:"constraint,omitempty"`
+	LogProbs        *int            `json:"logprobs,omitempty"`
+	Tools           []string        `json:"tools,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding messages as DecodeMessage does.
+func (t *Transcript) UnmarshalJSON(data []byte) error {
+	type plain Transcript
+	var tmp struct {
+		*plain
+		Messages []*llmmsg `json:"messages"`
+		Answer   *llmmsg   `json:"answer,omitempty"`
+	}
+	tmp.plain = (*plain)(t)
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	t.Messages = make([]Message, len(tmp.Messages))
+	for i, msg := range tmp.Messages {
+		t.Messages[i] = msg
+	}
+	t.Answer = nil
+	if tmp.Answer != nil {
+		t.Answer = tmp.Answer
+	}
+	return nil
+}
+
+// TranscriptSink receives the transcripts of WithTranscript.
+type TranscriptSink interface {
+	// WriteTranscript stores t. It may be called concurrently.
+	WriteTranscript(t *Transcript) error
+}
+
+// TranscriptSinkFunc adapts an ordinary function to the TranscriptSink interface.
+type TranscriptSinkFunc func(t *Transcript) error
+
+// WriteTranscript implements TranscriptSink.
+func (f TranscriptSinkFunc) WriteTranscript(t *Transcript) error {
+	return f(t)
+}
+
+// JSONLSink writes transcripts as JSON lines to an io.Writer.
+type JSONLSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLSink creates a JSONLSink writing to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// OpenTranscriptFile opens (or creates) the file at path for appending transcripts.
+// Close the sink to close the file.
+func OpenTranscriptFile(path string) (*JSONLSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLSink(f), nil
+}
+
+// WriteTranscript implements TranscriptSink, writing t as a single line.
+func (s *JSONLSink) WriteTranscript(t *Transcript) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ReadTranscripts decodes the JSON lines written by a JSONLSink, e.g. to build
+// evaluation datasets from recorded traffic.
+func ReadTranscripts(r io.Reader) ([]*Transcript, error) {
+	var transcripts []*Transcript
+	dec := json.NewDecoder(r)
+	for {
+		t := &Transcript{}
+		if err := dec.Decode(t); err == io.EOF {
+			return transcripts, nil
+		} else if err != nil {
+			return transcripts, err
+		}
+		transcripts = append(transcripts, t)
+	}
+}
+
+// WithTranscript returns a Middleware that writes a Transcript of every request,
+// including failed ones, to sink, for audit trails and offline evaluation. Prompts
+// and messages are recorded as sent, i.e. after the request's redactors.
+// Errors from sink are ignored so that recording never fails a request.
+func WithTranscript(sink TranscriptSink) Middleware {
+	return func(next Model) Model {
+		return &transcriptModel{Model: next, sink: sink}
+	}
+}
+
+// transcriptModel is the Model returned by WithTranscript.
+type transcriptModel struct {
+	Model
+	sink TranscriptSink
+}
+
+// Unwrap returns the wrapped model.
+func (m *transcriptModel) Unwrap() Model {
+	return m.Model
+}
+
+// ChatCompletion implements Model.
+func (m *transcriptModel) ChatCompletion(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(messages, opts, false, func() (Response, error) {
+		return m.Model.ChatCompletion(ctx, messages, opts...)
+	})
+}
+
+// ChatCompletionStream implements Model.
+func (m *transcriptModel) ChatCompletionStream(ctx context.Context, messages []Message, opts ...ChatOption) (Response, error) {
+	return m.do(messages, opts, true, func() (Response, error) {
+		return m.Model.ChatCompletionStream(ctx, messages, opts...)
+	})
+}
+
+// do runs request and records it.
+func (m *transcriptModel) do(messages []Message, opts []ChatOption, stream bool, request func() (Response, error)) (Response, error) {
+	start := time.Now()
+	resp, err := request()
+
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	prompts, sent := options.redact(messages)
+	t := &Transcript{
+		Time:     start,
+		Model:    m.Name(),
+		Stream:   stream,
+		Prompts:  prompts,
+		Messages: make([]Message, len(sent)),
+		Duration: time.Since(start),
+	}
+	t.Options, _ = requestOptions(options)
+	for i, msg := range sent {
+		t.Messages[i] = asLLMMessage(msg)
+	}
+	if resp != nil {
+		if answer := resp.Answer(); answer != nil {
+			t.Answer = asLLMMessage(answer)
+		}
+		t.Usage, t.Meta = resp.Usage(), resp.Meta()
+	}
+	if err != nil {
+		t.Error = err.Error()
+	}
+	_ = m.sink.WriteTranscript(t)
+	return resp, err
+}
+
+// requestOptions summarizes the options of opts that shape the answer. The
+// summary is complete except for the schema when it cannot be generated.
+func requestOptions(opts *ChatOptions) (TranscriptOptions, error) {
+	to := TranscriptOptions{
+		MaxTokens:       opts.maxTokens,
+		Temperature:     opts.temperature,
+		TopK:            opts.topK,
+		TopP:            opts.topP,
+		Seed:            opts.seed,
+		ReasoningEffort: opts.reasoningEffort,
+		ResponseFormat:  opts.responseFormat,
+		LogProbs:        opts.logProbs,
+	}
+	if c := opts.constraint; c != nil {
+		to.ConstraintKind, to.Constraint = c.kind, c.spec
+	}
+	for _, tool := range opts.allTools() {
+		to.Tools = append(to.Tools, ToolName(tool))
+	}
+	if rs := opts.responseSchema; rs != nil {
+		to.SchemaName, to.SchemaStrict = rs.name, rs.strict
+		schema, err := rs.raw()
+		if err != nil {
+			return to, err
+		}
+		to.Schema = schema
+	}
+	return to, nil
+}
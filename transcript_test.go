@@ -0,0 +1,59 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+func TestTranscriptOptions(t *testing.T) {
+	var got *Transcript
+	sink := TranscriptSinkFunc(func(tr *Transcript) error {
+		got = tr
+		return nil
+	})
+	model := Wrap(NewEchoModel(), WithTranscript(sink))
+	schema := json.RawMessage(`{"type":"object","properties":{"answer":{"type":"string"}}}`)
+	_, err := model.ChatCompletion(context.Background(), []Message{NewUserMessage("hi")},
+		WithResponseSchema("answer", schema, true),
+		WithConstraint(constants.ConstraintRegex, "yes|no"),
+		WithLogProbs(2),
+	)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	to := got.Options
+	if to.SchemaName != "answer" || !to.SchemaStrict || string(to.Schema) != string(schema) {
+		t.Errorf("schema = %q %v %s, want answer true %s", to.SchemaName, to.SchemaStrict, to.Schema, schema)
+	}
+	if to.ConstraintKind != constants.ConstraintRegex || to.Constraint != "yes|no" {
+		t.Errorf("constraint = %q %q, want regex yes|no", to.ConstraintKind, to.Constraint)
+	}
+	if to.LogProbs == nil || *to.LogProbs != 2 {
+		t.Errorf("logprobs = %v, want 2", to.LogProbs)
+	}
+}
+
+func TestCacheKeyOptions(t *testing.T) {
+	messages := []Message{NewUserMessage("hi")}
+	key := func(opts ...ChatOption) string {
+		options := &ChatOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		k, err := cacheKey("model", messages, options)
+		if err != nil {
+			t.Fatalf("cacheKey: %v", err)
+		}
+		return k
+	}
+	regex := key(WithConstraint(constants.ConstraintRegex, "yes|no"))
+	if regex == key() || regex == key(WithConstraint(constants.ConstraintRegex, "maybe")) {
+		t.Error("cache key ignores the constraint")
+	}
+	if key(WithResponseSchema("a", json.RawMessage(`{"type":"object"}`), false)) == key() {
+		t.Error("cache key ignores the response schema")
+	}
+}
@@ -0,0 +1,363 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ValidationError describes a JSON value that does not satisfy its schema.
+type ValidationError struct {
+	// Path locates the offending value, e.g. "$.items[2].name".
+	Path string
+	// Message describes the violated constraint.
+	Message string
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors collects every violation found in a value.
+type ValidationErrors []*ValidationError
+
+// Error implements error.
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// WithValidateOutput validates structured output against its declared schema.
+// ChatCompletion and ChatCompletionStream return the response along with the
+// ValidationErrors (or an *OutputParseError) when the answer does not match
+// WithResponseSchema; streams are validated once they complete, after the watcher
+// has seen the answer. Complete re-asks the model with the validation errors, and
+// a Runner answers tool calls whose arguments do not match the tool's parameters
+// with an error result so the model can retry.
+func WithValidateOutput(validate bool) ChatOption {
+	return func(opts *ChatOptions) { opts.validateOutput = validate }
+}
+
+// validateAnswer checks the answer of a complete response against the response
+// schema when output validation is requested. Answers calling tools are skipped.
+func validateAnswer(options *ChatOptions, resp Response) error {
+	if !options.validateOutput || options.responseSchema == nil || resp.Answer() == nil || len(resp.ToolCalls()) > 0 {
+		return nil
+	}
+	return options.responseSchema.validate(resp.Answer().Content())
+}
+
+// ValidateJSON checks the JSON document data against schema, which may be a *Schema,
+// a jsonschema.Definition, json.RawMessage or any value marshaling to a JSON Schema.
+// Violations are reported as ValidationErrors. Local references ("#", "#/$defs/..."
+// and "#/definitions/...") and allOf, anyOf and oneOf are checked; other keywords that Schema does not model
+// are ignored.
+func ValidateJSON(schema any, data []byte) error {
+	s, err := asSchema(schema)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return s.Validate(value)
+}
+
+// Validate checks a decoded JSON value (as produced by json.Unmarshal into any)
+// against s and returns ValidationErrors listing every violation, or nil.
+func (s *Schema) Validate(value any) error {
+	sv := &schemaValidator{root: s}
+	sv.validate(s, "$", value, 0)
+	if len(sv.errs) > 0 {
+		return sv.errs
+	}
+	return nil
+}
+
+// maxRefDepth bounds the chain of $ref followed without descending into a value,
+// which guards against circular references.
+const maxRefDepth = 32
+
+// schemaValidator collects the violations of a value against a root schema.
+type schemaValidator struct {
+	root *Schema
+	errs ValidationErrors
+}
+
+// asSchema converts a schema given in any supported form to *Schema.
+func asSchema(schema any) (*Schema, error) {
+	if s, ok := schema.(*Schema); ok {
+		return s, nil
+	}
+	data, ok := schema.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(schema); err != nil {
+			return nil, err
+		}
+	}
+	s := new(Schema)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unsupported schema: %w", err)
+	}
+	return s, nil
+}
+
+// validate records the violations of value at path against s; refs counts the
+// references followed since the last descent into value.
+func (sv *schemaValidator) validate(s *Schema, path string, value any, refs int) {
+	fail := func(format string, args ...any) {
+		sv.errs = append(sv.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Ref != "" {
+		target, err := sv.resolve(s.Ref)
+		switch {
+		case err != nil:
+			fail("%v", err)
+		case refs >= maxRefDepth:
+			fail("$ref %q is circular", s.Ref)
+		default:
+			sv.validate(target, path, value, refs+1)
+		}
+	}
+	for _, sub := range s.AllOf {
+		sv.validate(sub, path, value, refs)
+	}
+	if len(s.AnyOf) > 0 && sv.matching(s.AnyOf, path, value, refs) == 0 {
+		fail("value does not match any schema in anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := sv.matching(s.OneOf, path, value, refs); n != 1 {
+			fail("value matches %d schemas in oneOf, want exactly one", n)
+		}
+	}
+
+	if !matchesType(s.Type, value) {
+		fail("expected %s, got %s", s.Type, jsonTypeName(value))
+		return
+	}
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		fail("value %v is not one of %v", value, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("value %v is less than minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("value %v is greater than maximum %v", v, *s.Maximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("length %d is less than minLength %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("length %d is greater than maxLength %d", n, *s.MaxLength)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("%q is not a date-time", v)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("%d items is less than minItems %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("%d items is greater than maxItems %d", len(v), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				sv.validate(s.Items, path+"["+strconv.Itoa(i)+"]", item, 0)
+			}
+		}
+	case map[string]any:
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			fail("%d properties is less than minProperties %d", len(v), *s.MinProperties)
+		}
+		if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+			fail("%d properties is greater than maxProperties %d", len(v), *s.MaxProperties)
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				sv.validate(prop, path+"."+name, v[name], 0)
+				continue
+			}
+			switch extra := s.additionalSchema(); {
+			case extra != nil:
+				sv.validate(extra, path+"."+name, v[name], 0)
+			case s.AdditionalProperties == false:
+				fail("unexpected property %q", name)
+			}
+		}
+	}
+}
+
+// matching returns how many of schemas value satisfies.
+func (sv *schemaValidator) matching(schemas []*Schema, path string, value any, refs int) int {
+	n := 0
+	for _, s := range schemas {
+		branch := &schemaValidator{root: sv.root}
+		branch.validate(s, path, value, refs)
+		if len(branch.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// resolve returns the schema a local reference points to.
+func (sv *schemaValidator) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return sv.root, nil
+	}
+	var defs map[string]*Schema
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if ok {
+		defs = sv.root.Defs
+	} else if name, ok = strings.CutPrefix(ref, "#/definitions/"); ok {
+		defs = sv.root.Definitions
+	} else {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	if target, ok := defs[name]; ok && target != nil {
+		return target, nil
+	}
+	return nil, fmt.Errorf("unresolved $ref %q", ref)
+}
+
+// additionalSchema returns the schema of additional properties, if it is one.
+func (s *Schema) additionalSchema() *Schema {
+	switch ap := s.AdditionalProperties.(type) {
+	case *Schema:
+		return ap
+	case map[string]any:
+		extra, err := asSchema(ap)
+		if err == nil {
+			return extra
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value has the given schema type.
+// An empty type accepts any value.
+func matchesType(typ jsonschema.DataType, value any) bool {
+	switch typ {
+	case "":
+		return true
+	case jsonschema.Null:
+		return value == nil
+	case jsonschema.Boolean:
+		_, ok := value.(bool)
+		return ok
+	case jsonschema.String:
+		_, ok := value.(string)
+		return ok
+	case jsonschema.Number:
+		_, ok := value.(float64)
+		return ok
+	case jsonschema.Integer:
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case jsonschema.Array:
+		_, ok := value.([]any)
+		return ok
+	case jsonschema.Object:
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+// jsonTypeName names the JSON type of a decoded value.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// enumContains reports whether value equals one of the enum values; numbers are
+// compared by value regardless of their Go type.
+func enumContains(enum []any, value any) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+		if f, ok := value.(float64); ok {
+			if n, err := strconv.ParseFloat(fmt.Sprint(e), 64); err == nil && n == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateToolArguments is a ToolMiddleware that rejects tool calls whose arguments
+// do not match the tool's parameter schema, so the model receives the violations
+// instead of the tool running with bad input. A Runner adds it automatically when
+// WithValidateOutput is set.
+func ValidateToolArguments(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, tool Tool, tcall ToolCall) (string, error) {
+		def, ok := tool.Definition().(*FunctionDefinition)
+		if !ok || def.Parameters == nil {
+			return next(ctx, tool, tcall)
+		}
+		schema, err := asSchema(def.Parameters)
+		if err != nil {
+			// Schemas outside what Schema models are not validated
+			return next(ctx, tool, tcall)
+		}
+		args := tcall.Function().Arguments()
+		if strings.TrimSpace(args) == "" {
+			args = "{}"
+		}
+		var value any
+		if err := json.Unmarshal([]byte(args), &value); err != nil {
+			return "", fmt.Errorf("invalid arguments for tool %s: %w", def.Name, err)
+		}
+		if err := schema.Validate(value); err != nil {
+			return "", fmt.Errorf("invalid arguments for tool %s: %w", def.Name, err)
+		}
+		return next(ctx, tool, tcall)
+	}
+}
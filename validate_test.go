@@ -0,0 +1,78 @@
+package openllm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateJSONComposition(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"shape": {"oneOf": [{"$ref": "#/$defs/circle"}, {"$ref": "#/definitions/square"}]}
+		},
+		"$defs": {"circle": {"type": "object", "properties": {"radius": {"type": "number"}}, "required": ["radius"]}},
+		"definitions": {"square": {"type": "object", "properties": {"side": {"type": "number"}}, "required": ["side"]}}
+	}`)
+	tests := []struct {
+		doc  string
+		want bool
+	}{
+		{`{"id": "a", "shape": {"radius": 1}}`, true},
+		{`{"id": 7, "shape": {"side": 2}}`, true},
+		{`{"id": true, "shape": {"radius": 1}}`, false},
+		{`{"id": "a", "shape": {"width": 1}}`, false},
+		{`{"id": "a", "shape": {"radius": 1, "side": 2}}`, false},
+	}
+	for _, tt := range tests {
+		err := ValidateJSON(json.RawMessage(schema), []byte(tt.doc))
+		if (err == nil) != tt.want {
+			t.Errorf("ValidateJSON(%s) = %v, want valid %v", tt.doc, err, tt.want)
+		}
+	}
+
+	var verrs ValidationErrors
+	if err := ValidateJSON(json.RawMessage(`{"$ref": "#/$defs/missing"}`), []byte(`{}`)); !errors.As(err, &verrs) {
+		t.Errorf("unresolved $ref = %v, want ValidationErrors", err)
+	}
+	if err := ValidateJSON(json.RawMessage(`{"$ref": "#/$defs/a", "$defs": {"a": {"$ref": "#/$defs/a"}}}`), []byte(`{}`)); !errors.As(err, &verrs) {
+		t.Errorf("circular $ref = %v, want ValidationErrors", err)
+	}
+}
+
+func TestChatCompletionValidateOutput(t *testing.T) {
+	srv, _ := constraintServer(t)
+	model := NewLLMWithAPIKey("local", "", "key", WithBaseURL(srv.URL))
+	messages := []Message{NewUserMessage("Is the sky blue?")}
+	schema := json.RawMessage(`{"type": "object", "properties": {"answer": {"type": "string"}}, "required": ["answer"]}`)
+
+	resp, err := model.ChatCompletion(context.Background(), messages, WithResponseSchema("answer", schema, false), WithValidateOutput(true))
+	var perr *OutputParseError
+	if !errors.As(err, &perr) || resp == nil {
+		t.Fatalf("ChatCompletion = %v, %v; want the response and an *OutputParseError", resp, err)
+	}
+	if _, err := model.ChatCompletion(context.Background(), messages, WithResponseSchema("answer", schema, false)); err != nil {
+		t.Errorf("ChatCompletion without WithValidateOutput: %v", err)
+	}
+}
+
+func TestChatCompletionStreamValidateOutput(t *testing.T) {
+	schema := json.RawMessage(`{"type": "object", "properties": {"answer": {"type": "string"}}, "required": ["answer"]}`)
+	messages := []Message{NewUserMessage("Is the sky blue?")}
+	opts := []ChatOption{WithResponseSchema("answer", schema, false), WithValidateOutput(true)}
+
+	model := NewFakeStreamModel("fake", FakeStream{Content: `{"answer": true}`})
+	resp, err := model.ChatCompletionStream(context.Background(), messages, opts...)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || resp == nil {
+		t.Fatalf("ChatCompletionStream = %v, %v; want the response and ValidationErrors", resp, err)
+	}
+
+	model = NewFakeStreamModel("fake", FakeStream{Content: `{"answer": "yes"}`})
+	if _, err := model.ChatCompletionStream(context.Background(), messages, opts...); err != nil {
+		t.Errorf("ChatCompletionStream with a valid answer: %v", err)
+	}
+}
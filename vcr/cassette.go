@@ -0,0 +1,132 @@
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cassetteVersion is the version of the fixture file format.
+const cassetteVersion = 1
+
+// Cassette is the content of a fixture file: the interactions recorded, in order.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Headers are not recorded, as they carry the
+// credentials; the body and URL are scrubbed before they are saved.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	// Body holds the body of a regular response.
+	Body string `json:"body,omitempty"`
+	// Chunks holds the body of an event stream, one server-sent event per chunk,
+	// so a replayed stream is delivered event by event.
+	Chunks []string `json:"chunks,omitempty"`
+}
+
+// loadCassette reads the cassette at path.
+func loadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("vcr: invalid cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("vcr: unsupported cassette version %d in %s", c.Version, path)
+	}
+	return &c, nil
+}
+
+// save writes the cassette to path, creating its directory.
+func (c *Cassette) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// DefaultMatcher matches requests by method, URL and body, comparing JSON bodies
+// by value so that key order and formatting do not matter.
+func DefaultMatcher(req, recorded Request) bool {
+	return req.Method == recorded.Method && req.URL == recorded.URL && sameBody(req.Body, recorded.Body)
+}
+
+// sameBody reports whether two bodies are equal, as JSON values when both are JSON.
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, []byte(a)) != nil || json.Compact(&cb, []byte(b)) != nil {
+		return false
+	}
+	var va, vb any
+	if json.Unmarshal(ca.Bytes(), &va) != nil || json.Unmarshal(cb.Bytes(), &vb) != nil {
+		return false
+	}
+	na, _ := json.Marshal(va)
+	nb, _ := json.Marshal(vb)
+	return bytes.Equal(na, nb)
+}
+
+// scrubURL removes the values of the sensitive query parameters of rawURL.
+func scrubURL(rawURL string, params []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	for key := range query {
+		for _, param := range params {
+			if strings.EqualFold(key, param) {
+				query.Set(key, scrubbed)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// splitEvents splits an event stream after each blank line, keeping the delimiters,
+// so that the chunks concatenate to body.
+func splitEvents(body string) []string {
+	var chunks []string
+	for body != "" {
+		i := strings.Index(body, "\n\n")
+		if i < 0 {
+			chunks = append(chunks, body)
+			break
+		}
+		chunks = append(chunks, body[:i+2])
+		body = body[i+2:]
+	}
+	return chunks
+}
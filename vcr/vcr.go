@@ -0,0 +1,268 @@
+// Package vcr records real provider responses to fixture files ("cassettes") and
+// replays them deterministically, so provider integration tests can run in CI
+// without network access or API keys. A Recorder is an http.RoundTripper; pass
+// its Client to a provider with openllm.WithHTTPClient. Streamed responses are
+// recorded event by event and replayed the same way.
+package vcr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the provider.
+type Mode int
+
+const (
+	// ModeReplay serves recorded responses and fails requests that were not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the provider and records them, replacing the cassette.
+	ModeRecord
+	// ModeAuto replays the cassette when it exists and records it otherwise.
+	ModeAuto
+)
+
+// scrubbed replaces the values of scrubbed secrets.
+const scrubbed = "REDACTED"
+
+// ErrNoInteraction is returned in replay mode for requests that match no
+// unused recorded interaction.
+var ErrNoInteraction = errors.New("vcr: no recorded interaction matches the request")
+
+// Option configures a Recorder.
+type Option func(r *Recorder)
+
+// WithTransport sends recorded requests through rt instead of http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(r *Recorder) { r.transport = rt }
+}
+
+// WithMatcher sets how requests are matched to recorded ones (default DefaultMatcher).
+func WithMatcher(match func(req, recorded Request) bool) Option {
+	return func(r *Recorder) { r.match = match }
+}
+
+// WithScrubQueryParams adds query parameters whose values are replaced before
+// recording, besides "key" and "api_key".
+func WithScrubQueryParams(params ...string) Option {
+	return func(r *Recorder) { r.scrubParams = append(r.scrubParams, params...) }
+}
+
+// WithScrubber adds a function applied to every interaction before it is saved
+// and to every request before it is matched, e.g. to mask account IDs in URLs or
+// bodies. It must apply the same changes to both for requests to match.
+func WithScrubber(scrub func(i *Interaction)) Option {
+	return func(r *Recorder) { r.scrubbers = append(r.scrubbers, scrub) }
+}
+
+// Recorder records or replays HTTP interactions with a cassette file.
+// It is safe for concurrent use.
+type Recorder struct {
+	path        string
+	recording   bool
+	transport   http.RoundTripper
+	match       func(req, recorded Request) bool
+	scrubParams []string
+	scrubbers   []func(i *Interaction)
+
+	mu       sync.Mutex
+	cassette *Cassette
+	// used marks the recorded interactions already replayed.
+	used []bool
+}
+
+// New creates a Recorder for the cassette at path. Replay mode fails when the
+// cassette cannot be read.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:        path,
+		transport:   http.DefaultTransport,
+		match:       DefaultMatcher,
+		scrubParams: []string{"key", "api_key"},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	cassette, err := loadCassette(path)
+	switch {
+	case mode == ModeRecord || mode == ModeAuto && errors.Is(err, fs.ErrNotExist):
+		r.recording = true
+		r.cassette = &Cassette{Version: cassetteVersion}
+	case err != nil:
+		return nil, err
+	default:
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Recording reports whether the Recorder sends requests to the provider.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Client returns an *http.Client that uses the Recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop saves the cassette when recording; it does nothing when replaying.
+// Call it once every response body has been read and closed.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.save(r.path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// fail canceled requests as http.Transport does, also when replaying
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := Request{Method: req.Method, URL: scrubURL(req.URL.String(), r.scrubParams), Body: string(body)}
+	if r.recording {
+		return r.record(req, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+// record sends req and records its response once the body has been read.
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Content-Length")
+	i := &Interaction{Request: recorded, Response: Response{StatusCode: resp.StatusCode, Header: header}}
+	stream := isEventStream(resp.Header)
+	resp.Body = &recordingBody{body: resp.Body, done: func(data []byte) {
+		if stream {
+			i.Response.Chunks = splitEvents(string(data))
+		} else {
+			i.Response.Body = string(data)
+		}
+		for _, scrub := range r.scrubbers {
+			scrub(i)
+		}
+		r.mu.Lock()
+		r.cassette.Interactions = append(r.cassette.Interactions, i)
+		r.mu.Unlock()
+	}}
+	return resp, nil
+}
+
+// replay serves the first unused recorded interaction matching the request.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	probe := &Interaction{Request: recorded}
+	for _, scrub := range r.scrubbers {
+		scrub(probe)
+	}
+
+	r.mu.Lock()
+	var found *Interaction
+	for i, candidate := range r.cassette.Interactions {
+		if !r.used[i] && r.match(probe.Request, candidate.Request) {
+			r.used[i] = true
+			found = candidate
+			break
+		}
+	}
+	r.mu.Unlock()
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+
+	chunks := append([]string(nil), found.Response.Chunks...)
+	if found.Response.Chunks == nil {
+		chunks = []string{found.Response.Body}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Response.StatusCode, http.StatusText(found.Response.StatusCode)),
+		StatusCode:    found.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        found.Response.Header.Clone(),
+		Body:          &chunkReader{chunks: chunks},
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// isEventStream reports whether the response is a server-sent event stream.
+func isEventStream(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return strings.EqualFold(mediaType, "text/event-stream")
+}
+
+// recordingBody captures a response body as it is read and hands it to done
+// when it is exhausted or closed.
+type recordingBody struct {
+	body io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(data []byte)
+}
+
+// Read implements io.Reader.
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+	return b.body.Close()
+}
+
+// chunkReader serves a replayed body one chunk per read at most.
+type chunkReader struct {
+	chunks []string
+}
+
+// Read implements io.Reader.
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.chunks) > 0 && c.chunks[0] == "" {
+		c.chunks = c.chunks[1:]
+	}
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.chunks[0])
+	c.chunks[0] = c.chunks[0][n:]
+	return n, nil
+}
+
+// Close implements io.Closer.
+func (c *chunkReader) Close() error {
+	return nil
+}
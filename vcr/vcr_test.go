@@ -0,0 +1,60 @@
+package vcr_test
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/thecxx/openllm"
+	"github.com/thecxx/openllm/modeltest"
+	"github.com/thecxx/openllm/vcr"
+)
+
+// The cassettes in testdata run the modeltest suite against the OpenAI and
+// Anthropic adapters. They were generated offline from scripted responses in
+// each provider's wire format, so IDs and token counts are synthetic; record
+// re-records them from the live APIs, with the keys in OPENAI_API_KEY and
+// ANTHROPIC_API_KEY:
+//
+//	go test ./vcr -record
+var record = flag.Bool("record", false, "record the cassettes from the live provider APIs")
+
+// transport sends the requests recorded with -record.
+var transport http.RoundTripper = http.DefaultTransport
+
+// recorder returns a Recorder for the cassette at path, replaying it unless
+// -record is set, and saves the cassette when the test ends.
+func recorder(t *testing.T, path string) *vcr.Recorder {
+	t.Helper()
+	mode := vcr.ModeReplay
+	if *record {
+		mode = vcr.ModeRecord
+	}
+	rec, err := vcr.New(path, mode, vcr.WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return rec
+}
+
+func TestOpenAI(t *testing.T) {
+	rec := recorder(t, "testdata/openai.json")
+	modeltest.TestModel(t, func(t *testing.T) openllm.Model {
+		return openllm.NewLLMWithAPIKey("gpt-4o-mini", "", os.Getenv("OPENAI_API_KEY"),
+			openllm.WithBaseURL("https://api.openai.com/v1"), openllm.WithHTTPClient(rec.Client()))
+	}, modeltest.WithoutReasoning(), modeltest.WithChatOptions(openllm.WithTemperature(0)))
+}
+
+func TestAnthropic(t *testing.T) {
+	rec := recorder(t, "testdata/anthropic.json")
+	modeltest.TestModel(t, func(t *testing.T) openllm.Model {
+		return openllm.NewAnthropicLLMWithAPIKey("claude-sonnet-4-5", "", os.Getenv("ANTHROPIC_API_KEY"),
+			openllm.WithBaseURL("https://api.anthropic.com/"), openllm.WithHTTPClient(rec.Client()))
+	})
+}
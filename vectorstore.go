@@ -0,0 +1,151 @@
+package openllm
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// VectorDocument is a piece of text indexed in a VectorStore.
+type VectorDocument struct {
+	// ID identifies the document; upserting an existing ID replaces it.
+	ID string `json:"id"`
+	// Text is the content returned to the model as context.
+	Text string `json:"text"`
+	// Metadata holds application data such as the source URL or title.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// Vector is the embedding of Text.
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+// ScoredDocument is a document found by a query, with its similarity to the query.
+type ScoredDocument struct {
+	VectorDocument
+	// Score is the similarity to the query, higher is closer; the in-memory
+	// store reports the cosine similarity.
+	Score float64 `json:"score"`
+}
+
+// VectorStore indexes documents by embedding. Adapters for external databases
+// implement it directly, or through VectorStoreFuncs.
+type VectorStore interface {
+	// Upsert inserts documents or replaces those with the same ID.
+	Upsert(ctx context.Context, docs ...VectorDocument) error
+	// Query returns up to k documents closest to vector, closest first.
+	Query(ctx context.Context, vector []float32, k int) ([]ScoredDocument, error)
+	// Delete removes the documents with the given IDs; unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// VectorStoreFuncs adapts a set of functions, typically calls into a vector
+// database client, to VectorStore.
+type VectorStoreFuncs struct {
+	UpsertFunc func(ctx context.Context, docs ...VectorDocument) error
+	QueryFunc  func(ctx context.Context, vector []float32, k int) ([]ScoredDocument, error)
+	DeleteFunc func(ctx context.Context, ids ...string) error
+}
+
+// Upsert implements VectorStore.
+func (f VectorStoreFuncs) Upsert(ctx context.Context, docs ...VectorDocument) error {
+	return f.UpsertFunc(ctx, docs...)
+}
+
+// Query implements VectorStore.
+func (f VectorStoreFuncs) Query(ctx context.Context, vector []float32, k int) ([]ScoredDocument, error) {
+	return f.QueryFunc(ctx, vector, k)
+}
+
+// Delete implements VectorStore.
+func (f VectorStoreFuncs) Delete(ctx context.Context, ids ...string) error {
+	return f.DeleteFunc(ctx, ids...)
+}
+
+// MemoryVectorStore is a VectorStore held in memory that searches by brute-force
+// cosine similarity, suitable for tests and up to tens of thousands of documents.
+// It is safe for concurrent use.
+type MemoryVectorStore struct {
+	mu   sync.RWMutex
+	docs map[string]VectorDocument
+}
+
+// NewMemoryVectorStore creates an empty MemoryVectorStore.
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{docs: make(map[string]VectorDocument)}
+}
+
+// Upsert implements VectorStore.
+func (s *MemoryVectorStore) Upsert(_ context.Context, docs ...VectorDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, doc := range docs {
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Query implements VectorStore.
+func (s *MemoryVectorStore) Query(_ context.Context, vector []float32, k int) ([]ScoredDocument, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := make([]ScoredDocument, 0, len(s.docs))
+	for _, doc := range s.docs {
+		results = append(results, ScoredDocument{VectorDocument: doc, Score: CosineSimilarity(vector, doc.Vector)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if k >= 0 && k < len(results) {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Delete implements VectorStore.
+func (s *MemoryVectorStore) Delete(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	return nil
+}
+
+// Len returns the number of documents in the store.
+func (s *MemoryVectorStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// IndexDocuments embeds the documents that have no vector yet with embedder and
+// upserts them all into store. It returns the tokens spent on embedding.
+func IndexDocuments(ctx context.Context, store VectorStore, embedder Embedder, docs ...VectorDocument) (Usage, error) {
+	var (
+		texts   []string
+		indexes []int
+	)
+	for i, doc := range docs {
+		if doc.Vector == nil {
+			texts = append(texts, doc.Text)
+			indexes = append(indexes, i)
+		}
+	}
+	var usage Usage
+	if len(texts) > 0 {
+		vectors, embedUsage, err := embedder.Embed(ctx, texts, WithInputType(constants.EmbedInputDocument))
+		if err != nil {
+			return embedUsage, err
+		}
+		usage = embedUsage
+		docs = append([]VectorDocument(nil), docs...)
+		for i, vector := range vectors {
+			docs[indexes[i]].Vector = vector
+		}
+	}
+	return usage, store.Upsert(ctx, docs...)
+}
@@ -0,0 +1,85 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// vertexEmbedBatchSize is the maximum number of instances per Vertex AI predict request.
+const vertexEmbedBatchSize = 250
+
+type vertexEmbedder struct {
+	project  string
+	location string
+	model    string
+	api      *httpAPI
+}
+
+// NewVertexEmbedder creates an Embedder for a Vertex AI text embedding model, e.g.
+// "text-embedding-005", in a Google Cloud project and location such as "us-central1".
+// token returns an OAuth2 access token for each request, e.g. from
+// golang.org/x/oauth2/google's default credentials; it is not cached here.
+// Client options can set the HTTP client, proxy and endpoint.
+func NewVertexEmbedder(project, location, model string, token func(ctx context.Context) (string, error), opts ...ClientOption) Embedder {
+	baseURL := fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+	api := newHTTPAPI(constants.ProviderVertex, baseURL, opts, func(ctx context.Context, req *http.Request) error {
+		t, err := token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	})
+	return &vertexEmbedder{project: project, location: location, model: model, api: api}
+}
+
+// Embed implements Embedder with the predict endpoint.
+func (e *vertexEmbedder) Embed(ctx context.Context, inputs []string, opts ...EmbedOption) ([][]float32, Usage, error) {
+	options := &EmbedOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	path := fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/google/models/%s:predict", e.project, e.location, e.model)
+	taskType := embedInputType(options.inputType, "RETRIEVAL_QUERY", "RETRIEVAL_DOCUMENT")
+
+	return embedBatches(ctx, inputs, options, vertexEmbedBatchSize, func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+		type instance struct {
+			Content  string `json:"content"`
+			TaskType string `json:"task_type"`
+		}
+		req := struct {
+			Instances  []instance `json:"instances"`
+			Parameters struct {
+				OutputDimensionality *int `json:"outputDimensionality,omitempty"`
+			} `json:"parameters"`
+		}{}
+		for _, text := range batch {
+			req.Instances = append(req.Instances, instance{Content: text, TaskType: taskType})
+		}
+		req.Parameters.OutputDimensionality = options.dimensions
+
+		var resp struct {
+			Predictions []struct {
+				Embeddings struct {
+					Values     []float32 `json:"values"`
+					Statistics struct {
+						TokenCount float64 `json:"token_count"`
+					} `json:"statistics"`
+				} `json:"embeddings"`
+			} `json:"predictions"`
+		}
+		if err := e.api.post(ctx, path, &req, &resp); err != nil {
+			return nil, Usage{}, err
+		}
+		vectors := make([][]float32, len(resp.Predictions))
+		tokens := 0
+		for i, prediction := range resp.Predictions {
+			vectors[i] = prediction.Embeddings.Values
+			tokens += int(prediction.Embeddings.Statistics.TokenCount)
+		}
+		return vectors, Usage{InputTokens: tokens, TotalTokens: tokens}, nil
+	})
+}
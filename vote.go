@@ -0,0 +1,126 @@
+package openllm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Aggregator picks the winning sample of SampleAndVote. It returns the index of
+// the winner in samples and the usage of any model calls it made.
+type Aggregator func(ctx context.Context, messages []Message, samples []Response) (winner int, usage Usage, err error)
+
+// MajorityVote returns an Aggregator that picks the most common answer, with
+// ties going to the earliest sample. Answers are compared after normalize; nil
+// compares them ignoring case and surrounding or repeated whitespace. Pass a
+// normalize that extracts the final answer when samples include reasoning.
+func MajorityVote(normalize func(answer string) string) Aggregator {
+	if normalize == nil {
+		normalize = func(answer string) string {
+			return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+		}
+	}
+	return func(ctx context.Context, messages []Message, samples []Response) (int, Usage, error) {
+		keys := make([]string, len(samples))
+		counts := make(map[string]int, len(samples))
+		for i, sample := range samples {
+			keys[i] = normalize(sample.Answer().Content())
+			counts[keys[i]]++
+		}
+		winner := 0
+		for i, key := range keys {
+			if counts[key] > counts[keys[winner]] {
+				winner = i
+			}
+		}
+		return winner, Usage{}, nil
+	}
+}
+
+// JudgeVote returns an Aggregator that shows the conversation and all answers
+// to judge and lets it pick the best one (see Classify).
+func JudgeVote(judge Model, opts ...ChatOption) Aggregator {
+	return func(ctx context.Context, messages []Message, samples []Response) (int, Usage, error) {
+		var sb strings.Builder
+		sb.WriteString("Conversation:\n" + renderTranscript(messages) + "\nCandidate answers:\n")
+		labels := make([]string, len(samples))
+		for i, sample := range samples {
+			labels[i] = strconv.Itoa(i + 1)
+			fmt.Fprintf(&sb, "\n[%d]\n%s\n", i+1, sample.Answer().Content())
+		}
+		sb.WriteString("\nWhich candidate answer is the most correct and complete?")
+
+		class, err := Classify(ctx, judge, sb.String(), labels, opts...)
+		if err != nil {
+			return 0, Usage{}, err
+		}
+		winner, _ := strconv.Atoi(class.Label)
+		return winner - 1, class.Response.Usage(), nil
+	}
+}
+
+// VoteResult is the outcome of SampleAndVote.
+type VoteResult struct {
+	// Response is the winning sample.
+	Response Response
+	// Winner is the index of the winning sample in Samples.
+	Winner int
+	// Samples are the successful samples, in request order.
+	Samples []Response
+	// Usage is the token usage of all samples and of the aggregator.
+	Usage Usage
+}
+
+// SampleAndVote requests n answers to messages concurrently and lets aggregate
+// pick one, trading cost for reliability (self-consistency). Sample i is sent
+// with WithSeed(i) after opts, so providers that support seeds return diverse
+// answers; set a temperature in opts for the others. Failed samples are left
+// out; the request fails only when all samples fail.
+func SampleAndVote(ctx context.Context, model Model, messages []Message, n int, aggregate Aggregator, opts ...ChatOption) (*VoteResult, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("sample and vote: n must be positive, got %d", n)
+	}
+	responses := make([]Response, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sampleOpts := append(opts[:len(opts):len(opts)], WithSeed(i))
+			responses[i], errs[i] = model.ChatCompletion(ctx, messages, sampleOpts...)
+		}(i)
+	}
+	wg.Wait()
+
+	result := &VoteResult{}
+	var firstErr error
+	for i, resp := range responses {
+		if resp != nil {
+			result.Usage = result.Usage.Add(resp.Usage())
+		}
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		result.Samples = append(result.Samples, resp)
+	}
+	if len(result.Samples) == 0 {
+		return result, firstErr
+	}
+
+	winner, usage, err := aggregate(ctx, messages, result.Samples)
+	result.Usage = result.Usage.Add(usage)
+	if err != nil {
+		return result, err
+	}
+	if winner < 0 || winner >= len(result.Samples) {
+		return result, fmt.Errorf("sample and vote: aggregator chose sample %d of %d", winner, len(result.Samples))
+	}
+	result.Winner, result.Response = winner, result.Samples[winner]
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package openllm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/thecxx/openllm/constants"
+)
+
+// voyageBaseURL is the Voyage AI API endpoint.
+const voyageBaseURL = "https://api.voyageai.com"
+
+type voyageReranker struct {
+	model string
+	api   *httpAPI
+}
+
+// NewVoyageReranker creates a Reranker for a Voyage AI rerank model, e.g. "rerank-2".
+// Client options can set the HTTP client, proxy and endpoint.
+func NewVoyageReranker(model, apiKey string, opts ...ClientOption) Reranker {
+	api := newHTTPAPI(constants.ProviderVoyage, voyageBaseURL, opts, func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return nil
+	})
+	return &voyageReranker{model: model, api: api}
+}
+
+// Rerank implements Reranker.
+func (r *voyageReranker) Rerank(ctx context.Context, query string, documents []string, opts ...RerankOption) ([]RerankResult, Usage, error) {
+	options := &RerankOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if len(documents) == 0 {
+		return nil, Usage{}, nil
+	}
+	req := struct {
+		Model     string   `json:"model"`
+		Query     string   `json:"query"`
+		Documents []string `json:"documents"`
+		TopK      int      `json:"top_k,omitempty"`
+	}{Model: r.model, Query: query, Documents: documents, TopK: options.topN}
+	var resp struct {
+		Data  []rerankScore `json:"data"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := r.api.post(ctx, "/v1/rerank", &req, &resp); err != nil {
+		return nil, Usage{}, err
+	}
+	results, err := rerankResults(documents, resp.Data)
+	tokens := resp.Usage.TotalTokens
+	return results, Usage{InputTokens: tokens, TotalTokens: tokens}, err
+}